@@ -0,0 +1,128 @@
+// Command passkeyclient runs the passkey ceremonies against a running auth
+// service with a software authenticator, no browser needed:
+//
+//	go run ./cmd/passkeyclient -url http://localhost:8001 -email a@b.co -password secret123
+//
+// It logs in with the password, registers a passkey, logs in with it, then
+// replays the same key from a clone with a stale counter, which must be refused.
+// The origin has to be one of WEBAUTHN_ORIGINS.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/teammachinist/tutuplapak/services/auth/internal/webauthn"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/webauthn/webauthntest"
+)
+
+type client struct {
+	baseURL string
+	token   string
+}
+
+func main() {
+	baseURL := flag.String("url", "http://localhost:8001", "auth service base URL")
+	origin := flag.String("origin", "http://localhost:8080", "origin the authenticator reports")
+	email := flag.String("email", "", "account email")
+	password := flag.String("password", "", "account password")
+	flag.Parse()
+
+	if *email == "" || *password == "" {
+		log.Fatalf("-email and -password are required")
+	}
+
+	c := &client{baseURL: *baseURL}
+	authenticator := webauthntest.New(*origin)
+
+	var login struct {
+		Token string `json:"token"`
+	}
+	c.must("POST", "/v1/login/email", map[string]string{"email": *email, "password": *password}, http.StatusOK, &login)
+	if login.Token == "" {
+		log.Fatalf("password login did not return a token, is two-factor enabled?")
+	}
+	c.token = login.Token
+
+	var creation struct {
+		PublicKey webauthn.CreationOptions `json:"publicKey"`
+	}
+	c.must("POST", "/v1/passkey/register/begin", nil, http.StatusOK, &creation)
+	registration, err := authenticator.Create(creation.PublicKey)
+	if err != nil {
+		log.Fatalf("create: %v", err)
+	}
+	var passkey struct {
+		ID string `json:"id"`
+	}
+	c.must("POST", "/v1/passkey/register/finish", map[string]any{"name": "passkeyclient", "credential": registration}, http.StatusCreated, &passkey)
+	fmt.Println("registered passkey", passkey.ID)
+
+	c.token = ""
+	clone := authenticator.Clone()
+
+	assertion := c.assert(authenticator, *email)
+	c.must("POST", "/v1/passkey/login/finish", map[string]any{"credential": assertion}, http.StatusOK, &login)
+	fmt.Println("logged in with passkey")
+
+	// The clone still holds the counter from before the login above
+	assertion = c.assert(clone, *email)
+	c.must("POST", "/v1/passkey/login/finish", map[string]any{"credential": assertion}, http.StatusUnauthorized, nil)
+	fmt.Println("cloned passkey refused")
+}
+
+func (c *client) assert(authenticator *webauthntest.Authenticator, email string) *webauthn.AssertionCredential {
+	var request struct {
+		PublicKey webauthn.RequestOptions `json:"publicKey"`
+	}
+	c.must("POST", "/v1/passkey/login/begin", map[string]string{"email": email}, http.StatusOK, &request)
+	assertion, err := authenticator.Get(request.PublicKey)
+	if err != nil {
+		log.Fatalf("get: %v", err)
+	}
+	return assertion
+}
+
+// must - Send body as JSON and fail unless the response has the wanted status
+func (c *client) must(method, path string, body any, want int, out any) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			log.Fatalf("%s %s: %v", method, path, err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		log.Fatalf("%s %s: %v", method, path, err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != want {
+		log.Fatalf("%s %s: got %d, want %d: %s", method, path, resp.StatusCode, want, data)
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			log.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+}
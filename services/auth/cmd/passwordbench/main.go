@@ -0,0 +1,97 @@
+// Command passwordbench times argon2id on this machine to pick the
+// PASSWORD_ARGON2_* settings. Run it where auth runs, with the same CPU limits:
+//
+//	go run ./cmd/passwordbench -target 250ms -max-memory 131072
+//
+// It prints how long one hash takes for a range of memory/iteration settings and
+// suggests the strongest one that stays under the target.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/teammachinist/tutuplapak/services/auth/internal/password"
+)
+
+func main() {
+	target := flag.Duration("target", 250*time.Millisecond, "longest acceptable time for one hash")
+	minMemory := flag.Uint("min-memory", 19*1024, "smallest memory cost to try, KiB")
+	maxMemory := flag.Uint("max-memory", 128*1024, "largest memory cost to try, KiB")
+	maxIterations := flag.Uint("max-iterations", 6, "largest iteration count to try")
+	parallelism := flag.Uint("parallelism", 2, "argon2id lanes, at most the CPUs one request may use")
+	rounds := flag.Int("rounds", 5, "hashes per setting, the median is reported")
+	flag.Parse()
+
+	if *parallelism < 1 || *parallelism > 255 {
+		log.Fatalf("parallelism must be between 1 and 255")
+	}
+
+	out := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(out, "memory (KiB)\titerations\tparallelism\tmedian")
+
+	var best *password.Params
+	for memory := uint32(*minMemory); memory <= uint32(*maxMemory); memory *= 2 {
+		for iterations := uint32(1); iterations <= uint32(*maxIterations); iterations++ {
+			params := password.DefaultParams()
+			params.Memory = memory
+			params.Iterations = iterations
+			params.Parallelism = uint8(*parallelism)
+
+			median, err := measure(params, *rounds)
+			if err != nil {
+				log.Fatalf("m=%d t=%d: %v", memory, iterations, err)
+			}
+			fmt.Fprintf(out, "%d\t%d\t%d\t%s\n", memory, iterations, params.Parallelism, median.Round(time.Millisecond))
+
+			if median > *target {
+				// More iterations at this memory only get slower
+				break
+			}
+			if best == nil || cost(params) > cost(*best) {
+				best = &params
+			}
+		}
+	}
+	out.Flush()
+
+	if best == nil {
+		fmt.Printf("\nNo setting hashes within %s, lower -min-memory or raise -target\n", *target)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\nSuggested for %s:\n", *target)
+	fmt.Printf("PASSWORD_ARGON2_MEMORY=%d\n", best.Memory)
+	fmt.Printf("PASSWORD_ARGON2_ITERATIONS=%d\n", best.Iterations)
+	fmt.Printf("PASSWORD_ARGON2_PARALLELISM=%d\n", best.Parallelism)
+	fmt.Printf("\nEvery concurrent login holds %d MiB while hashing, size memory limits for it.\n", best.Memory/1024)
+}
+
+func measure(params password.Params, rounds int) (time.Duration, error) {
+	hasher, err := password.NewHasher(params)
+	if err != nil {
+		return 0, err
+	}
+
+	timings := make([]time.Duration, 0, rounds)
+	for i := 0; i < max(rounds, 1); i++ {
+		start := time.Now()
+		if _, err := hasher.Hash("correct horse battery staple"); err != nil {
+			return 0, err
+		}
+		timings = append(timings, time.Since(start))
+	}
+
+	slices.Sort(timings)
+	return timings[len(timings)/2], nil
+}
+
+// cost - work an attacker pays per guess; memory weighs most as it resists GPUs
+func cost(params password.Params) uint64 {
+	return uint64(params.Memory) * uint64(params.Iterations)
+}
@@ -109,8 +109,8 @@ func ValidatePassword(password string) *ValidationError {
 		return &ValidationError{Field: "password", Message: "password must be at least 8 characters long"}
 	}
 
-	if len(password) > 32 {
-		return &ValidationError{Field: "password", Message: "password must be at most 32 characters long"}
+	if len(password) > 128 {
+		return &ValidationError{Field: "password", Message: "password must be at most 128 characters long"}
 	}
 
 	return nil
@@ -119,6 +119,36 @@ func (c *RedisCache) Exists(ctx context.Context, key string) (bool, error) {
 	return exists, nil
 }
 
+// Increment - Atomically bump a counter, the expiration is set when the key is created
+// so the window is fixed from the first increment
+func (c *RedisCache) Increment(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	pipe := c.client.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.ExpireNX(ctx, key, expiration)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.ErrorCtx(ctx, "Redis INCR failed", "key", key, "error", err)
+		return 0, err
+	}
+
+	logger.DebugCtx(ctx, "Redis INCR success", "key", key, "value", incr.Val())
+	return incr.Val(), nil
+}
+
+// TTL - Remaining lifetime of a key, zero when the key does not exist or never expires
+func (c *RedisCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := c.client.TTL(ctx, key).Result()
+	if err != nil {
+		logger.ErrorCtx(ctx, "Redis TTL failed", "key", key, "error", err)
+		return 0, err
+	}
+
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
 func (c *RedisCache) Ping(ctx context.Context) error {
 	err := c.client.Ping(ctx).Err()
 	if err != nil {
@@ -2,17 +2,33 @@ package database
 
 import (
 	"context"
-	_ "embed"
+	"embed"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/teammachinist/tutuplapak/services/auth/internal/logger"
 )
 
-//go:embed migrations/001_create_users_auth_table.sql
-var migrationSQL string
+// Embed directory as auth now has multiple sql files, same as core
+
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+// Applied migrations are recorded by filename, so migrations that alter existing
+// tables run exactly once. Every migration is still idempotent (IF NOT EXISTS) as
+// databases created before tracking existed replay them once.
+const createMigrationsTableSQL = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    filename VARCHAR(255) PRIMARY KEY,
+    applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+// Serializes migrations across replicas starting at the same time
+const migrationLockID = 7_240_001
 
 //go:embed seeds/seeds_data.sql
 var seedSQL string
@@ -89,17 +105,8 @@ func (db *DB) GetStats() *pgxpool.Stat {
 
 // initializeDatabase handles migrations and seeding intelligently
 func (db *DB) initializeDatabase(ctx context.Context) error {
-	// Check if migration needed
-	migrationNeeded, err := db.isMigrationNeeded(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to check migration status: %w", err)
-	}
-
-	if migrationNeeded {
-		logger.InfoCtx(ctx, "Executing auth core database migration")
-		if err := db.runMigrations(ctx); err != nil {
-			return fmt.Errorf("migration failed: %w", err)
-		}
+	if err := db.runMigrations(ctx); err != nil {
+		return fmt.Errorf("migration failed: %w", err)
 	}
 
 	// Check if seeding needed (development only)
@@ -120,50 +127,88 @@ func (db *DB) initializeDatabase(ctx context.Context) error {
 	return nil
 }
 
-func (db *DB) isMigrationNeeded(ctx context.Context) (bool, error) {
-	// Check if main table exists
-	var exists bool
-	err := db.Pool.QueryRow(ctx, `
-        SELECT EXISTS (
-            SELECT FROM information_schema.tables 
-            WHERE table_schema = 'public' 
-            AND table_name = 'users_auth'
-        )
-    `).Scan(&exists)
-
+func (db *DB) isSeedingNeeded(ctx context.Context) (bool, error) {
+	// Check if any seed data exists
+	var count int
+	err := db.Pool.QueryRow(ctx, "SELECT COUNT(*) FROM users_auth").Scan(&count)
 	if err != nil {
-		return false, fmt.Errorf("failed to check table existence: %w", err)
+		return false, fmt.Errorf("failed to check seed data: %w", err)
 	}
 
-	return !exists, nil // Need migration if table doesn't exist
+	return count == 0, nil // Need seeding if no data exists
 }
 
-func (db *DB) isSeedingNeeded(ctx context.Context) (bool, error) {
-	// First check if table exists
-	migrationNeeded, err := db.isMigrationNeeded(ctx)
+func (db *DB) runMigrations(ctx context.Context) error {
+	entries, err := migrationFS.ReadDir("migrations")
 	if err != nil {
-		return false, err
+		return fmt.Errorf("failed to read migrations directory: %w", err)
 	}
 
-	if migrationNeeded {
-		return false, nil // Can't seed if table doesn't exist
+	// Sort files to ensure order (001_, 002_, etc.)
+	var filenames []string
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".sql") {
+			filenames = append(filenames, entry.Name())
+		}
 	}
+	sort.Strings(filenames)
 
-	// Check if any seed data exists
-	var count int
-	err = db.Pool.QueryRow(ctx, "SELECT COUNT(*) FROM users_auth").Scan(&count)
+	conn, err := db.Pool.Acquire(ctx)
 	if err != nil {
-		return false, fmt.Errorf("failed to check seed data: %w", err)
+		return fmt.Errorf("failed to acquire connection: %w", err)
 	}
+	defer conn.Release()
 
-	return count == 0, nil // Need seeding if no data exists
-}
+	// Session-level lock, held on this connection until unlocked
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID)
 
-func (db *DB) runMigrations(ctx context.Context) error {
-	_, err := db.Pool.Exec(ctx, migrationSQL)
-	if err != nil {
-		return fmt.Errorf("failed to execute migrations: %w", err)
+	if _, err := conn.Exec(ctx, createMigrationsTableSQL); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	// Execute pending migrations in order
+	for _, filename := range filenames {
+		var applied bool
+		err := conn.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM schema_migrations WHERE filename = $1)", filename).Scan(&applied)
+		if err != nil {
+			return fmt.Errorf("failed to check migration %s: %w", filename, err)
+		}
+		if applied {
+			continue
+		}
+
+		migrationSQL, err := migrationFS.ReadFile("migrations/" + filename)
+		if err != nil {
+			return fmt.Errorf("failed to read migration %s: %w", filename, err)
+		}
+
+		logger.InfoCtx(ctx, "Executing auth database migration", "file", filename)
+
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to begin migration %s: %w", filename, err)
+		}
+
+		if _, err := tx.Exec(ctx, string(migrationSQL)); err != nil {
+			tx.Rollback(ctx)
+			return fmt.Errorf("failed to execute migration %s: %w", filename, err)
+		}
+
+		if _, err := tx.Exec(ctx, "INSERT INTO schema_migrations (filename) VALUES ($1)", filename); err != nil {
+			tx.Rollback(ctx)
+			return fmt.Errorf("failed to record migration %s: %w", filename, err)
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return fmt.Errorf("failed to commit migration %s: %w", filename, err)
+		}
+
+		logger.InfoCtx(ctx, "Applied migration", "file", filename)
 	}
+
 	return nil
 }
 
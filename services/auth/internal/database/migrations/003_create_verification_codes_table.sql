@@ -0,0 +1,18 @@
+-- How the account was created decides where codes are delivered (email or SMS)
+ALTER TABLE users_auth ADD COLUMN IF NOT EXISTS registered_via VARCHAR(10) NOT NULL DEFAULT 'email';
+UPDATE users_auth SET registered_via = 'phone' WHERE (email IS NULL OR email = '') AND phone IS NOT NULL;
+
+-- One-time codes (password reset, ...), only the hash is stored
+CREATE TABLE IF NOT EXISTS verification_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_auth_id UUID NOT NULL REFERENCES users_auth(id) ON DELETE CASCADE,
+    purpose VARCHAR(32) NOT NULL,
+    target VARCHAR(255) NOT NULL,
+    code_hash VARCHAR(64) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    used BOOLEAN NOT NULL DEFAULT FALSE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_verification_codes_user_purpose ON verification_codes(user_auth_id, purpose) WHERE used = FALSE;
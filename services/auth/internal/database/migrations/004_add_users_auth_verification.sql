@@ -0,0 +1,3 @@
+-- Set when an email/phone is linked through a confirmed OTP
+ALTER TABLE users_auth ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users_auth ADD COLUMN IF NOT EXISTS phone_verified BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,42 @@
+-- Roles are granted to accounts, permissions to roles. Both end up in the access token.
+CREATE TABLE IF NOT EXISTS roles (
+    name VARCHAR(32) PRIMARY KEY,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    is_default BOOLEAN NOT NULL DEFAULT FALSE -- Granted on registration
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role VARCHAR(32) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    permission VARCHAR(64) NOT NULL,
+    PRIMARY KEY (role, permission)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_auth_id UUID NOT NULL REFERENCES users_auth(id) ON DELETE CASCADE,
+    role VARCHAR(32) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_auth_id, role)
+);
+
+INSERT INTO roles (name, description, is_default) VALUES
+    ('buyer', 'Can purchase products', TRUE),
+    ('seller', 'Can list and manage own products', TRUE),
+    ('admin', 'Can manage users and any product', FALSE)
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('buyer', 'purchase:create'),
+    ('seller', 'product:create'),
+    ('seller', 'product:update'),
+    ('seller', 'product:delete'),
+    ('admin', 'purchase:create'),
+    ('admin', 'product:create'),
+    ('admin', 'product:update'),
+    ('admin', 'product:delete'),
+    ('admin', 'user:manage')
+ON CONFLICT (role, permission) DO NOTHING;
+
+-- Existing accounts keep what they could already do
+INSERT INTO user_roles (user_auth_id, role)
+SELECT u.id, r.name FROM users_auth u CROSS JOIN roles r WHERE r.is_default
+ON CONFLICT (user_auth_id, role) DO NOTHING;
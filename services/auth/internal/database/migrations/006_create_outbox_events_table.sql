@@ -0,0 +1,23 @@
+-- Core's user.id, set once the registration saga created the profile. NULL while pending.
+ALTER TABLE users_auth ADD COLUMN IF NOT EXISTS user_id UUID;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_auth_user_id ON users_auth(user_id);
+
+-- Transactional outbox: events are written with the change that caused them and
+-- delivered to other services by the dispatcher until they succeed
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    aggregate_id UUID NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    processed BOOLEAN NOT NULL DEFAULT FALSE,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_due ON outbox_events(next_attempt_at) WHERE processed = FALSE;
+
+-- At most one undelivered event of a type per aggregate
+CREATE UNIQUE INDEX IF NOT EXISTS idx_outbox_events_pending ON outbox_events(event_type, aggregate_id) WHERE processed = FALSE;
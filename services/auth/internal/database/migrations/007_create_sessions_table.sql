@@ -0,0 +1,25 @@
+-- One row per login. The id is the refresh token family and the access token's
+-- sid claim, so revoking a session ends both.
+CREATE TABLE IF NOT EXISTS sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_auth_id UUID NOT NULL REFERENCES users_auth(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id) WHERE revoked_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_sessions_user_auth_id ON sessions(user_auth_id) WHERE revoked_at IS NULL;
+
+-- Logins from before sessions existed keep working: their live refresh token
+-- families become sessions without device details
+INSERT INTO sessions (id, user_auth_id, user_id, created_at, last_seen_at, expires_at)
+SELECT family_id, user_auth_id, user_id, MIN(created_at), MAX(created_at), MAX(expires_at)
+FROM refresh_tokens
+WHERE revoked = FALSE AND expires_at > CURRENT_TIMESTAMP
+GROUP BY family_id, user_auth_id, user_id
+ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,21 @@
+-- Authenticator app (TOTP) enrollment, at most one per account. enabled_at stays
+-- NULL until the first code is confirmed; last_used_step keeps a code from
+-- being accepted twice.
+CREATE TABLE IF NOT EXISTS totp_factors (
+    user_auth_id UUID PRIMARY KEY REFERENCES users_auth(id) ON DELETE CASCADE,
+    secret VARCHAR(64) NOT NULL,
+    enabled_at TIMESTAMP WITH TIME ZONE,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Single-use codes for when the authenticator is lost, only the hash is stored
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_auth_id UUID NOT NULL REFERENCES users_auth(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_recovery_codes_user_hash ON recovery_codes(user_auth_id, code_hash);
@@ -0,0 +1,15 @@
+-- WebAuthn passkeys, any number per account. sign_count is the authenticator's
+-- counter from the last login; one that stops increasing points to a cloned key.
+CREATE TABLE IF NOT EXISTS passkey_credentials (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_auth_id UUID NOT NULL REFERENCES users_auth(id) ON DELETE CASCADE,
+    credential_id BYTEA NOT NULL UNIQUE,
+    public_key BYTEA NOT NULL, -- COSE_Key
+    sign_count BIGINT NOT NULL DEFAULT 0,
+    aaguid UUID NOT NULL,
+    name VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_passkey_credentials_user_auth_id ON passkey_credentials(user_auth_id);
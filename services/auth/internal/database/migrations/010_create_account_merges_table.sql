@@ -0,0 +1,24 @@
+-- One row per account folded into another. The merged account's users_auth row
+-- is deleted by the merge, this is what's left of it: who it was, who proved it
+-- and what moved. No foreign keys, the record outlives both accounts.
+CREATE TABLE IF NOT EXISTS account_merges (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    target_user_auth_id UUID NOT NULL,
+    source_user_auth_id UUID NOT NULL,
+    target_user_id UUID NOT NULL,
+    source_user_id UUID NOT NULL,
+    source_email VARCHAR(255) NOT NULL DEFAULT '',
+    source_phone VARCHAR(20) NOT NULL DEFAULT '',
+    verified_via VARCHAR(10) NOT NULL,
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    -- pending until Core and Files have moved the merged user's products and files
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    products_moved INTEGER NOT NULL DEFAULT 0,
+    files_moved INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_merges_target ON account_merges(target_user_auth_id);
+CREATE INDEX IF NOT EXISTS idx_account_merges_source_pending ON account_merges(source_user_auth_id) WHERE status = 'pending';
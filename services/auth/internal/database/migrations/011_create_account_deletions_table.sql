@@ -0,0 +1,19 @@
+-- One row per deleted account. The users_auth row goes with the request, this
+-- tracks the rest: Core deleting the profile, products and files and scrubbing
+-- purchases. It stays pending until Core reports back, the account.deleted
+-- event is retried until then. Only ids are kept, nothing that identifies the
+-- person.
+CREATE TABLE IF NOT EXISTS account_deletions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_auth_id UUID NOT NULL,
+    user_id UUID,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    products_deleted INTEGER NOT NULL DEFAULT 0,
+    files_deleted INTEGER NOT NULL DEFAULT 0,
+    purchases_scrubbed INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_deletions_user_auth ON account_deletions(user_auth_id);
+CREATE INDEX IF NOT EXISTS idx_account_deletions_pending ON account_deletions(created_at) WHERE status = 'pending';
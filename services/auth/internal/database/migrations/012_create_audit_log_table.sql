@@ -0,0 +1,35 @@
+-- Append-only record of auth events: registrations, logins, contact linking,
+-- rejected tokens and password changes. user_auth_id is not a foreign key so
+-- entries outlive a deleted account; they go when older than AUDIT_RETENTION.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    event_type VARCHAR(32) NOT NULL,
+    outcome VARCHAR(16) NOT NULL, -- success, failure, challenged (second factor required)
+    user_auth_id UUID,
+    identifier VARCHAR(255) NOT NULL DEFAULT '', -- Email/phone given, the only trace of a failure without an account
+    method VARCHAR(16) NOT NULL DEFAULT '', -- Login method or link channel
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    request_id VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_user_auth ON audit_log(user_auth_id, created_at DESC) WHERE user_auth_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_audit_log_event_type ON audit_log(event_type, created_at DESC);
+
+-- Entries are never changed, only aged out
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_no_update ON audit_log;
+CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log
+FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'audit:read')
+ON CONFLICT (role, permission) DO NOTHING;
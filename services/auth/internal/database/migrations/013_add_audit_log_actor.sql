@@ -0,0 +1,9 @@
+-- Impersonation: the admin acting as the user the entry is attributed to.
+-- Like user_auth_id it is not a foreign key, entries outlive the admin account.
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS actor_user_id UUID;
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor_user_id, created_at DESC) WHERE actor_user_id IS NOT NULL;
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'user:impersonate')
+ON CONFLICT (role, permission) DO NOTHING;
@@ -0,0 +1,4 @@
+-- Admins maintain the product category taxonomy in core
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'category:manage')
+ON CONFLICT (role, permission) DO NOTHING;
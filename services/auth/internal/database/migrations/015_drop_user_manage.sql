@@ -0,0 +1,7 @@
+-- Nothing ever checked user:manage, and admins only manage their own products
+-- like any seller. What they can do beyond that is granted on its own.
+DELETE FROM role_permissions WHERE permission = 'user:manage';
+
+UPDATE roles
+SET description = 'Can read the audit log, impersonate users and manage categories'
+WHERE name = 'admin';
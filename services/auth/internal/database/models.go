@@ -10,11 +10,152 @@ import (
 	"github.com/google/uuid"
 )
 
+type AccountDeletions struct {
+	ID                uuid.UUID  `json:"id"`
+	UserAuthID        uuid.UUID  `json:"user_auth_id"`
+	UserID            *uuid.UUID `json:"user_id"`
+	Status            string     `json:"status"`
+	ProductsDeleted   int32      `json:"products_deleted"`
+	FilesDeleted      int32      `json:"files_deleted"`
+	PurchasesScrubbed int32      `json:"purchases_scrubbed"`
+	CreatedAt         time.Time  `json:"created_at"`
+	CompletedAt       *time.Time `json:"completed_at"`
+}
+
+type AccountMerges struct {
+	ID               uuid.UUID  `json:"id"`
+	TargetUserAuthID uuid.UUID  `json:"target_user_auth_id"`
+	SourceUserAuthID uuid.UUID  `json:"source_user_auth_id"`
+	TargetUserID     uuid.UUID  `json:"target_user_id"`
+	SourceUserID     uuid.UUID  `json:"source_user_id"`
+	SourceEmail      string     `json:"source_email"`
+	SourcePhone      string     `json:"source_phone"`
+	VerifiedVia      string     `json:"verified_via"`
+	IpAddress        string     `json:"ip_address"`
+	UserAgent        string     `json:"user_agent"`
+	Status           string     `json:"status"`
+	ProductsMoved    int32      `json:"products_moved"`
+	FilesMoved       int32      `json:"files_moved"`
+	CreatedAt        time.Time  `json:"created_at"`
+	CompletedAt      *time.Time `json:"completed_at"`
+}
+
+type AuditLog struct {
+	ID          uuid.UUID  `json:"id"`
+	EventType   string     `json:"event_type"`
+	Outcome     string     `json:"outcome"`
+	UserAuthID  *uuid.UUID `json:"user_auth_id"`
+	Identifier  string     `json:"identifier"`
+	Method      string     `json:"method"`
+	Reason      string     `json:"reason"`
+	IpAddress   string     `json:"ip_address"`
+	UserAgent   string     `json:"user_agent"`
+	RequestID   string     `json:"request_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ActorUserID *uuid.UUID `json:"actor_user_id"`
+}
+
+type OutboxEvents struct {
+	ID            uuid.UUID `json:"id"`
+	AggregateID   uuid.UUID `json:"aggregate_id"`
+	EventType     string    `json:"event_type"`
+	Payload       []byte    `json:"payload"`
+	Attempts      int32     `json:"attempts"`
+	LastError     string    `json:"last_error"`
+	Processed     bool      `json:"processed"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type PasskeyCredentials struct {
+	ID           uuid.UUID  `json:"id"`
+	UserAuthID   uuid.UUID  `json:"user_auth_id"`
+	CredentialID []byte     `json:"credential_id"`
+	PublicKey    []byte     `json:"public_key"`
+	SignCount    int64      `json:"sign_count"`
+	Aaguid       uuid.UUID  `json:"aaguid"`
+	Name         string     `json:"name"`
+	CreatedAt    time.Time  `json:"created_at"`
+	LastUsedAt   *time.Time `json:"last_used_at"`
+}
+
+type RecoveryCodes struct {
+	ID         uuid.UUID  `json:"id"`
+	UserAuthID uuid.UUID  `json:"user_auth_id"`
+	CodeHash   string     `json:"code_hash"`
+	UsedAt     *time.Time `json:"used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type RefreshTokens struct {
+	ID         uuid.UUID `json:"id"`
+	UserAuthID uuid.UUID `json:"user_auth_id"`
+	UserID     uuid.UUID `json:"user_id"`
+	FamilyID   uuid.UUID `json:"family_id"`
+	TokenHash  string    `json:"token_hash"`
+	Revoked    bool      `json:"revoked"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type RolePermissions struct {
+	Role       string `json:"role"`
+	Permission string `json:"permission"`
+}
+
+type Roles struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	IsDefault   bool   `json:"is_default"`
+}
+
+type Sessions struct {
+	ID         uuid.UUID  `json:"id"`
+	UserAuthID uuid.UUID  `json:"user_auth_id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	UserAgent  string     `json:"user_agent"`
+	IpAddress  string     `json:"ip_address"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+type TotpFactors struct {
+	UserAuthID   uuid.UUID  `json:"user_auth_id"`
+	Secret       string     `json:"secret"`
+	EnabledAt    *time.Time `json:"enabled_at"`
+	LastUsedStep int64      `json:"last_used_step"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type UserRoles struct {
+	UserAuthID uuid.UUID `json:"user_auth_id"`
+	Role       string    `json:"role"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
 type UsersAuth struct {
-	ID           uuid.UUID `json:"id"`
-	Email        string    `json:"email"`
-	Phone        string    `json:"phone"`
-	PasswordHash string    `json:"password_hash"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID            uuid.UUID  `json:"id"`
+	Email         string     `json:"email"`
+	Phone         string     `json:"phone"`
+	PasswordHash  string     `json:"password_hash"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	RegisteredVia string     `json:"registered_via"`
+	EmailVerified bool       `json:"email_verified"`
+	PhoneVerified bool       `json:"phone_verified"`
+	UserID        *uuid.UUID `json:"user_id"`
+}
+
+type VerificationCodes struct {
+	ID         uuid.UUID `json:"id"`
+	UserAuthID uuid.UUID `json:"user_auth_id"`
+	Purpose    string    `json:"purpose"`
+	Target     string    `json:"target"`
+	CodeHash   string    `json:"code_hash"`
+	Attempts   int32     `json:"attempts"`
+	Used       bool      `json:"used"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
 }
@@ -11,16 +11,85 @@ import (
 )
 
 type Querier interface {
+	AssignDefaultRoles(ctx context.Context, userAuthID uuid.UUID) error
 	CheckEmailExists(ctx context.Context, email string) (bool, error)
 	CheckPhoneExists(ctx context.Context, phone string) (bool, error)
+	ClaimOutboxEvents(ctx context.Context, arg ClaimOutboxEventsParams) ([]OutboxEvents, error)
+	CompleteAccountDeletion(ctx context.Context, arg CompleteAccountDeletionParams) error
+	CompleteAccountMerge(ctx context.Context, arg CompleteAccountMergeParams) error
+	CompleteOutboxEvent(ctx context.Context, id uuid.UUID) error
+	ConsumeVerificationCode(ctx context.Context, id uuid.UUID) (int64, error)
+	CountRecoveryCodes(ctx context.Context, userAuthID uuid.UUID) (int64, error)
+	CreateAccountDeletion(ctx context.Context, arg CreateAccountDeletionParams) (AccountDeletions, error)
+	CreateAccountMerge(ctx context.Context, arg CreateAccountMergeParams) (AccountMerges, error)
+	// Without a user_auth_id the entry goes to the account the identifier belongs
+	// to, if any, so failed logins show up on the account they were aimed at
+	CreateAuditLogEntry(ctx context.Context, arg CreateAuditLogEntryParams) error
+	CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) (OutboxEvents, error)
+	CreatePasskeyCredential(ctx context.Context, arg CreatePasskeyCredentialParams) (PasskeyCredentials, error)
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error
+	CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshTokens, error)
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Sessions, error)
 	CreateUserByEmail(ctx context.Context, arg CreateUserByEmailParams) (CreateUserByEmailRow, error)
 	CreateUserByPhone(ctx context.Context, arg CreateUserByPhoneParams) (CreateUserByPhoneRow, error)
+	CreateVerificationCode(ctx context.Context, arg CreateVerificationCodeParams) (VerificationCodes, error)
+	// Completed merges into an account being deleted, they name the accounts it
+	// absorbed
+	DeleteAccountMerges(ctx context.Context, targetUserAuthID uuid.UUID) error
+	// Events of an account being deleted and of the merges into it, their payloads
+	// hold email addresses and phone numbers
+	DeleteAccountOutboxEvents(ctx context.Context, aggregateID uuid.UUID) error
+	// Oldest first and in batches, so retention never holds a long lock
+	DeleteAuditLogBefore(ctx context.Context, arg DeleteAuditLogBeforeParams) (int64, error)
+	// The merged account of an account merge. Only deletes the row while it still
+	// belongs to the Core profile the merge moves.
+	DeleteMergedUserAuth(ctx context.Context, arg DeleteMergedUserAuthParams) (int64, error)
+	DeletePasskeyCredential(ctx context.Context, arg DeletePasskeyCredentialParams) (int64, error)
+	DeleteRecoveryCodes(ctx context.Context, userAuthID uuid.UUID) error
+	DeleteTOTPFactor(ctx context.Context, userAuthID uuid.UUID) error
 	DeleteUserAuth(ctx context.Context, id uuid.UUID) error
+	EnableTOTPFactor(ctx context.Context, arg EnableTOTPFactorParams) (int64, error)
+	EnqueueOutboxEvent(ctx context.Context, arg EnqueueOutboxEventParams) error
+	GetActiveVerificationCode(ctx context.Context, arg GetActiveVerificationCodeParams) (VerificationCodes, error)
+	GetPasskeyCredentialByCredentialID(ctx context.Context, credentialID []byte) (PasskeyCredentials, error)
+	GetPendingOutboxEvent(ctx context.Context, arg GetPendingOutboxEventParams) (OutboxEvents, error)
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (RefreshTokens, error)
+	GetSession(ctx context.Context, id uuid.UUID) (Sessions, error)
+	GetTOTPFactor(ctx context.Context, userAuthID uuid.UUID) (TotpFactors, error)
 	GetUserAuthByEmail(ctx context.Context, email string) (GetUserAuthByEmailRow, error)
 	GetUserAuthByID(ctx context.Context, id uuid.UUID) (GetUserAuthByIDRow, error)
 	GetUserAuthByPhone(ctx context.Context, phone string) (GetUserAuthByPhoneRow, error)
+	GetUserAuthByUserID(ctx context.Context, userID *uuid.UUID) (GetUserAuthByUserIDRow, error)
+	HasPendingAccountMerge(ctx context.Context, sourceUserAuthID uuid.UUID) (bool, error)
+	HasPendingAccountMergeInto(ctx context.Context, targetUserAuthID uuid.UUID) (bool, error)
+	IncrementVerificationCodeAttempts(ctx context.Context, id uuid.UUID) (int32, error)
+	InvalidateVerificationCodes(ctx context.Context, arg InvalidateVerificationCodesParams) error
+	ListAccountMerges(ctx context.Context, targetUserAuthID uuid.UUID) ([]AccountMerges, error)
+	ListActiveSessions(ctx context.Context, userID uuid.UUID) ([]Sessions, error)
+	// Newest first. Every filter is optional: NULL user/time bounds and an empty
+	// event type match everything.
+	ListAuditLog(ctx context.Context, arg ListAuditLogParams) ([]AuditLog, error)
+	ListPasskeyCredentials(ctx context.Context, userAuthID uuid.UUID) ([]PasskeyCredentials, error)
+	ListUserAuthPage(ctx context.Context, arg ListUserAuthPageParams) ([]ListUserAuthPageRow, error)
+	ListUserPermissions(ctx context.Context, userAuthID uuid.UUID) ([]string, error)
+	ListUserRoles(ctx context.Context, userAuthID uuid.UUID) ([]string, error)
+	RescheduleOutboxEvent(ctx context.Context, arg RescheduleOutboxEventParams) error
+	RevokeRefreshToken(ctx context.Context, id uuid.UUID) (int64, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error
+	RevokeSession(ctx context.Context, arg RevokeSessionParams) (int64, error)
+	RevokeUserRefreshTokens(ctx context.Context, userAuthID uuid.UUID) error
+	RevokeUserSessions(ctx context.Context, userAuthID uuid.UUID) ([]uuid.UUID, error)
+	SetUserAuthUserID(ctx context.Context, arg SetUserAuthUserIDParams) error
+	// Replaces an unconfirmed enrollment, returns no row once TOTP is enabled
+	StartTOTPEnrollment(ctx context.Context, arg StartTOTPEnrollmentParams) (TotpFactors, error)
+	TouchSession(ctx context.Context, arg TouchSessionParams) (int64, error)
 	UpdateUserAuthEmail(ctx context.Context, arg UpdateUserAuthEmailParams) (UsersAuth, error)
+	UpdateUserAuthPassword(ctx context.Context, arg UpdateUserAuthPasswordParams) error
 	UpdateUserAuthPhone(ctx context.Context, arg UpdateUserAuthPhoneParams) (UsersAuth, error)
+	// Compare-and-set on the counter so two logins can't both move it to the same value
+	UsePasskeyCredential(ctx context.Context, arg UsePasskeyCredentialParams) (int64, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error)
+	UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (int64, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,9 @@
+-- name: CreateAccountDeletion :one
+INSERT INTO account_deletions (user_auth_id, user_id)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: CompleteAccountDeletion :exec
+UPDATE account_deletions
+SET status = 'completed', products_deleted = $2, files_deleted = $3, purchases_scrubbed = $4, completed_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND status = 'pending';
@@ -0,0 +1,31 @@
+-- name: CreateAccountMerge :one
+INSERT INTO account_merges (
+    target_user_auth_id, source_user_auth_id, target_user_id, source_user_id,
+    source_email, source_phone, verified_via, ip_address, user_agent
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: ListAccountMerges :many
+SELECT * FROM account_merges
+WHERE target_user_auth_id = $1
+ORDER BY created_at DESC;
+
+-- name: CompleteAccountMerge :exec
+UPDATE account_merges
+SET status = 'completed', products_moved = $2, files_moved = $3, completed_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND status = 'pending';
+
+-- name: HasPendingAccountMerge :one
+SELECT EXISTS(
+    SELECT 1 FROM account_merges WHERE source_user_auth_id = $1 AND status = 'pending'
+) AS exists;
+
+-- name: HasPendingAccountMergeInto :one
+SELECT EXISTS(
+    SELECT 1 FROM account_merges WHERE target_user_auth_id = $1 AND status = 'pending'
+) AS exists;
+
+-- Completed merges into an account being deleted, they name the accounts it
+-- absorbed
+-- name: DeleteAccountMerges :exec
+DELETE FROM account_merges WHERE target_user_auth_id = $1 AND status = 'completed';
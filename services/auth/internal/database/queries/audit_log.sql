@@ -0,0 +1,30 @@
+-- Without a user_auth_id the entry goes to the account the identifier belongs
+-- to, if any, so failed logins show up on the account they were aimed at
+-- name: CreateAuditLogEntry :exec
+INSERT INTO audit_log (event_type, outcome, user_auth_id, identifier, method, reason, ip_address, user_agent, request_id, actor_user_id)
+VALUES (
+    @event_type, @outcome,
+    COALESCE(sqlc.narg('user_auth_id')::uuid, (
+        SELECT id FROM users_auth
+        WHERE @identifier::text <> '' AND (email = @identifier::text OR phone = @identifier::text)
+        LIMIT 1
+    )),
+    @identifier::text, @method, @reason, @ip_address, @user_agent, @request_id, sqlc.narg('actor_user_id')
+);
+
+-- Newest first. Every filter is optional: NULL user/time bounds and an empty
+-- event type match everything.
+-- name: ListAuditLog :many
+SELECT * FROM audit_log
+WHERE (sqlc.narg('user_auth_id')::uuid IS NULL OR user_auth_id = sqlc.narg('user_auth_id')::uuid)
+  AND (@event_type::text = '' OR event_type = @event_type::text)
+  AND (sqlc.narg('created_from')::timestamptz IS NULL OR created_at >= sqlc.narg('created_from')::timestamptz)
+  AND (sqlc.narg('created_to')::timestamptz IS NULL OR created_at < sqlc.narg('created_to')::timestamptz)
+ORDER BY created_at DESC, id DESC
+LIMIT @page_limit::int OFFSET @page_offset::int;
+
+-- Oldest first and in batches, so retention never holds a long lock
+-- name: DeleteAuditLogBefore :execrows
+DELETE FROM audit_log WHERE id IN (
+    SELECT id FROM audit_log WHERE created_at < $1 ORDER BY created_at LIMIT $2
+);
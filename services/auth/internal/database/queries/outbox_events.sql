@@ -0,0 +1,39 @@
+-- name: CreateOutboxEvent :one
+INSERT INTO outbox_events (aggregate_id, event_type, payload, next_attempt_at)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: EnqueueOutboxEvent :exec
+INSERT INTO outbox_events (aggregate_id, event_type, payload)
+VALUES ($1, $2, $3)
+ON CONFLICT (event_type, aggregate_id) WHERE processed = FALSE DO NOTHING;
+
+-- name: GetPendingOutboxEvent :one
+SELECT * FROM outbox_events
+WHERE event_type = $1 AND aggregate_id = $2 AND processed = FALSE;
+
+-- name: ClaimOutboxEvents :many
+UPDATE outbox_events SET attempts = attempts + 1, next_attempt_at = $1
+WHERE id IN (
+    SELECT id FROM outbox_events
+    WHERE processed = FALSE AND next_attempt_at <= CURRENT_TIMESTAMP
+    ORDER BY next_attempt_at
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: RescheduleOutboxEvent :exec
+UPDATE outbox_events SET last_error = $2, next_attempt_at = $3 WHERE id = $1 AND processed = FALSE;
+
+-- name: CompleteOutboxEvent :exec
+UPDATE outbox_events SET processed = TRUE, last_error = '' WHERE id = $1;
+
+-- Events of an account being deleted and of the merges into it, their payloads
+-- hold email addresses and phone numbers
+-- name: DeleteAccountOutboxEvents :exec
+DELETE FROM outbox_events
+WHERE aggregate_id = $1
+   OR aggregate_id IN (
+       SELECT id FROM account_merges WHERE target_user_auth_id = $1 AND status = 'completed'
+   );
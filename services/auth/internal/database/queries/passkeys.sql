@@ -0,0 +1,18 @@
+-- name: CreatePasskeyCredential :one
+INSERT INTO passkey_credentials (user_auth_id, credential_id, public_key, sign_count, aaguid, name)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetPasskeyCredentialByCredentialID :one
+SELECT * FROM passkey_credentials WHERE credential_id = $1;
+
+-- name: ListPasskeyCredentials :many
+SELECT * FROM passkey_credentials WHERE user_auth_id = $1 ORDER BY created_at;
+
+-- name: UsePasskeyCredential :execrows
+-- Compare-and-set on the counter so two logins can't both move it to the same value
+UPDATE passkey_credentials SET sign_count = @new_sign_count, last_used_at = CURRENT_TIMESTAMP
+WHERE id = @id AND sign_count = @old_sign_count;
+
+-- name: DeletePasskeyCredential :execrows
+DELETE FROM passkey_credentials WHERE id = $1 AND user_auth_id = $2;
@@ -0,0 +1,16 @@
+-- name: CreateRefreshToken :one
+INSERT INTO refresh_tokens (user_auth_id, user_id, family_id, token_hash, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetRefreshTokenByHash :one
+SELECT * FROM refresh_tokens WHERE token_hash = $1;
+
+-- name: RevokeRefreshToken :execrows
+UPDATE refresh_tokens SET revoked = TRUE WHERE id = $1 AND revoked = FALSE;
+
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens SET revoked = TRUE WHERE family_id = $1 AND revoked = FALSE;
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens SET revoked = TRUE WHERE user_auth_id = $1 AND revoked = FALSE;
@@ -0,0 +1,13 @@
+-- name: AssignDefaultRoles :exec
+INSERT INTO user_roles (user_auth_id, role)
+SELECT $1, name FROM roles WHERE is_default
+ON CONFLICT (user_auth_id, role) DO NOTHING;
+
+-- name: ListUserRoles :many
+SELECT role FROM user_roles WHERE user_auth_id = $1 ORDER BY role;
+
+-- name: ListUserPermissions :many
+SELECT DISTINCT rp.permission FROM role_permissions rp
+JOIN user_roles ur ON ur.role = rp.role
+WHERE ur.user_auth_id = $1
+ORDER BY rp.permission;
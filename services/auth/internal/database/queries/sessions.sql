@@ -0,0 +1,25 @@
+-- name: CreateSession :one
+INSERT INTO sessions (user_auth_id, user_id, user_agent, ip_address, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetSession :one
+SELECT * FROM sessions WHERE id = $1;
+
+-- name: TouchSession :execrows
+UPDATE sessions SET last_seen_at = CURRENT_TIMESTAMP, expires_at = $2
+WHERE id = $1 AND revoked_at IS NULL;
+
+-- name: ListActiveSessions :many
+SELECT * FROM sessions
+WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+ORDER BY last_seen_at DESC;
+
+-- name: RevokeSession :execrows
+UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: RevokeUserSessions :many
+UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP
+WHERE user_auth_id = $1 AND revoked_at IS NULL
+RETURNING id;
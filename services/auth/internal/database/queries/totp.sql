@@ -0,0 +1,35 @@
+-- name: StartTOTPEnrollment :one
+-- Replaces an unconfirmed enrollment, returns no row once TOTP is enabled
+INSERT INTO totp_factors (user_auth_id, secret)
+VALUES ($1, $2)
+ON CONFLICT (user_auth_id) DO UPDATE
+SET secret = EXCLUDED.secret, last_used_step = 0, created_at = CURRENT_TIMESTAMP
+WHERE totp_factors.enabled_at IS NULL
+RETURNING *;
+
+-- name: GetTOTPFactor :one
+SELECT * FROM totp_factors WHERE user_auth_id = $1;
+
+-- name: EnableTOTPFactor :execrows
+UPDATE totp_factors SET enabled_at = CURRENT_TIMESTAMP, last_used_step = $2
+WHERE user_auth_id = $1 AND enabled_at IS NULL;
+
+-- name: UseTOTPStep :execrows
+UPDATE totp_factors SET last_used_step = $2
+WHERE user_auth_id = $1 AND enabled_at IS NOT NULL AND last_used_step < $2;
+
+-- name: DeleteTOTPFactor :exec
+DELETE FROM totp_factors WHERE user_auth_id = $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes (user_auth_id, code_hash) VALUES ($1, $2);
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes WHERE user_auth_id = $1;
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes SET used_at = CURRENT_TIMESTAMP
+WHERE user_auth_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: CountRecoveryCodes :one
+SELECT COUNT(*) FROM recovery_codes WHERE user_auth_id = $1 AND used_at IS NULL;
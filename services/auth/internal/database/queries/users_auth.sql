@@ -4,19 +4,23 @@ SELECT
     COALESCE(email, '') as email,
     COALESCE(phone, '') as phone,
     password_hash,
-    created_at
+    created_at,
+    registered_via,
+    user_id
 FROM users_auth
 WHERE id = $1;
 
 -- name: CreateUserByPhone :one
-INSERT INTO users_auth (phone, password_hash)
-VALUES ($1, $2)
+INSERT INTO users_auth (phone, password_hash, registered_via)
+VALUES ($1, $2, 'phone')
 RETURNING 
     id, 
     COALESCE(email, '') as email,
     COALESCE(phone, '') as phone,       
     password_hash, 
-    created_at;
+    created_at,
+    registered_via,
+    user_id;
 
 -- name: GetUserAuthByPhone :one
 SELECT
@@ -24,7 +28,9 @@ SELECT
     COALESCE(email, '') as email,
     COALESCE(phone, '') as phone,
     password_hash,
-    created_at
+    created_at,
+    registered_via,
+    user_id
 FROM users_auth
 WHERE phone = $1;
 
@@ -32,14 +38,16 @@ WHERE phone = $1;
 SELECT EXISTS(SELECT 1 FROM users_auth WHERE phone = $1) as exists;
 
 -- name: CreateUserByEmail :one
-INSERT INTO users_auth (email, password_hash)
-VALUES ($1, $2)
+INSERT INTO users_auth (email, password_hash, registered_via)
+VALUES ($1, $2, 'email')
 RETURNING 
     id, 
     COALESCE(email, '') as email,
     COALESCE(phone, '') as phone,       
     password_hash, 
-    created_at;
+    created_at,
+    registered_via,
+    user_id;
 
 -- name: GetUserAuthByEmail :one
 SELECT
@@ -47,7 +55,9 @@ SELECT
     COALESCE(email, '') as email,
     COALESCE(phone, '') as phone,
     password_hash,
-    created_at
+    created_at,
+    registered_via,
+    user_id
 FROM users_auth
 WHERE email = $1;
 
@@ -57,8 +67,37 @@ SELECT EXISTS(SELECT 1 FROM users_auth WHERE email = $1) as exists;
 -- name: DeleteUserAuth :exec
 DELETE FROM users_auth WHERE id = $1;
 
+-- The merged account of an account merge. Only deletes the row while it still
+-- belongs to the Core profile the merge moves.
+-- name: DeleteMergedUserAuth :execrows
+DELETE FROM users_auth WHERE id = $1 AND user_id = $2;
+
 -- name: UpdateUserAuthEmail :one
-UPDATE users_auth SET email = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1 RETURNING *;
+UPDATE users_auth SET email = $2, email_verified = TRUE, updated_at = CURRENT_TIMESTAMP WHERE id = $1 RETURNING *;
 
 -- name: UpdateUserAuthPhone :one
-UPDATE users_auth SET phone = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1 RETURNING *;
\ No newline at end of file
+UPDATE users_auth SET phone = $2, phone_verified = TRUE, updated_at = CURRENT_TIMESTAMP WHERE id = $1 RETURNING *;
+
+-- name: UpdateUserAuthPassword :exec
+UPDATE users_auth SET password_hash = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1;
+
+-- name: SetUserAuthUserID :exec
+UPDATE users_auth SET user_id = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1;
+
+-- name: ListUserAuthPage :many
+SELECT id, user_id, created_at FROM users_auth
+WHERE id > $1
+ORDER BY id
+LIMIT $2;
+
+-- name: GetUserAuthByUserID :one
+SELECT
+    id,
+    COALESCE(email, '') as email,
+    COALESCE(phone, '') as phone,
+    password_hash,
+    created_at,
+    registered_via,
+    user_id
+FROM users_auth
+WHERE user_id = $1;
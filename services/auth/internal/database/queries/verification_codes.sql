@@ -0,0 +1,19 @@
+-- name: CreateVerificationCode :one
+INSERT INTO verification_codes (user_auth_id, purpose, target, code_hash, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetActiveVerificationCode :one
+SELECT * FROM verification_codes
+WHERE user_auth_id = $1 AND purpose = $2 AND used = FALSE AND expires_at > CURRENT_TIMESTAMP
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: IncrementVerificationCodeAttempts :one
+UPDATE verification_codes SET attempts = attempts + 1 WHERE id = $1 RETURNING attempts;
+
+-- name: ConsumeVerificationCode :execrows
+UPDATE verification_codes SET used = TRUE WHERE id = $1 AND used = FALSE;
+
+-- name: InvalidateVerificationCodes :exec
+UPDATE verification_codes SET used = TRUE WHERE user_auth_id = $1 AND purpose = $2 AND used = FALSE;
@@ -35,14 +35,16 @@ func (q *Queries) CheckPhoneExists(ctx context.Context, phone string) (bool, err
 }
 
 const createUserByEmail = `-- name: CreateUserByEmail :one
-INSERT INTO users_auth (email, password_hash)
-VALUES ($1, $2)
+INSERT INTO users_auth (email, password_hash, registered_via)
+VALUES ($1, $2, 'email')
 RETURNING 
     id, 
     COALESCE(email, '') as email,
     COALESCE(phone, '') as phone,       
     password_hash, 
-    created_at
+    created_at,
+    registered_via,
+    user_id
 `
 
 type CreateUserByEmailParams struct {
@@ -51,11 +53,13 @@ type CreateUserByEmailParams struct {
 }
 
 type CreateUserByEmailRow struct {
-	ID           uuid.UUID `json:"id"`
-	Email        string    `json:"email"`
-	Phone        string    `json:"phone"`
-	PasswordHash string    `json:"password_hash"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID            uuid.UUID  `json:"id"`
+	Email         string     `json:"email"`
+	Phone         string     `json:"phone"`
+	PasswordHash  string     `json:"password_hash"`
+	CreatedAt     time.Time  `json:"created_at"`
+	RegisteredVia string     `json:"registered_via"`
+	UserID        *uuid.UUID `json:"user_id"`
 }
 
 func (q *Queries) CreateUserByEmail(ctx context.Context, arg CreateUserByEmailParams) (CreateUserByEmailRow, error) {
@@ -67,19 +71,23 @@ func (q *Queries) CreateUserByEmail(ctx context.Context, arg CreateUserByEmailPa
 		&i.Phone,
 		&i.PasswordHash,
 		&i.CreatedAt,
+		&i.RegisteredVia,
+		&i.UserID,
 	)
 	return i, err
 }
 
 const createUserByPhone = `-- name: CreateUserByPhone :one
-INSERT INTO users_auth (phone, password_hash)
-VALUES ($1, $2)
+INSERT INTO users_auth (phone, password_hash, registered_via)
+VALUES ($1, $2, 'phone')
 RETURNING 
     id, 
     COALESCE(email, '') as email,
     COALESCE(phone, '') as phone,       
     password_hash, 
-    created_at
+    created_at,
+    registered_via,
+    user_id
 `
 
 type CreateUserByPhoneParams struct {
@@ -88,11 +96,13 @@ type CreateUserByPhoneParams struct {
 }
 
 type CreateUserByPhoneRow struct {
-	ID           uuid.UUID `json:"id"`
-	Email        string    `json:"email"`
-	Phone        string    `json:"phone"`
-	PasswordHash string    `json:"password_hash"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID            uuid.UUID  `json:"id"`
+	Email         string     `json:"email"`
+	Phone         string     `json:"phone"`
+	PasswordHash  string     `json:"password_hash"`
+	CreatedAt     time.Time  `json:"created_at"`
+	RegisteredVia string     `json:"registered_via"`
+	UserID        *uuid.UUID `json:"user_id"`
 }
 
 func (q *Queries) CreateUserByPhone(ctx context.Context, arg CreateUserByPhoneParams) (CreateUserByPhoneRow, error) {
@@ -104,10 +114,31 @@ func (q *Queries) CreateUserByPhone(ctx context.Context, arg CreateUserByPhonePa
 		&i.Phone,
 		&i.PasswordHash,
 		&i.CreatedAt,
+		&i.RegisteredVia,
+		&i.UserID,
 	)
 	return i, err
 }
 
+const deleteMergedUserAuth = `-- name: DeleteMergedUserAuth :execrows
+DELETE FROM users_auth WHERE id = $1 AND user_id = $2
+`
+
+type DeleteMergedUserAuthParams struct {
+	ID     uuid.UUID  `json:"id"`
+	UserID *uuid.UUID `json:"user_id"`
+}
+
+// The merged account of an account merge. Only deletes the row while it still
+// belongs to the Core profile the merge moves.
+func (q *Queries) DeleteMergedUserAuth(ctx context.Context, arg DeleteMergedUserAuthParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteMergedUserAuth, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deleteUserAuth = `-- name: DeleteUserAuth :exec
 DELETE FROM users_auth WHERE id = $1
 `
@@ -123,17 +154,21 @@ SELECT
     COALESCE(email, '') as email,
     COALESCE(phone, '') as phone,
     password_hash,
-    created_at
+    created_at,
+    registered_via,
+    user_id
 FROM users_auth
 WHERE email = $1
 `
 
 type GetUserAuthByEmailRow struct {
-	ID           uuid.UUID `json:"id"`
-	Email        string    `json:"email"`
-	Phone        string    `json:"phone"`
-	PasswordHash string    `json:"password_hash"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID            uuid.UUID  `json:"id"`
+	Email         string     `json:"email"`
+	Phone         string     `json:"phone"`
+	PasswordHash  string     `json:"password_hash"`
+	CreatedAt     time.Time  `json:"created_at"`
+	RegisteredVia string     `json:"registered_via"`
+	UserID        *uuid.UUID `json:"user_id"`
 }
 
 func (q *Queries) GetUserAuthByEmail(ctx context.Context, email string) (GetUserAuthByEmailRow, error) {
@@ -145,6 +180,8 @@ func (q *Queries) GetUserAuthByEmail(ctx context.Context, email string) (GetUser
 		&i.Phone,
 		&i.PasswordHash,
 		&i.CreatedAt,
+		&i.RegisteredVia,
+		&i.UserID,
 	)
 	return i, err
 }
@@ -155,17 +192,21 @@ SELECT
     COALESCE(email, '') as email,
     COALESCE(phone, '') as phone,
     password_hash,
-    created_at
+    created_at,
+    registered_via,
+    user_id
 FROM users_auth
 WHERE id = $1
 `
 
 type GetUserAuthByIDRow struct {
-	ID           uuid.UUID `json:"id"`
-	Email        string    `json:"email"`
-	Phone        string    `json:"phone"`
-	PasswordHash string    `json:"password_hash"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID            uuid.UUID  `json:"id"`
+	Email         string     `json:"email"`
+	Phone         string     `json:"phone"`
+	PasswordHash  string     `json:"password_hash"`
+	CreatedAt     time.Time  `json:"created_at"`
+	RegisteredVia string     `json:"registered_via"`
+	UserID        *uuid.UUID `json:"user_id"`
 }
 
 func (q *Queries) GetUserAuthByID(ctx context.Context, id uuid.UUID) (GetUserAuthByIDRow, error) {
@@ -177,6 +218,8 @@ func (q *Queries) GetUserAuthByID(ctx context.Context, id uuid.UUID) (GetUserAut
 		&i.Phone,
 		&i.PasswordHash,
 		&i.CreatedAt,
+		&i.RegisteredVia,
+		&i.UserID,
 	)
 	return i, err
 }
@@ -187,17 +230,21 @@ SELECT
     COALESCE(email, '') as email,
     COALESCE(phone, '') as phone,
     password_hash,
-    created_at
+    created_at,
+    registered_via,
+    user_id
 FROM users_auth
 WHERE phone = $1
 `
 
 type GetUserAuthByPhoneRow struct {
-	ID           uuid.UUID `json:"id"`
-	Email        string    `json:"email"`
-	Phone        string    `json:"phone"`
-	PasswordHash string    `json:"password_hash"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID            uuid.UUID  `json:"id"`
+	Email         string     `json:"email"`
+	Phone         string     `json:"phone"`
+	PasswordHash  string     `json:"password_hash"`
+	CreatedAt     time.Time  `json:"created_at"`
+	RegisteredVia string     `json:"registered_via"`
+	UserID        *uuid.UUID `json:"user_id"`
 }
 
 func (q *Queries) GetUserAuthByPhone(ctx context.Context, phone string) (GetUserAuthByPhoneRow, error) {
@@ -209,12 +256,104 @@ func (q *Queries) GetUserAuthByPhone(ctx context.Context, phone string) (GetUser
 		&i.Phone,
 		&i.PasswordHash,
 		&i.CreatedAt,
+		&i.RegisteredVia,
+		&i.UserID,
 	)
 	return i, err
 }
 
+const getUserAuthByUserID = `-- name: GetUserAuthByUserID :one
+SELECT
+    id,
+    COALESCE(email, '') as email,
+    COALESCE(phone, '') as phone,
+    password_hash,
+    created_at,
+    registered_via,
+    user_id
+FROM users_auth
+WHERE user_id = $1
+`
+
+type GetUserAuthByUserIDRow struct {
+	ID            uuid.UUID  `json:"id"`
+	Email         string     `json:"email"`
+	Phone         string     `json:"phone"`
+	PasswordHash  string     `json:"password_hash"`
+	CreatedAt     time.Time  `json:"created_at"`
+	RegisteredVia string     `json:"registered_via"`
+	UserID        *uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) GetUserAuthByUserID(ctx context.Context, userID *uuid.UUID) (GetUserAuthByUserIDRow, error) {
+	row := q.db.QueryRow(ctx, getUserAuthByUserID, userID)
+	var i GetUserAuthByUserIDRow
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Phone,
+		&i.PasswordHash,
+		&i.CreatedAt,
+		&i.RegisteredVia,
+		&i.UserID,
+	)
+	return i, err
+}
+
+const listUserAuthPage = `-- name: ListUserAuthPage :many
+SELECT id, user_id, created_at FROM users_auth
+WHERE id > $1
+ORDER BY id
+LIMIT $2
+`
+
+type ListUserAuthPageParams struct {
+	ID    uuid.UUID `json:"id"`
+	Limit int32     `json:"limit"`
+}
+
+type ListUserAuthPageRow struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    *uuid.UUID `json:"user_id"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (q *Queries) ListUserAuthPage(ctx context.Context, arg ListUserAuthPageParams) ([]ListUserAuthPageRow, error) {
+	rows, err := q.db.Query(ctx, listUserAuthPage, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListUserAuthPageRow{}
+	for rows.Next() {
+		var i ListUserAuthPageRow
+		if err := rows.Scan(&i.ID, &i.UserID, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setUserAuthUserID = `-- name: SetUserAuthUserID :exec
+UPDATE users_auth SET user_id = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1
+`
+
+type SetUserAuthUserIDParams struct {
+	ID     uuid.UUID  `json:"id"`
+	UserID *uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) SetUserAuthUserID(ctx context.Context, arg SetUserAuthUserIDParams) error {
+	_, err := q.db.Exec(ctx, setUserAuthUserID, arg.ID, arg.UserID)
+	return err
+}
+
 const updateUserAuthEmail = `-- name: UpdateUserAuthEmail :one
-UPDATE users_auth SET email = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1 RETURNING id, email, phone, password_hash, created_at, updated_at
+UPDATE users_auth SET email = $2, email_verified = TRUE, updated_at = CURRENT_TIMESTAMP WHERE id = $1 RETURNING id, email, phone, password_hash, created_at, updated_at, registered_via, email_verified, phone_verified, user_id
 `
 
 type UpdateUserAuthEmailParams struct {
@@ -232,12 +371,16 @@ func (q *Queries) UpdateUserAuthEmail(ctx context.Context, arg UpdateUserAuthEma
 		&i.PasswordHash,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.RegisteredVia,
+		&i.EmailVerified,
+		&i.PhoneVerified,
+		&i.UserID,
 	)
 	return i, err
 }
 
 const updateUserAuthPhone = `-- name: UpdateUserAuthPhone :one
-UPDATE users_auth SET phone = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1 RETURNING id, email, phone, password_hash, created_at, updated_at
+UPDATE users_auth SET phone = $2, phone_verified = TRUE, updated_at = CURRENT_TIMESTAMP WHERE id = $1 RETURNING id, email, phone, password_hash, created_at, updated_at, registered_via, email_verified, phone_verified, user_id
 `
 
 type UpdateUserAuthPhoneParams struct {
@@ -255,6 +398,24 @@ func (q *Queries) UpdateUserAuthPhone(ctx context.Context, arg UpdateUserAuthPho
 		&i.PasswordHash,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.RegisteredVia,
+		&i.EmailVerified,
+		&i.PhoneVerified,
+		&i.UserID,
 	)
 	return i, err
 }
+
+const updateUserAuthPassword = `-- name: UpdateUserAuthPassword :exec
+UPDATE users_auth SET password_hash = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1
+`
+
+type UpdateUserAuthPasswordParams struct {
+	ID           uuid.UUID `json:"id"`
+	PasswordHash string    `json:"password_hash"`
+}
+
+func (q *Queries) UpdateUserAuthPassword(ctx context.Context, arg UpdateUserAuthPasswordParams) error {
+	_, err := q.db.Exec(ctx, updateUserAuthPassword, arg.ID, arg.PasswordHash)
+	return err
+}
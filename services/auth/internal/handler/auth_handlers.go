@@ -1,14 +1,21 @@
 package handler
 
 import (
+	"context"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 
 	"github.com/teammachinist/tutuplapak/services/auth/internal/logger"
 	"github.com/teammachinist/tutuplapak/services/auth/internal/model"
 	"github.com/teammachinist/tutuplapak/services/auth/internal/service"
+	"github.com/teammachinist/tutuplapak/services/auth/pkg/authz"
 )
 
 type UserHandler struct {
@@ -30,13 +37,20 @@ func (h *UserHandler) LoginByPhone(c *gin.Context) {
 		return
 	}
 
-	loginResponse, err := h.userService.LoginByPhone(ctx, req.Phone, req.Password)
+	loginResponse, err := h.userService.LoginByPhone(ctx, req.Phone, req.Password, clientInfo(c))
+	h.auditAuthResponse(ctx, c, model.AuditLogin, "phone", req.Phone, loginResponse, err)
 	if err != nil {
 		logger.WarnCtx(ctx, "Login failed", "method", "phone", "error", err.Error())
 		h.handleAuthError(c, err)
 		return
 	}
 
+	if loginResponse.Status == model.LoginTOTPRequired {
+		logger.InfoCtx(ctx, "Login awaiting second factor", "method", "phone")
+		c.JSON(http.StatusOK, loginResponse)
+		return
+	}
+
 	logger.InfoCtx(ctx, "Login successful", "method", "phone")
 	c.JSON(http.StatusOK, loginResponse)
 }
@@ -52,13 +66,21 @@ func (h *UserHandler) RegisterByPhone(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.userService.RegisterByPhone(ctx, req.Phone, req.Password)
+	resp, err := h.userService.RegisterByPhone(ctx, req.Phone, req.Password, clientInfo(c))
+	h.auditAuthResponse(ctx, c, model.AuditRegister, "phone", req.Phone, resp, err)
 	if err != nil {
 		logger.WarnCtx(ctx, "Registration failed", "method", "phone", "error", err.Error())
 		h.handleAuthError(c, err)
 		return
 	}
 
+	// Account exists but its profile is still being created, log in later
+	if resp.Status == model.RegistrationPending {
+		logger.InfoCtx(ctx, "Registration pending", "method", "phone")
+		c.JSON(http.StatusAccepted, resp)
+		return
+	}
+
 	logger.InfoCtx(ctx, "Registration successful", "method", "phone")
 	c.JSON(http.StatusCreated, resp)
 }
@@ -74,17 +96,143 @@ func (h *UserHandler) LoginWithEmail(c *gin.Context) {
 		return
 	}
 
-	loginResponse, err := h.userService.LoginWithEmail(ctx, req.Email, req.Password)
+	loginResponse, err := h.userService.LoginWithEmail(ctx, req.Email, req.Password, clientInfo(c))
+	h.auditAuthResponse(ctx, c, model.AuditLogin, "email", req.Email, loginResponse, err)
 	if err != nil {
 		logger.WarnCtx(ctx, "Login failed", "method", "email", "error", err.Error())
 		h.handleAuthError(c, err)
 		return
 	}
 
+	if loginResponse.Status == model.LoginTOTPRequired {
+		logger.InfoCtx(ctx, "Login awaiting second factor", "method", "email")
+		c.JSON(http.StatusOK, loginResponse)
+		return
+	}
+
 	logger.InfoCtx(ctx, "Login successful", "method", "email")
 	c.JSON(http.StatusOK, loginResponse)
 }
 
+func (h *UserHandler) LoginWithTOTP(c *gin.Context) {
+	ctx := logger.WithRequestID(c.Request.Context())
+	logger.InfoCtx(ctx, "Login attempt", "method", "totp")
+
+	var req model.TOTPLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WarnCtx(ctx, "Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	loginResponse, err := h.userService.CompleteTOTPLogin(ctx, req.ChallengeToken, req.Code, clientInfo(c))
+	h.auditAuthResponse(ctx, c, model.AuditLogin, "totp", "", loginResponse, err)
+	if err != nil {
+		logger.WarnCtx(ctx, "Login failed", "method", "totp", "error", err.Error())
+		h.handleAuthError(c, err)
+		return
+	}
+
+	logger.InfoCtx(ctx, "Login successful", "method", "totp")
+	c.JSON(http.StatusOK, loginResponse)
+}
+
+func (h *UserHandler) StartPasswordlessLogin(c *gin.Context) {
+	ctx := logger.WithRequestID(c.Request.Context())
+	logger.InfoCtx(ctx, "Passwordless login requested")
+
+	var req model.PasswordlessStartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WarnCtx(ctx, "Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if err := h.userService.StartPasswordlessLogin(ctx, req.Email, req.Phone, req.Method, c.ClientIP()); err != nil {
+		logger.WarnCtx(ctx, "Passwordless login request failed", "error", err.Error())
+		h.handleAuthError(c, err)
+		return
+	}
+
+	// Same response whether or not the account exists
+	c.JSON(http.StatusOK, gin.H{"message": "if the account exists, a login code has been sent"})
+}
+
+func (h *UserHandler) VerifyPasswordlessLogin(c *gin.Context) {
+	ctx := logger.WithRequestID(c.Request.Context())
+	logger.InfoCtx(ctx, "Login attempt", "method", "passwordless")
+
+	var req model.PasswordlessVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WarnCtx(ctx, "Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	loginResponse, err := h.userService.VerifyPasswordlessLogin(ctx, req.Email, req.Phone, req.Code, req.Token, clientInfo(c))
+	h.auditAuthResponse(ctx, c, model.AuditLogin, "passwordless", req.Email+req.Phone, loginResponse, err)
+	if err != nil {
+		logger.WarnCtx(ctx, "Login failed", "method", "passwordless", "error", err.Error())
+		h.handleAuthError(c, err)
+		return
+	}
+
+	if loginResponse.Status == model.LoginTOTPRequired {
+		logger.InfoCtx(ctx, "Login awaiting second factor", "method", "passwordless")
+		c.JSON(http.StatusOK, loginResponse)
+		return
+	}
+
+	logger.InfoCtx(ctx, "Login successful", "method", "passwordless")
+	c.JSON(http.StatusOK, loginResponse)
+}
+
+func (h *UserHandler) BeginPasskeyLogin(c *gin.Context) {
+	ctx := logger.WithRequestID(c.Request.Context())
+
+	// Body is optional, without an email/phone any discoverable passkey may answer
+	var req model.PasskeyLoginBeginRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			logger.WarnCtx(ctx, "Invalid request body", "error", err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	options, err := h.userService.BeginPasskeyLogin(ctx, req.Email, req.Phone)
+	if err != nil {
+		logger.WarnCtx(ctx, "Passkey login start failed", "error", err.Error())
+		h.handleAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"publicKey": options})
+}
+
+func (h *UserHandler) FinishPasskeyLogin(c *gin.Context) {
+	ctx := logger.WithRequestID(c.Request.Context())
+	logger.InfoCtx(ctx, "Login attempt", "method", "passkey")
+
+	var req model.PasskeyLoginFinishRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WarnCtx(ctx, "Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	loginResponse, err := h.userService.FinishPasskeyLogin(ctx, &req.Credential, clientInfo(c))
+	h.auditAuthResponse(ctx, c, model.AuditLogin, "passkey", "", loginResponse, err)
+	if err != nil {
+		logger.WarnCtx(ctx, "Login failed", "method", "passkey", "error", err.Error())
+		h.handleAuthError(c, err)
+		return
+	}
+
+	logger.InfoCtx(ctx, "Login successful", "method", "passkey")
+	c.JSON(http.StatusOK, loginResponse)
+}
+
 func (h *UserHandler) RegisterWithEmail(c *gin.Context) {
 	ctx := logger.WithRequestID(c.Request.Context())
 	logger.InfoCtx(ctx, "Registration attempt", "method", "email")
@@ -96,27 +244,581 @@ func (h *UserHandler) RegisterWithEmail(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.userService.RegisterWithEmail(ctx, req.Email, req.Password)
+	resp, err := h.userService.RegisterWithEmail(ctx, req.Email, req.Password, clientInfo(c))
+	h.auditAuthResponse(ctx, c, model.AuditRegister, "email", req.Email, resp, err)
 	if err != nil {
 		logger.WarnCtx(ctx, "Registration failed", "method", "email", "error", err.Error())
 		h.handleAuthError(c, err)
 		return
 	}
 
+	// Account exists but its profile is still being created, log in later
+	if resp.Status == model.RegistrationPending {
+		logger.InfoCtx(ctx, "Registration pending", "method", "email")
+		c.JSON(http.StatusAccepted, resp)
+		return
+	}
+
 	logger.InfoCtx(ctx, "Registration successful", "method", "email")
 	c.JSON(http.StatusCreated, resp)
 }
 
+func (h *UserHandler) RefreshToken(c *gin.Context) {
+	ctx := logger.WithRequestID(c.Request.Context())
+	logger.InfoCtx(ctx, "Token refresh attempt")
+
+	var req model.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WarnCtx(ctx, "Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	resp, err := h.userService.RefreshToken(ctx, req.RefreshToken)
+	if err != nil {
+		logger.WarnCtx(ctx, "Token refresh failed", "error", err.Error())
+		h.handleAuthError(c, err)
+		return
+	}
+
+	logger.InfoCtx(ctx, "Token refresh successful")
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *UserHandler) Logout(c *gin.Context) {
+	ctx := logger.WithRequestID(c.Request.Context())
+	logger.InfoCtx(ctx, "Logout attempt")
+
+	token := bearerToken(c)
+	if token == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization token required"})
+		return
+	}
+
+	// Body is optional, an access token alone is enough to log out
+	var req model.LogoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			logger.WarnCtx(ctx, "Invalid request body", "error", err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	if err := h.userService.Logout(ctx, token, req.RefreshToken); err != nil {
+		logger.WarnCtx(ctx, "Logout failed", "error", err.Error())
+		h.handleAuthError(c, err)
+		return
+	}
+
+	logger.InfoCtx(ctx, "Logout successful")
+	c.JSON(http.StatusOK, gin.H{"message": "logged out successfully"})
+}
+
+func (h *UserHandler) ForgotPassword(c *gin.Context) {
+	ctx := logger.WithRequestID(c.Request.Context())
+	logger.InfoCtx(ctx, "Password reset requested")
+
+	var req model.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WarnCtx(ctx, "Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if err := h.userService.ForgotPassword(ctx, req.Email, req.Phone); err != nil {
+		logger.WarnCtx(ctx, "Password reset request failed", "error", err.Error())
+		h.handleAuthError(c, err)
+		return
+	}
+
+	// Same response whether or not the account exists
+	c.JSON(http.StatusOK, gin.H{"message": "if the account exists, a reset code has been sent"})
+}
+
+func (h *UserHandler) ResetPassword(c *gin.Context) {
+	ctx := logger.WithRequestID(c.Request.Context())
+	logger.InfoCtx(ctx, "Password reset attempt")
+
+	var req model.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WarnCtx(ctx, "Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	err := h.userService.ResetPassword(ctx, req.Email, req.Phone, req.Code, req.NewPassword, c.ClientIP())
+	h.userService.Audit(ctx, model.AuditEntry{
+		EventType:  model.AuditPasswordChange,
+		Method:     "reset",
+		Identifier: req.Email + req.Phone,
+		Client:     clientInfo(c),
+	}, err)
+	if err != nil {
+		logger.WarnCtx(ctx, "Password reset failed", "error", err.Error())
+		h.handleAuthError(c, err)
+		return
+	}
+
+	logger.InfoCtx(ctx, "Password reset successful")
+	c.JSON(http.StatusOK, gin.H{"message": "password has been reset"})
+}
+
+func (h *UserHandler) ListSessions(c *gin.Context) {
+	ctx := logger.WithRequestID(c.Request.Context())
+
+	claims, ok := h.authenticate(ctx, c)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.userService.ListSessions(ctx, claims)
+	if err != nil {
+		logger.WarnCtx(ctx, "Failed to list sessions", "user_id", claims.UserID, "error", err.Error())
+		h.handleAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+func (h *UserHandler) RevokeSession(c *gin.Context) {
+	ctx := logger.WithRequestID(c.Request.Context())
+	logger.InfoCtx(ctx, "Session revoke attempt")
+
+	claims, ok := h.authenticate(ctx, c)
+	if !ok {
+		return
+	}
+
+	if err := h.userService.RevokeSession(ctx, claims, c.Param("sessionId")); err != nil {
+		logger.WarnCtx(ctx, "Session revoke failed", "user_id", claims.UserID, "error", err.Error())
+		h.handleAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "session revoked"})
+}
+
+func (h *UserHandler) RevokeAllSessions(c *gin.Context) {
+	ctx := logger.WithRequestID(c.Request.Context())
+	logger.InfoCtx(ctx, "Revoke all sessions attempt")
+
+	claims, ok := h.authenticate(ctx, c)
+	if !ok {
+		return
+	}
+
+	if err := h.userService.RevokeAllSessions(ctx, claims); err != nil {
+		logger.WarnCtx(ctx, "Revoke all sessions failed", "user_id", claims.UserID, "error", err.Error())
+		h.handleAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "all sessions revoked"})
+}
+
+func (h *UserHandler) TOTPStatus(c *gin.Context) {
+	ctx := logger.WithRequestID(c.Request.Context())
+
+	claims, ok := h.authenticate(ctx, c)
+	if !ok {
+		return
+	}
+
+	status, err := h.userService.TOTPStatus(ctx, claims)
+	if err != nil {
+		logger.WarnCtx(ctx, "Failed to get TOTP status", "user_id", claims.UserID, "error", err.Error())
+		h.handleAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+func (h *UserHandler) EnrollTOTP(c *gin.Context) {
+	ctx := logger.WithRequestID(c.Request.Context())
+	logger.InfoCtx(ctx, "TOTP enrollment attempt")
+
+	claims, ok := h.authenticate(ctx, c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.userService.EnrollTOTP(ctx, claims)
+	if err != nil {
+		logger.WarnCtx(ctx, "TOTP enrollment failed", "user_id", claims.UserID, "error", err.Error())
+		h.handleAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *UserHandler) ConfirmTOTP(c *gin.Context) {
+	ctx := logger.WithRequestID(c.Request.Context())
+	logger.InfoCtx(ctx, "TOTP confirm attempt")
+
+	claims, ok := h.authenticate(ctx, c)
+	if !ok {
+		return
+	}
+
+	var req model.TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WarnCtx(ctx, "Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	resp, err := h.userService.ConfirmTOTP(ctx, claims, req.Code)
+	if err != nil {
+		logger.WarnCtx(ctx, "TOTP confirm failed", "user_id", claims.UserID, "error", err.Error())
+		h.handleAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *UserHandler) DisableTOTP(c *gin.Context) {
+	ctx := logger.WithRequestID(c.Request.Context())
+	logger.InfoCtx(ctx, "TOTP disable attempt")
+
+	claims, ok := h.authenticate(ctx, c)
+	if !ok {
+		return
+	}
+
+	var req model.TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WarnCtx(ctx, "Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if err := h.userService.DisableTOTP(ctx, claims, req.Code); err != nil {
+		logger.WarnCtx(ctx, "TOTP disable failed", "user_id", claims.UserID, "error", err.Error())
+		h.handleAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication disabled"})
+}
+
+func (h *UserHandler) BeginPasskeyRegistration(c *gin.Context) {
+	ctx := logger.WithRequestID(c.Request.Context())
+	logger.InfoCtx(ctx, "Passkey registration started")
+
+	claims, ok := h.authenticate(ctx, c)
+	if !ok {
+		return
+	}
+
+	options, err := h.userService.BeginPasskeyRegistration(ctx, claims)
+	if err != nil {
+		logger.WarnCtx(ctx, "Passkey registration start failed", "user_id", claims.UserID, "error", err.Error())
+		h.handleAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"publicKey": options})
+}
+
+func (h *UserHandler) FinishPasskeyRegistration(c *gin.Context) {
+	ctx := logger.WithRequestID(c.Request.Context())
+	logger.InfoCtx(ctx, "Passkey registration attempt")
+
+	claims, ok := h.authenticate(ctx, c)
+	if !ok {
+		return
+	}
+
+	var req model.PasskeyRegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WarnCtx(ctx, "Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	passkey, err := h.userService.FinishPasskeyRegistration(ctx, claims, req.Name, &req.Credential)
+	if err != nil {
+		logger.WarnCtx(ctx, "Passkey registration failed", "user_id", claims.UserID, "error", err.Error())
+		h.handleAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, passkey)
+}
+
+func (h *UserHandler) ListPasskeys(c *gin.Context) {
+	ctx := logger.WithRequestID(c.Request.Context())
+
+	claims, ok := h.authenticate(ctx, c)
+	if !ok {
+		return
+	}
+
+	passkeys, err := h.userService.ListPasskeys(ctx, claims)
+	if err != nil {
+		logger.WarnCtx(ctx, "Failed to list passkeys", "user_id", claims.UserID, "error", err.Error())
+		h.handleAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, passkeys)
+}
+
+func (h *UserHandler) DeletePasskey(c *gin.Context) {
+	ctx := logger.WithRequestID(c.Request.Context())
+	logger.InfoCtx(ctx, "Passkey delete attempt")
+
+	claims, ok := h.authenticate(ctx, c)
+	if !ok {
+		return
+	}
+
+	if err := h.userService.DeletePasskey(ctx, claims, c.Param("passkeyId")); err != nil {
+		logger.WarnCtx(ctx, "Passkey delete failed", "user_id", claims.UserID, "error", err.Error())
+		h.handleAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "passkey deleted"})
+}
+
+func (h *UserHandler) StartAccountMerge(c *gin.Context) {
+	ctx := logger.WithRequestID(c.Request.Context())
+	logger.InfoCtx(ctx, "Account merge attempt")
+
+	claims, ok := h.authenticate(ctx, c)
+	if !ok {
+		return
+	}
+
+	var req model.AccountMergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WarnCtx(ctx, "Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	response, err := h.userService.StartAccountMerge(ctx, claims, req.Email, req.Phone)
+	if err != nil {
+		logger.WarnCtx(ctx, "Account merge start failed", "user_id", claims.UserID, "error", err.Error())
+		h.handleAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *UserHandler) ConfirmAccountMerge(c *gin.Context) {
+	ctx := logger.WithRequestID(c.Request.Context())
+	logger.InfoCtx(ctx, "Account merge confirmation")
+
+	claims, ok := h.authenticate(ctx, c)
+	if !ok {
+		return
+	}
+
+	var req model.AccountMergeConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WarnCtx(ctx, "Invalid request body", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	response, err := h.userService.ConfirmAccountMerge(ctx, claims, req.Email, req.Phone, req.Code, req.TOTPCode, clientInfo(c))
+	if err != nil {
+		logger.WarnCtx(ctx, "Account merge failed", "user_id", claims.UserID, "error", err.Error())
+		h.handleAuthError(c, err)
+		return
+	}
+
+	// Pending merges finish moving products and files in the background
+	status := http.StatusOK
+	if response.Status == model.AccountMergePending {
+		status = http.StatusAccepted
+	}
+	c.JSON(status, response)
+}
+
+func (h *UserHandler) ListAccountMerges(c *gin.Context) {
+	ctx := logger.WithRequestID(c.Request.Context())
+
+	claims, ok := h.authenticate(ctx, c)
+	if !ok {
+		return
+	}
+
+	merges, err := h.userService.ListAccountMerges(ctx, claims)
+	if err != nil {
+		logger.WarnCtx(ctx, "Failed to list account merges", "user_id", claims.UserID, "error", err.Error())
+		h.handleAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, merges)
+}
+
+// ListAuditLog - Admin query over the audit log, filtered by userAuthId,
+// eventType and a from/to range (RFC 3339), paged with limit and offset
+// GET /v1/admin/audit
+func (h *UserHandler) ListAuditLog(c *gin.Context) {
+	ctx := logger.WithRequestID(c.Request.Context())
+
+	claims, _ := authz.GetClaimsFromGin(c)
+
+	filter := model.AuditLogFilter{EventType: c.Query("eventType")}
+	if value := c.Query("userAuthId"); value != "" {
+		userAuthID, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid userAuthId format"})
+			return
+		}
+		filter.UserAuthID = &userAuthID
+	}
+	for param, dest := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := c.Query(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + param + " format, expected RFC 3339"})
+				return
+			}
+			*dest = &t
+		}
+	}
+	for param, dest := range map[string]*int{"limit": &filter.Limit, "offset": &filter.Offset} {
+		if value := c.Query(param); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + param})
+				return
+			}
+			*dest = n
+		}
+	}
+
+	entries, err := h.userService.ListAuditLog(ctx, filter)
+	if err != nil {
+		logger.ErrorCtx(ctx, "Failed to list audit log", "user_id", claims.UserID, "error", err.Error())
+		h.handleAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// Impersonate - Admin only: a short-lived token acting as the given user,
+// read-only unless readOnly is false. The reason goes to the audit log.
+// POST /v1/admin/impersonate
+func (h *UserHandler) Impersonate(c *gin.Context) {
+	ctx := logger.WithRequestID(c.Request.Context())
+
+	claims, _ := authz.GetClaimsFromGin(c)
+
+	var req model.ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WarnCtx(ctx, "Invalid impersonate request", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "userId and reason are required"})
+		return
+	}
+	readOnly := req.ReadOnly == nil || *req.ReadOnly
+
+	response, err := h.userService.Impersonate(ctx, claims, req.UserID, req.Reason, readOnly, clientInfo(c))
+	if err != nil {
+		logger.WarnCtx(ctx, "Impersonation refused", "user_id", claims.UserID, "target_user_id", req.UserID, "error", err.Error())
+		switch err.Error() {
+		case "user not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "cannot impersonate an admin", "not allowed while impersonating":
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case "cannot impersonate yourself":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			h.handleAuthError(c, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// auditAuthResponse - Record a login or registration. Flows that don't name the
+// account up front (TOTP step, passkeys, magic links) are attributed to the
+// email/phone of the account that answered.
+func (h *UserHandler) auditAuthResponse(ctx context.Context, c *gin.Context, eventType, method, identifier string, resp *model.AuthResponse, err error) {
+	entry := model.AuditEntry{
+		EventType:  eventType,
+		Method:     method,
+		Identifier: identifier,
+		Client:     clientInfo(c),
+	}
+	if err == nil {
+		if entry.Identifier == "" {
+			entry.Identifier = resp.Email
+			if entry.Identifier == "" {
+				entry.Identifier = resp.Phone
+			}
+		}
+		if resp.Status == model.LoginTOTPRequired {
+			entry.Outcome = model.AuditChallenged
+		}
+	}
+	h.userService.Audit(ctx, entry, err)
+}
+
+func clientInfo(c *gin.Context) model.ClientInfo {
+	return model.ClientInfo{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+func bearerToken(c *gin.Context) string {
+	authHeader := c.GetHeader("Authorization")
+	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
+		return authHeader[7:]
+	}
+	return authHeader
+}
+
 // Helper to reduce duplication and centralize error handling
 func (h *UserHandler) handleAuthError(c *gin.Context, err error) {
+	var lockedErr *service.LoginLockedError
+	if errors.As(err, &lockedErr) {
+		// Round up so clients never retry a moment too early
+		retryAfter := int(math.Ceil(lockedErr.RetryAfter.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+
 	errorMsg := err.Error()
 
 	switch errorMsg {
-	case "email not found", "phone not found":
+	case "account setup pending":
+		// The dispatcher retries Core within seconds
+		c.Header("Retry-After", "5")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": errorMsg})
+	case "too many login requests, try again later", "verification code recently sent, try again later",
+		"too many verification codes requested, try again later":
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": errorMsg})
+	case "email not found", "phone not found", "account not found":
 		c.JSON(http.StatusNotFound, gin.H{"error": errorMsg})
-	case "invalid credentials":
+	case "invalid credentials", "invalid token", "token has expired", "token has been revoked",
+		"invalid refresh token", "refresh token has expired", "refresh token has been revoked",
+		"invalid or expired challenge", "invalid two-factor code", "invalid passkey":
 		c.JSON(http.StatusUnauthorized, gin.H{"error": errorMsg})
-	case "email address already exists", "phone number already exists":
+	case "invalid or expired code", "invalid or expired link", "provide either email or phone, not both",
+		"two-factor authentication not enabled", "two-factor enrollment not started",
+		"invalid login method", "magic link login is not enabled", "cannot merge an account into itself":
+		c.JSON(http.StatusBadRequest, gin.H{"error": errorMsg})
+	case "session not found", "passkey not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": errorMsg})
+	case "email address already exists", "phone number already exists", "two-factor authentication already enabled",
+		"passkey already registered", "both accounts have an email address", "both accounts have a phone number":
 		c.JSON(http.StatusConflict, gin.H{"error": errorMsg})
 	default:
 		// Check for validation errors (format/length issues)
@@ -131,3 +833,54 @@ func (h *UserHandler) handleAuthError(c *gin.Context, err error) {
 		}
 	}
 }
+
+// authenticate - Validate the bearer token, revocation included. Writes the
+// error response and returns false when the request may not proceed.
+func (h *UserHandler) authenticate(ctx context.Context, c *gin.Context) (*authz.Claims, bool) {
+	token := bearerToken(c)
+	if token == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization token required"})
+		return nil, false
+	}
+
+	claims, err := h.userService.ValidateTokenInternal(token)
+	if err != nil {
+		h.userService.Audit(ctx, model.AuditEntry{
+			EventType: model.AuditTokenRejected,
+			Method:    "bearer",
+			Client:    clientInfo(c),
+		}, err)
+		h.handleAuthError(c, err)
+		return nil, false
+	}
+
+	// Every route here that writes manages credentials, an impersonator only gets to look
+	if _, impersonating := claims.Impersonator(); impersonating && !isSafeMethod(c.Request.Method) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "not allowed while impersonating"})
+		return nil, false
+	}
+	return claims, true
+}
+
+// RequireAuth - authenticate as middleware, for routes guarded with the
+// authz.Require*Gin checks. Claims are stored where authz.GetClaimsFromGin
+// finds them.
+func (h *UserHandler) RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := logger.WithRequestID(c.Request.Context())
+
+		claims, ok := h.authenticate(ctx, c)
+		if !ok {
+			c.Abort()
+			return
+		}
+
+		c.Set("user_id", claims.UserID)
+		c.Set("claims", claims)
+		c.Next()
+	}
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
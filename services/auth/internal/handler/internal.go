@@ -1,11 +1,15 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 
 	"github.com/teammachinist/tutuplapak/services/auth/internal/logger"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/model"
 	"github.com/teammachinist/tutuplapak/services/auth/internal/service"
 	"github.com/teammachinist/tutuplapak/services/auth/pkg/authz"
 )
@@ -20,12 +24,6 @@ func NewInternalHandler(userService *service.UserService) *InternalHandler {
 	}
 }
 
-// UpdateUserAuthRequest for updating user auth info
-type UpdateUserAuthRequest struct {
-	Phone string `json:"phone"`
-	Email string `json:"email"`
-}
-
 // ValidateToken - For other services to validate tokens via HTTP
 // POST /internal/validate
 func (h *InternalHandler) ValidateToken(c *gin.Context) {
@@ -43,6 +41,12 @@ func (h *InternalHandler) ValidateToken(c *gin.Context) {
 
 	claims, err := h.userService.ValidateTokenInternal(req.Token)
 	if err != nil {
+		// The client is the calling service, the end user never reaches this route
+		h.userService.Audit(ctx, model.AuditEntry{
+			EventType: model.AuditTokenRejected,
+			Method:    "service",
+			Client:    model.ClientInfo{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()},
+		}, err)
 		logger.DebugCtx(ctx, "Token validation failed", "error", err.Error())
 		c.JSON(http.StatusOK, authz.ValidateTokenResponse{
 			Valid: false,
@@ -53,84 +57,167 @@ func (h *InternalHandler) ValidateToken(c *gin.Context) {
 
 	logger.DebugCtx(ctx, "Token validation successful", "user_auth_id", claims.UserID)
 	c.JSON(http.StatusOK, authz.ValidateTokenResponse{
-		Valid:  true,
-		UserID: claims.UserID,
+		Valid:       true,
+		UserID:      claims.UserID,
+		SessionID:   claims.SessionID,
+		Roles:       claims.Roles,
+		Permissions: claims.Permissions,
+		Actor:       claims.Actor,
+		ReadOnly:    claims.ReadOnly,
 	})
 }
 
-// UpdateUserAuth - Update user auth phone or email
-// PUT /internal/userauth/:userAuthID
-func (h *InternalHandler) UpdateUserAuth(c *gin.Context) {
+// ReportTokenRejections - Tokens core and files rejected on their own, for the
+// audit log
+// POST /internal/audit/token-rejections
+func (h *InternalHandler) ReportTokenRejections(c *gin.Context) {
+	ctx := logger.WithRequestID(c.Request.Context())
+
+	var req authz.TokenRejectionReport
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WarnCtx(ctx, "Invalid token rejection report", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	h.userService.AuditTokenRejections(ctx, c.GetString("service"), req)
+	c.Status(http.StatusNoContent)
+}
+
+// StartLink - Send an OTP to the email/phone the user wants to link
+// POST /internal/userauth/:userAuthID/link/:channel
+func (h *InternalHandler) StartLink(c *gin.Context) {
 	ctx := logger.WithRequestID(c.Request.Context())
 	userAuthID := c.Param("userAuthID")
+	channel := c.Param("channel")
 
-	if userAuthID == "" {
-		logger.WarnCtx(ctx, "Missing user auth ID in request")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "user auth ID required"})
+	var req authz.StartLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WarnCtx(ctx, "Invalid start link request", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	resp, err := h.userService.StartLinkVerification(ctx, userAuthID, channel, req.Target)
+	h.auditLink(ctx, model.AuditLinkStart, userAuthID, channel, req.Target, req.ClientInfo, err)
+	if err != nil {
+		logger.WarnCtx(ctx, "Failed to start link verification", "user_auth_id", userAuthID, "channel", channel, "error", err.Error())
+		h.handleUpdateError(c, err)
 		return
 	}
 
-	var req UpdateUserAuthRequest
+	c.JSON(http.StatusOK, resp)
+}
+
+// VerifyLink - Confirm the OTP and commit the link
+// POST /internal/userauth/:userAuthID/link/:channel/verify
+func (h *InternalHandler) VerifyLink(c *gin.Context) {
+	ctx := logger.WithRequestID(c.Request.Context())
+	userAuthID := c.Param("userAuthID")
+	channel := c.Param("channel")
+
+	var req authz.VerifyLinkRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		logger.WarnCtx(ctx, "Invalid update user auth request", "error", err.Error())
+		logger.WarnCtx(ctx, "Invalid verify link request", "error", err.Error())
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
 		return
 	}
 
-	// Validate that at least one field is provided
-	if req.Phone == "" && req.Email == "" {
-		logger.WarnCtx(ctx, "No update fields provided", "user_auth_id", userAuthID)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "phone or email required"})
+	resp, err := h.userService.ConfirmLinkVerification(ctx, userAuthID, channel, req.Code)
+	var target string
+	if resp != nil {
+		target = resp.Target
+	}
+	h.auditLink(ctx, model.AuditLinkVerify, userAuthID, channel, target, req.ClientInfo, err)
+	if err != nil {
+		logger.WarnCtx(ctx, "Failed to verify link", "user_auth_id", userAuthID, "channel", channel, "error", err.Error())
+		h.handleUpdateError(c, err)
 		return
 	}
 
-	// Handle phone update
-	if req.Phone != "" {
-		logger.InfoCtx(ctx, "Updating user auth phone", "user_auth_id", userAuthID)
-		if err := h.userService.LinkPhone(ctx, userAuthID, req.Phone); err != nil {
-			logger.WarnCtx(ctx, "Failed to update phone", "user_auth_id", userAuthID, "error", err.Error())
-			h.handleUpdateError(c, err)
-			return
-		}
-		logger.InfoCtx(ctx, "Phone updated successfully", "user_auth_id", userAuthID)
+	c.JSON(http.StatusOK, resp)
+}
+
+// DeleteAccount - Delete the account once its password checks out, the rest of
+// the user's data follows asynchronously
+// POST /internal/userauth/:userAuthID/delete
+func (h *InternalHandler) DeleteAccount(c *gin.Context) {
+	ctx := logger.WithRequestID(c.Request.Context())
+	userAuthID := c.Param("userAuthID")
+
+	var req authz.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.WarnCtx(ctx, "Invalid delete account request", "error", err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
 	}
 
-	// Handle email update
-	if req.Email != "" {
-		logger.InfoCtx(ctx, "Updating user auth email", "user_auth_id", userAuthID)
-		if err := h.userService.LinkEmail(ctx, userAuthID, req.Email); err != nil {
-			logger.WarnCtx(ctx, "Failed to update email", "user_auth_id", userAuthID, "error", err.Error())
-			h.handleUpdateError(c, err)
-			return
-		}
-		logger.InfoCtx(ctx, "Email updated successfully", "user_auth_id", userAuthID)
+	resp, err := h.userService.DeleteAccount(ctx, userAuthID, req.Password, req.TOTPCode, req.IPAddress)
+	if err != nil {
+		logger.WarnCtx(ctx, "Failed to delete account", "user_auth_id", userAuthID, "error", err.Error())
+		h.handleUpdateError(c, err)
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "updated successfully"})
+	c.JSON(http.StatusOK, resp)
+}
+
+// auditLink - Record a step of linking an email/phone, with the client details
+// Core forwarded
+func (h *InternalHandler) auditLink(ctx context.Context, eventType, userAuthID, channel, target string, client authz.ClientInfo, err error) {
+	entry := model.AuditEntry{
+		EventType:  eventType,
+		Method:     channel,
+		Identifier: target,
+		Client:     model.ClientInfo{IP: client.IPAddress, UserAgent: client.UserAgent},
+	}
+	if id, parseErr := uuid.Parse(userAuthID); parseErr == nil {
+		entry.UserAuthID = &id
+	}
+	h.userService.Audit(ctx, entry, err)
 }
 
 // Helper to handle update errors
 func (h *InternalHandler) handleUpdateError(c *gin.Context, err error) {
+	var lockedErr *service.LoginLockedError
+	if errors.As(err, &lockedErr) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+
 	errorMsg := err.Error()
 
 	switch errorMsg {
-	case "user not found":
+	case "invalid credentials", "invalid two-factor code":
+		c.JSON(http.StatusUnauthorized, gin.H{"error": errorMsg})
+	case "account merge in progress":
+		c.JSON(http.StatusConflict, gin.H{"error": errorMsg})
+	case "user not found", "user auth not found":
 		c.JSON(http.StatusNotFound, gin.H{"error": errorMsg})
 	case "email address already exists", "phone number already exists":
 		c.JSON(http.StatusConflict, gin.H{"error": errorMsg})
 	case "invalid email address format", "invalid phone number format",
-		"phone must start with international calling code prefix '+'":
+		"phone must start with international calling code prefix '+'",
+		"email is required", "phone is required", "code is required",
+		"invalid user_auth_id format", "invalid link channel", "invalid or expired code",
+		"password is required", "two-factor code required":
 		c.JSON(http.StatusBadRequest, gin.H{"error": errorMsg})
+	case "verification code recently sent, try again later", "too many verification codes requested, try again later":
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": errorMsg})
 	default:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
 	}
 }
 
-// RegisterInternalRoutes - Register internal API routes
-func (h *InternalHandler) RegisterInternalRoutes(router *gin.Engine) {
+// RegisterInternalRoutes - Register internal API routes, only reachable with a
+// signed request from the listed services
+func (h *InternalHandler) RegisterInternalRoutes(router *gin.Engine, services *authz.ServiceVerifier) {
 	internal := router.Group("/internal")
 	{
-		internal.POST("/validate", h.ValidateToken)
-		internal.PUT("/userauth/:userAuthID", h.UpdateUserAuth)
+		internal.POST("/validate", services.InternalOnlyGin(authz.ServiceCore, authz.ServiceFiles), h.ValidateToken)
+		internal.POST("/audit/token-rejections", services.InternalOnlyGin(authz.ServiceCore, authz.ServiceFiles), h.ReportTokenRejections)
+		internal.POST("/userauth/:userAuthID/link/:channel", services.InternalOnlyGin(authz.ServiceCore), h.StartLink)
+		internal.POST("/userauth/:userAuthID/link/:channel/verify", services.InternalOnlyGin(authz.ServiceCore), h.VerifyLink)
+		internal.POST("/userauth/:userAuthID/delete", services.InternalOnlyGin(authz.ServiceCore), h.DeleteAccount)
 	}
 }
@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/teammachinist/tutuplapak/services/auth/pkg/authz"
+)
+
+type JWKSHandler struct {
+	jwks *authz.JWKS
+}
+
+func NewJWKSHandler(jwks *authz.JWKS) *JWKSHandler {
+	return &JWKSHandler{
+		jwks: jwks,
+	}
+}
+
+// GetJWKS - Public signing keys for verifying access tokens locally
+// GET /.well-known/jwks.json
+func (h *JWKSHandler) GetJWKS(c *gin.Context) {
+	// Short cache so a newly added key propagates well within a token lifetime
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.jwks)
+}
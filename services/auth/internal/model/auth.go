@@ -4,44 +4,445 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
+
+	"github.com/teammachinist/tutuplapak/services/auth/internal/webauthn"
 )
 
 // User Models
 type UserAuth struct {
-	ID           uuid.UUID `json:"id"`
-	Email        string    `json:"email"`
-	Phone        string    `json:"phone"`
-	PasswordHash string    `json:"password_hash"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID            uuid.UUID  `json:"id"`
+	UserID        *uuid.UUID `json:"user_id"` // Core's user.id, nil until the profile is created
+	Email         string     `json:"email"`
+	Phone         string     `json:"phone"`
+	PasswordHash  string     `json:"password_hash"`
+	RegisteredVia string     `json:"registered_via"`
+	CreatedAt     time.Time  `json:"created_at"`
 }
 
+// Registration channels, also where one-time codes are delivered
+const (
+	RegisteredViaEmail = "email"
+	RegisteredViaPhone = "phone"
+)
+
 type EmailAuthRequest struct {
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=8,max=32"`
+	Password string `json:"password" binding:"required,min=8,max=128"`
 }
 
 type PhoneAuthRequest struct {
 	Phone    string `json:"phone" binding:"required"`
-	Password string `json:"password" binding:"required,min=8,max=32"`
+	Password string `json:"password" binding:"required,min=8,max=128"`
 }
 
 type AuthResponse struct {
+	Email          string `json:"email"`
+	Phone          string `json:"phone"`
+	Token          string `json:"token"`
+	RefreshToken   string `json:"refreshToken"`
+	Status         string `json:"status,omitempty"`
+	ChallengeToken string `json:"challengeToken,omitempty"`
+}
+
+// Registration status, pending until Core has created the user's profile.
+// No tokens are issued while pending; logging in later completes it.
+const RegistrationPending = "pending"
+
+// Login status when the password was right but the account has TOTP enabled.
+// No tokens yet; ChallengeToken and a code are exchanged for them.
+const LoginTOTPRequired = "totp_required"
+
+// Outbox Models
+type OutboxEvent struct {
+	ID            uuid.UUID `json:"id"`
+	AggregateID   uuid.UUID `json:"aggregate_id"`
+	EventType     string    `json:"event_type"`
+	Payload       []byte    `json:"payload"`
+	Attempts      int32     `json:"attempts"`
+	LastError     string    `json:"last_error"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+}
+
+// Outbox event types
+const (
+	EventUserRegistered = "user.registered" // Create the Core profile, aggregate is users_auth.id
+	EventAccountMerged  = "account.merged"  // Move products and files in Core/Files, aggregate is account_merges.id
+	EventAccountDeleted = "account.deleted" // Delete the profile, products and files in Core/Files, aggregate is account_deletions.id
+)
+
+// UserRegisteredPayload - body of Core's POST /internal/user
+type UserRegisteredPayload struct {
+	UserAuthID string `json:"user_auth_id"`
+	Email      string `json:"email"`
+	Phone      string `json:"phone"`
+}
+
+// AccountMergedPayload - body of Core's POST /internal/user/merge. Email/Phone
+// is the identity that moved onto the surviving profile.
+type AccountMergedPayload struct {
+	MergeID      string `json:"merge_id"`
+	SourceUserID string `json:"source_user_id"`
+	TargetUserID string `json:"target_user_id"`
+	Email        string `json:"email"`
+	Phone        string `json:"phone"`
+}
+
+// AccountDeletedPayload - Core's DELETE /internal/user/:userAuthID is keyed by
+// UserAuthID, the rest is for its logs
+type AccountDeletedPayload struct {
+	DeletionID string `json:"deletion_id"`
+	UserAuthID string `json:"user_auth_id"`
+	UserID     string `json:"user_id"`
+}
+
+// Token Models
+type RefreshToken struct {
+	ID         uuid.UUID `json:"id"`
+	UserAuthID uuid.UUID `json:"user_auth_id"`
+	UserID     uuid.UUID `json:"user_id"`
+	FamilyID   uuid.UUID `json:"family_id"`
+	Revoked    bool      `json:"revoked"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+// Session Models
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	UserAuthID uuid.UUID `json:"user_auth_id"`
+	UserID     uuid.UUID `json:"user_id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Revoked    bool      `json:"revoked"`
+}
+
+// ClientInfo - where a login came from, recorded on its session
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// SessionResponse - a signed-in device as shown to its user. LastSeenAt moves
+// with every token refresh.
+type SessionResponse struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"userAgent"`
+	IPAddress  string    `json:"ipAddress"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	Current    bool      `json:"current"`
+}
+
+// Passwordless Models
+type PasswordlessStartRequest struct {
+	Email  string `json:"email"`
+	Phone  string `json:"phone"`
+	Method string `json:"method"` // "code" (default) or "link"
+}
+
+// PasswordlessVerifyRequest - either the code with the email/phone it was sent
+// to, or the token from a magic link
+type PasswordlessVerifyRequest struct {
 	Email string `json:"email"`
 	Phone string `json:"phone"`
+	Code  string `json:"code"`
 	Token string `json:"token"`
 }
 
-// Password utility functions
-func HashPassword(password string) (string, error) {
-	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", err
-	}
-	return string(hashedBytes), nil
+const (
+	PasswordlessMethodCode = "code"
+	PasswordlessMethodLink = "link"
+)
+
+// PasswordlessGrant - the outstanding one-time login of an account, kept in
+// Redis only. Nonce identifies it; CodeHash is set for codes.
+type PasswordlessGrant struct {
+	Nonce    string `json:"nonce"`
+	Method   string `json:"method"`
+	CodeHash string `json:"code_hash,omitempty"`
+}
+
+// Two-factor Models
+type TOTPFactor struct {
+	UserAuthID   uuid.UUID `json:"user_auth_id"`
+	Secret       string    `json:"secret"`
+	Enabled      bool      `json:"enabled"` // False while enrollment awaits its first code
+	LastUsedStep int64     `json:"last_used_step"`
+}
+
+// LoginChallenge - a login that passed the password step, kept until the
+// second factor is supplied
+type LoginChallenge struct {
+	UserAuthID uuid.UUID `json:"user_auth_id"`
+	UserID     string    `json:"user_id"`
+	Identifier string    `json:"identifier"` // Email or phone logged in with, for throttling
+}
+
+type TOTPLoginRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	Code           string `json:"code" binding:"required"` // TOTP or recovery code
+}
+
+type TOTPCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TOTPEnrollResponse - URI is otpauth://, for rendering as a QR code
+type TOTPEnrollResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type TOTPStatusResponse struct {
+	Enabled                bool  `json:"enabled"`
+	RecoveryCodesRemaining int64 `json:"recoveryCodesRemaining"`
+}
+
+// RecoveryCodesResponse - shown once, only hashes are kept
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// Passkey Models
+type Passkey struct {
+	ID           uuid.UUID  `json:"id"`
+	UserAuthID   uuid.UUID  `json:"user_auth_id"`
+	CredentialID []byte     `json:"credential_id"`
+	PublicKey    []byte     `json:"public_key"` // COSE_Key
+	SignCount    uint32     `json:"sign_count"`
+	Name         string     `json:"name"`
+	CreatedAt    time.Time  `json:"created_at"`
+	LastUsedAt   *time.Time `json:"last_used_at"`
+}
+
+// PasskeyCeremony - a registration or login in progress, kept in Redis under its
+// challenge until the authenticator's response comes back
+type PasskeyCeremony struct {
+	Type       string    `json:"type"`
+	UserAuthID uuid.UUID `json:"user_auth_id"` // Registrations only
+}
+
+const (
+	PasskeyCeremonyRegistration = "registration"
+	PasskeyCeremonyLogin        = "login"
+)
+
+type PasskeyRegisterRequest struct {
+	Name       string                          `json:"name" binding:"max=64"`
+	Credential webauthn.RegistrationCredential `json:"credential"`
+}
+
+// PasskeyLoginBeginRequest - email/phone is optional, without it any passkey the
+// authenticator holds for us may answer
+type PasskeyLoginBeginRequest struct {
+	Email string `json:"email"`
+	Phone string `json:"phone"`
+}
+
+type PasskeyLoginFinishRequest struct {
+	Credential webauthn.AssertionCredential `json:"credential"`
+}
+
+type PasskeyResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+}
+
+// Account Merge Models
+type AccountMerge struct {
+	ID               uuid.UUID  `json:"id"`
+	TargetUserAuthID uuid.UUID  `json:"target_user_auth_id"`
+	SourceUserAuthID uuid.UUID  `json:"source_user_auth_id"`
+	TargetUserID     uuid.UUID  `json:"target_user_id"`
+	SourceUserID     uuid.UUID  `json:"source_user_id"`
+	SourceEmail      string     `json:"source_email"`
+	SourcePhone      string     `json:"source_phone"`
+	VerifiedVia      string     `json:"verified_via"` // Channel the merged account was proven over
+	IPAddress        string     `json:"ip_address"`
+	UserAgent        string     `json:"user_agent"`
+	Status           string     `json:"status"`
+	ProductsMoved    int32      `json:"products_moved"`
+	FilesMoved       int32      `json:"files_moved"`
+	CreatedAt        time.Time  `json:"created_at"`
+	CompletedAt      *time.Time `json:"completed_at"`
+}
+
+const (
+	AccountMergePending   = "pending"
+	AccountMergeCompleted = "completed"
+)
+
+// AccountMergeRequest - the email or phone of the other account, which is folded
+// into the signed-in one
+type AccountMergeRequest struct {
+	Email string `json:"email"`
+	Phone string `json:"phone"`
+}
+
+// AccountMergeConfirmRequest - TOTPCode is the other account's, needed when it
+// has two-factor enabled
+type AccountMergeConfirmRequest struct {
+	Email    string `json:"email"`
+	Phone    string `json:"phone"`
+	Code     string `json:"code" binding:"required"`
+	TOTPCode string `json:"totpCode"`
+}
+
+type AccountMergeStartResponse struct {
+	Target    string    `json:"target"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type AccountMergeResponse struct {
+	ID            uuid.UUID  `json:"id"`
+	MergedEmail   string     `json:"mergedEmail"`
+	MergedPhone   string     `json:"mergedPhone"`
+	Status        string     `json:"status"`
+	ProductsMoved int32      `json:"productsMoved"`
+	FilesMoved    int32      `json:"filesMoved"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	CompletedAt   *time.Time `json:"completedAt"`
+}
+
+// Account Deletion Models
+type AccountDeletion struct {
+	ID                uuid.UUID  `json:"id"`
+	UserAuthID        uuid.UUID  `json:"user_auth_id"`
+	UserID            *uuid.UUID `json:"user_id"`
+	Status            string     `json:"status"`
+	ProductsDeleted   int32      `json:"products_deleted"`
+	FilesDeleted      int32      `json:"files_deleted"`
+	PurchasesScrubbed int32      `json:"purchases_scrubbed"`
+	CreatedAt         time.Time  `json:"created_at"`
+	CompletedAt       *time.Time `json:"completed_at"`
+}
+
+const (
+	AccountDeletionPending   = "pending"
+	AccountDeletionCompleted = "completed"
+)
+
+// Audit Log Models
+const (
+	AuditRegister       = "register"
+	AuditLogin          = "login"
+	AuditLinkStart      = "link.start"
+	AuditLinkVerify     = "link.verify"
+	AuditTokenRejected  = "token.rejected"
+	AuditPasswordChange = "password.change"
+	AuditImpersonate    = "impersonate"
+)
+
+const (
+	AuditSuccess    = "success"
+	AuditFailure    = "failure"
+	AuditChallenged = "challenged" // Password passed, second factor still required
+)
+
+// AuditEntry - one auth event to record. Without UserAuthID the entry is
+// attributed to the account Identifier belongs to. ActorUserID is the admin
+// when the event was done through impersonation.
+type AuditEntry struct {
+	EventType   string
+	Outcome     string
+	UserAuthID  *uuid.UUID
+	Identifier  string
+	Method      string
+	Reason      string
+	Client      ClientInfo
+	ActorUserID *uuid.UUID
+}
+
+// AuditLogFilter - what the admin audit query matches, zero values match all
+type AuditLogFilter struct {
+	UserAuthID *uuid.UUID
+	EventType  string
+	From       *time.Time
+	To         *time.Time
+	Limit      int
+	Offset     int
+}
+
+type AuditLogEntry struct {
+	ID          uuid.UUID  `json:"id"`
+	EventType   string     `json:"eventType"`
+	Outcome     string     `json:"outcome"`
+	UserAuthID  *uuid.UUID `json:"userAuthId"`
+	Identifier  string     `json:"identifier"`
+	Method      string     `json:"method"`
+	Reason      string     `json:"reason"`
+	IPAddress   string     `json:"ipAddress"`
+	UserAgent   string     `json:"userAgent"`
+	RequestID   string     `json:"requestId"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	ActorUserID *uuid.UUID `json:"actorUserId,omitempty"`
+}
+
+type AuditLogResponse struct {
+	Entries []AuditLogEntry `json:"entries"`
+	Limit   int             `json:"limit"`
+	Offset  int             `json:"offset"`
+	HasMore bool            `json:"hasMore"`
+}
+
+// UserAccess - what the account may do, embedded in its access tokens
+type UserAccess struct {
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
+
+// Impersonation Models
+// ReadOnly defaults to true, a token that can write has to be asked for
+type ImpersonateRequest struct {
+	UserID   string `json:"userId" binding:"required"`
+	Reason   string `json:"reason" binding:"required"`
+	ReadOnly *bool  `json:"readOnly"`
+}
+
+type ImpersonateResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	UserID    string    `json:"userId"`
+	ReadOnly  bool      `json:"readOnly"`
+}
+
+// Password Reset Models
+type VerificationCode struct {
+	ID         uuid.UUID `json:"id"`
+	UserAuthID uuid.UUID `json:"user_auth_id"`
+	Purpose    string    `json:"purpose"`
+	Target     string    `json:"target"`
+	CodeHash   string    `json:"code_hash"`
+	Attempts   int32     `json:"attempts"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Either email or phone identifies the account
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+	Phone string `json:"phone"`
 }
 
-func CheckPassword(password, hashedPassword string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
-	return err == nil
+type ResetPasswordRequest struct {
+	Email       string `json:"email"`
+	Phone       string `json:"phone"`
+	Code        string `json:"code" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required,min=8,max=128"`
 }
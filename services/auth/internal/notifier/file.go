@@ -0,0 +1,61 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileNotifier - appends messages as JSON lines to a file, for local development
+// and scripted flows that need to read the code back
+type FileNotifier struct {
+	mu   sync.Mutex
+	path string
+}
+
+type fileMessage struct {
+	Channel string    `json:"channel"`
+	To      string    `json:"to"`
+	Subject string    `json:"subject,omitempty"`
+	Body    string    `json:"body"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+func NewFileNotifier(path string) (*FileNotifier, error) {
+	if path == "" {
+		return nil, fmt.Errorf("notifier file path is required")
+	}
+	return &FileNotifier{path: path}, nil
+}
+
+func (n *FileNotifier) SendEmail(ctx context.Context, to, subject, body string) error {
+	return n.write(fileMessage{Channel: "email", To: to, Subject: subject, Body: body, SentAt: time.Now()})
+}
+
+func (n *FileNotifier) SendSMS(ctx context.Context, to, body string) error {
+	return n.write(fileMessage{Channel: "sms", To: to, Body: body, SentAt: time.Now()})
+}
+
+func (n *FileNotifier) write(msg fileMessage) error {
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, err := os.OpenFile(n.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open notification file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write notification: %w", err)
+	}
+	return nil
+}
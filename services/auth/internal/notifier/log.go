@@ -0,0 +1,24 @@
+package notifier
+
+import (
+	"context"
+
+	"github.com/teammachinist/tutuplapak/services/auth/internal/logger"
+)
+
+// LogNotifier - writes messages to the service log, for local development only
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (n *LogNotifier) SendEmail(ctx context.Context, to, subject, body string) error {
+	logger.InfoCtx(ctx, "Email notification", "to", to, "subject", subject, "body", body)
+	return nil
+}
+
+func (n *LogNotifier) SendSMS(ctx context.Context, to, body string) error {
+	logger.InfoCtx(ctx, "SMS notification", "to", to, "body", body)
+	return nil
+}
@@ -0,0 +1,35 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+)
+
+// Notifier delivers messages to users over email or SMS
+type Notifier interface {
+	SendEmail(ctx context.Context, to, subject, body string) error
+	SendSMS(ctx context.Context, to, body string) error
+}
+
+const (
+	DriverLog  = "log"
+	DriverFile = "file"
+)
+
+type Config struct {
+	Driver   string
+	FilePath string
+}
+
+// New - Pick the notifier implementation configured by NOTIFIER_DRIVER.
+// Only development drivers exist so far; real providers plug in here.
+func New(cfg Config) (Notifier, error) {
+	switch cfg.Driver {
+	case "", DriverLog:
+		return NewLogNotifier(), nil
+	case DriverFile:
+		return NewFileNotifier(cfg.FilePath)
+	default:
+		return nil, fmt.Errorf("unknown notifier driver %q", cfg.Driver)
+	}
+}
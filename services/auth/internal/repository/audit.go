@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/teammachinist/tutuplapak/services/auth/internal/database"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/model"
+)
+
+// CreateAuditLogEntry - Append one entry to the audit log
+func (r *UserRepository) CreateAuditLogEntry(ctx context.Context, entry *model.AuditEntry, requestID string) error {
+	return r.db.CreateAuditLogEntry(ctx, database.CreateAuditLogEntryParams{
+		EventType:   entry.EventType,
+		Outcome:     entry.Outcome,
+		UserAuthID:  entry.UserAuthID,
+		Identifier:  entry.Identifier,
+		Method:      entry.Method,
+		Reason:      entry.Reason,
+		IpAddress:   entry.Client.IP,
+		UserAgent:   entry.Client.UserAgent,
+		RequestID:   requestID,
+		ActorUserID: entry.ActorUserID,
+	})
+}
+
+// ListAuditLog - One page of entries matching filter, newest first
+func (r *UserRepository) ListAuditLog(ctx context.Context, filter model.AuditLogFilter) ([]model.AuditLogEntry, error) {
+	rows, err := r.db.ListAuditLog(ctx, database.ListAuditLogParams{
+		UserAuthID:  filter.UserAuthID,
+		EventType:   filter.EventType,
+		CreatedFrom: filter.From,
+		CreatedTo:   filter.To,
+		PageLimit:   int32(filter.Limit),
+		PageOffset:  int32(filter.Offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]model.AuditLogEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, model.AuditLogEntry{
+			ID:          row.ID,
+			EventType:   row.EventType,
+			Outcome:     row.Outcome,
+			UserAuthID:  row.UserAuthID,
+			Identifier:  row.Identifier,
+			Method:      row.Method,
+			Reason:      row.Reason,
+			IPAddress:   row.IpAddress,
+			UserAgent:   row.UserAgent,
+			RequestID:   row.RequestID,
+			CreatedAt:   row.CreatedAt,
+			ActorUserID: row.ActorUserID,
+		})
+	}
+	return entries, nil
+}
+
+// DeleteAuditLogBefore - Delete up to limit entries older than cutoff
+func (r *UserRepository) DeleteAuditLogBefore(ctx context.Context, cutoff time.Time, limit int32) (int64, error) {
+	return r.db.DeleteAuditLogBefore(ctx, database.DeleteAuditLogBeforeParams{
+		CreatedAt: cutoff,
+		Limit:     limit,
+	})
+}
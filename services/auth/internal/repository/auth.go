@@ -2,18 +2,22 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/teammachinist/tutuplapak/services/auth/internal/database"
 	"github.com/teammachinist/tutuplapak/services/auth/internal/model"
 )
 
 type UserRepository struct {
-	db *database.Queries
+	pool *pgxpool.Pool
+	db   *database.Queries
 }
 
-func NewUserRepository(db *database.Queries) *UserRepository {
-	return &UserRepository{db: db}
+func NewUserRepository(pool *pgxpool.Pool, db *database.Queries) *UserRepository {
+	return &UserRepository{pool: pool, db: db}
 }
 
 func (r *UserRepository) CheckPhoneExists(ctx context.Context, phone string) (bool, error) {
@@ -31,30 +35,39 @@ func (r *UserRepository) GetUserByPhone(ctx context.Context, phone string) (*mod
 	}
 
 	return &model.UserAuth{
-		ID:           user.ID,
-		Phone:        user.Phone,
-		PasswordHash: user.PasswordHash,
-		CreatedAt:    user.CreatedAt,
+		ID:            user.ID,
+		UserID:        user.UserID,
+		Email:         user.Email,
+		Phone:         user.Phone,
+		PasswordHash:  user.PasswordHash,
+		RegisteredVia: user.RegisteredVia,
+		CreatedAt:     user.CreatedAt,
 	}, nil
 }
 
-func (r *UserRepository) CreateUserByPhone(ctx context.Context, phone, passwordHash string) (*model.UserAuth, error) {
-	params := database.CreateUserByPhoneParams{
-		Phone:        phone,
-		PasswordHash: passwordHash,
-	}
-
-	user, err := r.db.CreateUserByPhone(ctx, params)
-	if err != nil {
-		return nil, err
-	}
-
-	return &model.UserAuth{
-		ID:           user.ID,
-		Phone:        user.Phone,
-		PasswordHash: user.PasswordHash,
-		CreatedAt:    user.CreatedAt,
-	}, nil
+// CreateUserByPhone - Create the account, grant the default roles and queue its
+// Core profile in one transaction. The event is first due at nextAttemptAt, giving
+// the caller that long to deliver it before the dispatcher does.
+func (r *UserRepository) CreateUserByPhone(ctx context.Context, phone, passwordHash string, nextAttemptAt time.Time) (*model.UserAuth, *model.OutboxEvent, error) {
+	return r.register(ctx, nextAttemptAt, func(q *database.Queries) (*model.UserAuth, error) {
+		user, err := q.CreateUserByPhone(ctx, database.CreateUserByPhoneParams{
+			Phone:        phone,
+			PasswordHash: passwordHash,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return &model.UserAuth{
+			ID:            user.ID,
+			UserID:        user.UserID,
+			Email:         user.Email,
+			Phone:         user.Phone,
+			PasswordHash:  user.PasswordHash,
+			RegisteredVia: user.RegisteredVia,
+			CreatedAt:     user.CreatedAt,
+		}, nil
+	})
 }
 
 func (r *UserRepository) CheckExistedUserAuthByEmail(ctx context.Context, email string) (bool, error) {
@@ -72,35 +85,116 @@ func (r *UserRepository) GetUserAuthByEmail(ctx context.Context, email string) (
 	}
 
 	return &model.UserAuth{
-		ID:           user.ID,
-		Email:        user.Email,
-		PasswordHash: user.PasswordHash,
-		CreatedAt:    user.CreatedAt,
+		ID:            user.ID,
+		UserID:        user.UserID,
+		Email:         user.Email,
+		Phone:         user.Phone,
+		PasswordHash:  user.PasswordHash,
+		RegisteredVia: user.RegisteredVia,
+		CreatedAt:     user.CreatedAt,
 	}, nil
 }
 
-func (r *UserRepository) RegisterWithEmail(ctx context.Context, email, passwordHash string) (*model.UserAuth, error) {
-	params := database.CreateUserByEmailParams{
-		Email:        email,
-		PasswordHash: passwordHash,
+func (r *UserRepository) GetUserAuthByID(ctx context.Context, userAuthID uuid.UUID) (*model.UserAuth, error) {
+	user, err := r.db.GetUserAuthByID(ctx, userAuthID)
+	if err != nil {
+		return nil, err
 	}
 
-	user, err := r.db.CreateUserByEmail(ctx, params)
+	return &model.UserAuth{
+		ID:            user.ID,
+		UserID:        user.UserID,
+		Email:         user.Email,
+		Phone:         user.Phone,
+		PasswordHash:  user.PasswordHash,
+		RegisteredVia: user.RegisteredVia,
+		CreatedAt:     user.CreatedAt,
+	}, nil
+}
+
+// GetUserAuthByUserID - The account behind Core's user.id, as carried in access tokens
+func (r *UserRepository) GetUserAuthByUserID(ctx context.Context, userID uuid.UUID) (*model.UserAuth, error) {
+	user, err := r.db.GetUserAuthByUserID(ctx, &userID)
 	if err != nil {
 		return nil, err
 	}
 
 	return &model.UserAuth{
-		ID:           user.ID,
-		Email:        user.Email,
-		PasswordHash: user.PasswordHash,
-		CreatedAt:    user.CreatedAt,
+		ID:            user.ID,
+		UserID:        user.UserID,
+		Email:         user.Email,
+		Phone:         user.Phone,
+		PasswordHash:  user.PasswordHash,
+		RegisteredVia: user.RegisteredVia,
+		CreatedAt:     user.CreatedAt,
 	}, nil
 }
 
-func (r *UserRepository) DeleteUserAuth(ctx context.Context, userAuthID uuid.UUID) error {
-	err := r.db.DeleteUserAuth(ctx, userAuthID)
-	return err
+// RegisterWithEmail - Same as CreateUserByPhone, for email registrations
+func (r *UserRepository) RegisterWithEmail(ctx context.Context, email, passwordHash string, nextAttemptAt time.Time) (*model.UserAuth, *model.OutboxEvent, error) {
+	return r.register(ctx, nextAttemptAt, func(q *database.Queries) (*model.UserAuth, error) {
+		user, err := q.CreateUserByEmail(ctx, database.CreateUserByEmailParams{
+			Email:        email,
+			PasswordHash: passwordHash,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return &model.UserAuth{
+			ID:            user.ID,
+			UserID:        user.UserID,
+			Email:         user.Email,
+			Phone:         user.Phone,
+			PasswordHash:  user.PasswordHash,
+			RegisteredVia: user.RegisteredVia,
+			CreatedAt:     user.CreatedAt,
+		}, nil
+	})
+}
+
+func (r *UserRepository) register(ctx context.Context, nextAttemptAt time.Time, create func(q *database.Queries) (*model.UserAuth, error)) (*model.UserAuth, *model.OutboxEvent, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	q := r.db.WithTx(tx)
+
+	userAuth, err := create(q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := q.AssignDefaultRoles(ctx, userAuth.ID); err != nil {
+		return nil, nil, err
+	}
+
+	payload, err := json.Marshal(model.UserRegisteredPayload{
+		UserAuthID: userAuth.ID.String(),
+		Email:      userAuth.Email,
+		Phone:      userAuth.Phone,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	event, err := q.CreateOutboxEvent(ctx, database.CreateOutboxEventParams{
+		AggregateID:   userAuth.ID,
+		EventType:     model.EventUserRegistered,
+		Payload:       payload,
+		NextAttemptAt: nextAttemptAt,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	return userAuth, toOutboxEvent(event), nil
 }
 
 func (r *UserRepository) UpdateUserAuthPhone(ctx context.Context, userAuthID uuid.UUID, phone string) error {
@@ -118,3 +212,10 @@ func (r *UserRepository) UpdateUserAuthEmail(ctx context.Context, userAuthID uui
 	})
 	return err
 }
+
+func (r *UserRepository) UpdateUserAuthPassword(ctx context.Context, userAuthID uuid.UUID, passwordHash string) error {
+	return r.db.UpdateUserAuthPassword(ctx, database.UpdateUserAuthPasswordParams{
+		ID:           userAuthID,
+		PasswordHash: passwordHash,
+	})
+}
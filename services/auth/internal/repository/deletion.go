@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/database"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/model"
+)
+
+// DeleteAccount - Delete the users_auth row, taking its sessions, codes,
+// passkeys, TOTP and roles with it, along with the outbox events and completed
+// merges that still hold its email/phone. The deletion record and the
+// account.deleted event that clears Core and Files are written in the same
+// transaction. Returns the revoked session ids.
+func (r *UserRepository) DeleteAccount(ctx context.Context, userAuth *model.UserAuth, nextAttemptAt time.Time) (*model.AccountDeletion, []uuid.UUID, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	q := r.db.WithTx(tx)
+
+	sessionIDs, err := q.RevokeUserSessions(ctx, userAuth.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := q.DeleteAccountOutboxEvents(ctx, userAuth.ID); err != nil {
+		return nil, nil, err
+	}
+	if err := q.DeleteAccountMerges(ctx, userAuth.ID); err != nil {
+		return nil, nil, err
+	}
+	if err := q.DeleteUserAuth(ctx, userAuth.ID); err != nil {
+		return nil, nil, err
+	}
+
+	row, err := q.CreateAccountDeletion(ctx, database.CreateAccountDeletionParams{
+		UserAuthID: userAuth.ID,
+		UserID:     userAuth.UserID,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var userID string
+	if row.UserID != nil {
+		userID = row.UserID.String()
+	}
+	payload, err := json.Marshal(model.AccountDeletedPayload{
+		DeletionID: row.ID.String(),
+		UserAuthID: row.UserAuthID.String(),
+		UserID:     userID,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := q.CreateOutboxEvent(ctx, database.CreateOutboxEventParams{
+		AggregateID:   row.ID,
+		EventType:     model.EventAccountDeleted,
+		Payload:       payload,
+		NextAttemptAt: nextAttemptAt,
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	return toAccountDeletionModel(row), sessionIDs, nil
+}
+
+// CompleteAccountDeleted - Record what Core removed and mark the event delivered
+func (r *UserRepository) CompleteAccountDeleted(ctx context.Context, eventID, deletionID uuid.UUID, productsDeleted, filesDeleted, purchasesScrubbed int32) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	q := r.db.WithTx(tx)
+
+	if err := q.CompleteAccountDeletion(ctx, database.CompleteAccountDeletionParams{
+		ID:                deletionID,
+		ProductsDeleted:   productsDeleted,
+		FilesDeleted:      filesDeleted,
+		PurchasesScrubbed: purchasesScrubbed,
+	}); err != nil {
+		return err
+	}
+
+	if err := q.CompleteOutboxEvent(ctx, eventID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// HasPendingAccountMergeInto - Whether an account merged into this one still has
+// products or files waiting to move to it
+func (r *UserRepository) HasPendingAccountMergeInto(ctx context.Context, targetUserAuthID uuid.UUID) (bool, error) {
+	return r.db.HasPendingAccountMergeInto(ctx, targetUserAuthID)
+}
+
+func toAccountDeletionModel(deletion database.AccountDeletions) *model.AccountDeletion {
+	return &model.AccountDeletion{
+		ID:                deletion.ID,
+		UserAuthID:        deletion.UserAuthID,
+		UserID:            deletion.UserID,
+		Status:            deletion.Status,
+		ProductsDeleted:   deletion.ProductsDeleted,
+		FilesDeleted:      deletion.FilesDeleted,
+		PurchasesScrubbed: deletion.PurchasesScrubbed,
+		CreatedAt:         deletion.CreatedAt,
+		CompletedAt:       deletion.CompletedAt,
+	}
+}
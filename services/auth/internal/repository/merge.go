@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/database"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/model"
+)
+
+// MergeAccounts - Fold merge.SourceUserAuthID into merge.TargetUserAuthID: the
+// source's email/phone move to the target and the source row is deleted, taking
+// its sessions, codes, passkeys and TOTP with it. The merge record and the
+// account.merged event that moves its products and files are written in the same
+// transaction. Returns the source's revoked session ids, or pgx.ErrNoRows when
+// the source is gone or no longer has the profile the merge was checked against.
+func (r *UserRepository) MergeAccounts(ctx context.Context, merge *model.AccountMerge, nextAttemptAt time.Time) (*model.AccountMerge, *model.OutboxEvent, []uuid.UUID, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	q := r.db.WithTx(tx)
+
+	sessionIDs, err := q.RevokeUserSessions(ctx, merge.SourceUserAuthID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Frees the source's email/phone for the target
+	deleted, err := q.DeleteMergedUserAuth(ctx, database.DeleteMergedUserAuthParams{
+		ID:     merge.SourceUserAuthID,
+		UserID: &merge.SourceUserID,
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if deleted == 0 {
+		return nil, nil, nil, pgx.ErrNoRows
+	}
+
+	if merge.SourceEmail != "" {
+		if _, err := q.UpdateUserAuthEmail(ctx, database.UpdateUserAuthEmailParams{
+			ID:    merge.TargetUserAuthID,
+			Email: merge.SourceEmail,
+		}); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	if merge.SourcePhone != "" {
+		if _, err := q.UpdateUserAuthPhone(ctx, database.UpdateUserAuthPhoneParams{
+			ID:    merge.TargetUserAuthID,
+			Phone: merge.SourcePhone,
+		}); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	row, err := q.CreateAccountMerge(ctx, database.CreateAccountMergeParams{
+		TargetUserAuthID: merge.TargetUserAuthID,
+		SourceUserAuthID: merge.SourceUserAuthID,
+		TargetUserID:     merge.TargetUserID,
+		SourceUserID:     merge.SourceUserID,
+		SourceEmail:      merge.SourceEmail,
+		SourcePhone:      merge.SourcePhone,
+		VerifiedVia:      merge.VerifiedVia,
+		IpAddress:        merge.IPAddress,
+		UserAgent:        merge.UserAgent,
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	payload, err := json.Marshal(model.AccountMergedPayload{
+		MergeID:      row.ID.String(),
+		SourceUserID: row.SourceUserID.String(),
+		TargetUserID: row.TargetUserID.String(),
+		Email:        row.SourceEmail,
+		Phone:        row.SourcePhone,
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	event, err := q.CreateOutboxEvent(ctx, database.CreateOutboxEventParams{
+		AggregateID:   row.ID,
+		EventType:     model.EventAccountMerged,
+		Payload:       payload,
+		NextAttemptAt: nextAttemptAt,
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return toAccountMergeModel(row), toOutboxEvent(event), sessionIDs, nil
+}
+
+// CompleteAccountMerged - Record what moved and mark the event delivered
+func (r *UserRepository) CompleteAccountMerged(ctx context.Context, eventID, mergeID uuid.UUID, productsMoved, filesMoved int32) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	q := r.db.WithTx(tx)
+
+	if err := q.CompleteAccountMerge(ctx, database.CompleteAccountMergeParams{
+		ID:            mergeID,
+		ProductsMoved: productsMoved,
+		FilesMoved:    filesMoved,
+	}); err != nil {
+		return err
+	}
+
+	if err := q.CompleteOutboxEvent(ctx, eventID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// HasPendingAccountMerge - Whether the account was merged away and its Core
+// profile still holds products or files waiting to move
+func (r *UserRepository) HasPendingAccountMerge(ctx context.Context, sourceUserAuthID uuid.UUID) (bool, error) {
+	return r.db.HasPendingAccountMerge(ctx, sourceUserAuthID)
+}
+
+// ListAccountMerges - Accounts merged into this one, newest first
+func (r *UserRepository) ListAccountMerges(ctx context.Context, targetUserAuthID uuid.UUID) ([]model.AccountMerge, error) {
+	rows, err := r.db.ListAccountMerges(ctx, targetUserAuthID)
+	if err != nil {
+		return nil, err
+	}
+
+	merges := make([]model.AccountMerge, 0, len(rows))
+	for _, row := range rows {
+		merges = append(merges, *toAccountMergeModel(row))
+	}
+	return merges, nil
+}
+
+func toAccountMergeModel(merge database.AccountMerges) *model.AccountMerge {
+	return &model.AccountMerge{
+		ID:               merge.ID,
+		TargetUserAuthID: merge.TargetUserAuthID,
+		SourceUserAuthID: merge.SourceUserAuthID,
+		TargetUserID:     merge.TargetUserID,
+		SourceUserID:     merge.SourceUserID,
+		SourceEmail:      merge.SourceEmail,
+		SourcePhone:      merge.SourcePhone,
+		VerifiedVia:      merge.VerifiedVia,
+		IPAddress:        merge.IpAddress,
+		UserAgent:        merge.UserAgent,
+		Status:           merge.Status,
+		ProductsMoved:    merge.ProductsMoved,
+		FilesMoved:       merge.FilesMoved,
+		CreatedAt:        merge.CreatedAt,
+		CompletedAt:      merge.CompletedAt,
+	}
+}
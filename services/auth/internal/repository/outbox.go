@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/database"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/model"
+)
+
+// EnqueueOutboxEvent - Queue an event, a no-op while one of the same type is
+// already pending for the aggregate
+func (r *UserRepository) EnqueueOutboxEvent(ctx context.Context, aggregateID uuid.UUID, eventType string, payload []byte) error {
+	return r.db.EnqueueOutboxEvent(ctx, database.EnqueueOutboxEventParams{
+		AggregateID: aggregateID,
+		EventType:   eventType,
+		Payload:     payload,
+	})
+}
+
+func (r *UserRepository) GetPendingOutboxEvent(ctx context.Context, eventType string, aggregateID uuid.UUID) (*model.OutboxEvent, error) {
+	event, err := r.db.GetPendingOutboxEvent(ctx, database.GetPendingOutboxEventParams{
+		EventType:   eventType,
+		AggregateID: aggregateID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toOutboxEvent(event), nil
+}
+
+// ClaimOutboxEvents - Take up to limit due events. Claimed events are hidden from
+// other dispatchers until lease passes, so a crashed dispatcher's work is retried.
+func (r *UserRepository) ClaimOutboxEvents(ctx context.Context, limit int32, lease time.Duration) ([]model.OutboxEvent, error) {
+	rows, err := r.db.ClaimOutboxEvents(ctx, database.ClaimOutboxEventsParams{
+		NextAttemptAt: time.Now().Add(lease),
+		Limit:         limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]model.OutboxEvent, 0, len(rows))
+	for _, row := range rows {
+		events = append(events, *toOutboxEvent(row))
+	}
+	return events, nil
+}
+
+func (r *UserRepository) RescheduleOutboxEvent(ctx context.Context, eventID uuid.UUID, lastError string, nextAttemptAt time.Time) error {
+	return r.db.RescheduleOutboxEvent(ctx, database.RescheduleOutboxEventParams{
+		ID:            eventID,
+		LastError:     lastError,
+		NextAttemptAt: nextAttemptAt,
+	})
+}
+
+// CompleteUserRegistered - Record Core's user.id on the account and mark the
+// event delivered, together so neither is lost
+func (r *UserRepository) CompleteUserRegistered(ctx context.Context, eventID, userAuthID, userID uuid.UUID) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	q := r.db.WithTx(tx)
+
+	if err := q.SetUserAuthUserID(ctx, database.SetUserAuthUserIDParams{
+		ID:     userAuthID,
+		UserID: &userID,
+	}); err != nil {
+		return err
+	}
+
+	if err := q.CompleteOutboxEvent(ctx, eventID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *UserRepository) SetUserAuthUserID(ctx context.Context, userAuthID, userID uuid.UUID) error {
+	return r.db.SetUserAuthUserID(ctx, database.SetUserAuthUserIDParams{
+		ID:     userAuthID,
+		UserID: &userID,
+	})
+}
+
+// ListUserAuthPage - Accounts ordered by id, starting after afterID. Only ID,
+// UserID and CreatedAt are filled in.
+func (r *UserRepository) ListUserAuthPage(ctx context.Context, afterID uuid.UUID, limit int32) ([]model.UserAuth, error) {
+	rows, err := r.db.ListUserAuthPage(ctx, database.ListUserAuthPageParams{
+		ID:    afterID,
+		Limit: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]model.UserAuth, 0, len(rows))
+	for _, row := range rows {
+		users = append(users, model.UserAuth{
+			ID:        row.ID,
+			UserID:    row.UserID,
+			CreatedAt: row.CreatedAt,
+		})
+	}
+	return users, nil
+}
+
+func toOutboxEvent(event database.OutboxEvents) *model.OutboxEvent {
+	return &model.OutboxEvent{
+		ID:            event.ID,
+		AggregateID:   event.AggregateID,
+		EventType:     event.EventType,
+		Payload:       event.Payload,
+		Attempts:      event.Attempts,
+		LastError:     event.LastError,
+		NextAttemptAt: event.NextAttemptAt,
+	}
+}
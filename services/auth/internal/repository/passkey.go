@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/database"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/model"
+)
+
+func (r *UserRepository) CreatePasskey(ctx context.Context, userAuthID uuid.UUID, credentialID, publicKey []byte, signCount uint32, aaguid uuid.UUID, name string) (*model.Passkey, error) {
+	passkey, err := r.db.CreatePasskeyCredential(ctx, database.CreatePasskeyCredentialParams{
+		UserAuthID:   userAuthID,
+		CredentialID: credentialID,
+		PublicKey:    publicKey,
+		SignCount:    int64(signCount),
+		Aaguid:       aaguid,
+		Name:         name,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toPasskeyModel(passkey), nil
+}
+
+func (r *UserRepository) GetPasskeyByCredentialID(ctx context.Context, credentialID []byte) (*model.Passkey, error) {
+	passkey, err := r.db.GetPasskeyCredentialByCredentialID(ctx, credentialID)
+	if err != nil {
+		return nil, err
+	}
+
+	return toPasskeyModel(passkey), nil
+}
+
+func (r *UserRepository) ListPasskeys(ctx context.Context, userAuthID uuid.UUID) ([]model.Passkey, error) {
+	rows, err := r.db.ListPasskeyCredentials(ctx, userAuthID)
+	if err != nil {
+		return nil, err
+	}
+
+	passkeys := make([]model.Passkey, 0, len(rows))
+	for _, row := range rows {
+		passkeys = append(passkeys, *toPasskeyModel(row))
+	}
+	return passkeys, nil
+}
+
+// UsePasskey - Move the sign count on from what the login verified against.
+// False when another login moved it first.
+func (r *UserRepository) UsePasskey(ctx context.Context, passkeyID uuid.UUID, oldSignCount, newSignCount uint32) (bool, error) {
+	rows, err := r.db.UsePasskeyCredential(ctx, database.UsePasskeyCredentialParams{
+		NewSignCount: int64(newSignCount),
+		ID:           passkeyID,
+		OldSignCount: int64(oldSignCount),
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// DeletePasskey - false when the account has no such passkey
+func (r *UserRepository) DeletePasskey(ctx context.Context, passkeyID, userAuthID uuid.UUID) (bool, error) {
+	rows, err := r.db.DeletePasskeyCredential(ctx, database.DeletePasskeyCredentialParams{
+		ID:         passkeyID,
+		UserAuthID: userAuthID,
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+func toPasskeyModel(passkey database.PasskeyCredentials) *model.Passkey {
+	return &model.Passkey{
+		ID:           passkey.ID,
+		UserAuthID:   passkey.UserAuthID,
+		CredentialID: passkey.CredentialID,
+		PublicKey:    passkey.PublicKey,
+		SignCount:    uint32(passkey.SignCount),
+		Name:         passkey.Name,
+		CreatedAt:    passkey.CreatedAt,
+		LastUsedAt:   passkey.LastUsedAt,
+	}
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/database"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/model"
+)
+
+func (r *UserRepository) CreateRefreshToken(ctx context.Context, userAuthID, userID, familyID uuid.UUID, tokenHash string, expiresAt time.Time) (*model.RefreshToken, error) {
+	token, err := r.db.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		UserAuthID: userAuthID,
+		UserID:     userID,
+		FamilyID:   familyID,
+		TokenHash:  tokenHash,
+		ExpiresAt:  expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toRefreshTokenModel(token), nil
+}
+
+func (r *UserRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	token, err := r.db.GetRefreshTokenByHash(ctx, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	return toRefreshTokenModel(token), nil
+}
+
+// RevokeRefreshToken reports whether this call did the revoking, so two
+// concurrent refreshes with the same token cannot both succeed.
+func (r *UserRepository) RevokeRefreshToken(ctx context.Context, id uuid.UUID) (bool, error) {
+	rows, err := r.db.RevokeRefreshToken(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+func (r *UserRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	return r.db.RevokeRefreshTokenFamily(ctx, familyID)
+}
+
+// RevokeUserRefreshTokens - Revoke every refresh token of the account, across all families
+func (r *UserRepository) RevokeUserRefreshTokens(ctx context.Context, userAuthID uuid.UUID) error {
+	return r.db.RevokeUserRefreshTokens(ctx, userAuthID)
+}
+
+func toRefreshTokenModel(token database.RefreshTokens) *model.RefreshToken {
+	return &model.RefreshToken{
+		ID:         token.ID,
+		UserAuthID: token.UserAuthID,
+		UserID:     token.UserID,
+		FamilyID:   token.FamilyID,
+		Revoked:    token.Revoked,
+		ExpiresAt:  token.ExpiresAt,
+	}
+}
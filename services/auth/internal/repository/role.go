@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/model"
+)
+
+// AssignDefaultRoles - Grant the roles every new account starts with
+func (r *UserRepository) AssignDefaultRoles(ctx context.Context, userAuthID uuid.UUID) error {
+	return r.db.AssignDefaultRoles(ctx, userAuthID)
+}
+
+// GetUserAccess - Roles of the account and the permissions they grant
+func (r *UserRepository) GetUserAccess(ctx context.Context, userAuthID uuid.UUID) (*model.UserAccess, error) {
+	roles, err := r.db.ListUserRoles(ctx, userAuthID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := r.db.ListUserPermissions(ctx, userAuthID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.UserAccess{
+		Roles:       roles,
+		Permissions: permissions,
+	}, nil
+}
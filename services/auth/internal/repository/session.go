@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/database"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/model"
+)
+
+func (r *UserRepository) CreateSession(ctx context.Context, userAuthID, userID uuid.UUID, client model.ClientInfo, expiresAt time.Time) (*model.Session, error) {
+	session, err := r.db.CreateSession(ctx, database.CreateSessionParams{
+		UserAuthID: userAuthID,
+		UserID:     userID,
+		UserAgent:  client.UserAgent,
+		IpAddress:  client.IP,
+		ExpiresAt:  expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toSessionModel(session), nil
+}
+
+func (r *UserRepository) GetSession(ctx context.Context, sessionID uuid.UUID) (*model.Session, error) {
+	session, err := r.db.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return toSessionModel(session), nil
+}
+
+// TouchSession - Record activity and push out expiry, false when the session is revoked
+func (r *UserRepository) TouchSession(ctx context.Context, sessionID uuid.UUID, expiresAt time.Time) (bool, error) {
+	rows, err := r.db.TouchSession(ctx, database.TouchSessionParams{
+		ID:        sessionID,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+func (r *UserRepository) ListActiveSessions(ctx context.Context, userID uuid.UUID) ([]model.Session, error) {
+	rows, err := r.db.ListActiveSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]model.Session, 0, len(rows))
+	for _, row := range rows {
+		sessions = append(sessions, *toSessionModel(row))
+	}
+	return sessions, nil
+}
+
+// RevokeSession - End one of the user's sessions along with its refresh tokens.
+// Reports false when there was no such live session.
+func (r *UserRepository) RevokeSession(ctx context.Context, sessionID, userID uuid.UUID) (bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	q := r.db.WithTx(tx)
+
+	rows, err := q.RevokeSession(ctx, database.RevokeSessionParams{
+		ID:     sessionID,
+		UserID: userID,
+	})
+	if err != nil {
+		return false, err
+	}
+	if rows == 0 {
+		return false, nil
+	}
+
+	if err := q.RevokeRefreshTokenFamily(ctx, sessionID); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// RevokeUserSessions - End every session of the account, returning their ids
+func (r *UserRepository) RevokeUserSessions(ctx context.Context, userAuthID uuid.UUID) ([]uuid.UUID, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	q := r.db.WithTx(tx)
+
+	sessionIDs, err := q.RevokeUserSessions(ctx, userAuthID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := q.RevokeUserRefreshTokens(ctx, userAuthID); err != nil {
+		return nil, err
+	}
+
+	return sessionIDs, tx.Commit(ctx)
+}
+
+func toSessionModel(session database.Sessions) *model.Session {
+	return &model.Session{
+		ID:         session.ID,
+		UserAuthID: session.UserAuthID,
+		UserID:     session.UserID,
+		UserAgent:  session.UserAgent,
+		IPAddress:  session.IpAddress,
+		CreatedAt:  session.CreatedAt,
+		LastSeenAt: session.LastSeenAt,
+		ExpiresAt:  session.ExpiresAt,
+		Revoked:    session.RevokedAt != nil,
+	}
+}
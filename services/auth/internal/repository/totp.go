@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/database"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/model"
+)
+
+// StartTOTPEnrollment - Store a new unconfirmed secret. Reports false when TOTP
+// is already enabled, the enabled secret is never replaced.
+func (r *UserRepository) StartTOTPEnrollment(ctx context.Context, userAuthID uuid.UUID, secret string) (bool, error) {
+	_, err := r.db.StartTOTPEnrollment(ctx, database.StartTOTPEnrollmentParams{
+		UserAuthID: userAuthID,
+		Secret:     secret,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetTOTPFactor - nil when the account never started enrollment
+func (r *UserRepository) GetTOTPFactor(ctx context.Context, userAuthID uuid.UUID) (*model.TOTPFactor, error) {
+	factor, err := r.db.GetTOTPFactor(ctx, userAuthID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.TOTPFactor{
+		UserAuthID:   factor.UserAuthID,
+		Secret:       factor.Secret,
+		Enabled:      factor.EnabledAt != nil,
+		LastUsedStep: factor.LastUsedStep,
+	}, nil
+}
+
+// EnableTOTP - Confirm the enrollment and replace the recovery codes in one go.
+// Reports false when there was no pending enrollment.
+func (r *UserRepository) EnableTOTP(ctx context.Context, userAuthID uuid.UUID, step int64, recoveryCodeHashes []string) (bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	q := r.db.WithTx(tx)
+
+	rows, err := q.EnableTOTPFactor(ctx, database.EnableTOTPFactorParams{
+		UserAuthID:   userAuthID,
+		LastUsedStep: step,
+	})
+	if err != nil {
+		return false, err
+	}
+	if rows == 0 {
+		return false, nil
+	}
+
+	if err := q.DeleteRecoveryCodes(ctx, userAuthID); err != nil {
+		return false, err
+	}
+	for _, codeHash := range recoveryCodeHashes {
+		if err := q.CreateRecoveryCode(ctx, database.CreateRecoveryCodeParams{
+			UserAuthID: userAuthID,
+			CodeHash:   codeHash,
+		}); err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// UseTOTPStep - Record step as used, false when it (or a later one) already was
+func (r *UserRepository) UseTOTPStep(ctx context.Context, userAuthID uuid.UUID, step int64) (bool, error) {
+	rows, err := r.db.UseTOTPStep(ctx, database.UseTOTPStepParams{
+		UserAuthID:   userAuthID,
+		LastUsedStep: step,
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// UseRecoveryCode reports whether this call used the code, so a code can only
+// ever be redeemed once
+func (r *UserRepository) UseRecoveryCode(ctx context.Context, userAuthID uuid.UUID, codeHash string) (bool, error) {
+	rows, err := r.db.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+		UserAuthID: userAuthID,
+		CodeHash:   codeHash,
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+func (r *UserRepository) CountRecoveryCodes(ctx context.Context, userAuthID uuid.UUID) (int64, error) {
+	return r.db.CountRecoveryCodes(ctx, userAuthID)
+}
+
+// DisableTOTP - Remove the secret and every recovery code
+func (r *UserRepository) DisableTOTP(ctx context.Context, userAuthID uuid.UUID) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	q := r.db.WithTx(tx)
+
+	if err := q.DeleteTOTPFactor(ctx, userAuthID); err != nil {
+		return err
+	}
+	if err := q.DeleteRecoveryCodes(ctx, userAuthID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/database"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/model"
+)
+
+func (r *UserRepository) CreateVerificationCode(ctx context.Context, userAuthID uuid.UUID, purpose, target, codeHash string, expiresAt time.Time) (*model.VerificationCode, error) {
+	code, err := r.db.CreateVerificationCode(ctx, database.CreateVerificationCodeParams{
+		UserAuthID: userAuthID,
+		Purpose:    purpose,
+		Target:     target,
+		CodeHash:   codeHash,
+		ExpiresAt:  expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toVerificationCodeModel(code), nil
+}
+
+// GetActiveVerificationCode - Latest unused, unexpired code for the purpose
+func (r *UserRepository) GetActiveVerificationCode(ctx context.Context, userAuthID uuid.UUID, purpose string) (*model.VerificationCode, error) {
+	code, err := r.db.GetActiveVerificationCode(ctx, database.GetActiveVerificationCodeParams{
+		UserAuthID: userAuthID,
+		Purpose:    purpose,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toVerificationCodeModel(code), nil
+}
+
+func (r *UserRepository) IncrementVerificationCodeAttempts(ctx context.Context, id uuid.UUID) (int32, error) {
+	return r.db.IncrementVerificationCodeAttempts(ctx, id)
+}
+
+// ConsumeVerificationCode reports whether this call used the code, so a code
+// can only ever be redeemed once
+func (r *UserRepository) ConsumeVerificationCode(ctx context.Context, id uuid.UUID) (bool, error) {
+	rows, err := r.db.ConsumeVerificationCode(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+func (r *UserRepository) InvalidateVerificationCodes(ctx context.Context, userAuthID uuid.UUID, purpose string) error {
+	return r.db.InvalidateVerificationCodes(ctx, database.InvalidateVerificationCodesParams{
+		UserAuthID: userAuthID,
+		Purpose:    purpose,
+	})
+}
+
+func toVerificationCodeModel(code database.VerificationCodes) *model.VerificationCode {
+	return &model.VerificationCode{
+		ID:         code.ID,
+		UserAuthID: code.UserAuthID,
+		Purpose:    code.Purpose,
+		Target:     code.Target,
+		CodeHash:   code.CodeHash,
+		Attempts:   code.Attempts,
+		ExpiresAt:  code.ExpiresAt,
+	}
+}
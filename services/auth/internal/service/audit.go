@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/teammachinist/tutuplapak/services/auth/internal/logger"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/model"
+	"github.com/teammachinist/tutuplapak/services/auth/pkg/authz"
+)
+
+// AuditRetentionConfig - entries older than Retention are deleted every
+// Interval, BatchSize rows per statement
+type AuditRetentionConfig struct {
+	Retention time.Duration
+	Interval  time.Duration
+	BatchSize int
+}
+
+const (
+	DefaultAuditPageSize = 50
+	MaxAuditPageSize     = 500
+
+	maxAuditFieldLength = 255 // identifier and reason columns
+)
+
+// Audit - Record the outcome of an auth event, a failure with err as the reason
+// when err is set. A failed write is logged and otherwise ignored, the audit log
+// being down must not stop anyone from logging in.
+func (s *UserService) Audit(ctx context.Context, entry model.AuditEntry, err error) {
+	if err != nil {
+		entry.Outcome = model.AuditFailure
+		entry.Reason = err.Error()
+	} else if entry.Outcome == "" {
+		entry.Outcome = model.AuditSuccess
+	}
+
+	entry.Identifier = truncateRunes(entry.Identifier, maxAuditFieldLength)
+	entry.Reason = truncateRunes(entry.Reason, maxAuditFieldLength)
+	entry.Client.UserAgent = truncateRunes(entry.Client.UserAgent, maxUserAgentLength)
+
+	if err := s.userRepo.CreateAuditLogEntry(ctx, &entry, logger.GetRequestID(ctx)); err != nil {
+		logger.ErrorCtx(ctx, "Failed to write audit log", "event_type", entry.EventType, "outcome", entry.Outcome, "error", err.Error())
+	}
+}
+
+// AuditTokenRejections - Record the tokens caller rejected without asking auth,
+// each under the request ID caller gave it. Anything past
+// authz.MaxTokenRejectionsPerReport is only counted.
+func (s *UserService) AuditTokenRejections(ctx context.Context, caller string, report authz.TokenRejectionReport) {
+	rejections := report.Rejections
+	dropped := max(report.Dropped, 0)
+	if len(rejections) > authz.MaxTokenRejectionsPerReport {
+		dropped += len(rejections) - authz.MaxTokenRejectionsPerReport
+		rejections = rejections[:authz.MaxTokenRejectionsPerReport]
+	}
+
+	for _, rejection := range rejections {
+		entryCtx := ctx
+		if rejection.RequestID != "" {
+			entryCtx = context.WithValue(ctx, logger.RequestIDKey, rejection.RequestID)
+		}
+		s.Audit(entryCtx, model.AuditEntry{
+			EventType: model.AuditTokenRejected,
+			Method:    "local",
+			Client:    model.ClientInfo{IP: rejection.IPAddress, UserAgent: rejection.UserAgent},
+		}, fmt.Errorf("%s: %s", caller, rejection.Reason))
+	}
+
+	if dropped > 0 {
+		s.Audit(ctx, model.AuditEntry{
+			EventType: model.AuditTokenRejected,
+			Method:    "local",
+		}, fmt.Errorf("%s: %d more not reported", caller, dropped))
+	}
+}
+
+// ListAuditLog - One page of the audit log, newest first. One extra row is read
+// to tell whether another page follows.
+func (s *UserService) ListAuditLog(ctx context.Context, filter model.AuditLogFilter) (*model.AuditLogResponse, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultAuditPageSize
+	}
+	filter.Limit = min(filter.Limit, MaxAuditPageSize)
+	filter.Offset = max(filter.Offset, 0)
+
+	page := filter
+	page.Limit++
+	entries, err := s.userRepo.ListAuditLog(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(entries) > filter.Limit
+	if hasMore {
+		entries = entries[:filter.Limit]
+	}
+
+	return &model.AuditLogResponse{
+		Entries: entries,
+		Limit:   filter.Limit,
+		Offset:  filter.Offset,
+		HasMore: hasMore,
+	}, nil
+}
+
+// RunAuditRetention - Delete entries past the retention period every interval
+// until ctx is done
+func (s *UserService) RunAuditRetention(ctx context.Context, cfg AuditRetentionConfig) {
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.PurgeAuditLog(ctx, cfg)
+			if err != nil {
+				logger.ErrorCtx(ctx, "Audit log retention failed", "deleted", deleted, "error", err.Error())
+				continue
+			}
+			if deleted > 0 {
+				logger.InfoCtx(ctx, "Audit log retention finished", "deleted", deleted)
+			}
+		}
+	}
+}
+
+// PurgeAuditLog - Delete every entry older than the retention period, a batch
+// at a time
+func (s *UserService) PurgeAuditLog(ctx context.Context, cfg AuditRetentionConfig) (int64, error) {
+	cutoff := time.Now().Add(-cfg.Retention)
+
+	var total int64
+	for {
+		deleted, err := s.userRepo.DeleteAuditLogBefore(ctx, cutoff, int32(cfg.BatchSize))
+		if err != nil {
+			return total, err
+		}
+		total += deleted
+		if deleted < int64(cfg.BatchSize) || ctx.Err() != nil {
+			return total, nil
+		}
+	}
+}
+
+// truncateRunes - s cut to at most n characters, never mid character
+func truncateRunes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/teammachinist/tutuplapak/services/auth/internal/database"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/repository"
+	"github.com/teammachinist/tutuplapak/services/auth/pkg/authz"
+)
+
+func TestAuditTokenRejections(t *testing.T) {
+	db := newFakeDB()
+	var entries []database.CreateAuditLogEntryParams
+	db.execs["CreateAuditLogEntry"] = func(args []any) (int64, error) {
+		entries = append(entries, database.CreateAuditLogEntryParams{
+			EventType: args[0].(string),
+			Method:    args[4].(string),
+			Reason:    args[5].(string),
+			IpAddress: args[6].(string),
+			RequestID: args[8].(string),
+		})
+		return 1, nil
+	}
+	s := &UserService{userRepo: repository.NewUserRepository(nil, database.New(db))}
+
+	// More than a report may hold, the rest is added to what core dropped
+	report := authz.TokenRejectionReport{Dropped: 3}
+	for i := range authz.MaxTokenRejectionsPerReport + 2 {
+		report.Rejections = append(report.Rejections, authz.TokenRejection{
+			Reason:     "token has been revoked",
+			RequestID:  fmt.Sprintf("req-%d", i),
+			ClientInfo: authz.ClientInfo{IPAddress: "203.0.113.7"},
+		})
+	}
+	s.AuditTokenRejections(context.Background(), authz.ServiceCore, report)
+
+	if len(entries) != authz.MaxTokenRejectionsPerReport+1 {
+		t.Fatalf("%d entries, want %d and one for the rest", len(entries), authz.MaxTokenRejectionsPerReport)
+	}
+	first := entries[0]
+	if first.EventType != "token.rejected" || first.Method != "local" || first.Reason != "core: token has been revoked" ||
+		first.IpAddress != "203.0.113.7" || first.RequestID != "req-0" {
+		t.Errorf("unexpected entry %+v", first)
+	}
+	if last := entries[len(entries)-1]; last.Reason != "core: 5 more not reported" {
+		t.Errorf("got reason %q for the rest", last.Reason)
+	}
+}
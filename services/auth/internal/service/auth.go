@@ -7,19 +7,24 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v5/pgconn"
 
 	"github.com/teammachinist/tutuplapak/services/auth/internal/cache"
 	"github.com/teammachinist/tutuplapak/services/auth/internal/database"
 	"github.com/teammachinist/tutuplapak/services/auth/internal/logger"
 	"github.com/teammachinist/tutuplapak/services/auth/internal/model"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/notifier"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/password"
 	"github.com/teammachinist/tutuplapak/services/auth/internal/repository"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/webauthn"
 	"github.com/teammachinist/tutuplapak/services/auth/pkg/authz"
 )
 
@@ -30,96 +35,135 @@ type UserService struct {
 	coreServiceURL string
 	httpClient     *http.Client
 	cache          *cache.RedisCache
+	throttle       LoginThrottleConfig
+	notifier       notifier.Notifier
+	hasher         *password.Hasher
+	totpIssuer     string
+	passwordless   PasswordlessConfig
+	webauthn       *webauthn.Config
 }
 
 type JWTConfig struct {
-	Key      string
-	Duration time.Duration
-	Issuer   string
+	Keys             *SigningKeys
+	Duration         time.Duration
+	RefreshDuration  time.Duration
+	ImpersonationTTL time.Duration
+	Issuer           string
 }
 
 type TokenClaims struct {
-	UserID string `json:"user_id"`
+	UserID      string       `json:"user_id"`
+	SessionID   string       `json:"sid,omitempty"`
+	Roles       []string     `json:"roles,omitempty"`
+	Permissions []string     `json:"permissions,omitempty"`
+	Actor       *authz.Actor `json:"act,omitempty"`
+	ReadOnly    bool         `json:"read_only,omitempty"`
 	jwt.RegisteredClaims
 }
 
 const (
-	UserAuthByEmailKey = "userauth:email:%s"
-	UserAuthByPhoneKey = "userauth:phone:%s"
 	TokenValidationKey = "token:valid:%s"
+	RevokedTokenKey    = authz.RevokedTokenKey
+	RevokedUserKey     = authz.RevokedUserKey
+	RevokedSessionKey  = authz.RevokedSessionKey
 )
 
 const (
-	UserAuthTTL        = 15 * time.Minute
 	TokenValidationTTL = 5 * time.Minute
 )
 
-func NewUserService(userRepo *repository.UserRepository, db *database.Queries, jwtConfig *JWTConfig, coreServiceURL string, cache *cache.RedisCache) *UserService {
+func NewUserService(userRepo *repository.UserRepository, db *database.Queries, jwtConfig *JWTConfig, coreServiceURL string, signer *authz.ServiceSigner, cache *cache.RedisCache, throttle LoginThrottleConfig, notifier notifier.Notifier, hasher *password.Hasher, totpIssuer string, passwordless PasswordlessConfig, webauthn *webauthn.Config) *UserService {
 	return &UserService{
 		userRepo:       userRepo,
 		db:             db,
 		jwtConfig:      jwtConfig,
 		coreServiceURL: coreServiceURL,
 		cache:          cache,
+		throttle:       throttle,
+		notifier:       notifier,
+		hasher:         hasher,
+		totpIssuer:     totpIssuer,
+		passwordless:   passwordless,
+		webauthn:       webauthn,
+		// Core's /internal routes only accept requests signed by auth
 		httpClient: &http.Client{
-			Timeout: 5 * time.Second,
+			Timeout:   5 * time.Second,
+			Transport: signer.Transport(nil),
 		},
 	}
 }
 
-func (s *UserService) GenerateToken(userID string) (string, error) {
+func (s *UserService) GenerateToken(userID, sessionID string, access *model.UserAccess) (string, error) {
 	claims := TokenClaims{
-		UserID: userID,
+		UserID:      userID,
+		SessionID:   sessionID,
+		Roles:       access.Roles,
+		Permissions: access.Permissions,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.jwtConfig.Duration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    s.jwtConfig.Issuer,
+			Audience:  jwt.ClaimStrings{authz.AccessTokenAudience},
 		},
 	}
+	return s.signToken(claims)
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.jwtConfig.Key))
+func (s *UserService) signToken(claims TokenClaims) (string, error) {
+	key := s.jwtConfig.Keys.Active()
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.PrivateKey)
 }
 
 func (s *UserService) ValidateTokenInternal(tokenString string) (*authz.Claims, error) {
-	// Simple cache key (first 16 chars of token for security)
-	cacheKey := fmt.Sprintf(TokenValidationKey, tokenString[:min(16, len(tokenString))])
+	ctx := context.Background()
+
+	// Key by token hash; a prefix would collide since every JWT starts with the same header
+	cacheKey := fmt.Sprintf(TokenValidationKey, hashToken(tokenString))
 
-	// Try cache first
+	// Try cache first, revocation is still checked on a hit
 	var cachedClaims authz.Claims
-	if err := s.cache.Get(context.Background(), cacheKey, &cachedClaims); err == nil {
+	if err := s.cache.Get(ctx, cacheKey, &cachedClaims); err == nil {
+		if s.isTokenRevoked(ctx, &cachedClaims) {
+			return nil, errors.New("token has been revoked")
+		}
 		return &cachedClaims, nil
 	}
 
 	// Cache miss - validate token
-	token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(s.jwtConfig.Key), nil
-	})
-
+	claims, err := s.parseToken(tokenString)
 	if err != nil {
-		if errors.Is(err, jwt.ErrTokenExpired) {
-			return nil, errors.New("token has expired")
-		}
-		return nil, errors.New("invalid token")
+		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*TokenClaims); ok && token.Valid {
-		result := &authz.Claims{UserID: claims.UserID}
-
-		// Cache valid token for short time
-		s.cache.Set(context.Background(), cacheKey, result, TokenValidationTTL)
+	result := &authz.Claims{
+		UserID:      claims.UserID,
+		TokenID:     claims.ID,
+		SessionID:   claims.SessionID,
+		Roles:       claims.Roles,
+		Permissions: claims.Permissions,
+		Actor:       claims.Actor,
+		ReadOnly:    claims.ReadOnly,
+	}
+	if claims.IssuedAt != nil {
+		result.IssuedAt = claims.IssuedAt.Unix()
+	}
 
-		return result, nil
+	if s.isTokenRevoked(ctx, result) {
+		return nil, errors.New("token has been revoked")
 	}
 
-	return nil, errors.New("invalid token")
+	// Cache valid token for short time, never past its expiry
+	ttl := min(TokenValidationTTL, time.Until(claims.ExpiresAt.Time))
+	s.cache.Set(ctx, cacheKey, result, ttl)
+
+	return result, nil
 }
 
-func (s *UserService) LoginWithEmail(ctx context.Context, email, password string) (*model.AuthResponse, error) {
+func (s *UserService) LoginWithEmail(ctx context.Context, email, password string, client model.ClientInfo) (*model.AuthResponse, error) {
 	// Validate email format
 	if err := s.validateEmail(email); err != nil {
 		return nil, err
@@ -130,50 +174,37 @@ func (s *UserService) LoginWithEmail(ctx context.Context, email, password string
 		return nil, err
 	}
 
-	// Try cache first
-	cacheKey := fmt.Sprintf(UserAuthByEmailKey, email)
-	var cachedUser model.UserAuth
-
-	var userAuth *model.UserAuth
-	var err error
-
-	if cacheErr := s.cache.Get(ctx, cacheKey, &cachedUser); cacheErr == nil {
-		userAuth = &cachedUser
-	} else {
-		// Cache miss - get from DB
-		userAuth, err = s.userRepo.GetUserAuthByEmail(ctx, email)
-		if err != nil {
-			return nil, errors.New("email not found")
-		}
+	if err := s.checkLoginAllowed(ctx, email, client.IP); err != nil {
+		return nil, err
+	}
 
-		// Cache for future use
-		s.cache.Set(ctx, cacheKey, userAuth, UserAuthTTL)
+	// Always read from DB, the password hash must never sit in the cache
+	userAuth, err := s.userRepo.GetUserAuthByEmail(ctx, email)
+	if err != nil {
+		s.recordLoginFailure(ctx, email, client.IP)
+		return nil, errors.New("email not found")
 	}
 
 	// Check password
-	if !model.CheckPassword(password, userAuth.PasswordHash) {
+	match, needsRehash := s.hasher.Verify(password, userAuth.PasswordHash)
+	if !match {
+		s.recordLoginFailure(ctx, email, client.IP)
 		return nil, errors.New("invalid credentials")
 	}
 
-	coreUser, err := s.GetUserFromCore(userAuth.ID.String())
-	if err != nil {
-		return nil, errors.New("user profile not found")
+	if needsRehash {
+		s.rehashPassword(ctx, userAuth, password)
 	}
 
-	// Generate token with Core's user.id
-	token, err := s.GenerateToken(coreUser.ID)
+	userID, err := s.ensureProfile(ctx, userAuth)
 	if err != nil {
-		return nil, errors.New("failed to generate token")
+		return nil, err
 	}
 
-	return &model.AuthResponse{
-		Email: userAuth.Email,
-		Phone: userAuth.Phone,
-		Token: token,
-	}, nil
+	return s.finishLogin(ctx, userAuth, userID, email, client)
 }
 
-func (s *UserService) LoginByPhone(ctx context.Context, phone, password string) (*model.AuthResponse, error) {
+func (s *UserService) LoginByPhone(ctx context.Context, phone, password string, client model.ClientInfo) (*model.AuthResponse, error) {
 	// Validate phone format
 	if err := s.validatePhone(phone); err != nil {
 		return nil, err
@@ -184,50 +215,66 @@ func (s *UserService) LoginByPhone(ctx context.Context, phone, password string)
 		return nil, err
 	}
 
-	// Try cache first
-	cacheKey := fmt.Sprintf(UserAuthByPhoneKey, phone)
-	var cachedUser model.UserAuth
-
-	var userAuth *model.UserAuth
-	var err error
-
-	if cacheErr := s.cache.Get(ctx, cacheKey, &cachedUser); cacheErr == nil {
-		userAuth = &cachedUser
-	} else {
-		// Cache miss - get from DB
-		userAuth, err = s.userRepo.GetUserByPhone(ctx, phone)
-		if err != nil {
-			return nil, errors.New("phone not found")
-		}
+	if err := s.checkLoginAllowed(ctx, phone, client.IP); err != nil {
+		return nil, err
+	}
 
-		// Cache for future use
-		s.cache.Set(ctx, cacheKey, userAuth, UserAuthTTL)
+	// Always read from DB, the password hash must never sit in the cache
+	userAuth, err := s.userRepo.GetUserByPhone(ctx, phone)
+	if err != nil {
+		s.recordLoginFailure(ctx, phone, client.IP)
+		return nil, errors.New("phone not found")
 	}
 
 	// Check password
-	if !model.CheckPassword(password, userAuth.PasswordHash) {
+	match, needsRehash := s.hasher.Verify(password, userAuth.PasswordHash)
+	if !match {
+		s.recordLoginFailure(ctx, phone, client.IP)
 		return nil, errors.New("invalid credentials")
 	}
 
-	coreUser, err := s.GetUserFromCore(userAuth.ID.String())
+	if needsRehash {
+		s.rehashPassword(ctx, userAuth, password)
+	}
+
+	userID, err := s.ensureProfile(ctx, userAuth)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.finishLogin(ctx, userAuth, userID, phone, client)
+}
+
+// finishLogin - The first factor (password or one-time login) passed: issue
+// tokens, or a challenge for the second factor when the account has TOTP
+// enabled. Login failures stay counted until both pass.
+func (s *UserService) finishLogin(ctx context.Context, userAuth *model.UserAuth, userID, identifier string, client model.ClientInfo) (*model.AuthResponse, error) {
+	factor, err := s.userRepo.GetTOTPFactor(ctx, userAuth.ID)
 	if err != nil {
-		return nil, errors.New("user profile not found")
+		logger.ErrorCtx(ctx, "Failed to load TOTP factor", "user_auth_id", userAuth.ID.String(), "error", err.Error())
+		return nil, errors.New("failed to generate token")
 	}
+	if factor != nil && factor.Enabled {
+		return s.startLoginChallenge(ctx, userAuth, userID, identifier)
+	}
+
+	s.resetLoginFailures(ctx, identifier)
 
-	// Generate token with Core's user.id
-	token, err := s.GenerateToken(coreUser.ID)
+	// Generate access and refresh tokens with Core's user.id
+	tokens, err := s.startSession(ctx, userAuth.ID, userID, client)
 	if err != nil {
 		return nil, errors.New("failed to generate token")
 	}
 
 	return &model.AuthResponse{
-		Email: userAuth.Email,
-		Phone: userAuth.Phone,
-		Token: token,
+		Email:        userAuth.Email,
+		Phone:        userAuth.Phone,
+		Token:        tokens.Token,
+		RefreshToken: tokens.RefreshToken,
 	}, nil
 }
 
-func (s *UserService) RegisterWithEmail(ctx context.Context, email, password string) (*model.AuthResponse, error) {
+func (s *UserService) RegisterWithEmail(ctx context.Context, email, password string, client model.ClientInfo) (*model.AuthResponse, error) {
 	// Validate email format
 	if err := s.validateEmail(email); err != nil {
 		return nil, err
@@ -248,13 +295,13 @@ func (s *UserService) RegisterWithEmail(ctx context.Context, email, password str
 	}
 
 	// Hash password
-	passwordHash, err := model.HashPassword(password)
+	passwordHash, err := s.hasher.Hash(password)
 	if err != nil {
 		return nil, errors.New("failed to hash password")
 	}
 
-	// Create user auth
-	userAuth, err := s.userRepo.RegisterWithEmail(ctx, email, passwordHash)
+	// Create user auth, its roles and the event that creates the Core profile
+	userAuth, event, err := s.userRepo.RegisterWithEmail(ctx, email, passwordHash, time.Now().Add(OutboxLease))
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
@@ -265,36 +312,10 @@ func (s *UserService) RegisterWithEmail(ctx context.Context, email, password str
 		return nil, err
 	}
 
-	// Wait for core service to create user and return user.id
-	coreUser, err := s.CreateUserInCoreSync(ctx, userAuth.ID.String(), email, "")
-	if err != nil {
-		logger.WarnCtx(ctx, "Failed to create user in core service", "user_auth_id", userAuth.ID.String(), "error", err.Error())
-		// Rollback: Delete the userAuth record
-		if rollbackErr := s.userRepo.DeleteUserAuth(ctx, userAuth.ID); rollbackErr != nil {
-			// Log rollback failure but don't change the original error
-			logger.WarnCtx(ctx, "Failed to rollback userAuth creation", "user_auth_id", userAuth.ID.String(), "error", rollbackErr)
-		}
-		return nil, errors.New("failed to create user profile")
-	}
-
-	// Generate token with coreUser ID
-	token, err := s.GenerateToken(coreUser.ID)
-	if err != nil {
-		return nil, errors.New("failed to generate token")
-	}
-
-	// Cache the new user
-	cacheKey := fmt.Sprintf(UserAuthByEmailKey, email)
-	s.cache.Set(ctx, cacheKey, userAuth, UserAuthTTL)
-
-	return &model.AuthResponse{
-		Email: userAuth.Email,
-		Phone: userAuth.Phone,
-		Token: token,
-	}, nil
+	return s.completeRegistration(ctx, userAuth, event, client)
 }
 
-func (s *UserService) RegisterByPhone(ctx context.Context, phone, password string) (*model.AuthResponse, error) {
+func (s *UserService) RegisterByPhone(ctx context.Context, phone, password string, client model.ClientInfo) (*model.AuthResponse, error) {
 	// Validate inputs
 	if err := s.validatePhone(phone); err != nil {
 		return nil, err
@@ -313,13 +334,13 @@ func (s *UserService) RegisterByPhone(ctx context.Context, phone, password strin
 	}
 
 	// Hash password
-	passwordHash, err := model.HashPassword(password)
+	passwordHash, err := s.hasher.Hash(password)
 	if err != nil {
 		return nil, errors.New("failed to hash password")
 	}
 
-	// Create user auth
-	userAuth, err := s.userRepo.CreateUserByPhone(ctx, phone, passwordHash)
+	// Create user auth, its roles and the event that creates the Core profile
+	userAuth, event, err := s.userRepo.CreateUserByPhone(ctx, phone, passwordHash, time.Now().Add(OutboxLease))
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
@@ -330,41 +351,24 @@ func (s *UserService) RegisterByPhone(ctx context.Context, phone, password strin
 		return nil, err
 	}
 
-	// Wait for core service to create user and return user.id
-	coreUser, err := s.CreateUserInCoreSync(ctx, userAuth.ID.String(), "", phone)
-	if err != nil {
-		logger.WarnCtx(ctx, "Failed to create user in core service", "user_auth_id", userAuth.ID.String(), "error", err.Error())
-		// Rollback: Delete the userAuth record
-		if rollbackErr := s.userRepo.DeleteUserAuth(ctx, userAuth.ID); rollbackErr != nil {
-			// Log rollback failure but don't change the original error
-			logger.WarnCtx(ctx, "Failed to rollback userAuth creation", "user_auth_id", userAuth.ID.String(), "error", rollbackErr)
-		}
-		return nil, errors.New("failed to create user profile")
-	}
+	return s.completeRegistration(ctx, userAuth, event, client)
+}
 
-	// Generate token with user_auth_id
-	token, err := s.GenerateToken(coreUser.ID)
+// rehashPassword - Upgrade a stored hash to the current algorithm and parameters
+// while the plaintext is at hand. A failure only delays it to the next login.
+func (s *UserService) rehashPassword(ctx context.Context, userAuth *model.UserAuth, password string) {
+	passwordHash, err := s.hasher.Hash(password)
 	if err != nil {
-		return nil, errors.New("failed to generate token")
+		logger.WarnCtx(ctx, "Failed to rehash password", "user_auth_id", userAuth.ID.String(), "error", err.Error())
+		return
 	}
 
-	// Cache the new user
-	cacheKey := fmt.Sprintf(UserAuthByPhoneKey, phone)
-	s.cache.Set(ctx, cacheKey, userAuth, UserAuthTTL)
-
-	return &model.AuthResponse{
-		Email: userAuth.Email,
-		Phone: userAuth.Phone,
-		Token: token,
-	}, nil
-}
-
-// Helper function for min
-func min(a, b int) int {
-	if a < b {
-		return a
+	if err := s.userRepo.UpdateUserAuthPassword(ctx, userAuth.ID, passwordHash); err != nil {
+		logger.WarnCtx(ctx, "Failed to store rehashed password", "user_auth_id", userAuth.ID.String(), "error", err.Error())
+		return
 	}
-	return b
+
+	logger.InfoCtx(ctx, "Password rehashed", "user_auth_id", userAuth.ID.String())
 }
 
 // Validation methods - no logging here
@@ -407,83 +411,14 @@ func (s *UserService) validatePassword(password string) error {
 		return errors.New("password must be at least 8 characters long")
 	}
 
-	if len(password) > 32 {
-		return errors.New("password must be at most 32 characters long")
+	// argon2id takes any length, the cap only bounds the work one request causes
+	if len(password) > 128 {
+		return errors.New("password must be at most 128 characters long")
 	}
 
 	return nil
 }
 
-// LinkPhone - Link phone number to existing user auth
-func (s *UserService) LinkPhone(ctx context.Context, userAuthID, phone string) error {
-	if err := s.validatePhone(phone); err != nil {
-		return err
-	}
-
-	// Check if phone already exists (different user)
-	exists, err := s.userRepo.CheckPhoneExists(ctx, phone)
-	if err != nil {
-		return err
-	}
-	if exists {
-		return errors.New("phone number already exists")
-	}
-
-	userAuthUUID, err := uuid.Parse(userAuthID)
-	if err != nil {
-		return errors.New("invalid user_auth_id format")
-	}
-
-	err = s.userRepo.UpdateUserAuthPhone(ctx, userAuthUUID, phone)
-	if err != nil {
-		if strings.Contains(err.Error(), "no rows") {
-			return errors.New("user auth not found")
-		}
-		return err
-	}
-
-	// Invalidate cache for this user
-	// Optionally add cache by getting the updated user auth data
-
-	return nil
-}
-
-// LinkEmail - Link email to existing user auth
-func (s *UserService) LinkEmail(ctx context.Context, userAuthID, email string) error {
-	// Validate email format
-	if err := s.validateEmail(email); err != nil {
-		return err
-	}
-
-	// Check if email already exists (different user)
-	exists, err := s.userRepo.CheckExistedUserAuthByEmail(ctx, email)
-	if err != nil {
-		return err
-	}
-	if exists {
-		return errors.New("email address already exists")
-	}
-
-	userAuthUUID, err := uuid.Parse(userAuthID)
-	if err != nil {
-		return errors.New("invalid user_auth_id format")
-	}
-
-	// Update user auth with email
-	err = s.userRepo.UpdateUserAuthEmail(ctx, userAuthUUID, email)
-	if err != nil {
-		if strings.Contains(err.Error(), "no rows") {
-			return errors.New("user auth not found")
-		}
-		return err
-	}
-
-	// Invalidate cache for this user
-	// Optionally add cache by getting the updated user auth data
-
-	return nil
-}
-
 // HTTP calls to Core will be placed here first for a quick refactor
 // CreateUserInCoreSync - Call core service and return the created user with user.id
 type CoreUser struct {
@@ -579,3 +514,118 @@ func (s *UserService) GetUserFromCore(userAuthID string) (*CoreUser, error) {
 
 	return &coreUser, nil
 }
+
+// CoreUserRef - one row of Core's user listing, ordered by user_auth_id
+type CoreUserRef struct {
+	ID         uuid.UUID `json:"id"`
+	UserAuthID uuid.UUID `json:"user_auth_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ListCoreUsers - Page through Core's users by user_auth_id, starting after after
+func (s *UserService) ListCoreUsers(ctx context.Context, after uuid.UUID, limit int) ([]CoreUserRef, error) {
+	query := url.Values{}
+	query.Set("after", after.String())
+	query.Set("limit", strconv.Itoa(limit))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", s.coreServiceURL+"/internal/user?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call core service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("core service returned status %d", resp.StatusCode)
+	}
+
+	var page struct {
+		Users []CoreUserRef `json:"users"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("failed to decode core service response: %w", err)
+	}
+
+	return page.Users, nil
+}
+
+// CoreDeletionResult - what Core removed with a profile
+type CoreDeletionResult struct {
+	ProductsDeleted   int32 `json:"products_deleted"`
+	FilesDeleted      int32 `json:"files_deleted"`
+	PurchasesScrubbed int32 `json:"purchases_scrubbed"`
+}
+
+// DeleteUserInCore - Remove the Core profile of user_auth_id, if there is one,
+// with its products and files, scrubbing it from purchases
+func (s *UserService) DeleteUserInCore(ctx context.Context, userAuthID string) (*CoreDeletionResult, error) {
+	req, err := http.NewRequestWithContext(ctx, "DELETE", s.coreServiceURL+"/internal/user/"+userAuthID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call core service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("core service returned status %d", resp.StatusCode)
+	}
+
+	var result CoreDeletionResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode core service response: %w", err)
+	}
+
+	return &result, nil
+}
+
+// CoreMergeResult - what Core moved from the merged profile
+type CoreMergeResult struct {
+	ProductsMoved int32 `json:"products_moved"`
+	FilesMoved    int32 `json:"files_moved"`
+}
+
+// MergeUsersInCore - Move the merged profile's products and files to the
+// surviving profile and delete it
+func (s *UserService) MergeUsersInCore(ctx context.Context, payload *model.AccountMergedPayload) (*CoreMergeResult, error) {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.coreServiceURL+"/internal/user/merge", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call core service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errorBody map[string]interface{}
+		if decodeErr := json.NewDecoder(resp.Body).Decode(&errorBody); decodeErr == nil {
+			if errorMsg, ok := errorBody["error"].(string); ok {
+				return nil, fmt.Errorf("core service error (status %d): %s", resp.StatusCode, errorMsg)
+			}
+		}
+		return nil, fmt.Errorf("core service returned status %d", resp.StatusCode)
+	}
+
+	var result CoreMergeResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode core service response: %w", err)
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/teammachinist/tutuplapak/services/auth/internal/logger"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/model"
+	"github.com/teammachinist/tutuplapak/services/auth/pkg/authz"
+)
+
+// DeleteAccount - Delete the account for Core's DELETE /v1/user once the user
+// has re-entered its password, and a TOTP code when two-factor is on. The
+// account can't sign in from here on; its profile, products and files go when
+// the account.deleted event is delivered, which is retried until Core has
+// removed all of them.
+func (s *UserService) DeleteAccount(ctx context.Context, userAuthID, password, totpCode, clientIP string) (*authz.DeleteAccountResponse, error) {
+	userAuthUUID, err := uuid.Parse(userAuthID)
+	if err != nil {
+		return nil, errors.New("invalid user_auth_id format")
+	}
+	if password == "" {
+		return nil, errors.New("password is required")
+	}
+
+	userAuth, err := s.userRepo.GetUserAuthByID(ctx, userAuthUUID)
+	if err != nil {
+		return nil, errors.New("user auth not found")
+	}
+
+	identifier := userAuth.Email
+	if identifier == "" {
+		identifier = userAuth.Phone
+	}
+
+	// Same limits as logging in, or this would be a way around them
+	if err := s.checkLoginAllowed(ctx, identifier, clientIP); err != nil {
+		return nil, err
+	}
+	if match, _ := s.hasher.Verify(password, userAuth.PasswordHash); !match {
+		s.recordLoginFailure(ctx, identifier, clientIP)
+		return nil, errors.New("invalid credentials")
+	}
+
+	factor, err := s.userRepo.GetTOTPFactor(ctx, userAuth.ID)
+	if err != nil {
+		return nil, err
+	}
+	if factor != nil && factor.Enabled {
+		if totpCode == "" {
+			return nil, errors.New("two-factor code required")
+		}
+		ok, err := s.verifySecondFactor(ctx, factor, totpCode)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, errors.New("invalid two-factor code")
+		}
+	}
+
+	// The merged account's products would move onto a profile being deleted
+	merging, err := s.userRepo.HasPendingAccountMergeInto(ctx, userAuth.ID)
+	if err != nil {
+		return nil, err
+	}
+	if merging {
+		return nil, errors.New("account merge in progress")
+	}
+
+	deletion, sessionIDs, err := s.userRepo.DeleteAccount(ctx, userAuth, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sessionID := range sessionIDs {
+		s.markSessionRevoked(ctx, sessionID)
+	}
+	if userAuth.UserID != nil {
+		if err := s.revokeUserAccessTokens(ctx, userAuth.UserID.String()); err != nil {
+			logger.WarnCtx(ctx, "Failed to revoke access tokens", "user_id", userAuth.UserID.String(), "error", err.Error())
+		}
+	}
+	s.clearAccountKeys(ctx, userAuth)
+
+	logger.InfoCtx(ctx, "Account deleted", "deletion_id", deletion.ID.String(), "user_auth_id", userAuth.ID.String())
+	return &authz.DeleteAccountResponse{
+		DeletionID: deletion.ID.String(),
+		Status:     deletion.Status,
+	}, nil
+}
+
+// deliverAccountDeleted - Have Core delete the profile with its products and
+// files and scrub its purchases. Core works through those in an order that
+// lets a redelivery finish whatever a failed attempt left behind.
+func (s *UserService) deliverAccountDeleted(ctx context.Context, event *model.OutboxEvent) error {
+	var payload model.AccountDeletedPayload
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		err = fmt.Errorf("invalid payload: %w", err)
+		s.retryOutboxEvent(ctx, event, err)
+		return err
+	}
+
+	result, err := s.DeleteUserInCore(ctx, payload.UserAuthID)
+	if err != nil {
+		s.retryOutboxEvent(ctx, event, err)
+		return err
+	}
+
+	if err := s.userRepo.CompleteAccountDeleted(ctx, event.ID, event.AggregateID,
+		result.ProductsDeleted, result.FilesDeleted, result.PurchasesScrubbed); err != nil {
+		s.retryOutboxEvent(ctx, event, err)
+		return err
+	}
+
+	logger.InfoCtx(ctx, "Account deletion completed", "deletion_id", payload.DeletionID, "user_auth_id", payload.UserAuthID,
+		"products_deleted", result.ProductsDeleted, "files_deleted", result.FilesDeleted,
+		"purchases_scrubbed", result.PurchasesScrubbed, "attempts", event.Attempts)
+	return nil
+}
+
+// clearAccountKeys - Drop the Redis keys named after the account, they would
+// otherwise linger until they expire
+func (s *UserService) clearAccountKeys(ctx context.Context, userAuth *model.UserAuth) {
+	id := userAuth.ID.String()
+	keys := []string{
+		fmt.Sprintf(LinkVerificationCooldownKey, authz.LinkChannelEmail, id),
+		fmt.Sprintf(LinkVerificationCooldownKey, authz.LinkChannelPhone, id),
+		fmt.Sprintf(MergeVerificationCooldownKey, id),
+		fmt.Sprintf(PasswordResetCooldownKey, id),
+		fmt.Sprintf(PasswordlessGrantKey, id),
+		fmt.Sprintf(PasswordlessCooldownKey, id),
+	}
+	for _, key := range keys {
+		s.cache.Delete(ctx, key)
+	}
+
+	for _, identifier := range []string{userAuth.Email, userAuth.Phone} {
+		if identifier != "" {
+			s.resetLoginFailures(ctx, identifier)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeDB - database.DBTX answering sqlc queries from Go functions keyed by the
+// query's "-- name:", so service tests run without Postgres. Handlers run one
+// at a time, like statements against a single row would.
+type fakeDB struct {
+	mu      sync.Mutex
+	queries map[string]func(args []any) ([][]any, error) // :one and :many, rows of column values
+	execs   map[string]func(args []any) (int64, error)   // :exec and :execrows, rows affected
+}
+
+func newFakeDB() *fakeDB {
+	return &fakeDB{
+		queries: make(map[string]func(args []any) ([][]any, error)),
+		execs:   make(map[string]func(args []any) (int64, error)),
+	}
+}
+
+func queryName(sql string) string {
+	name, _, _ := strings.Cut(strings.TrimPrefix(sql, "-- name: "), " ")
+	return name
+}
+
+func (f *fakeDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	handler, ok := f.execs[queryName(sql)]
+	if !ok {
+		return pgconn.CommandTag{}, fmt.Errorf("fakeDB: unexpected exec %s", queryName(sql))
+	}
+	rows, err := handler(args)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return pgconn.NewCommandTag(fmt.Sprintf("UPDATE %d", rows)), nil
+}
+
+func (f *fakeDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	handler, ok := f.queries[queryName(sql)]
+	if !ok {
+		return nil, fmt.Errorf("fakeDB: unexpected query %s", queryName(sql))
+	}
+	rows, err := handler(args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{rows: rows, index: -1}, nil
+}
+
+func (f *fakeDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	rows, err := f.Query(ctx, sql, args...)
+	if err != nil {
+		return &fakeRows{err: err, index: -1}
+	}
+	return rows.(*fakeRows)
+}
+
+// fakeRows - pgx.Rows and pgx.Row over values already in Go types
+type fakeRows struct {
+	rows  [][]any
+	index int
+	err   error
+}
+
+func (r *fakeRows) Close()                                       {}
+func (r *fakeRows) Err() error                                   { return r.err }
+func (r *fakeRows) CommandTag() pgconn.CommandTag                { return pgconn.CommandTag{} }
+func (r *fakeRows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (r *fakeRows) RawValues() [][]byte                          { return nil }
+func (r *fakeRows) Conn() *pgx.Conn                              { return nil }
+
+func (r *fakeRows) Next() bool {
+	if r.err != nil || r.index+1 >= len(r.rows) {
+		return false
+	}
+	r.index++
+	return true
+}
+
+func (r *fakeRows) Values() ([]any, error) {
+	return r.rows[r.index], nil
+}
+
+// Scan - As pgx.Rows, or as pgx.Row when Next was never called
+func (r *fakeRows) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.index < 0 {
+		if !r.Next() {
+			return pgx.ErrNoRows
+		}
+	}
+
+	row := r.rows[r.index]
+	if len(row) != len(dest) {
+		return fmt.Errorf("fakeDB: row has %d columns, scanning %d", len(row), len(dest))
+	}
+	for i, value := range row {
+		target := reflect.ValueOf(dest[i]).Elem()
+		if value == nil {
+			target.SetZero()
+			continue
+		}
+		target.Set(reflect.ValueOf(value))
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"github.com/teammachinist/tutuplapak/services/auth/internal/logger"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/model"
+	"github.com/teammachinist/tutuplapak/services/auth/pkg/authz"
+)
+
+// Impersonate - Mint a short-lived access token for userID on behalf of the
+// admin in claims, for support to see what the user sees. The token carries
+// the user's roles, the admin in its act claim and no session, so it can't be
+// refreshed and logging out of the user's sessions leaves it alone; revoking
+// all of the user's tokens does not. Every attempt is audited with its reason.
+func (s *UserService) Impersonate(ctx context.Context, claims *authz.Claims, userID, reason string, readOnly bool, client model.ClientInfo) (*model.ImpersonateResponse, error) {
+	entry := model.AuditEntry{
+		EventType: model.AuditImpersonate,
+		Method:    "read_write",
+		Reason:    truncateRunes(strings.TrimSpace(reason), maxAuditFieldLength),
+		Client:    client,
+	}
+	if readOnly {
+		entry.Method = "read_only"
+	}
+	if actorID, err := uuid.Parse(claims.UserID); err == nil {
+		entry.ActorUserID = &actorID
+	}
+
+	response, err := s.impersonate(ctx, claims, userID, &entry, readOnly)
+	// A refusal is recorded with the error as its reason
+	s.Audit(ctx, entry, err)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.WarnCtx(ctx, "Impersonation token issued", "actor_user_id", claims.UserID, "user_id", userID, "read_only", readOnly)
+	return response, nil
+}
+
+func (s *UserService) impersonate(ctx context.Context, claims *authz.Claims, userID string, entry *model.AuditEntry, readOnly bool) (*model.ImpersonateResponse, error) {
+	if entry.Reason == "" {
+		return nil, errors.New("reason is required")
+	}
+	if _, impersonating := claims.Impersonator(); impersonating {
+		return nil, errors.New("not allowed while impersonating")
+	}
+
+	targetID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid userId format")
+	}
+	if targetID.String() == claims.UserID {
+		return nil, errors.New("cannot impersonate yourself")
+	}
+
+	target, err := s.userRepo.GetUserAuthByUserID(ctx, targetID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	entry.UserAuthID = &target.ID
+
+	access, err := s.userRepo.GetUserAccess(ctx, target.ID)
+	if err != nil {
+		return nil, err
+	}
+	// Acting as another admin would hand out permissions beyond the user's own
+	if slices.Contains(access.Roles, authz.RoleAdmin) {
+		return nil, errors.New("cannot impersonate an admin")
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(s.jwtConfig.ImpersonationTTL)
+	token, err := s.signToken(TokenClaims{
+		UserID:      userID,
+		Roles:       access.Roles,
+		Permissions: access.Permissions,
+		Actor:       &authz.Actor{UserID: claims.UserID},
+		ReadOnly:    readOnly,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    s.jwtConfig.Issuer,
+			Audience:  jwt.ClaimStrings{authz.AccessTokenAudience},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ImpersonateResponse{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		UserID:    userID,
+		ReadOnly:  readOnly,
+	}, nil
+}
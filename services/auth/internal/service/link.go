@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/teammachinist/tutuplapak/services/auth/internal/logger"
+	"github.com/teammachinist/tutuplapak/services/auth/pkg/authz"
+)
+
+const (
+	PurposeLinkEmail = "link_email"
+	PurposeLinkPhone = "link_phone"
+
+	LinkVerificationCooldownKey = "link:cooldown:%s:%s" // link:cooldown:{channel}:{userAuthID}
+)
+
+const (
+	LinkVerificationCodeTTL  = 10 * time.Minute
+	LinkVerificationCooldown = 1 * time.Minute
+)
+
+// StartLinkVerification - Send an OTP to the email/phone being linked. Nothing is
+// linked yet; the pending link is the outstanding code and its target.
+func (s *UserService) StartLinkVerification(ctx context.Context, userAuthID, channel, target string) (*authz.StartLinkResponse, error) {
+	userAuthUUID, err := uuid.Parse(userAuthID)
+	if err != nil {
+		return nil, errors.New("invalid user_auth_id format")
+	}
+
+	purpose, err := linkPurpose(channel)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkLinkTarget(ctx, channel, target); err != nil {
+		return nil, err
+	}
+
+	// Every send costs an email/SMS, don't let it be used to spam an address
+	cooldownKey := fmt.Sprintf(LinkVerificationCooldownKey, channel, userAuthID)
+	if onCooldown, err := s.cache.Exists(ctx, cooldownKey); err == nil && onCooldown {
+		return nil, errors.New("verification code recently sent, try again later")
+	}
+
+	stored, err := s.sendVerificationCodeTo(ctx, userAuthUUID, purpose, channel == authz.LinkChannelPhone, target, LinkVerificationCodeTTL,
+		"Verify your TutupLapak email",
+		"Your verification code is %s. It expires in 10 minutes.")
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache.Set(ctx, cooldownKey, true, LinkVerificationCooldown)
+
+	logger.InfoCtx(ctx, "Link verification sent", "user_auth_id", userAuthID, "channel", channel)
+	return &authz.StartLinkResponse{
+		Target:    stored.Target,
+		ExpiresAt: stored.ExpiresAt,
+	}, nil
+}
+
+// ConfirmLinkVerification - Redeem the OTP and link the address it was sent to,
+// marking it verified
+func (s *UserService) ConfirmLinkVerification(ctx context.Context, userAuthID, channel, code string) (*authz.VerifyLinkResponse, error) {
+	userAuthUUID, err := uuid.Parse(userAuthID)
+	if err != nil {
+		return nil, errors.New("invalid user_auth_id format")
+	}
+
+	purpose, err := linkPurpose(channel)
+	if err != nil {
+		return nil, err
+	}
+
+	if code == "" {
+		return nil, errors.New("code is required")
+	}
+
+	stored, err := s.redeemVerificationCode(ctx, userAuthUUID, purpose, code)
+	if err != nil {
+		return nil, err
+	}
+
+	// Someone else may have claimed the address while the code was pending
+	if err := s.checkLinkTarget(ctx, channel, stored.Target); err != nil {
+		return nil, err
+	}
+
+	if channel == authz.LinkChannelPhone {
+		err = s.userRepo.UpdateUserAuthPhone(ctx, userAuthUUID, stored.Target)
+	} else {
+		err = s.userRepo.UpdateUserAuthEmail(ctx, userAuthUUID, stored.Target)
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows") {
+			return nil, errors.New("user auth not found")
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, alreadyExistsError(channel)
+		}
+		return nil, err
+	}
+
+	logger.InfoCtx(ctx, "Link verified", "user_auth_id", userAuthID, "channel", channel)
+	return &authz.VerifyLinkResponse{Target: stored.Target}, nil
+}
+
+// checkLinkTarget - Validate the address and make sure no other account owns it
+func (s *UserService) checkLinkTarget(ctx context.Context, channel, target string) error {
+	var exists bool
+	var err error
+
+	if channel == authz.LinkChannelPhone {
+		if err := s.validatePhone(target); err != nil {
+			return err
+		}
+		exists, err = s.userRepo.CheckPhoneExists(ctx, target)
+	} else {
+		if err := s.validateEmail(target); err != nil {
+			return err
+		}
+		exists, err = s.userRepo.CheckExistedUserAuthByEmail(ctx, target)
+	}
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return alreadyExistsError(channel)
+	}
+	return nil
+}
+
+func linkPurpose(channel string) (string, error) {
+	switch channel {
+	case authz.LinkChannelEmail:
+		return PurposeLinkEmail, nil
+	case authz.LinkChannelPhone:
+		return PurposeLinkPhone, nil
+	default:
+		return "", errors.New("invalid link channel")
+	}
+}
+
+func alreadyExistsError(channel string) error {
+	if channel == authz.LinkChannelPhone {
+		return errors.New("phone number already exists")
+	}
+	return errors.New("email address already exists")
+}
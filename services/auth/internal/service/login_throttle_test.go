@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/teammachinist/tutuplapak/services/auth/internal/cache"
+	"github.com/teammachinist/tutuplapak/services/auth/pkg/redistest"
+)
+
+var testThrottle = LoginThrottleConfig{
+	MaxAttempts:   3,
+	MaxIPAttempts: 5,
+	Window:        15 * time.Minute,
+	BaseLockout:   time.Minute,
+	MaxLockout:    5 * time.Minute,
+}
+
+func newThrottleTestService(t *testing.T) (*UserService, *redistest.Server) {
+	t.Helper()
+
+	redis := redistest.Start(t)
+	redisCache := cache.NewRedisCache(cache.CacheConfig{Addr: redis.Addr()})
+	t.Cleanup(func() { redisCache.Close() })
+
+	return &UserService{cache: redisCache, throttle: testThrottle}, redis
+}
+
+// retryAfter - How long login is refused, 0 when it is allowed
+func retryAfter(t *testing.T, s *UserService, identifier, ip string) time.Duration {
+	t.Helper()
+
+	err := s.checkLoginAllowed(context.Background(), identifier, ip)
+	if err == nil {
+		return 0
+	}
+	var locked *LoginLockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return locked.RetryAfter
+}
+
+func failLogins(s *UserService, identifier, ip string, n int) {
+	for range n {
+		s.recordLoginFailure(context.Background(), identifier, ip)
+	}
+}
+
+func TestLoginLockoutAfterMaxAttempts(t *testing.T) {
+	s, _ := newThrottleTestService(t)
+
+	failLogins(s, "alice@example.com", "10.0.0.1", testThrottle.MaxAttempts-1)
+	if wait := retryAfter(t, s, "alice@example.com", "10.0.0.1"); wait != 0 {
+		t.Fatalf("locked out after %d failures", testThrottle.MaxAttempts-1)
+	}
+
+	failLogins(s, "alice@example.com", "10.0.0.1", 1)
+	if wait := retryAfter(t, s, "alice@example.com", "10.0.0.1"); wait != testThrottle.BaseLockout {
+		t.Errorf("retry after %v, want %v", wait, testThrottle.BaseLockout)
+	}
+
+	// The lockout is per identifier, the same IP may still try other accounts
+	if wait := retryAfter(t, s, "bob@example.com", "10.0.0.1"); wait != 0 {
+		t.Errorf("other identifier locked out for %v", wait)
+	}
+}
+
+func TestLoginLockoutBacksOff(t *testing.T) {
+	s, redis := newThrottleTestService(t)
+	failLogins(s, "alice@example.com", "10.0.0.1", testThrottle.MaxAttempts)
+
+	want := []time.Duration{2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	for i, lockout := range want {
+		redis.FastForward(retryAfter(t, s, "alice@example.com", "10.0.0.1"))
+		if wait := retryAfter(t, s, "alice@example.com", "10.0.0.1"); wait != 0 {
+			t.Fatalf("still locked out for %v after the lockout ran out", wait)
+		}
+
+		failLogins(s, "alice@example.com", "10.0.0.1", 1)
+		if wait := retryAfter(t, s, "alice@example.com", "10.0.0.1"); wait != lockout {
+			t.Errorf("failure %d: retry after %v, want %v", testThrottle.MaxAttempts+i+1, wait, lockout)
+		}
+	}
+}
+
+func TestLoginFailuresForgottenAfterWindow(t *testing.T) {
+	s, redis := newThrottleTestService(t)
+
+	failLogins(s, "alice@example.com", "10.0.0.1", testThrottle.MaxAttempts-1)
+	redis.FastForward(testThrottle.Window)
+	failLogins(s, "alice@example.com", "10.0.0.2", 1)
+
+	if wait := retryAfter(t, s, "alice@example.com", "10.0.0.2"); wait != 0 {
+		t.Errorf("failures outside the window counted, locked out for %v", wait)
+	}
+}
+
+func TestLoginLockoutPerIP(t *testing.T) {
+	s, _ := newThrottleTestService(t)
+
+	// Spraying one attempt each at many accounts
+	for i := range testThrottle.MaxIPAttempts {
+		failLogins(s, fmt.Sprintf("user%d@example.com", i), "10.0.0.1", 1)
+	}
+
+	if wait := retryAfter(t, s, "fresh@example.com", "10.0.0.1"); wait != testThrottle.BaseLockout {
+		t.Errorf("retry after %v from the spraying IP, want %v", wait, testThrottle.BaseLockout)
+	}
+	if wait := retryAfter(t, s, "fresh@example.com", "10.0.0.2"); wait != 0 {
+		t.Errorf("other IP locked out for %v", wait)
+	}
+}
+
+func TestLoginSuccessResetsIdentifierOnly(t *testing.T) {
+	s, _ := newThrottleTestService(t)
+	ctx := context.Background()
+
+	failLogins(s, "alice@example.com", "10.0.0.1", testThrottle.MaxAttempts)
+	failLogins(s, "bob@example.com", "10.0.0.1", testThrottle.MaxIPAttempts-testThrottle.MaxAttempts-1)
+	s.resetLoginFailures(ctx, "alice@example.com")
+
+	if wait := retryAfter(t, s, "alice@example.com", "10.0.0.2"); wait != 0 {
+		t.Errorf("identifier still locked out for %v after a successful login", wait)
+	}
+
+	// The IP's earlier failures still count towards its limit
+	failLogins(s, "carol@example.com", "10.0.0.1", 1)
+	if wait := retryAfter(t, s, "dave@example.com", "10.0.0.1"); wait == 0 {
+		t.Error("a successful login reset the IP's failures")
+	}
+}
+
+func TestLoginThrottleFailsOpen(t *testing.T) {
+	s, redis := newThrottleTestService(t)
+	failLogins(s, "alice@example.com", "10.0.0.1", testThrottle.MaxAttempts)
+
+	redis.SetFailure("LOADING Redis is loading the dataset in memory")
+	if wait := retryAfter(t, s, "alice@example.com", "10.0.0.1"); wait != 0 {
+		t.Errorf("login refused for %v while Redis is unavailable", wait)
+	}
+}
@@ -0,0 +1,15 @@
+package service
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/teammachinist/tutuplapak/services/auth/internal/logger"
+)
+
+func TestMain(m *testing.M) {
+	logger.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	os.Exit(m.Run())
+}
@@ -0,0 +1,272 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/teammachinist/tutuplapak/services/auth/internal/logger"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/model"
+	"github.com/teammachinist/tutuplapak/services/auth/pkg/authz"
+)
+
+const (
+	PurposeMergeEmail = "merge_email"
+	PurposeMergePhone = "merge_phone"
+
+	MergeVerificationCooldownKey = "merge:cooldown:%s" // merge:cooldown:{userAuthID}
+)
+
+const (
+	MergeVerificationCodeTTL  = 10 * time.Minute
+	MergeVerificationCooldown = 1 * time.Minute
+)
+
+// StartAccountMerge - Begin folding the account registered with email/phone into
+// the signed-in one. Being signed in proves the first account; a code sent to
+// the other account's email/phone proves the second.
+func (s *UserService) StartAccountMerge(ctx context.Context, claims *authz.Claims, email, phone string) (*model.AccountMergeStartResponse, error) {
+	target, err := s.accountFromClaims(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	source, channel, err := s.findMergeSource(ctx, target, email, phone)
+	if err != nil {
+		return nil, err
+	}
+
+	cooldownKey := fmt.Sprintf(MergeVerificationCooldownKey, target.ID.String())
+	if onCooldown, err := s.cache.Exists(ctx, cooldownKey); err == nil && onCooldown {
+		return nil, errors.New("verification code recently sent, try again later")
+	}
+
+	useSMS := channel == authz.LinkChannelPhone
+	identifier := source.Email
+	if useSMS {
+		identifier = source.Phone
+	}
+
+	stored, err := s.sendVerificationCodeTo(ctx, target.ID, mergePurpose(channel), useSMS, identifier, MergeVerificationCodeTTL,
+		"Merge your TutupLapak accounts",
+		"Your account merge code is %s. It expires in 10 minutes. If you did not ask to merge accounts, ignore this message.")
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache.Set(ctx, cooldownKey, true, MergeVerificationCooldown)
+
+	logger.InfoCtx(ctx, "Account merge started", "user_auth_id", target.ID.String(), "source_user_auth_id", source.ID.String(), "channel", channel)
+	return &model.AccountMergeStartResponse{
+		Target:    stored.Target,
+		ExpiresAt: stored.ExpiresAt,
+	}, nil
+}
+
+// ConfirmAccountMerge - Redeem the code and merge, with the other account's TOTP
+// code too when it has two-factor enabled. The other account's email/phone
+// move to this one and its auth record is deleted; its products and files are
+// moved to this account's profile by the account.merged event, right away when
+// Core answers and by the dispatcher otherwise.
+func (s *UserService) ConfirmAccountMerge(ctx context.Context, claims *authz.Claims, email, phone, code, totpCode string, client model.ClientInfo) (*model.AccountMergeResponse, error) {
+	target, err := s.accountFromClaims(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	channel, identifier := authz.LinkChannelEmail, email
+	if email == "" {
+		channel, identifier = authz.LinkChannelPhone, phone
+	}
+
+	stored, err := s.redeemVerificationCode(ctx, target.ID, mergePurpose(channel), code)
+	if err != nil {
+		return nil, err
+	}
+	if stored.Target != identifier {
+		return nil, errors.New("invalid or expired code")
+	}
+
+	// Either account may have changed while the code was out
+	source, _, err := s.findMergeSource(ctx, target, email, phone)
+	if err != nil {
+		return nil, err
+	}
+
+	// A code to the inbox alone must not get past the other account's second factor
+	factor, err := s.userRepo.GetTOTPFactor(ctx, source.ID)
+	if err != nil {
+		return nil, err
+	}
+	if factor != nil && factor.Enabled {
+		if totpCode == "" {
+			return nil, errors.New("two-factor code required")
+		}
+		ok, err := s.verifySecondFactor(ctx, factor, totpCode)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, errors.New("invalid two-factor code")
+		}
+	}
+
+	targetUserID, err := s.mergeProfileID(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	sourceUserID, err := s.mergeProfileID(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(client.UserAgent) > maxUserAgentLength {
+		client.UserAgent = client.UserAgent[:maxUserAgentLength]
+	}
+
+	merge, event, sessionIDs, err := s.userRepo.MergeAccounts(ctx, &model.AccountMerge{
+		TargetUserAuthID: target.ID,
+		SourceUserAuthID: source.ID,
+		TargetUserID:     targetUserID,
+		SourceUserID:     sourceUserID,
+		SourceEmail:      source.Email,
+		SourcePhone:      source.Phone,
+		VerifiedVia:      channel,
+		IPAddress:        client.IP,
+		UserAgent:        client.UserAgent,
+	}, time.Now().Add(OutboxLease))
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows") {
+			return nil, errAccountNotFound
+		}
+		return nil, err
+	}
+
+	// The source's tokens stay valid until they expire unless Redis knows
+	for _, sessionID := range sessionIDs {
+		s.markSessionRevoked(ctx, sessionID)
+	}
+
+	logger.InfoCtx(ctx, "Accounts merged", "merge_id", merge.ID.String(), "user_auth_id", target.ID.String(),
+		"source_user_auth_id", source.ID.String(), "user_id", targetUserID.String(), "source_user_id", sourceUserID.String(),
+		"verified_via", channel)
+
+	result, err := s.deliverAccountMerged(ctx, event)
+	if err != nil {
+		logger.WarnCtx(ctx, "Account merge pending", "merge_id", merge.ID.String(), "error", err.Error())
+		return toAccountMergeResponse(merge), nil
+	}
+
+	completedAt := time.Now()
+	merge.Status = model.AccountMergeCompleted
+	merge.ProductsMoved = result.ProductsMoved
+	merge.FilesMoved = result.FilesMoved
+	merge.CompletedAt = &completedAt
+	return toAccountMergeResponse(merge), nil
+}
+
+// ListAccountMerges - Accounts merged into the signed-in one, newest first
+func (s *UserService) ListAccountMerges(ctx context.Context, claims *authz.Claims) ([]model.AccountMergeResponse, error) {
+	userAuth, err := s.accountFromClaims(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	merges, err := s.userRepo.ListAccountMerges(ctx, userAuth.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]model.AccountMergeResponse, 0, len(merges))
+	for i := range merges {
+		response = append(response, *toAccountMergeResponse(&merges[i]))
+	}
+	return response, nil
+}
+
+// findMergeSource - The account to fold into target, by exactly one of email or
+// phone. Each identity must end up on one account, so the two can't both have
+// an email or both have a phone.
+func (s *UserService) findMergeSource(ctx context.Context, target *model.UserAuth, email, phone string) (*model.UserAuth, string, error) {
+	source, err := s.findUserAuth(ctx, email, phone)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if source.ID == target.ID {
+		return nil, "", errors.New("cannot merge an account into itself")
+	}
+	if source.Email != "" && target.Email != "" {
+		return nil, "", errors.New("both accounts have an email address")
+	}
+	if source.Phone != "" && target.Phone != "" {
+		return nil, "", errors.New("both accounts have a phone number")
+	}
+
+	if email != "" {
+		return source, authz.LinkChannelEmail, nil
+	}
+	return source, authz.LinkChannelPhone, nil
+}
+
+// mergeProfileID - Core's user.id of a merging account, creating the profile
+// first if it is still pending
+func (s *UserService) mergeProfileID(ctx context.Context, userAuth *model.UserAuth) (uuid.UUID, error) {
+	userID, err := s.ensureProfile(ctx, userAuth)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return uuid.Parse(userID)
+}
+
+// deliverAccountMerged - Have Core move the merged profile's products and files
+// and drop the profile. Core's merge is idempotent, so redelivery is harmless.
+func (s *UserService) deliverAccountMerged(ctx context.Context, event *model.OutboxEvent) (*CoreMergeResult, error) {
+	var payload model.AccountMergedPayload
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		err = fmt.Errorf("invalid payload: %w", err)
+		s.retryOutboxEvent(ctx, event, err)
+		return nil, err
+	}
+
+	result, err := s.MergeUsersInCore(ctx, &payload)
+	if err != nil {
+		s.retryOutboxEvent(ctx, event, err)
+		return nil, err
+	}
+
+	if err := s.userRepo.CompleteAccountMerged(ctx, event.ID, event.AggregateID, result.ProductsMoved, result.FilesMoved); err != nil {
+		s.retryOutboxEvent(ctx, event, err)
+		return nil, err
+	}
+
+	logger.InfoCtx(ctx, "Account merge completed", "merge_id", payload.MergeID, "user_id", payload.TargetUserID,
+		"source_user_id", payload.SourceUserID, "products_moved", result.ProductsMoved, "files_moved", result.FilesMoved,
+		"attempts", event.Attempts)
+	return result, nil
+}
+
+func mergePurpose(channel string) string {
+	if channel == authz.LinkChannelPhone {
+		return PurposeMergePhone
+	}
+	return PurposeMergeEmail
+}
+
+func toAccountMergeResponse(merge *model.AccountMerge) *model.AccountMergeResponse {
+	return &model.AccountMergeResponse{
+		ID:            merge.ID,
+		MergedEmail:   merge.SourceEmail,
+		MergedPhone:   merge.SourcePhone,
+		Status:        merge.Status,
+		ProductsMoved: merge.ProductsMoved,
+		FilesMoved:    merge.FilesMoved,
+		CreatedAt:     merge.CreatedAt,
+		CompletedAt:   merge.CompletedAt,
+	}
+}
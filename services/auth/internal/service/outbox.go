@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/teammachinist/tutuplapak/services/auth/internal/logger"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/model"
+)
+
+// OutboxConfig - how often the dispatcher looks for due events and how many it
+// takes at once
+type OutboxConfig struct {
+	Interval  time.Duration
+	BatchSize int
+}
+
+const (
+	OutboxLease       = 30 * time.Second // A claimed event is retried after this if never settled
+	OutboxBaseBackoff = 5 * time.Second  // First retry delay, doubled for every further attempt
+	OutboxMaxBackoff  = 10 * time.Minute
+)
+
+var errAccountSetupPending = errors.New("account setup pending")
+
+// RunOutboxDispatcher - Deliver due outbox events until ctx is done. Every
+// replica runs one; claims are row locked so an event goes to one dispatcher.
+func (s *UserService) RunOutboxDispatcher(ctx context.Context, cfg OutboxConfig) {
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.dispatchOutbox(ctx, cfg.BatchSize)
+		}
+	}
+}
+
+func (s *UserService) dispatchOutbox(ctx context.Context, batchSize int) {
+	events, err := s.userRepo.ClaimOutboxEvents(ctx, int32(batchSize), OutboxLease)
+	if err != nil {
+		logger.ErrorCtx(ctx, "Failed to claim outbox events", "error", err.Error())
+		return
+	}
+
+	for i := range events {
+		event := &events[i]
+		switch event.EventType {
+		case model.EventUserRegistered:
+			s.deliverUserRegistered(ctx, event)
+		case model.EventAccountMerged:
+			s.deliverAccountMerged(ctx, event)
+		case model.EventAccountDeleted:
+			s.deliverAccountDeleted(ctx, event)
+		default:
+			s.retryOutboxEvent(ctx, event, fmt.Errorf("unknown event type %q", event.EventType))
+		}
+	}
+}
+
+// deliverUserRegistered - Create the Core profile and record its user.id on the
+// account. Core keys profiles by user_auth_id, so redelivery is harmless.
+func (s *UserService) deliverUserRegistered(ctx context.Context, event *model.OutboxEvent) (string, error) {
+	var payload model.UserRegisteredPayload
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		err = fmt.Errorf("invalid payload: %w", err)
+		s.retryOutboxEvent(ctx, event, err)
+		return "", err
+	}
+
+	coreUser, err := s.CreateUserInCoreSync(ctx, payload.UserAuthID, payload.Email, payload.Phone)
+	if err != nil {
+		s.retryOutboxEvent(ctx, event, err)
+		return "", err
+	}
+
+	userID, err := uuid.Parse(coreUser.ID)
+	if err != nil {
+		err = fmt.Errorf("invalid user id from core: %w", err)
+		s.retryOutboxEvent(ctx, event, err)
+		return "", err
+	}
+
+	if err := s.userRepo.CompleteUserRegistered(ctx, event.ID, event.AggregateID, userID); err != nil {
+		s.retryOutboxEvent(ctx, event, err)
+		return "", err
+	}
+
+	logger.InfoCtx(ctx, "User profile created in core", "user_auth_id", event.AggregateID.String(), "user_id", coreUser.ID, "attempts", event.Attempts)
+	return coreUser.ID, nil
+}
+
+// retryOutboxEvent - Record the failure and back off exponentially
+func (s *UserService) retryOutboxEvent(ctx context.Context, event *model.OutboxEvent, cause error) {
+	backoff := OutboxBaseBackoff
+	for i := int32(1); i < event.Attempts && backoff < OutboxMaxBackoff; i++ {
+		backoff *= 2
+	}
+	backoff = min(backoff, OutboxMaxBackoff)
+
+	logger.WarnCtx(ctx, "Outbox delivery failed", "event_id", event.ID.String(), "event_type", event.EventType,
+		"attempts", event.Attempts, "retry_in", backoff.String(), "error", cause.Error())
+
+	if err := s.userRepo.RescheduleOutboxEvent(ctx, event.ID, cause.Error(), time.Now().Add(backoff)); err != nil {
+		// The claim lease expires on its own, the event is retried after it
+		logger.ErrorCtx(ctx, "Failed to reschedule outbox event", "event_id", event.ID.String(), "error", err.Error())
+	}
+}
+
+// completeRegistration - Try to create the Core profile right away so most
+// registrations get tokens immediately. On failure the account is reported
+// pending and the dispatcher keeps retrying.
+func (s *UserService) completeRegistration(ctx context.Context, userAuth *model.UserAuth, event *model.OutboxEvent, client model.ClientInfo) (*model.AuthResponse, error) {
+	userID, err := s.deliverUserRegistered(ctx, event)
+	if err != nil {
+		logger.WarnCtx(ctx, "User profile pending", "user_auth_id", userAuth.ID.String(), "error", err.Error())
+		return &model.AuthResponse{
+			Email:  userAuth.Email,
+			Phone:  userAuth.Phone,
+			Status: model.RegistrationPending,
+		}, nil
+	}
+
+	// Generate access and refresh tokens with Core's user.id
+	tokens, err := s.startSession(ctx, userAuth.ID, userID, client)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+
+	return &model.AuthResponse{
+		Email:        userAuth.Email,
+		Phone:        userAuth.Phone,
+		Token:        tokens.Token,
+		RefreshToken: tokens.RefreshToken,
+	}, nil
+}
+
+// ensureProfile - Core's user.id for the account. Accounts still pending (or
+// created before user_id was recorded) get their profile delivered now.
+func (s *UserService) ensureProfile(ctx context.Context, userAuth *model.UserAuth) (string, error) {
+	if userAuth.UserID != nil {
+		return userAuth.UserID.String(), nil
+	}
+
+	payload, err := json.Marshal(model.UserRegisteredPayload{
+		UserAuthID: userAuth.ID.String(),
+		Email:      userAuth.Email,
+		Phone:      userAuth.Phone,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.userRepo.EnqueueOutboxEvent(ctx, userAuth.ID, model.EventUserRegistered, payload); err != nil {
+		logger.ErrorCtx(ctx, "Failed to enqueue user profile", "user_auth_id", userAuth.ID.String(), "error", err.Error())
+		return "", errAccountSetupPending
+	}
+
+	// Gone when a dispatcher completed it in the meantime, the next login sees user_id
+	event, err := s.userRepo.GetPendingOutboxEvent(ctx, model.EventUserRegistered, userAuth.ID)
+	if err != nil {
+		return "", errAccountSetupPending
+	}
+
+	userID, err := s.deliverUserRegistered(ctx, event)
+	if err != nil {
+		return "", errAccountSetupPending
+	}
+	return userID, nil
+}
@@ -0,0 +1,301 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/teammachinist/tutuplapak/services/auth/internal/logger"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/model"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/webauthn"
+	"github.com/teammachinist/tutuplapak/services/auth/pkg/authz"
+)
+
+const (
+	PasskeyCeremonyKey     = "passkey:ceremony:%s"      // By challenge
+	PasskeyCeremonyUsedKey = "passkey:ceremony:used:%s" // By challenge
+)
+
+const defaultPasskeyName = "Passkey"
+
+var (
+	errInvalidPasskey  = errors.New("invalid passkey")
+	errPasskeyNotFound = errors.New("passkey not found")
+)
+
+// BeginPasskeyRegistration - Creation options for adding a passkey to the
+// signed-in account
+func (s *UserService) BeginPasskeyRegistration(ctx context.Context, claims *authz.Claims) (*webauthn.CreationOptions, error) {
+	userAuth, err := s.accountFromClaims(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	passkeys, err := s.userRepo.ListPasskeys(ctx, userAuth.ID)
+	if err != nil {
+		return nil, err
+	}
+	exclude := make([][]byte, 0, len(passkeys))
+	for _, passkey := range passkeys {
+		exclude = append(exclude, passkey.CredentialID)
+	}
+
+	challenge, err := s.startPasskeyCeremony(ctx, model.PasskeyCeremony{
+		Type:       model.PasskeyCeremonyRegistration,
+		UserAuthID: userAuth.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	name := userAuth.Email
+	if name == "" {
+		name = userAuth.Phone
+	}
+
+	// The user handle is users_auth.id, it comes back with every login
+	options := s.webauthn.NewCreationOptions(challenge, userAuth.ID[:], name, name, exclude)
+	return &options, nil
+}
+
+// FinishPasskeyRegistration - Verify the authenticator's response and store the
+// new passkey
+func (s *UserService) FinishPasskeyRegistration(ctx context.Context, claims *authz.Claims, name string, cred *webauthn.RegistrationCredential) (*model.PasskeyResponse, error) {
+	userAuth, err := s.accountFromClaims(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, ceremony, err := s.takePasskeyCeremony(ctx, cred.Response.ClientDataJSON, model.PasskeyCeremonyRegistration)
+	if err != nil {
+		return nil, err
+	}
+	if ceremony.UserAuthID != userAuth.ID {
+		return nil, errInvalidChallenge
+	}
+
+	credential, err := s.webauthn.VerifyRegistration(challenge, cred)
+	if err != nil {
+		logger.WarnCtx(ctx, "Passkey registration rejected", "user_auth_id", userAuth.ID.String(), "error", err.Error())
+		return nil, errInvalidPasskey
+	}
+
+	aaguid, err := uuid.FromBytes(credential.AAGUID)
+	if err != nil {
+		return nil, errInvalidPasskey
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = defaultPasskeyName
+	}
+
+	passkey, err := s.userRepo.CreatePasskey(ctx, userAuth.ID, credential.ID, credential.PublicKey, credential.SignCount, aaguid, name)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, errors.New("passkey already registered")
+		}
+		return nil, err
+	}
+
+	logger.InfoCtx(ctx, "Passkey registered", "user_auth_id", userAuth.ID.String(), "passkey_id", passkey.ID.String())
+	return toPasskeyResponse(passkey), nil
+}
+
+// BeginPasskeyLogin - Request options for logging in. With an email/phone the
+// account's passkeys are listed; unknown accounts get the same options as no
+// identifier at all, so this can't be used to probe which accounts exist.
+func (s *UserService) BeginPasskeyLogin(ctx context.Context, email, phone string) (*webauthn.RequestOptions, error) {
+	var allow [][]byte
+	if email != "" || phone != "" {
+		userAuth, err := s.findUserAuth(ctx, email, phone)
+		if err != nil && !errors.Is(err, errAccountNotFound) {
+			return nil, err
+		}
+		if userAuth != nil {
+			passkeys, err := s.userRepo.ListPasskeys(ctx, userAuth.ID)
+			if err != nil {
+				return nil, err
+			}
+			for _, passkey := range passkeys {
+				allow = append(allow, passkey.CredentialID)
+			}
+		}
+	}
+
+	challenge, err := s.startPasskeyCeremony(ctx, model.PasskeyCeremony{Type: model.PasskeyCeremonyLogin})
+	if err != nil {
+		return nil, err
+	}
+
+	options := s.webauthn.NewRequestOptions(challenge, allow)
+	return &options, nil
+}
+
+// FinishPasskeyLogin - Verify the assertion and log in. Passkeys require user
+// verification (biometric or PIN on the device), so they stand in for both
+// factors and no TOTP challenge follows.
+func (s *UserService) FinishPasskeyLogin(ctx context.Context, cred *webauthn.AssertionCredential, client model.ClientInfo) (*model.AuthResponse, error) {
+	challenge, _, err := s.takePasskeyCeremony(ctx, cred.Response.ClientDataJSON, model.PasskeyCeremonyLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	credentialID, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(cred.RawID, "="))
+	if err != nil {
+		return nil, errInvalidPasskey
+	}
+	passkey, err := s.userRepo.GetPasskeyByCredentialID(ctx, credentialID)
+	if err != nil {
+		return nil, errInvalidPasskey
+	}
+
+	// Discoverable passkeys name their account, it has to be the one the key belongs to
+	if cred.Response.UserHandle != "" {
+		userHandle, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(cred.Response.UserHandle, "="))
+		if err != nil || !bytes.Equal(userHandle, passkey.UserAuthID[:]) {
+			return nil, errInvalidPasskey
+		}
+	}
+
+	signCount, err := s.webauthn.VerifyAssertion(challenge, cred, passkey.PublicKey, passkey.SignCount)
+	if errors.Is(err, webauthn.ErrCloneDetected) {
+		logger.WarnCtx(ctx, "Passkey sign count went backwards, possible clone", "user_auth_id", passkey.UserAuthID.String(),
+			"passkey_id", passkey.ID.String(), "stored_sign_count", passkey.SignCount)
+		return nil, errInvalidPasskey
+	}
+	if err != nil {
+		logger.WarnCtx(ctx, "Passkey login rejected", "passkey_id", passkey.ID.String(), "error", err.Error())
+		return nil, errInvalidPasskey
+	}
+
+	used, err := s.userRepo.UsePasskey(ctx, passkey.ID, passkey.SignCount, signCount)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		// Another login moved the counter since it was read, one of them is a clone
+		logger.WarnCtx(ctx, "Passkey used concurrently, possible clone", "passkey_id", passkey.ID.String())
+		return nil, errInvalidPasskey
+	}
+
+	userAuth, err := s.userRepo.GetUserAuthByID(ctx, passkey.UserAuthID)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := s.ensureProfile(ctx, userAuth)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.startSession(ctx, userAuth.ID, userID, client)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+
+	logger.InfoCtx(ctx, "Passkey login", "user_auth_id", userAuth.ID.String(), "passkey_id", passkey.ID.String())
+	return &model.AuthResponse{
+		Email:        userAuth.Email,
+		Phone:        userAuth.Phone,
+		Token:        tokens.Token,
+		RefreshToken: tokens.RefreshToken,
+	}, nil
+}
+
+// ListPasskeys - The signed-in account's passkeys, oldest first
+func (s *UserService) ListPasskeys(ctx context.Context, claims *authz.Claims) ([]model.PasskeyResponse, error) {
+	userAuth, err := s.accountFromClaims(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	passkeys, err := s.userRepo.ListPasskeys(ctx, userAuth.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]model.PasskeyResponse, 0, len(passkeys))
+	for i := range passkeys {
+		response = append(response, *toPasskeyResponse(&passkeys[i]))
+	}
+	return response, nil
+}
+
+// DeletePasskey - Remove one of the signed-in account's passkeys
+func (s *UserService) DeletePasskey(ctx context.Context, claims *authz.Claims, passkeyID string) error {
+	passkeyUUID, err := uuid.Parse(passkeyID)
+	if err != nil {
+		return errors.New("invalid passkey id format")
+	}
+
+	userAuth, err := s.accountFromClaims(ctx, claims)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := s.userRepo.DeletePasskey(ctx, passkeyUUID, userAuth.ID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return errPasskeyNotFound
+	}
+
+	logger.InfoCtx(ctx, "Passkey deleted", "user_auth_id", userAuth.ID.String(), "passkey_id", passkeyID)
+	return nil
+}
+
+// startPasskeyCeremony - New challenge, remembered until the ceremony times out
+func (s *UserService) startPasskeyCeremony(ctx context.Context, ceremony model.PasskeyCeremony) (string, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.cache.Set(ctx, fmt.Sprintf(PasskeyCeremonyKey, challenge), ceremony, s.webauthn.Timeout); err != nil {
+		logger.ErrorCtx(ctx, "Failed to store passkey ceremony", "type", ceremony.Type, "error", err.Error())
+		return "", errors.New("failed to start passkey ceremony")
+	}
+	return challenge, nil
+}
+
+// takePasskeyCeremony - Find the ceremony a response answers and use it up, each
+// challenge is good for one response
+func (s *UserService) takePasskeyCeremony(ctx context.Context, clientDataJSON, ceremonyType string) (string, *model.PasskeyCeremony, error) {
+	challenge, err := webauthn.ClientDataChallenge(clientDataJSON)
+	if err != nil || challenge == "" {
+		return "", nil, errInvalidChallenge
+	}
+
+	key := fmt.Sprintf(PasskeyCeremonyKey, challenge)
+	var ceremony model.PasskeyCeremony
+	if err := s.cache.Get(ctx, key, &ceremony); err != nil || ceremony.Type != ceremonyType {
+		return "", nil, errInvalidChallenge
+	}
+
+	// INCR is atomic, only the first response to a challenge sees 1
+	used, err := s.cache.Increment(ctx, fmt.Sprintf(PasskeyCeremonyUsedKey, challenge), s.webauthn.Timeout)
+	if err != nil || used != 1 {
+		return "", nil, errInvalidChallenge
+	}
+	s.cache.Delete(ctx, key)
+
+	return challenge, &ceremony, nil
+}
+
+func toPasskeyResponse(passkey *model.Passkey) *model.PasskeyResponse {
+	return &model.PasskeyResponse{
+		ID:         passkey.ID,
+		Name:       passkey.Name,
+		CreatedAt:  passkey.CreatedAt,
+		LastUsedAt: passkey.LastUsedAt,
+	}
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/teammachinist/tutuplapak/services/auth/internal/logger"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/model"
+)
+
+const (
+	PurposePasswordReset = "password_reset"
+
+	PasswordResetCooldownKey = "password:reset:cooldown:%s" // password:reset:cooldown:{userAuthID}
+)
+
+const (
+	PasswordResetCodeTTL  = 15 * time.Minute
+	PasswordResetCooldown = 1 * time.Minute
+)
+
+// ForgotPassword - Send a reset code to the channel the account registered with.
+// Unknown accounts get the same response so the endpoint can't be used to probe
+// which emails/phones exist.
+func (s *UserService) ForgotPassword(ctx context.Context, email, phone string) error {
+	userAuth, err := s.findUserAuth(ctx, email, phone)
+	if err != nil {
+		if errors.Is(err, errAccountNotFound) {
+			logger.InfoCtx(ctx, "Password reset requested for unknown account")
+			return nil
+		}
+		return err
+	}
+
+	// One code per cooldown, a resend replaces the previous code
+	cooldownKey := fmt.Sprintf(PasswordResetCooldownKey, userAuth.ID.String())
+	if onCooldown, err := s.cache.Exists(ctx, cooldownKey); err == nil && onCooldown {
+		logger.InfoCtx(ctx, "Password reset on cooldown", "user_auth_id", userAuth.ID.String())
+		return nil
+	}
+
+	err = s.sendVerificationCode(ctx, userAuth, PurposePasswordReset, PasswordResetCodeTTL,
+		"Reset your TutupLapak password",
+		"Your password reset code is %s. It expires in 15 minutes. If you did not request this, ignore this message.")
+	if errors.Is(err, errVerificationSendLimit) {
+		return nil // Answered like any other request, see above
+	}
+	if err != nil {
+		return err
+	}
+
+	s.cache.Set(ctx, cooldownKey, true, PasswordResetCooldown)
+	return nil
+}
+
+// ResetPassword - Redeem a reset code, set the new password and sign the account
+// out everywhere. Wrong codes count against the same identifier and client IP
+// limits as failed logins, which a reset would otherwise get around.
+func (s *UserService) ResetPassword(ctx context.Context, email, phone, code, newPassword, clientIP string) error {
+	if err := s.validatePassword(newPassword); err != nil {
+		return err
+	}
+
+	identifier := email + phone
+	if err := s.checkLoginAllowed(ctx, identifier, clientIP); err != nil {
+		return err
+	}
+
+	userAuth, err := s.findUserAuth(ctx, email, phone)
+	if err != nil {
+		if errors.Is(err, errAccountNotFound) {
+			s.recordLoginFailure(ctx, identifier, clientIP)
+			return errors.New("invalid or expired code")
+		}
+		return err
+	}
+
+	if _, err := s.redeemVerificationCode(ctx, userAuth.ID, PurposePasswordReset, code); err != nil {
+		s.recordLoginFailure(ctx, identifier, clientIP)
+		return err
+	}
+	s.resetLoginFailures(ctx, identifier)
+
+	passwordHash, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+
+	if err := s.userRepo.UpdateUserAuthPassword(ctx, userAuth.ID, passwordHash); err != nil {
+		return err
+	}
+
+	// The new password is set either way, sessions failing to end is only logged
+	s.revokeAllSessions(ctx, userAuth)
+
+	logger.InfoCtx(ctx, "Password reset", "user_auth_id", userAuth.ID.String())
+	return nil
+}
+
+// revokeAllSessions - End every session with its refresh tokens and revoke every
+// access token issued so far. Best effort past the sessions: access tokens expire
+// on their own.
+func (s *UserService) revokeAllSessions(ctx context.Context, userAuth *model.UserAuth) error {
+	sessionIDs, err := s.userRepo.RevokeUserSessions(ctx, userAuth.ID)
+	if err != nil {
+		logger.WarnCtx(ctx, "Failed to revoke sessions", "user_auth_id", userAuth.ID.String(), "error", err.Error())
+		return err
+	}
+	for _, sessionID := range sessionIDs {
+		s.markSessionRevoked(ctx, sessionID)
+	}
+
+	// Access tokens carry Core's user.id. Accounts from before it was recorded
+	// on users_auth still have to look it up.
+	var userID string
+	if userAuth.UserID != nil {
+		userID = userAuth.UserID.String()
+	} else {
+		coreUser, err := s.GetUserFromCore(userAuth.ID.String())
+		if err != nil {
+			logger.WarnCtx(ctx, "Failed to resolve user for token revocation", "user_auth_id", userAuth.ID.String(), "error", err.Error())
+			return nil
+		}
+		userID = coreUser.ID
+	}
+
+	if err := s.revokeUserAccessTokens(ctx, userID); err != nil {
+		logger.WarnCtx(ctx, "Failed to revoke access tokens", "user_id", userID, "error", err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/teammachinist/tutuplapak/services/auth/internal/logger"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/model"
+)
+
+// PasswordlessConfig - one-time login codes and magic links. Links are only
+// offered when both LinkURL and LinkSecret are set; LinkURL is the frontend page
+// that posts the token back, so mail scanners following the link don't use it.
+type PasswordlessConfig struct {
+	CodeTTL    time.Duration
+	Cooldown   time.Duration // Between two sends to one account
+	MaxSends   int           // Per account per hour
+	MaxIPSends int           // Per client IP per hour
+	LinkURL    string
+	LinkSecret []byte
+}
+
+const (
+	PasswordlessGrantKey    = "passwordless:grant:%s"    // passwordless:grant:{userAuthID}
+	PasswordlessAttemptsKey = "passwordless:attempts:%s" // passwordless:attempts:{nonce}
+	PasswordlessUsedKey     = "passwordless:used:%s"     // passwordless:used:{nonce}
+	PasswordlessCooldownKey = "passwordless:cooldown:%s" // passwordless:cooldown:{userAuthID}
+	PasswordlessSendsKey    = "passwordless:sends:%s"    // passwordless:sends:{id|ip}:{value}
+)
+
+const passwordlessSendWindow = time.Hour
+
+var (
+	errInvalidLoginCode = errors.New("invalid or expired code")
+	errInvalidLoginLink = errors.New("invalid or expired link")
+)
+
+// StartPasswordlessLogin - Send a one-time login code or magic link over the
+// account's registration channel. A new send replaces whatever was outstanding.
+// Unknown and rate limited accounts get the same response so the endpoint
+// can't be used to probe which emails/phones exist.
+func (s *UserService) StartPasswordlessLogin(ctx context.Context, email, phone, method, clientIP string) error {
+	switch method {
+	case "", model.PasswordlessMethodCode:
+		method = model.PasswordlessMethodCode
+	case model.PasswordlessMethodLink:
+		if !s.magicLinksEnabled() {
+			return errors.New("magic link login is not enabled")
+		}
+	default:
+		return errors.New("invalid login method")
+	}
+
+	if !s.allowPasswordlessSend(ctx, throttleSubject(subjectIP, clientIP), s.passwordless.MaxIPSends) {
+		return errors.New("too many login requests, try again later")
+	}
+
+	userAuth, err := s.findUserAuth(ctx, email, phone)
+	if err != nil {
+		if errors.Is(err, errAccountNotFound) {
+			logger.InfoCtx(ctx, "Passwordless login requested for unknown account")
+			return nil
+		}
+		return err
+	}
+
+	cooldownKey := fmt.Sprintf(PasswordlessCooldownKey, userAuth.ID.String())
+	if onCooldown, err := s.cache.Exists(ctx, cooldownKey); err == nil && onCooldown {
+		logger.InfoCtx(ctx, "Passwordless login on cooldown", "user_auth_id", userAuth.ID.String())
+		return nil
+	}
+	if !s.allowPasswordlessSend(ctx, throttleSubject(subjectIdentifier, userAuth.ID.String()), s.passwordless.MaxSends) {
+		logger.WarnCtx(ctx, "Passwordless login send limit reached", "user_auth_id", userAuth.ID.String())
+		return nil
+	}
+
+	nonce, err := generateRefreshToken()
+	if err != nil {
+		return err
+	}
+	grant := model.PasswordlessGrant{Nonce: nonce, Method: method}
+
+	var secret, bodyFormat string
+	if method == model.PasswordlessMethodLink {
+		secret = s.magicLink(userAuth.ID, nonce, time.Now().Add(s.passwordless.CodeTTL))
+		bodyFormat = "Sign in to TutupLapak with this link: %s It expires in %d minutes and works once. If you did not request this, ignore this message."
+	} else {
+		secret, err = generateVerificationCode()
+		if err != nil {
+			return err
+		}
+		grant.CodeHash = hashToken(secret)
+		bodyFormat = "Your TutupLapak login code is %s. It expires in %d minutes. If you did not request this, ignore this message."
+	}
+
+	// Redis is the only copy of the grant, without it there is nothing to redeem
+	if err := s.cache.Set(ctx, fmt.Sprintf(PasswordlessGrantKey, userAuth.ID.String()), grant, s.passwordless.CodeTTL); err != nil {
+		logger.ErrorCtx(ctx, "Failed to store passwordless grant", "user_auth_id", userAuth.ID.String(), "error", err.Error())
+		return errors.New("failed to send login code")
+	}
+
+	body := fmt.Sprintf(bodyFormat, secret, int(s.passwordless.CodeTTL.Minutes()))
+	useSMS, target := deliveryTarget(userAuth)
+	if useSMS {
+		err = s.notifier.SendSMS(ctx, target, body)
+	} else {
+		err = s.notifier.SendEmail(ctx, target, "Sign in to TutupLapak", body)
+	}
+	if err != nil {
+		logger.ErrorCtx(ctx, "Failed to deliver passwordless login", "user_auth_id", userAuth.ID.String(), "error", err.Error())
+		return errors.New("failed to send login code")
+	}
+
+	s.cache.Set(ctx, cooldownKey, true, s.passwordless.Cooldown)
+	logger.InfoCtx(ctx, "Passwordless login sent", "user_auth_id", userAuth.ID.String(), "method", method)
+	return nil
+}
+
+// VerifyPasswordlessLogin - Redeem a login code (with the email/phone it was
+// sent to) or a magic link token, then log in like a correct password would
+func (s *UserService) VerifyPasswordlessLogin(ctx context.Context, email, phone, code, token string, client model.ClientInfo) (*model.AuthResponse, error) {
+	var userAuth *model.UserAuth
+	var identifier string
+	var err error
+
+	if token != "" {
+		userAuth, err = s.accountFromMagicLink(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+		identifier = userAuth.Email
+		if identifier == "" {
+			identifier = userAuth.Phone
+		}
+	} else {
+		if code == "" {
+			return nil, errors.New("code or token is required")
+		}
+		identifier = email + phone // findUserAuth rejects both being set
+	}
+
+	if err := s.checkLoginAllowed(ctx, identifier, client.IP); err != nil {
+		return nil, err
+	}
+
+	if userAuth == nil {
+		userAuth, err = s.findUserAuth(ctx, email, phone)
+		if errors.Is(err, errAccountNotFound) {
+			s.recordLoginFailure(ctx, identifier, client.IP)
+			return nil, errInvalidLoginCode
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.redeemPasswordlessGrant(ctx, userAuth.ID, code, token); err != nil {
+		s.recordLoginFailure(ctx, identifier, client.IP)
+		return nil, err
+	}
+
+	userID, err := s.ensureProfile(ctx, userAuth)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.InfoCtx(ctx, "Passwordless login redeemed", "user_auth_id", userAuth.ID.String())
+	return s.finishLogin(ctx, userAuth, userID, identifier, client)
+}
+
+// redeemPasswordlessGrant - Check the code (or link nonce) against the account's
+// outstanding grant and use it up. A code allows a few wrong guesses before the
+// grant is burned.
+func (s *UserService) redeemPasswordlessGrant(ctx context.Context, userAuthID uuid.UUID, code, token string) error {
+	invalid := errInvalidLoginCode
+	if token != "" {
+		invalid = errInvalidLoginLink
+	}
+
+	grantKey := fmt.Sprintf(PasswordlessGrantKey, userAuthID.String())
+	var grant model.PasswordlessGrant
+	if err := s.cache.Get(ctx, grantKey, &grant); err != nil {
+		return invalid
+	}
+
+	if token != "" {
+		_, nonce, _ := parseMagicLink(token)
+		if grant.Method != model.PasswordlessMethodLink || subtle.ConstantTimeCompare([]byte(nonce), []byte(grant.Nonce)) != 1 {
+			return invalid
+		}
+	} else {
+		if grant.Method != model.PasswordlessMethodCode || subtle.ConstantTimeCompare([]byte(hashToken(code)), []byte(grant.CodeHash)) != 1 {
+			attempts, err := s.cache.Increment(ctx, fmt.Sprintf(PasswordlessAttemptsKey, grant.Nonce), s.passwordless.CodeTTL)
+			if err != nil || attempts >= MaxVerificationAttempts {
+				logger.WarnCtx(ctx, "Passwordless grant burned after too many attempts", "user_auth_id", userAuthID.String())
+				s.cache.Delete(ctx, grantKey)
+			}
+			return invalid
+		}
+	}
+
+	// INCR is atomic, only the first redeem of a grant sees 1
+	used, err := s.cache.Increment(ctx, fmt.Sprintf(PasswordlessUsedKey, grant.Nonce), s.passwordless.CodeTTL)
+	if err != nil || used != 1 {
+		return invalid
+	}
+	s.cache.Delete(ctx, grantKey)
+	return nil
+}
+
+// allowPasswordlessSend - Count a send against subject's hourly budget. Fails
+// closed: without Redis no grant could be stored anyway.
+func (s *UserService) allowPasswordlessSend(ctx context.Context, subject string, limit int) bool {
+	if limit <= 0 {
+		return true
+	}
+	sends, err := s.cache.Increment(ctx, fmt.Sprintf(PasswordlessSendsKey, subject), passwordlessSendWindow)
+	if err != nil {
+		return false
+	}
+	return sends <= int64(limit)
+}
+
+func (s *UserService) magicLinksEnabled() bool {
+	return s.passwordless.LinkURL != "" && len(s.passwordless.LinkSecret) > 0
+}
+
+// magicLink - LinkURL with a signed token naming the account, the grant's nonce
+// and when the link stops working
+func (s *UserService) magicLink(userAuthID uuid.UUID, nonce string, expiresAt time.Time) string {
+	payload := userAuthID.String() + "." + nonce + "." + strconv.FormatInt(expiresAt.Unix(), 10)
+	token := base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + s.signMagicLink(payload)
+
+	link, err := url.Parse(s.passwordless.LinkURL)
+	if err != nil {
+		return s.passwordless.LinkURL + "?token=" + url.QueryEscape(token)
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String()
+}
+
+func (s *UserService) signMagicLink(payload string) string {
+	mac := hmac.New(sha256.New, s.passwordless.LinkSecret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// accountFromMagicLink - Verify the token's signature and expiry and load the
+// account it names. Whether it is still unused is up to the grant.
+func (s *UserService) accountFromMagicLink(ctx context.Context, token string) (*model.UserAuth, error) {
+	if !s.magicLinksEnabled() {
+		return nil, errInvalidLoginLink
+	}
+
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, errInvalidLoginLink
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || !hmac.Equal([]byte(signature), []byte(s.signMagicLink(string(payload)))) {
+		return nil, errInvalidLoginLink
+	}
+
+	userAuthID, _, expiresAt := parseMagicLink(token)
+	if userAuthID == uuid.Nil || time.Now().After(expiresAt) {
+		return nil, errInvalidLoginLink
+	}
+
+	userAuth, err := s.userRepo.GetUserAuthByID(ctx, userAuthID)
+	if err != nil {
+		return nil, errInvalidLoginLink
+	}
+	return userAuth, nil
+}
+
+// parseMagicLink - Fields of a token's payload, zero values when malformed.
+// Does not check the signature.
+func parseMagicLink(token string) (uuid.UUID, string, time.Time) {
+	encoded, _, _ := strings.Cut(token, ".")
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return uuid.Nil, "", time.Time{}
+	}
+
+	parts := strings.Split(string(payload), ".")
+	if len(parts) != 3 {
+		return uuid.Nil, "", time.Time{}
+	}
+	userAuthID, err := uuid.Parse(parts[0])
+	if err != nil {
+		return uuid.Nil, "", time.Time{}
+	}
+	expiresAt, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return uuid.Nil, "", time.Time{}
+	}
+	return userAuthID, parts[1], time.Unix(expiresAt, 0)
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/teammachinist/tutuplapak/services/auth/internal/cache"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/database"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/model"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/repository"
+	"github.com/teammachinist/tutuplapak/services/auth/pkg/redistest"
+)
+
+var testPasswordless = PasswordlessConfig{
+	CodeTTL:    15 * time.Minute,
+	Cooldown:   time.Minute,
+	MaxSends:   10,
+	MaxIPSends: 10,
+	LinkURL:    "https://tutuplapak.example/login/link",
+	LinkSecret: []byte("magic-link-secret"),
+}
+
+// outbox - notifier.Notifier keeping the last message instead of sending it
+type outbox struct {
+	last string
+}
+
+func (o *outbox) SendEmail(ctx context.Context, to, subject, body string) error {
+	o.last = body
+	return nil
+}
+
+func (o *outbox) SendSMS(ctx context.Context, to, body string) error {
+	o.last = body
+	return nil
+}
+
+type passwordlessTest struct {
+	service *UserService
+	redis   *redistest.Server
+	outbox  *outbox
+	account uuid.UUID
+}
+
+func newPasswordlessTest(t *testing.T) *passwordlessTest {
+	t.Helper()
+
+	redis := redistest.Start(t)
+	redisCache := cache.NewRedisCache(cache.CacheConfig{Addr: redis.Addr()})
+	t.Cleanup(func() { redisCache.Close() })
+
+	account := uuid.New()
+	accountRow := func(args []any) ([][]any, error) {
+		return [][]any{{account, "alice@example.com", "", "", time.Now(), "email", (*uuid.UUID)(nil)}}, nil
+	}
+	db := newFakeDB()
+	db.queries["GetUserAuthByEmail"] = accountRow
+	db.queries["GetUserAuthByID"] = func(args []any) ([][]any, error) {
+		if args[0].(uuid.UUID) != account {
+			return nil, nil
+		}
+		return accountRow(args)
+	}
+
+	box := &outbox{}
+	return &passwordlessTest{
+		service: &UserService{
+			userRepo:     repository.NewUserRepository(nil, database.New(db)),
+			cache:        redisCache,
+			notifier:     box,
+			passwordless: testPasswordless,
+		},
+		redis:   redis,
+		outbox:  box,
+		account: account,
+	}
+}
+
+// send - Request a login by method and return the link's token or the code
+func (p *passwordlessTest) send(t *testing.T, method string) string {
+	t.Helper()
+
+	p.outbox.last = ""
+	if err := p.service.StartPasswordlessLogin(context.Background(), "alice@example.com", "", method, "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if method == model.PasswordlessMethodCode {
+		code := regexp.MustCompile(`code is (\d+)\.`).FindStringSubmatch(p.outbox.last)
+		if code == nil {
+			t.Fatalf("no code in %q", p.outbox.last)
+		}
+		return code[1]
+	}
+
+	link := regexp.MustCompile(`https://\S+`).FindString(p.outbox.last)
+	parsed, err := url.Parse(link)
+	if err != nil || !strings.HasPrefix(link, testPasswordless.LinkURL+"?") {
+		t.Fatalf("no link in %q", p.outbox.last)
+	}
+	return parsed.Query().Get("token")
+}
+
+// redeemLink - What verifying the link does before logging in
+func (p *passwordlessTest) redeemLink(token string) error {
+	ctx := context.Background()
+	userAuth, err := p.service.accountFromMagicLink(ctx, token)
+	if err != nil {
+		return err
+	}
+	return p.service.redeemPasswordlessGrant(ctx, userAuth.ID, "", token)
+}
+
+func TestMagicLinkWorksOnce(t *testing.T) {
+	p := newPasswordlessTest(t)
+	token := p.send(t, model.PasswordlessMethodLink)
+
+	userAuth, err := p.service.accountFromMagicLink(context.Background(), token)
+	if err != nil {
+		t.Fatalf("link rejected: %v", err)
+	}
+	if userAuth.ID != p.account {
+		t.Errorf("link names account %s, want %s", userAuth.ID, p.account)
+	}
+
+	if err := p.redeemLink(token); err != nil {
+		t.Fatalf("first use rejected: %v", err)
+	}
+	if err := p.redeemLink(token); err != errInvalidLoginLink {
+		t.Errorf("second use: got %v", err)
+	}
+}
+
+func TestMagicLinkReplacedByNewerSend(t *testing.T) {
+	p := newPasswordlessTest(t)
+	first := p.send(t, model.PasswordlessMethodLink)
+	p.redis.FastForward(testPasswordless.Cooldown)
+	second := p.send(t, model.PasswordlessMethodLink)
+
+	if err := p.redeemLink(first); err != errInvalidLoginLink {
+		t.Errorf("superseded link: got %v", err)
+	}
+	if err := p.redeemLink(second); err != nil {
+		t.Errorf("latest link rejected: %v", err)
+	}
+}
+
+func TestMagicLinkExpiresWithGrant(t *testing.T) {
+	p := newPasswordlessTest(t)
+	token := p.send(t, model.PasswordlessMethodLink)
+	p.redis.FastForward(testPasswordless.CodeTTL)
+
+	if err := p.redeemLink(token); err != errInvalidLoginLink {
+		t.Errorf("link redeemed after its grant expired: %v", err)
+	}
+}
+
+func TestMagicLinkSignature(t *testing.T) {
+	p := newPasswordlessTest(t)
+	token := p.send(t, model.PasswordlessMethodLink)
+	encoded, signature, _ := strings.Cut(token, ".")
+	payload, _ := base64.RawURLEncoding.DecodeString(encoded)
+	_, nonce, expiresAt := parseMagicLink(token)
+
+	otherSecret := &UserService{passwordless: PasswordlessConfig{LinkURL: testPasswordless.LinkURL, LinkSecret: []byte("another-secret")}}
+	otherLink, _ := url.Parse(otherSecret.magicLink(p.account, nonce, expiresAt))
+
+	tests := map[string]string{
+		"other account": base64.RawURLEncoding.EncodeToString([]byte(strings.Replace(string(payload), p.account.String(), uuid.NewString(), 1))) + "." + signature,
+		"later expiry":  base64.RawURLEncoding.EncodeToString([]byte(p.account.String()+"."+nonce+".9999999999")) + "." + signature,
+		"signature":     encoded + "." + strings.Repeat("A", len(signature)),
+		"unsigned":      encoded,
+		"other secret":  otherLink.Query().Get("token"),
+		"garbage":       "not-a-token",
+		"expired":       mustQueryToken(t, p.service.magicLink(p.account, nonce, time.Now().Add(-time.Second))),
+	}
+
+	for name, tampered := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := p.service.accountFromMagicLink(context.Background(), tampered); err != errInvalidLoginLink {
+				t.Errorf("got %v", err)
+			}
+		})
+	}
+
+	// None of the rejected tokens used up the real one
+	if err := p.redeemLink(token); err != nil {
+		t.Errorf("genuine link rejected: %v", err)
+	}
+}
+
+func TestMagicLinksDisabledWithoutSecret(t *testing.T) {
+	p := newPasswordlessTest(t)
+	token := p.send(t, model.PasswordlessMethodLink)
+	p.service.passwordless.LinkSecret = nil
+
+	if _, err := p.service.accountFromMagicLink(context.Background(), token); err != errInvalidLoginLink {
+		t.Errorf("got %v", err)
+	}
+	if err := p.service.StartPasswordlessLogin(context.Background(), "alice@example.com", "", model.PasswordlessMethodLink, "10.0.0.1"); err == nil {
+		t.Error("link sent while links are disabled")
+	}
+}
+
+func TestLoginCodeWorksOnce(t *testing.T) {
+	p := newPasswordlessTest(t)
+	ctx := context.Background()
+	code := p.send(t, model.PasswordlessMethodCode)
+
+	if err := p.service.redeemPasswordlessGrant(ctx, p.account, code, ""); err != nil {
+		t.Fatalf("code rejected: %v", err)
+	}
+	if err := p.service.redeemPasswordlessGrant(ctx, p.account, code, ""); err != errInvalidLoginCode {
+		t.Errorf("second use: got %v", err)
+	}
+}
+
+func TestLoginCodeBurnedAfterWrongGuesses(t *testing.T) {
+	p := newPasswordlessTest(t)
+	ctx := context.Background()
+	code := p.send(t, model.PasswordlessMethodCode)
+
+	for range MaxVerificationAttempts {
+		p.service.redeemPasswordlessGrant(ctx, p.account, "000000"+code, "")
+	}
+	if err := p.service.redeemPasswordlessGrant(ctx, p.account, code, ""); err != errInvalidLoginCode {
+		t.Errorf("correct code after %d wrong guesses: got %v", MaxVerificationAttempts, err)
+	}
+}
+
+func TestLinkDoesNotRedeemCode(t *testing.T) {
+	p := newPasswordlessTest(t)
+	p.send(t, model.PasswordlessMethodCode)
+
+	// Validly signed, but the outstanding grant is a code
+	token := mustQueryToken(t, p.service.magicLink(p.account, "guessed-nonce", time.Now().Add(time.Minute)))
+	if err := p.redeemLink(token); err != errInvalidLoginLink {
+		t.Errorf("link redeemed a code grant: %v", err)
+	}
+}
+
+func mustQueryToken(t *testing.T, link string) string {
+	t.Helper()
+	parsed, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Query().Get("token")
+}
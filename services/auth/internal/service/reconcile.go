@@ -0,0 +1,206 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/teammachinist/tutuplapak/services/auth/internal/logger"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/model"
+)
+
+// ReconcileConfig - how often accounts are compared against Core's profiles.
+// Rows younger than GracePeriod are left alone, they may still be in flight.
+type ReconcileConfig struct {
+	Interval    time.Duration
+	GracePeriod time.Duration
+	PageSize    int
+}
+
+// ReconcileResult - repairs made by one reconciliation pass
+type ReconcileResult struct {
+	Enqueued int // Accounts without a profile, profile creation queued
+	Deleted  int // Profiles without an account, removed from Core
+	Linked   int // Accounts whose user_id was missing or stale
+}
+
+// RunReconciler - Reconcile accounts with Core's profiles every interval until ctx
+// is done. Every repair is idempotent, so replicas running it at once is harmless.
+func (s *UserService) RunReconciler(ctx context.Context, cfg ReconcileConfig) {
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result, err := s.Reconcile(ctx, cfg)
+			if err != nil {
+				logger.ErrorCtx(ctx, "Reconciliation failed", "error", err.Error())
+				continue
+			}
+			logger.InfoCtx(ctx, "Reconciliation finished", "enqueued", result.Enqueued, "deleted", result.Deleted, "linked", result.Linked)
+		}
+	}
+}
+
+// Reconcile - Walk users_auth and Core's users side by side, both ordered by
+// user_auth_id, and repair whatever exists on one side only
+func (s *UserService) Reconcile(ctx context.Context, cfg ReconcileConfig) (*ReconcileResult, error) {
+	cutoff := time.Now().Add(-cfg.GracePeriod)
+	result := &ReconcileResult{}
+
+	accounts := &pageCursor[model.UserAuth]{
+		fetch: func(after uuid.UUID) ([]model.UserAuth, error) {
+			return s.userRepo.ListUserAuthPage(ctx, after, int32(cfg.PageSize))
+		},
+		key: func(userAuth model.UserAuth) uuid.UUID { return userAuth.ID },
+	}
+	profiles := &pageCursor[CoreUserRef]{
+		fetch: func(after uuid.UUID) ([]CoreUserRef, error) {
+			return s.ListCoreUsers(ctx, after, cfg.PageSize)
+		},
+		key: func(coreUser CoreUserRef) uuid.UUID { return coreUser.UserAuthID },
+	}
+
+	for {
+		account, accountOK, err := accounts.peek()
+		if err != nil {
+			return result, fmt.Errorf("failed to list accounts: %w", err)
+		}
+		profile, profileOK, err := profiles.peek()
+		if err != nil {
+			return result, fmt.Errorf("failed to list core users: %w", err)
+		}
+		if !accountOK && !profileOK {
+			return result, nil
+		}
+
+		var order int
+		switch {
+		case !profileOK:
+			order = -1
+		case !accountOK:
+			order = 1
+		default:
+			// Same order as Postgres sorts uuids in
+			order = bytes.Compare(account.ID[:], profile.UserAuthID[:])
+		}
+
+		switch {
+		case order < 0:
+			// Account without a profile
+			if account.CreatedAt.Before(cutoff) && s.reconcileMissingProfile(ctx, &account) {
+				result.Enqueued++
+			}
+			accounts.next()
+		case order > 0:
+			// Profile without an account
+			if profile.CreatedAt.Before(cutoff) && s.reconcileOrphanProfile(ctx, &profile) {
+				result.Deleted++
+			}
+			profiles.next()
+		default:
+			if s.reconcileUserID(ctx, &account, &profile) {
+				result.Linked++
+			}
+			accounts.next()
+			profiles.next()
+		}
+	}
+}
+
+func (s *UserService) reconcileMissingProfile(ctx context.Context, account *model.UserAuth) bool {
+	userAuth, err := s.userRepo.GetUserAuthByID(ctx, account.ID)
+	if err != nil {
+		logger.WarnCtx(ctx, "Failed to load account for reconciliation", "user_auth_id", account.ID.String(), "error", err.Error())
+		return false
+	}
+
+	payload, err := json.Marshal(model.UserRegisteredPayload{
+		UserAuthID: userAuth.ID.String(),
+		Email:      userAuth.Email,
+		Phone:      userAuth.Phone,
+	})
+	if err != nil {
+		return false
+	}
+
+	if err := s.userRepo.EnqueueOutboxEvent(ctx, userAuth.ID, model.EventUserRegistered, payload); err != nil {
+		logger.WarnCtx(ctx, "Failed to enqueue missing user profile", "user_auth_id", userAuth.ID.String(), "error", err.Error())
+		return false
+	}
+
+	logger.InfoCtx(ctx, "Queued missing user profile", "user_auth_id", userAuth.ID.String())
+	return true
+}
+
+func (s *UserService) reconcileOrphanProfile(ctx context.Context, profile *CoreUserRef) bool {
+	// A merged account's profile lives on until its products and files have moved
+	pending, err := s.userRepo.HasPendingAccountMerge(ctx, profile.UserAuthID)
+	if err != nil || pending {
+		return false
+	}
+
+	if _, err := s.DeleteUserInCore(ctx, profile.UserAuthID.String()); err != nil {
+		logger.WarnCtx(ctx, "Failed to delete orphaned user profile", "user_auth_id", profile.UserAuthID.String(), "error", err.Error())
+		return false
+	}
+
+	logger.InfoCtx(ctx, "Deleted orphaned user profile", "user_auth_id", profile.UserAuthID.String(), "user_id", profile.ID.String())
+	return true
+}
+
+func (s *UserService) reconcileUserID(ctx context.Context, account *model.UserAuth, profile *CoreUserRef) bool {
+	if account.UserID != nil && *account.UserID == profile.ID {
+		return false
+	}
+
+	if err := s.userRepo.SetUserAuthUserID(ctx, account.ID, profile.ID); err != nil {
+		logger.WarnCtx(ctx, "Failed to record user id", "user_auth_id", account.ID.String(), "error", err.Error())
+		return false
+	}
+	return true
+}
+
+// pageCursor - iterates a keyset-paginated listing one row at a time
+type pageCursor[T any] struct {
+	fetch func(after uuid.UUID) ([]T, error)
+	key   func(T) uuid.UUID
+
+	page  []T
+	after uuid.UUID
+	done  bool
+}
+
+// peek - The current row, fetching the next page when the current one is used up
+func (c *pageCursor[T]) peek() (T, bool, error) {
+	var zero T
+	if len(c.page) == 0 && !c.done {
+		page, err := c.fetch(c.after)
+		if err != nil {
+			return zero, false, err
+		}
+		if len(page) == 0 {
+			c.done = true
+		}
+		c.page = page
+	}
+	if len(c.page) == 0 {
+		return zero, false, nil
+	}
+	return c.page[0], true, nil
+}
+
+func (c *pageCursor[T]) next() {
+	if len(c.page) == 0 {
+		return
+	}
+	c.after = c.key(c.page[0])
+	c.page = c.page[1:]
+}
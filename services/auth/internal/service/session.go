@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/teammachinist/tutuplapak/services/auth/internal/logger"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/model"
+	"github.com/teammachinist/tutuplapak/services/auth/pkg/authz"
+)
+
+// Browsers send long user agents, only so much is worth keeping
+const maxUserAgentLength = 512
+
+var errSessionNotFound = errors.New("session not found")
+
+// startSession - Record a new login and issue its first tokens
+func (s *UserService) startSession(ctx context.Context, userAuthID uuid.UUID, userID string, client model.ClientInfo) (*model.TokenResponse, error) {
+	coreUserID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user id: %w", err)
+	}
+
+	if len(client.UserAgent) > maxUserAgentLength {
+		client.UserAgent = client.UserAgent[:maxUserAgentLength]
+	}
+
+	session, err := s.userRepo.CreateSession(ctx, userAuthID, coreUserID, client, time.Now().Add(s.jwtConfig.RefreshDuration))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return s.issueTokens(ctx, userAuthID, userID, session.ID)
+}
+
+// ListSessions - The user's signed-in devices, most recently active first
+func (s *UserService) ListSessions(ctx context.Context, claims *authz.Claims) ([]model.SessionResponse, error) {
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, errors.New("invalid token")
+	}
+
+	sessions, err := s.userRepo.ListActiveSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]model.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, model.SessionResponse{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			Current:    session.ID.String() == claims.SessionID,
+		})
+	}
+	return response, nil
+}
+
+// RevokeSession - Sign one of the user's devices out
+func (s *UserService) RevokeSession(ctx context.Context, claims *authz.Claims, sessionID string) error {
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return errors.New("invalid session id format")
+	}
+
+	return s.endSession(ctx, claims.UserID, sessionID)
+}
+
+// RevokeAllSessions - Sign the user out everywhere, including this device
+func (s *UserService) RevokeAllSessions(ctx context.Context, claims *authz.Claims) error {
+	sessionID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return errSessionNotFound
+	}
+
+	session, err := s.userRepo.GetSession(ctx, sessionID)
+	if err != nil || session.UserID.String() != claims.UserID {
+		return errSessionNotFound
+	}
+
+	userID := session.UserID
+	return s.revokeAllSessions(ctx, &model.UserAuth{ID: session.UserAuthID, UserID: &userID})
+}
+
+// endSession - Revoke the session in the database and mark it in Redis, where
+// token validation sees it immediately
+func (s *UserService) endSession(ctx context.Context, userID, sessionID string) error {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return errors.New("invalid token")
+	}
+	sessionUUID, err := uuid.Parse(sessionID)
+	if err != nil {
+		return errSessionNotFound
+	}
+
+	revoked, err := s.userRepo.RevokeSession(ctx, sessionUUID, userUUID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return errSessionNotFound
+	}
+
+	s.markSessionRevoked(ctx, sessionUUID)
+	logger.InfoCtx(ctx, "Session revoked", "user_id", userID, "session_id", sessionID)
+	return nil
+}
+
+// markSessionRevoked - The marker only needs to outlive the session's access tokens
+func (s *UserService) markSessionRevoked(ctx context.Context, sessionID uuid.UUID) {
+	if err := s.cache.Set(ctx, fmt.Sprintf(RevokedSessionKey, sessionID.String()), true, s.jwtConfig.Duration); err != nil {
+		logger.WarnCtx(ctx, "Failed to mark session revoked", "session_id", sessionID.String(), "error", err.Error())
+	}
+}
+
+// isSessionRevoked - Database check for when the Redis markers can't be read
+func (s *UserService) isSessionRevoked(ctx context.Context, sessionID string) bool {
+	sessionUUID, err := uuid.Parse(sessionID)
+	if err != nil {
+		return false // Token from before sessions existed
+	}
+
+	session, err := s.userRepo.GetSession(ctx, sessionUUID)
+	if err != nil {
+		logger.WarnCtx(ctx, "Failed to check session revocation", "session_id", sessionID, "error", err.Error())
+		return false
+	}
+	return session.Revoked
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/teammachinist/tutuplapak/services/auth/internal/logger"
+	"github.com/teammachinist/tutuplapak/services/auth/pkg/authz"
+)
+
+// SigningKey - private key used to sign access tokens, ID is published as the JWT kid
+type SigningKey struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.Signer
+}
+
+// SigningKeys - every key in the set is published in the JWKS so tokens signed by
+// a retired key stay verifiable until they expire; only the active key signs.
+type SigningKeys struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+// LoadSigningKeys - Read <kid>.pem private keys (PKCS#8 or PKCS#1, RSA or Ed25519)
+// from dir. Without a directory an ephemeral Ed25519 key is generated, which only
+// works for a single replica and invalidates all tokens on restart.
+func LoadSigningKeys(dir, activeKeyID string) (*SigningKeys, error) {
+	if dir == "" {
+		logger.Warn("JWT_KEYS_DIR not set, generating ephemeral signing key")
+		return generateSigningKeys()
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list signing keys: %w", err)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no signing keys found in %s", dir)
+	}
+	sort.Strings(paths)
+
+	set := &SigningKeys{keys: make(map[string]*SigningKey, len(paths))}
+	for _, path := range paths {
+		key, err := readSigningKey(path)
+		if err != nil {
+			return nil, err
+		}
+		set.keys[key.ID] = key
+	}
+
+	// Default to the last key by name, so date-prefixed kids rotate naturally
+	if activeKeyID == "" {
+		activeKeyID = strings.TrimSuffix(filepath.Base(paths[len(paths)-1]), ".pem")
+	}
+
+	active, ok := set.keys[activeKeyID]
+	if !ok {
+		return nil, fmt.Errorf("active signing key %q not found in %s", activeKeyID, dir)
+	}
+	set.active = active
+
+	logger.Info("Loaded signing keys", "count", len(set.keys), "active_kid", active.ID, "alg", active.Method.Alg())
+	return set, nil
+}
+
+// Active - Key used for signing new tokens
+func (k *SigningKeys) Active() *SigningKey {
+	return k.active
+}
+
+// Lookup - Find the key a token was signed with by its kid
+func (k *SigningKeys) Lookup(keyID string) (*SigningKey, bool) {
+	key, ok := k.keys[keyID]
+	return key, ok
+}
+
+// JWKS - Public half of every key in the set
+func (k *SigningKeys) JWKS() (*authz.JWKS, error) {
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	jwks := &authz.JWKS{Keys: make([]authz.JWK, 0, len(ids))}
+	for _, id := range ids {
+		jwk, err := authz.NewJWK(id, k.keys[id].PrivateKey.Public())
+		if err != nil {
+			return nil, err
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks, nil
+}
+
+func readSigningKey(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key %s: %w", path, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("signing key %s is not PEM encoded", path)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key %s: %w", path, err)
+	}
+
+	keyID := strings.TrimSuffix(filepath.Base(path), ".pem")
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		if key.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("signing key %s: RSA keys must be at least 2048 bits", path)
+		}
+		return &SigningKey{ID: keyID, Method: jwt.SigningMethodRS256, PrivateKey: key}, nil
+	case ed25519.PrivateKey:
+		return &SigningKey{ID: keyID, Method: jwt.SigningMethodEdDSA, PrivateKey: key}, nil
+	default:
+		return nil, fmt.Errorf("signing key %s: only RSA and Ed25519 keys are supported", path)
+	}
+}
+
+func generateSigningKeys() (*SigningKeys, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	key := &SigningKey{
+		ID:         "ephemeral",
+		Method:     jwt.SigningMethodEdDSA,
+		PrivateKey: privateKey,
+	}
+
+	return &SigningKeys{
+		active: key,
+		keys:   map[string]*SigningKey{key.ID: key},
+	}, nil
+}
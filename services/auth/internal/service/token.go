@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"github.com/teammachinist/tutuplapak/services/auth/internal/logger"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/model"
+	"github.com/teammachinist/tutuplapak/services/auth/pkg/authz"
+)
+
+// issueTokens mints an access token plus a refresh token for sessionID. The session
+// is the refresh token family: a refresh keeps the family of the token it rotates.
+func (s *UserService) issueTokens(ctx context.Context, userAuthID uuid.UUID, userID string, sessionID uuid.UUID) (*model.TokenResponse, error) {
+	coreUserID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user id: %w", err)
+	}
+
+	// Roles are read fresh on every login/refresh, so grants take effect on the next token
+	access, err := s.userRepo.GetUserAccess(ctx, userAuthID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load roles: %w", err)
+	}
+
+	accessToken, err := s.GenerateToken(userID, sessionID.String(), access)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := generateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(s.jwtConfig.RefreshDuration)
+	if _, err := s.userRepo.CreateRefreshToken(ctx, userAuthID, coreUserID, sessionID, hashToken(refreshToken), expiresAt); err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return &model.TokenResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// RefreshToken rotates a refresh token: the presented token is revoked and a new
+// pair is issued in the same family. Presenting an already revoked token is treated
+// as theft and kills the whole family.
+func (s *UserService) RefreshToken(ctx context.Context, refreshToken string) (*model.TokenResponse, error) {
+	if refreshToken == "" {
+		return nil, errors.New("refresh token is required")
+	}
+
+	stored, err := s.userRepo.GetRefreshTokenByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		return nil, errors.New("invalid refresh token")
+	}
+
+	if stored.Revoked {
+		s.revokeRefreshFamily(ctx, stored)
+		return nil, errors.New("refresh token has been revoked")
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, errors.New("refresh token has expired")
+	}
+
+	// Keeps the session's last seen time current; a revoked session can't refresh
+	active, err := s.userRepo.TouchSession(ctx, stored.FamilyID, time.Now().Add(s.jwtConfig.RefreshDuration))
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, errors.New("refresh token has been revoked")
+	}
+
+	// Lost the race against a concurrent refresh with the same token
+	revoked, err := s.userRepo.RevokeRefreshToken(ctx, stored.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !revoked {
+		s.revokeRefreshFamily(ctx, stored)
+		return nil, errors.New("refresh token has been revoked")
+	}
+
+	tokens, err := s.issueTokens(ctx, stored.UserAuthID, stored.UserID.String(), stored.FamilyID)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+
+	return tokens, nil
+}
+
+// Logout revokes the access token until it expires and ends its session. Tokens
+// from before sessions existed instead revoke the refresh token family, when given.
+func (s *UserService) Logout(ctx context.Context, accessToken, refreshToken string) error {
+	claims, err := s.parseToken(accessToken)
+	if err != nil {
+		return err
+	}
+
+	if err := s.revokeAccessToken(ctx, claims); err != nil {
+		return err
+	}
+
+	if claims.SessionID != "" {
+		// Already ended elsewhere is still a successful logout
+		if err := s.endSession(ctx, claims.UserID, claims.SessionID); err != nil && !errors.Is(err, errSessionNotFound) {
+			return err
+		}
+		return nil
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	stored, err := s.userRepo.GetRefreshTokenByHash(ctx, hashToken(refreshToken))
+	if err != nil || stored.UserID.String() != claims.UserID {
+		return errors.New("invalid refresh token")
+	}
+
+	return s.userRepo.RevokeRefreshTokenFamily(ctx, stored.FamilyID)
+}
+
+func (s *UserService) revokeAccessToken(ctx context.Context, claims *TokenClaims) error {
+	if claims.ID == "" {
+		return errors.New("invalid token")
+	}
+
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return nil // Already expired, nothing to revoke
+	}
+
+	return s.cache.Set(ctx, fmt.Sprintf(RevokedTokenKey, claims.ID), true, ttl)
+}
+
+// revokeUserAccessTokens - Revoke every access token of the user issued until now,
+// including this very second as iat has no finer precision. The marker only
+// needs to outlive the longest possible access token, regular or impersonation.
+func (s *UserService) revokeUserAccessTokens(ctx context.Context, userID string) error {
+	ttl := max(s.jwtConfig.Duration, s.jwtConfig.ImpersonationTTL)
+	return s.cache.Set(ctx, fmt.Sprintf(RevokedUserKey, userID), time.Now().Unix(), ttl)
+}
+
+// isTokenRevoked checks the revocation markers in Redis. When Redis is unavailable
+// only the session is checked, against the database; single token and per-user
+// markers fail open as access tokens are short-lived.
+func (s *UserService) isTokenRevoked(ctx context.Context, claims *authz.Claims) bool {
+	revoked, err := s.cache.Exists(ctx, fmt.Sprintf(RevokedTokenKey, claims.TokenID))
+	if err != nil {
+		logger.WarnCtx(ctx, "Failed to check token revocation", "error", err.Error())
+		return s.isSessionRevoked(ctx, claims.SessionID)
+	}
+	if revoked {
+		return true
+	}
+
+	if claims.SessionID != "" {
+		if revoked, err := s.cache.Exists(ctx, fmt.Sprintf(RevokedSessionKey, claims.SessionID)); err == nil && revoked {
+			return true
+		}
+	}
+
+	var revokedAt int64
+	if err := s.cache.Get(ctx, fmt.Sprintf(RevokedUserKey, claims.UserID), &revokedAt); err != nil {
+		return false // Nothing revoked for this user (or cache down)
+	}
+	return claims.IssuedAt <= revokedAt
+}
+
+func (s *UserService) revokeRefreshFamily(ctx context.Context, token *model.RefreshToken) {
+	logger.WarnCtx(ctx, "Refresh token reuse detected, revoking family",
+		"user_auth_id", token.UserAuthID.String(),
+		"family_id", token.FamilyID.String())
+
+	if err := s.userRepo.RevokeRefreshTokenFamily(ctx, token.FamilyID); err != nil {
+		logger.WarnCtx(ctx, "Failed to revoke refresh token family", "family_id", token.FamilyID.String(), "error", err.Error())
+	}
+}
+
+func generateRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Only hashes are stored or used as cache keys, never raw tokens
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *UserService) parseToken(tokenString string) (*TokenClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := s.jwtConfig.Keys.Lookup(kid)
+		if !ok || token.Method != key.Method {
+			return nil, errors.New("unknown signing key")
+		}
+		return key.PrivateKey.Public(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuer(s.jwtConfig.Issuer),
+		jwt.WithAudience(authz.AccessTokenAudience))
+
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, errors.New("token has expired")
+		}
+		return nil, errors.New("invalid token")
+	}
+
+	claims, ok := token.Claims.(*TokenClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/teammachinist/tutuplapak/services/auth/internal/cache"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/database"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/repository"
+	"github.com/teammachinist/tutuplapak/services/auth/pkg/authz"
+	"github.com/teammachinist/tutuplapak/services/auth/pkg/redistest"
+)
+
+// refreshTokenTable - the refresh_tokens and sessions rows the refresh flow
+// reads and writes
+type refreshTokenTable struct {
+	tokens          map[string]*database.RefreshTokens // By token hash
+	revokedSessions map[uuid.UUID]bool
+}
+
+func newRefreshTokenTable(db *fakeDB) *refreshTokenTable {
+	table := &refreshTokenTable{
+		tokens:          make(map[string]*database.RefreshTokens),
+		revokedSessions: make(map[uuid.UUID]bool),
+	}
+
+	db.queries["CreateRefreshToken"] = func(args []any) ([][]any, error) {
+		token := &database.RefreshTokens{
+			ID:         uuid.New(),
+			UserAuthID: args[0].(uuid.UUID),
+			UserID:     args[1].(uuid.UUID),
+			FamilyID:   args[2].(uuid.UUID),
+			TokenHash:  args[3].(string),
+			ExpiresAt:  args[4].(time.Time),
+			CreatedAt:  time.Now(),
+		}
+		table.tokens[token.TokenHash] = token
+		return [][]any{refreshTokenRow(token)}, nil
+	}
+	db.queries["GetRefreshTokenByHash"] = func(args []any) ([][]any, error) {
+		token, ok := table.tokens[args[0].(string)]
+		if !ok {
+			return nil, nil
+		}
+		return [][]any{refreshTokenRow(token)}, nil
+	}
+	db.execs["RevokeRefreshToken"] = func(args []any) (int64, error) {
+		for _, token := range table.tokens {
+			if token.ID == args[0].(uuid.UUID) && !token.Revoked {
+				token.Revoked = true
+				return 1, nil
+			}
+		}
+		return 0, nil
+	}
+	db.execs["RevokeRefreshTokenFamily"] = func(args []any) (int64, error) {
+		var rows int64
+		for _, token := range table.tokens {
+			if token.FamilyID == args[0].(uuid.UUID) && !token.Revoked {
+				token.Revoked = true
+				rows++
+			}
+		}
+		return rows, nil
+	}
+	db.execs["TouchSession"] = func(args []any) (int64, error) {
+		if table.revokedSessions[args[0].(uuid.UUID)] {
+			return 0, nil
+		}
+		return 1, nil
+	}
+	db.queries["ListUserRoles"] = func(args []any) ([][]any, error) {
+		return [][]any{{"buyer"}}, nil
+	}
+	db.queries["ListUserPermissions"] = func(args []any) ([][]any, error) {
+		return [][]any{{"purchase:create"}}, nil
+	}
+
+	return table
+}
+
+func refreshTokenRow(t *database.RefreshTokens) []any {
+	return []any{t.ID, t.UserAuthID, t.UserID, t.FamilyID, t.TokenHash, t.Revoked, t.ExpiresAt, t.CreatedAt}
+}
+
+func (r *refreshTokenTable) token(refreshToken string) *database.RefreshTokens {
+	return r.tokens[hashToken(refreshToken)]
+}
+
+func newTokenTestService(t *testing.T) (*UserService, *refreshTokenTable) {
+	t.Helper()
+
+	keys, err := generateSigningKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := newFakeDB()
+	table := newRefreshTokenTable(db)
+	queries := database.New(db)
+
+	return &UserService{
+		userRepo: repository.NewUserRepository(nil, queries),
+		db:       queries,
+		jwtConfig: &JWTConfig{
+			Keys:            keys,
+			Duration:        15 * time.Minute,
+			RefreshDuration: time.Hour,
+			Issuer:          "tutuplapak-auth",
+		},
+	}, table
+}
+
+// login - A fresh session with its first token pair
+func login(t *testing.T, s *UserService) (string, uuid.UUID) {
+	t.Helper()
+
+	sessionID := uuid.New()
+	tokens, err := s.issueTokens(context.Background(), uuid.New(), uuid.NewString(), sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokens.RefreshToken, sessionID
+}
+
+func TestRefreshTokenRotates(t *testing.T) {
+	s, table := newTokenTestService(t)
+	ctx := context.Background()
+	first, sessionID := login(t, s)
+
+	tokens, err := s.RefreshToken(ctx, first)
+	if err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	if tokens.Token == "" || tokens.RefreshToken == "" || tokens.RefreshToken == first {
+		t.Fatalf("expected a new token pair, got %+v", tokens)
+	}
+
+	if !table.token(first).Revoked {
+		t.Error("rotated refresh token is still usable")
+	}
+	second := table.token(tokens.RefreshToken)
+	if second == nil || second.Revoked || second.FamilyID != sessionID {
+		t.Errorf("new refresh token should be live in family %s, got %+v", sessionID, second)
+	}
+
+	claims, err := s.parseToken(tokens.Token)
+	if err != nil {
+		t.Fatalf("access token rejected: %v", err)
+	}
+	if claims.SessionID != sessionID.String() {
+		t.Errorf("access token session %q, want %q", claims.SessionID, sessionID)
+	}
+
+	if _, err := s.RefreshToken(ctx, tokens.RefreshToken); err != nil {
+		t.Errorf("rotated token failed to refresh: %v", err)
+	}
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	s, table := newTokenTestService(t)
+	ctx := context.Background()
+	first, _ := login(t, s)
+
+	tokens, err := s.RefreshToken(ctx, first)
+	if err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+
+	// An attacker replaying the old token kills the legitimate client's token too
+	if _, err := s.RefreshToken(ctx, first); err == nil || err.Error() != "refresh token has been revoked" {
+		t.Fatalf("reused token: got error %v", err)
+	}
+	if !table.token(tokens.RefreshToken).Revoked {
+		t.Error("family survived refresh token reuse")
+	}
+	if _, err := s.RefreshToken(ctx, tokens.RefreshToken); err == nil {
+		t.Error("token of a revoked family still refreshes")
+	}
+}
+
+func TestRefreshTokenReuseLeavesOtherFamilies(t *testing.T) {
+	s, table := newTokenTestService(t)
+	ctx := context.Background()
+	stolen, _ := login(t, s)
+	other, _ := login(t, s)
+
+	if _, err := s.RefreshToken(ctx, stolen); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.RefreshToken(ctx, stolen); err == nil {
+		t.Fatal("reused token refreshed")
+	}
+
+	if table.token(other).Revoked {
+		t.Error("reuse in one family revoked another")
+	}
+}
+
+func TestRefreshTokenConcurrentUse(t *testing.T) {
+	s, table := newTokenTestService(t)
+	ctx := context.Background()
+	first, _ := login(t, s)
+
+	var wg sync.WaitGroup
+	results := make([]error, 2)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, results[i] = s.RefreshToken(ctx, first)
+		}(i)
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range results {
+		if err == nil {
+			succeeded++
+		}
+	}
+	if succeeded > 1 {
+		t.Fatal("one refresh token rotated twice")
+	}
+	if !table.token(first).Revoked {
+		t.Error("presented refresh token still usable")
+	}
+}
+
+func TestRefreshTokenRejects(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("unknown", func(t *testing.T) {
+		s, _ := newTokenTestService(t)
+		if _, err := s.RefreshToken(ctx, "not-a-token"); err == nil || err.Error() != "invalid refresh token" {
+			t.Errorf("got error %v", err)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		s, _ := newTokenTestService(t)
+		if _, err := s.RefreshToken(ctx, ""); err == nil || err.Error() != "refresh token is required" {
+			t.Errorf("got error %v", err)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		s, table := newTokenTestService(t)
+		token, _ := login(t, s)
+		table.token(token).ExpiresAt = time.Now().Add(-time.Second)
+
+		if _, err := s.RefreshToken(ctx, token); err == nil || err.Error() != "refresh token has expired" {
+			t.Errorf("got error %v", err)
+		}
+	})
+
+	t.Run("revoked session", func(t *testing.T) {
+		s, table := newTokenTestService(t)
+		token, sessionID := login(t, s)
+		table.revokedSessions[sessionID] = true
+
+		if _, err := s.RefreshToken(ctx, token); err == nil || err.Error() != "refresh token has been revoked" {
+			t.Errorf("got error %v", err)
+		}
+		if table.token(token).Revoked {
+			t.Error("refresh of a revoked session rotated the token")
+		}
+	})
+}
+
+func TestRevokeUserAccessTokens(t *testing.T) {
+	s, _ := newTokenTestService(t)
+	redis := redistest.Start(t)
+	s.cache = cache.NewRedisCache(cache.CacheConfig{Addr: redis.Addr()})
+	t.Cleanup(func() { s.cache.Close() })
+	s.jwtConfig.ImpersonationTTL = time.Hour
+	ctx := context.Background()
+
+	userID := uuid.NewString()
+	issuedAt := time.Now().Unix()
+	if err := s.revokeUserAccessTokens(ctx, userID); err != nil {
+		t.Fatal(err)
+	}
+
+	// A token issued in the second of the revocation is indistinguishable from
+	// one issued just before it
+	if !s.isTokenRevoked(ctx, &authz.Claims{UserID: userID, TokenID: "same-second", IssuedAt: issuedAt}) {
+		t.Error("token issued in the revocation's second survived it")
+	}
+	later := time.Unix(issuedAt, 0).Add(time.Second).Unix()
+	if s.isTokenRevoked(ctx, &authz.Claims{UserID: userID, TokenID: "next-second", IssuedAt: later}) {
+		t.Error("token issued after the revocation rejected")
+	}
+
+	// Impersonation tokens may live longer than regular ones
+	if ttl := redis.TTL(fmt.Sprintf(RevokedUserKey, userID)); ttl != time.Hour {
+		t.Errorf("marker lives %v, want the impersonation TTL", ttl)
+	}
+}
@@ -0,0 +1,325 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/teammachinist/tutuplapak/services/auth/internal/logger"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/model"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/totp"
+	"github.com/teammachinist/tutuplapak/services/auth/pkg/authz"
+)
+
+const (
+	LoginChallengeKey         = "login:challenge:%s" // By challenge token hash
+	LoginChallengeAttemptsKey = "login:challenge:attempts:%s"
+)
+
+const (
+	LoginChallengeTTL  = 5 * time.Minute
+	totpSkew           = 1 // Steps accepted either side of now, for clock drift
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10 // Characters, shown split in two halves
+)
+
+var (
+	errInvalidChallenge   = errors.New("invalid or expired challenge")
+	errInvalidTOTPCode    = errors.New("invalid two-factor code")
+	errTOTPAlreadyEnabled = errors.New("two-factor authentication already enabled")
+	errTOTPNotEnabled     = errors.New("two-factor authentication not enabled")
+	errTOTPNotEnrolling   = errors.New("two-factor enrollment not started")
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// startLoginChallenge - The password was right, hand out a short-lived token
+// that CompleteTOTPLogin exchanges for real tokens together with a code
+func (s *UserService) startLoginChallenge(ctx context.Context, userAuth *model.UserAuth, userID, identifier string) (*model.AuthResponse, error) {
+	challengeToken, err := generateRefreshToken()
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+
+	challenge := model.LoginChallenge{
+		UserAuthID: userAuth.ID,
+		UserID:     userID,
+		Identifier: identifier,
+	}
+	if err := s.cache.Set(ctx, fmt.Sprintf(LoginChallengeKey, hashToken(challengeToken)), challenge, LoginChallengeTTL); err != nil {
+		logger.ErrorCtx(ctx, "Failed to store login challenge", "user_auth_id", userAuth.ID.String(), "error", err.Error())
+		return nil, errors.New("failed to generate token")
+	}
+
+	logger.InfoCtx(ctx, "Two-factor challenge issued", "user_auth_id", userAuth.ID.String())
+	return &model.AuthResponse{
+		Email:          userAuth.Email,
+		Phone:          userAuth.Phone,
+		Status:         model.LoginTOTPRequired,
+		ChallengeToken: challengeToken,
+	}, nil
+}
+
+// CompleteTOTPLogin - Second login step: redeem the challenge with a TOTP or
+// recovery code. A challenge allows a few wrong codes before it is burned, and
+// each wrong code counts towards the account's login lockout.
+func (s *UserService) CompleteTOTPLogin(ctx context.Context, challengeToken, code string, client model.ClientInfo) (*model.AuthResponse, error) {
+	challengeKey := fmt.Sprintf(LoginChallengeKey, hashToken(challengeToken))
+
+	var challenge model.LoginChallenge
+	if err := s.cache.Get(ctx, challengeKey, &challenge); err != nil {
+		return nil, errInvalidChallenge
+	}
+
+	if err := s.checkLoginAllowed(ctx, challenge.Identifier, client.IP); err != nil {
+		return nil, err
+	}
+
+	factor, err := s.userRepo.GetTOTPFactor(ctx, challenge.UserAuthID)
+	if err != nil {
+		return nil, err
+	}
+	// Disabled since the password step, nothing left to check against
+	if factor == nil || !factor.Enabled {
+		s.cache.Delete(ctx, challengeKey)
+		return nil, errInvalidChallenge
+	}
+
+	ok, err := s.verifySecondFactor(ctx, factor, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		s.recordLoginFailure(ctx, challenge.Identifier, client.IP)
+
+		attemptsKey := fmt.Sprintf(LoginChallengeAttemptsKey, hashToken(challengeToken))
+		attempts, err := s.cache.Increment(ctx, attemptsKey, LoginChallengeTTL)
+		if err != nil || attempts >= MaxVerificationAttempts {
+			logger.WarnCtx(ctx, "Login challenge burned after too many attempts", "user_auth_id", challenge.UserAuthID.String())
+			s.cache.Delete(ctx, challengeKey)
+		}
+		return nil, errInvalidTOTPCode
+	}
+
+	s.cache.Delete(ctx, challengeKey)
+	s.resetLoginFailures(ctx, challenge.Identifier)
+
+	userAuth, err := s.userRepo.GetUserAuthByID(ctx, challenge.UserAuthID)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.startSession(ctx, userAuth.ID, challenge.UserID, client)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+
+	return &model.AuthResponse{
+		Email:        userAuth.Email,
+		Phone:        userAuth.Phone,
+		Token:        tokens.Token,
+		RefreshToken: tokens.RefreshToken,
+	}, nil
+}
+
+// TOTPStatus - Whether TOTP is on and how many recovery codes are left
+func (s *UserService) TOTPStatus(ctx context.Context, claims *authz.Claims) (*model.TOTPStatusResponse, error) {
+	userAuth, err := s.accountFromClaims(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	factor, err := s.userRepo.GetTOTPFactor(ctx, userAuth.ID)
+	if err != nil {
+		return nil, err
+	}
+	if factor == nil || !factor.Enabled {
+		return &model.TOTPStatusResponse{}, nil
+	}
+
+	remaining, err := s.userRepo.CountRecoveryCodes(ctx, userAuth.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.TOTPStatusResponse{
+		Enabled:                true,
+		RecoveryCodesRemaining: remaining,
+	}, nil
+}
+
+// EnrollTOTP - Generate a secret for the user's authenticator app. It takes
+// effect once ConfirmTOTP sees a code from it; enrolling again before that
+// replaces the secret.
+func (s *UserService) EnrollTOTP(ctx context.Context, claims *authz.Claims) (*model.TOTPEnrollResponse, error) {
+	userAuth, err := s.accountFromClaims(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	started, err := s.userRepo.StartTOTPEnrollment(ctx, userAuth.ID, secret)
+	if err != nil {
+		return nil, err
+	}
+	if !started {
+		return nil, errTOTPAlreadyEnabled
+	}
+
+	account := userAuth.Email
+	if account == "" {
+		account = userAuth.Phone
+	}
+
+	logger.InfoCtx(ctx, "TOTP enrollment started", "user_auth_id", userAuth.ID.String())
+	return &model.TOTPEnrollResponse{
+		Secret: secret,
+		URI:    totp.ProvisioningURI(s.totpIssuer, account, secret),
+	}, nil
+}
+
+// ConfirmTOTP - Enable TOTP with the first code from the app, proving it was set
+// up correctly. Returns the recovery codes, the only time they are shown.
+func (s *UserService) ConfirmTOTP(ctx context.Context, claims *authz.Claims, code string) (*model.RecoveryCodesResponse, error) {
+	userAuth, err := s.accountFromClaims(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	factor, err := s.userRepo.GetTOTPFactor(ctx, userAuth.ID)
+	if err != nil {
+		return nil, err
+	}
+	if factor == nil {
+		return nil, errTOTPNotEnrolling
+	}
+	if factor.Enabled {
+		return nil, errTOTPAlreadyEnabled
+	}
+
+	step, ok := totp.Validate(factor.Secret, normalizeCode(code), time.Now(), totpSkew)
+	if !ok {
+		return nil, errInvalidTOTPCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	enabled, err := s.userRepo.EnableTOTP(ctx, userAuth.ID, step, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		// Lost the race against a concurrent confirm or disable
+		return nil, errTOTPNotEnrolling
+	}
+
+	logger.InfoCtx(ctx, "TOTP enabled", "user_auth_id", userAuth.ID.String())
+	return &model.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableTOTP - Turn TOTP off, which takes a current TOTP or recovery code
+func (s *UserService) DisableTOTP(ctx context.Context, claims *authz.Claims, code string) error {
+	userAuth, err := s.accountFromClaims(ctx, claims)
+	if err != nil {
+		return err
+	}
+
+	factor, err := s.userRepo.GetTOTPFactor(ctx, userAuth.ID)
+	if err != nil {
+		return err
+	}
+	if factor == nil || !factor.Enabled {
+		return errTOTPNotEnabled
+	}
+
+	ok, err := s.verifySecondFactor(ctx, factor, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errInvalidTOTPCode
+	}
+
+	if err := s.userRepo.DisableTOTP(ctx, userAuth.ID); err != nil {
+		return err
+	}
+
+	logger.InfoCtx(ctx, "TOTP disabled", "user_auth_id", userAuth.ID.String())
+	return nil
+}
+
+// verifySecondFactor - Accept a TOTP code (each time step only once) or an
+// unused recovery code
+func (s *UserService) verifySecondFactor(ctx context.Context, factor *model.TOTPFactor, code string) (bool, error) {
+	code = normalizeCode(code)
+
+	if len(code) == totp.Digits {
+		step, ok := totp.Validate(factor.Secret, code, time.Now(), totpSkew)
+		if !ok {
+			return false, nil
+		}
+		return s.userRepo.UseTOTPStep(ctx, factor.UserAuthID, step)
+	}
+
+	used, err := s.userRepo.UseRecoveryCode(ctx, factor.UserAuthID, hashToken(code))
+	if err != nil {
+		return false, err
+	}
+	if used {
+		logger.InfoCtx(ctx, "Recovery code used", "user_auth_id", factor.UserAuthID.String())
+	}
+	return used, nil
+}
+
+// accountFromClaims - The account behind an access token
+func (s *UserService) accountFromClaims(ctx context.Context, claims *authz.Claims) (*model.UserAuth, error) {
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, errors.New("invalid token")
+	}
+
+	userAuth, err := s.userRepo.GetUserAuthByUserID(ctx, userID)
+	if err != nil {
+		return nil, errors.New("invalid token")
+	}
+	return userAuth, nil
+}
+
+// normalizeCode - Codes are typed by hand, ignore spacing, dashes and case
+func normalizeCode(code string) string {
+	code = strings.ReplaceAll(code, " ", "")
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ToLower(code)
+}
+
+// generateRecoveryCodes - Codes as shown to the user and the hashes to store
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, recoveryCodeLength*5/8+1)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))[:recoveryCodeLength]
+		codes = append(codes, code[:recoveryCodeLength/2]+"-"+code[recoveryCodeLength/2:])
+		hashes = append(hashes, hashToken(code))
+	}
+
+	return codes, hashes, nil
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/teammachinist/tutuplapak/services/auth/internal/database"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/model"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/repository"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/totp"
+)
+
+// newSecondFactorTestService - A service over one enabled TOTP factor and its
+// recovery codes, returning the codes as shown to the user
+func newSecondFactorTestService(t *testing.T) (*UserService, *model.TOTPFactor, []string) {
+	t.Helper()
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	factor := &model.TOTPFactor{UserAuthID: uuid.New(), Secret: secret, Enabled: true}
+	unused := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		unused[hash] = true
+	}
+
+	db := newFakeDB()
+	db.execs["UseTOTPStep"] = func(args []any) (int64, error) {
+		if args[0].(uuid.UUID) != factor.UserAuthID || args[1].(int64) <= factor.LastUsedStep {
+			return 0, nil
+		}
+		factor.LastUsedStep = args[1].(int64)
+		return 1, nil
+	}
+	db.execs["UseRecoveryCode"] = func(args []any) (int64, error) {
+		if args[0].(uuid.UUID) != factor.UserAuthID || !unused[args[1].(string)] {
+			return 0, nil
+		}
+		delete(unused, args[1].(string))
+		return 1, nil
+	}
+
+	return &UserService{userRepo: repository.NewUserRepository(nil, database.New(db))}, factor, codes
+}
+
+func TestSecondFactorTOTPStepUsedOnce(t *testing.T) {
+	s, factor, _ := newSecondFactorTestService(t)
+	ctx := context.Background()
+	code, _ := totp.Code(factor.Secret, totp.Step(time.Now()))
+
+	if ok, err := s.verifySecondFactor(ctx, factor, code); err != nil || !ok {
+		t.Fatalf("current code rejected: %v", err)
+	}
+	if ok, _ := s.verifySecondFactor(ctx, factor, code); ok {
+		t.Error("same code accepted twice")
+	}
+
+	// An older step still inside the window was already passed by the used one
+	previous, _ := totp.Code(factor.Secret, totp.Step(time.Now())-1)
+	if ok, _ := s.verifySecondFactor(ctx, factor, previous); ok {
+		t.Error("code of an earlier step accepted after a later one")
+	}
+}
+
+func TestSecondFactorTOTPWindow(t *testing.T) {
+	now := totp.Step(time.Now())
+
+	for _, offset := range []int64{-1, 1} {
+		s, factor, _ := newSecondFactorTestService(t)
+		code, _ := totp.Code(factor.Secret, now+offset)
+		if ok, _ := s.verifySecondFactor(context.Background(), factor, code); !ok {
+			t.Errorf("code %d step(s) off rejected", offset)
+		}
+	}
+
+	for _, offset := range []int64{-2, 2} {
+		s, factor, _ := newSecondFactorTestService(t)
+		code, _ := totp.Code(factor.Secret, now+offset)
+		if ok, _ := s.verifySecondFactor(context.Background(), factor, code); ok {
+			t.Errorf("code %d steps off accepted", offset)
+		}
+	}
+}
+
+func TestSecondFactorRecoveryCodes(t *testing.T) {
+	s, factor, codes := newSecondFactorTestService(t)
+	ctx := context.Background()
+
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("%d recovery codes, want %d", len(codes), recoveryCodeCount)
+	}
+
+	// Typed by hand: upper case, spaces, without the dash
+	typed := " " + strings.ToUpper(strings.ReplaceAll(codes[0], "-", " ")) + " "
+	if ok, err := s.verifySecondFactor(ctx, factor, typed); err != nil || !ok {
+		t.Fatalf("recovery code %q rejected: %v", typed, err)
+	}
+	if ok, _ := s.verifySecondFactor(ctx, factor, codes[0]); ok {
+		t.Error("recovery code accepted twice")
+	}
+
+	if ok, _ := s.verifySecondFactor(ctx, factor, codes[1]); !ok {
+		t.Error("second recovery code rejected after the first was used")
+	}
+	if ok, _ := s.verifySecondFactor(ctx, factor, "aaaaa-bbbbb"); ok {
+		t.Error("made up recovery code accepted")
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[string]bool)
+	for i, code := range codes {
+		if len(code) != recoveryCodeLength+1 || code[recoveryCodeLength/2] != '-' {
+			t.Errorf("code %q not shown as two halves", code)
+		}
+		if hashes[i] != hashToken(normalizeCode(code)) {
+			t.Errorf("hash of %q doesn't match what verification looks up", code)
+		}
+		if len(normalizeCode(code)) == totp.Digits {
+			t.Errorf("code %q could be mistaken for a TOTP code", code)
+		}
+		if seen[code] {
+			t.Errorf("code %q repeated", code)
+		}
+		seen[code] = true
+	}
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/teammachinist/tutuplapak/services/auth/internal/logger"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/model"
+)
+
+const (
+	MaxVerificationAttempts = 5
+	verificationCodeDigits  = 6
+
+	// Per account and purpose over a day, however many codes they are spread
+	// over; a fresh code must not mean five fresh guesses forever
+	MaxDailyVerificationSends    = 10
+	MaxDailyVerificationAttempts = 20
+
+	VerificationSendsKey    = "verification:sends:%s:%s"    // verification:sends:{userAuthID}:{purpose}
+	VerificationAttemptsKey = "verification:attempts:%s:%s" // verification:attempts:{userAuthID}:{purpose}
+)
+
+const verificationLimitWindow = 24 * time.Hour
+
+var (
+	errAccountNotFound       = errors.New("account not found")
+	errVerificationSendLimit = errors.New("too many verification codes requested, try again later")
+)
+
+// findUserAuth - Look up the account by exactly one of email or phone
+func (s *UserService) findUserAuth(ctx context.Context, email, phone string) (*model.UserAuth, error) {
+	switch {
+	case email != "" && phone != "":
+		return nil, errors.New("provide either email or phone, not both")
+	case email != "":
+		if err := s.validateEmail(email); err != nil {
+			return nil, err
+		}
+		userAuth, err := s.userRepo.GetUserAuthByEmail(ctx, email)
+		if err != nil {
+			return nil, errAccountNotFound
+		}
+		return userAuth, nil
+	case phone != "":
+		if err := s.validatePhone(phone); err != nil {
+			return nil, err
+		}
+		userAuth, err := s.userRepo.GetUserByPhone(ctx, phone)
+		if err != nil {
+			return nil, errAccountNotFound
+		}
+		return userAuth, nil
+	default:
+		return nil, errors.New("email or phone is required")
+	}
+}
+
+// sendVerificationCode - Deliver a fresh code over the account's registration channel
+func (s *UserService) sendVerificationCode(ctx context.Context, userAuth *model.UserAuth, purpose string, ttl time.Duration, subject, bodyFormat string) error {
+	useSMS, target := deliveryTarget(userAuth)
+	_, err := s.sendVerificationCodeTo(ctx, userAuth.ID, purpose, useSMS, target, ttl, subject, bodyFormat)
+	return err
+}
+
+// deliveryTarget - Where the account is messaged: its registration channel, or
+// whichever one it has
+func deliveryTarget(userAuth *model.UserAuth) (useSMS bool, target string) {
+	useSMS = userAuth.RegisteredVia == model.RegisteredViaPhone && userAuth.Phone != ""
+	if useSMS || userAuth.Email == "" {
+		return true, userAuth.Phone
+	}
+	return false, userAuth.Email
+}
+
+// sendVerificationCodeTo - Issue a fresh code for purpose (replacing any outstanding
+// one) and deliver it to target by SMS or email
+func (s *UserService) sendVerificationCodeTo(ctx context.Context, userAuthID uuid.UUID, purpose string, useSMS bool, target string, ttl time.Duration, subject, bodyFormat string) (*model.VerificationCode, error) {
+	// Fails open when Redis is unavailable, like the login throttle
+	sends, err := s.cache.Increment(ctx, fmt.Sprintf(VerificationSendsKey, userAuthID.String(), purpose), verificationLimitWindow)
+	if err == nil && sends > MaxDailyVerificationSends {
+		logger.WarnCtx(ctx, "Verification code send limit reached", "user_auth_id", userAuthID.String(), "purpose", purpose)
+		return nil, errVerificationSendLimit
+	}
+
+	code, err := generateVerificationCode()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.InvalidateVerificationCodes(ctx, userAuthID, purpose); err != nil {
+		return nil, err
+	}
+
+	stored, err := s.userRepo.CreateVerificationCode(ctx, userAuthID, purpose, target, hashToken(code), time.Now().Add(ttl))
+	if err != nil {
+		return nil, fmt.Errorf("failed to store verification code: %w", err)
+	}
+
+	body := fmt.Sprintf(bodyFormat, code)
+	if useSMS {
+		err = s.notifier.SendSMS(ctx, target, body)
+	} else {
+		err = s.notifier.SendEmail(ctx, target, subject, body)
+	}
+	if err != nil {
+		logger.ErrorCtx(ctx, "Failed to deliver verification code", "user_auth_id", userAuthID.String(), "purpose", purpose, "error", err.Error())
+		return nil, errors.New("failed to send verification code")
+	}
+
+	return stored, nil
+}
+
+// redeemVerificationCode - Check and consume the active code for purpose. Each code
+// allows a few wrong guesses before it is burned, and the account a few more a
+// day across all its codes, after which even the right code is refused.
+func (s *UserService) redeemVerificationCode(ctx context.Context, userAuthID uuid.UUID, purpose, code string) (*model.VerificationCode, error) {
+	attemptsKey := fmt.Sprintf(VerificationAttemptsKey, userAuthID.String(), purpose)
+	var dailyAttempts int64
+	if err := s.cache.Get(ctx, attemptsKey, &dailyAttempts); err == nil && dailyAttempts >= MaxDailyVerificationAttempts {
+		logger.WarnCtx(ctx, "Verification attempt limit reached", "user_auth_id", userAuthID.String(), "purpose", purpose)
+		return nil, errors.New("invalid or expired code")
+	}
+
+	stored, err := s.userRepo.GetActiveVerificationCode(ctx, userAuthID, purpose)
+	if err != nil {
+		return nil, errors.New("invalid or expired code")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashToken(code)), []byte(stored.CodeHash)) != 1 {
+		s.cache.Increment(ctx, attemptsKey, verificationLimitWindow)
+		attempts, err := s.userRepo.IncrementVerificationCodeAttempts(ctx, stored.ID)
+		if err == nil && attempts >= MaxVerificationAttempts {
+			logger.WarnCtx(ctx, "Verification code burned after too many attempts", "user_auth_id", userAuthID.String(), "purpose", purpose)
+			s.userRepo.ConsumeVerificationCode(ctx, stored.ID)
+		}
+		return nil, errors.New("invalid or expired code")
+	}
+
+	// Lost the race against a concurrent redeem of the same code
+	consumed, err := s.userRepo.ConsumeVerificationCode(ctx, stored.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, errors.New("invalid or expired code")
+	}
+
+	return stored, nil
+}
+
+func generateVerificationCode() (string, error) {
+	limit := big.NewInt(1)
+	for i := 0; i < verificationCodeDigits; i++ {
+		limit.Mul(limit, big.NewInt(10))
+	}
+
+	n, err := rand.Int(rand.Reader, limit)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate verification code: %w", err)
+	}
+	return fmt.Sprintf("%0*d", verificationCodeDigits, n), nil
+}
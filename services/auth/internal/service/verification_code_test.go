@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/teammachinist/tutuplapak/services/auth/internal/cache"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/database"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/repository"
+	"github.com/teammachinist/tutuplapak/services/auth/pkg/redistest"
+)
+
+// verificationCodeTable - the verification_codes rows of one account
+type verificationCodeTable struct {
+	codes []*database.VerificationCodes
+}
+
+func newVerificationCodeTable(db *fakeDB) *verificationCodeTable {
+	table := &verificationCodeTable{}
+
+	db.execs["InvalidateVerificationCodes"] = func(args []any) (int64, error) {
+		var rows int64
+		for _, code := range table.codes {
+			if code.Purpose == args[1].(string) && !code.Used {
+				code.Used = true
+				rows++
+			}
+		}
+		return rows, nil
+	}
+	db.queries["CreateVerificationCode"] = func(args []any) ([][]any, error) {
+		code := &database.VerificationCodes{
+			ID:         uuid.New(),
+			UserAuthID: args[0].(uuid.UUID),
+			Purpose:    args[1].(string),
+			Target:     args[2].(string),
+			CodeHash:   args[3].(string),
+			ExpiresAt:  args[4].(time.Time),
+			CreatedAt:  time.Now(),
+		}
+		table.codes = append(table.codes, code)
+		return [][]any{verificationCodeRow(code)}, nil
+	}
+	db.queries["GetActiveVerificationCode"] = func(args []any) ([][]any, error) {
+		for i := len(table.codes) - 1; i >= 0; i-- {
+			code := table.codes[i]
+			if code.Purpose == args[1].(string) && !code.Used && code.ExpiresAt.After(time.Now()) {
+				return [][]any{verificationCodeRow(code)}, nil
+			}
+		}
+		return nil, nil
+	}
+	db.queries["IncrementVerificationCodeAttempts"] = func(args []any) ([][]any, error) {
+		for _, code := range table.codes {
+			if code.ID == args[0].(uuid.UUID) {
+				code.Attempts++
+				return [][]any{{code.Attempts}}, nil
+			}
+		}
+		return nil, nil
+	}
+	db.execs["ConsumeVerificationCode"] = func(args []any) (int64, error) {
+		for _, code := range table.codes {
+			if code.ID == args[0].(uuid.UUID) && !code.Used {
+				code.Used = true
+				return 1, nil
+			}
+		}
+		return 0, nil
+	}
+
+	return table
+}
+
+func verificationCodeRow(c *database.VerificationCodes) []any {
+	return []any{c.ID, c.UserAuthID, c.Purpose, c.Target, c.CodeHash, c.Attempts, c.Used, c.ExpiresAt, c.CreatedAt}
+}
+
+func newVerificationTestService(t *testing.T) (*UserService, *outbox, *redistest.Server, uuid.UUID) {
+	t.Helper()
+
+	redis := redistest.Start(t)
+	redisCache := cache.NewRedisCache(cache.CacheConfig{Addr: redis.Addr()})
+	t.Cleanup(func() { redisCache.Close() })
+
+	account := uuid.New()
+	db := newFakeDB()
+	db.queries["GetUserAuthByEmail"] = func(args []any) ([][]any, error) {
+		if args[0].(string) != "alice@example.com" {
+			return nil, nil
+		}
+		return [][]any{{account, "alice@example.com", "", "", time.Now(), "email", (*uuid.UUID)(nil)}}, nil
+	}
+	newVerificationCodeTable(db)
+
+	box := &outbox{}
+	return &UserService{
+		userRepo: repository.NewUserRepository(nil, database.New(db)),
+		cache:    redisCache,
+		notifier: box,
+		throttle: testThrottle,
+	}, box, redis, account
+}
+
+// forgotPassword - Request a reset code, "" when none was sent
+func forgotPassword(t *testing.T, s *UserService, box *outbox) string {
+	t.Helper()
+
+	box.last = ""
+	if err := s.ForgotPassword(context.Background(), "alice@example.com", ""); err != nil {
+		t.Fatal(err)
+	}
+	code := regexp.MustCompile(`code is (\d+)\.`).FindStringSubmatch(box.last)
+	if code == nil {
+		return ""
+	}
+	return code[1]
+}
+
+func TestVerificationCodeDailySendLimit(t *testing.T) {
+	s, box, redis, _ := newVerificationTestService(t)
+
+	for i := range MaxDailyVerificationSends {
+		if forgotPassword(t, s, box) == "" {
+			t.Fatalf("no code sent on request %d", i+1)
+		}
+		redis.FastForward(PasswordResetCooldown)
+	}
+
+	// Answered the same as before, nothing is sent
+	if forgotPassword(t, s, box) != "" {
+		t.Fatal("code sent past the daily limit")
+	}
+
+	redis.FastForward(verificationLimitWindow)
+	if forgotPassword(t, s, box) == "" {
+		t.Error("no code sent the next day")
+	}
+}
+
+func TestVerificationCodeDailyAttemptLimit(t *testing.T) {
+	s, box, redis, account := newVerificationTestService(t)
+	ctx := context.Background()
+
+	// Each fresh code only gives MaxVerificationAttempts more guesses until the
+	// day's are used up
+	for guesses := 0; guesses < MaxDailyVerificationAttempts; guesses++ {
+		if guesses%MaxVerificationAttempts == 0 {
+			redis.FastForward(PasswordResetCooldown)
+			forgotPassword(t, s, box)
+		}
+		if _, err := s.redeemVerificationCode(ctx, account, PurposePasswordReset, "wrong"); err == nil {
+			t.Fatal("wrong code redeemed")
+		}
+	}
+
+	redis.FastForward(PasswordResetCooldown)
+	code := forgotPassword(t, s, box)
+	if _, err := s.redeemVerificationCode(ctx, account, PurposePasswordReset, code); err == nil {
+		t.Error("code redeemed past the daily attempt limit")
+	}
+
+	redis.FastForward(verificationLimitWindow)
+	code = forgotPassword(t, s, box)
+	if _, err := s.redeemVerificationCode(ctx, account, PurposePasswordReset, code); err != nil {
+		t.Errorf("code refused the next day: %v", err)
+	}
+}
+
+func TestResetPasswordThrottled(t *testing.T) {
+	s, box, _, _ := newVerificationTestService(t)
+	ctx := context.Background()
+	forgotPassword(t, s, box)
+
+	for range testThrottle.MaxAttempts {
+		if err := s.ResetPassword(ctx, "alice@example.com", "", "000000x", "N3w-passw0rd!", "10.0.0.1"); err == nil {
+			t.Fatal("wrong code accepted")
+		}
+	}
+
+	var locked *LoginLockedError
+	err := s.ResetPassword(ctx, "alice@example.com", "", "000000x", "N3w-passw0rd!", "10.0.0.1")
+	if !errors.As(err, &locked) {
+		t.Errorf("got error %v, want the identifier locked out", err)
+	}
+
+	// Guessing across accounts from one IP runs into the IP's limit
+	for i := range testThrottle.MaxIPAttempts {
+		s.ResetPassword(ctx, "nobody"+string(rune('a'+i))+"@example.com", "", "000000", "N3w-passw0rd!", "10.0.0.2")
+	}
+	err = s.ResetPassword(ctx, "bob@example.com", "", "000000", "N3w-passw0rd!", "10.0.0.2")
+	if !errors.As(err, &locked) {
+		t.Errorf("got error %v, want the IP locked out", err)
+	}
+}
@@ -0,0 +1,114 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The SHA1 seed of RFC 6238 appendix B
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCodeMatchesRFC6238(t *testing.T) {
+	// Appendix B lists 8 digits, apps show the last 6
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, v := range vectors {
+		code, err := Code(rfcSecret, Step(time.Unix(v.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != v.code {
+			t.Errorf("T=%d: code %s, want %s", v.unix, code, v.code)
+		}
+	}
+}
+
+func TestCodeAcceptsLowercaseSecret(t *testing.T) {
+	upper, _ := Code(rfcSecret, 1)
+	lower, err := Code(strings.ToLower(rfcSecret), 1)
+	if err != nil || lower != upper {
+		t.Errorf("lowercase secret gave %q, %v", lower, err)
+	}
+
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("invalid secret accepted")
+	}
+}
+
+func TestValidateWindow(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	current := Step(now)
+
+	for offset := int64(-3); offset <= 3; offset++ {
+		code, err := Code(secret, current+offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		step, ok := Validate(secret, code, now, 1)
+		inWindow := offset >= -1 && offset <= 1
+		if ok != inWindow {
+			t.Errorf("code %d steps from now: accepted %v, want %v", offset, ok, inWindow)
+		}
+		if ok && step != current+offset {
+			t.Errorf("code %d steps from now matched step %d, want %d", offset, step, current+offset)
+		}
+	}
+}
+
+func TestValidateNoSkew(t *testing.T) {
+	secret, _ := GenerateSecret()
+	now := time.Now()
+
+	previous, _ := Code(secret, Step(now)-1)
+	if _, ok := Validate(secret, previous, now, 0); ok {
+		t.Error("previous step accepted without skew")
+	}
+	current, _ := Code(secret, Step(now))
+	if _, ok := Validate(secret, current, now, 0); !ok {
+		t.Error("current step rejected")
+	}
+}
+
+func TestValidateRejectsMalformed(t *testing.T) {
+	secret, _ := GenerateSecret()
+	code, _ := Code(secret, Step(time.Now()))
+
+	for _, input := range []string{"", code[:5], code + "0", "abcdef"} {
+		if _, ok := Validate(secret, input, time.Now(), 1); ok {
+			t.Errorf("%q accepted", input)
+		}
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri, err := url.Parse(ProvisioningURI("TutupLapak", "alice@example.com", rfcSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/TutupLapak:alice@example.com" {
+		t.Errorf("unexpected uri %s", uri)
+	}
+	query := uri.Query()
+	if query.Get("secret") != rfcSecret || query.Get("issuer") != "TutupLapak" || query.Get("digits") != "6" || query.Get("period") != "30" {
+		t.Errorf("unexpected parameters %v", query)
+	}
+}
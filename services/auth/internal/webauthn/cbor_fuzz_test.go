@@ -0,0 +1,121 @@
+package webauthn
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+// COSE_Keys as authenticators encode them, on the curve's base point
+var (
+	seedES256Key = mustHex("a5010203262001215820" +
+		"6b17d1f2e12c4247f8bce6e563a440f277037d812deb33a0f4a13945d898c296" + "225820" +
+		"4fe342e2fe1a7f9b8ee7eb4a7c0f9e162bce33576b315ececbb6406837bf51f5")
+	seedEd25519Key = mustHex("a4010103272006215820" +
+		"d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a")
+)
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func FuzzDecodeCBOR(f *testing.F) {
+	for _, seed := range [][]byte{
+		seedES256Key,
+		seedEd25519Key,
+		{0xa3, 0x63, 'f', 'm', 't', 0x64, 'n', 'o', 'n', 'e', 0x67, 'a', 't', 't', 'S', 't', 'm', 't', 0xa0, 0x68, 'a', 'u', 't', 'h', 'D', 'a', 't', 'a', 0x41, 0x00},
+		{0x83, 0x01, 0x82, 0x02, 0x03, 0x82, 0xf4, 0xf5},       // [1, [2, 3], [false, true]]
+		{0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, // 2^64-1
+		{0x3b, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, // -2^63
+		{0x5a, 0xff, 0xff, 0xff, 0xff},                         // Byte string longer than the data
+		{0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, // Array longer than the data
+		{0xa1, 0x81, 0x01, 0x01},                               // Array as map key
+		{0x9f, 0x01, 0xff},                                     // Indefinite length
+		bytes.Repeat([]byte{0x81}, maxCBORDepth+2),             // Nested too deeply
+		{0xc0, 0x01}, // Tag
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		value, rest, err := decodeCBOR(data)
+		if err != nil {
+			return
+		}
+		if len(rest) > len(data) || !bytes.Equal(rest, data[len(data)-len(rest):]) {
+			t.Fatalf("rest %x is not what follows the item in %x", rest, data)
+		}
+
+		// The item alone decodes to the same value, with nothing left over
+		item := data[:len(data)-len(rest)]
+		again, left, err := decodeCBOR(item)
+		if err != nil || len(left) != 0 {
+			t.Fatalf("item %x decoded once, then failed: %v, %d bytes left", item, err, len(left))
+		}
+		if !reflect.DeepEqual(value, again) {
+			t.Fatalf("item %x decoded to %#v, then %#v", item, value, again)
+		}
+	})
+}
+
+func FuzzParsePublicKey(f *testing.F) {
+	f.Add(seedES256Key, []byte("data"), []byte{0x30, 0x06, 0x02, 0x01, 0x01, 0x02, 0x01, 0x01})
+	f.Add(seedEd25519Key, []byte("data"), make([]byte, 64))
+	f.Add([]byte{0xa2, 0x01, 0x03, 0x39, 0x01, 0x00}, []byte{}, []byte{}) // RSA without a modulus
+
+	f.Fuzz(func(t *testing.T, coseKey, data, sig []byte) {
+		verify, err := parsePublicKey(coseKey)
+		if err != nil {
+			if verify != nil {
+				t.Fatal("verifier returned with an error")
+			}
+			return
+		}
+		// Whatever the key, checking garbage must not panic or pass by accident
+		// of an empty signature
+		if verify(data, nil) {
+			t.Fatalf("empty signature accepted by key %x", coseKey)
+		}
+		verify(data, sig)
+	})
+}
+
+func FuzzParseAuthenticatorData(f *testing.F) {
+	header := make([]byte, 37)
+	f.Add(header)
+	attested := append(append([]byte{}, header...), make([]byte, 16)...)
+	attested[32] = flagUserPresent | flagUserVerified | flagAttestedCredData
+	attested = append(attested, 0x00, 0x04, 1, 2, 3, 4)
+	f.Add(append(bytes.Clone(attested), seedES256Key...))
+	withExtensions := append(bytes.Clone(attested), seedEd25519Key...)
+	withExtensions[32] |= flagExtensionData
+	f.Add(append(withExtensions, 0xa1, 0x63, 'c', 'r', 'p', 0xf5))
+	f.Add(append(bytes.Clone(attested[:37+16]), 0xff, 0xff)) // Credential id longer than the data
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		authData, err := parseAuthenticatorData(data)
+		if err != nil {
+			return
+		}
+		if !bytes.Equal(authData.rpIDHash, data[:32]) || authData.flags != data[32] {
+			t.Fatalf("header misread from %x", data)
+		}
+		if authData.flags&flagAttestedCredData == 0 {
+			if authData.credentialID != nil || authData.publicKey != nil {
+				t.Fatalf("credential read without the attested data flag from %x", data)
+			}
+			return
+		}
+		if len(authData.credentialID) == 0 || len(authData.credentialID) > maxCredentialIDLen {
+			t.Fatalf("credential id of %d bytes accepted", len(authData.credentialID))
+		}
+		if _, rest, err := decodeCBOR(authData.publicKey); err != nil || len(rest) != 0 {
+			t.Fatalf("public key %x is not exactly one CBOR item", authData.publicKey)
+		}
+	})
+}
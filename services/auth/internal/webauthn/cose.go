@@ -0,0 +1,120 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// COSE (RFC 9052/9053) key parameters
+const (
+	coseKeyType     = 1
+	coseKeyAlg      = 3
+	coseCurve       = -1 // EC2 and OKP
+	coseX           = -2 // EC2 and OKP
+	coseY           = -3 // EC2
+	coseRSAModulus  = -1
+	coseRSAExponent = -2
+
+	coseKeyTypeOKP = 1
+	coseKeyTypeEC2 = 2
+	coseKeyTypeRSA = 3
+
+	coseCurveP256    = 1
+	coseCurveEd25519 = 6
+)
+
+// Algorithms offered at registration, most preferred first
+const (
+	AlgES256 = -7
+	AlgEdDSA = -8
+	AlgRS256 = -257
+)
+
+var (
+	errUnsupportedKey   = errors.New("unsupported public key")
+	errInvalidSignature = errors.New("invalid signature")
+)
+
+// verifier - checks a signature over data
+type verifier func(data, sig []byte) bool
+
+// parsePublicKey - A verifier for a COSE_Key, failing for keys and algorithms
+// that aren't supported
+func parsePublicKey(coseKey []byte) (verifier, error) {
+	decoded, _, err := decodeCBOR(coseKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	params, ok := decoded.(map[any]any)
+	if !ok {
+		return nil, errUnsupportedKey
+	}
+
+	keyType, _ := params[int64(coseKeyType)].(int64)
+	alg, _ := params[int64(coseKeyAlg)].(int64)
+
+	switch {
+	case keyType == coseKeyTypeEC2 && alg == AlgES256:
+		curve, _ := params[int64(coseCurve)].(int64)
+		x, _ := params[int64(coseX)].([]byte)
+		y, _ := params[int64(coseY)].([]byte)
+		if curve != coseCurveP256 || len(x) != 32 || len(y) != 32 {
+			return nil, errUnsupportedKey
+		}
+
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, errUnsupportedKey
+		}
+		return func(data, sig []byte) bool {
+			digest := sha256.Sum256(data)
+			return ecdsa.VerifyASN1(key, digest[:], sig)
+		}, nil
+
+	case keyType == coseKeyTypeOKP && alg == AlgEdDSA:
+		curve, _ := params[int64(coseCurve)].(int64)
+		x, _ := params[int64(coseX)].([]byte)
+		if curve != coseCurveEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, errUnsupportedKey
+		}
+		return func(data, sig []byte) bool {
+			return ed25519.Verify(ed25519.PublicKey(x), data, sig)
+		}, nil
+
+	case keyType == coseKeyTypeRSA && alg == AlgRS256:
+		n, _ := params[int64(coseRSAModulus)].([]byte)
+		e, _ := params[int64(coseRSAExponent)].([]byte)
+		// 2048 bit minimum
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, errUnsupportedKey
+		}
+
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		return func(data, sig []byte) bool {
+			digest := sha256.Sum256(data)
+			return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) == nil
+		}, nil
+
+	default:
+		return nil, errUnsupportedKey
+	}
+}
+
+// verifySignature - Check sig over data with a COSE_Key as stored at registration
+func verifySignature(coseKey, data, sig []byte) error {
+	verify, err := parsePublicKey(coseKey)
+	if err != nil {
+		return err
+	}
+	if !verify(data, sig) {
+		return errInvalidSignature
+	}
+	return nil
+}
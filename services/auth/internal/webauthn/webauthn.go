@@ -0,0 +1,407 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Relying party side of the WebAuthn Level 2 registration and authentication
+// ceremonies. Binary fields travel as base64url, the form browsers produce with
+// PublicKeyCredential.toJSON(). Attestation is requested as "none" and never
+// relied upon: a passkey is trusted for what it proves at login, not its make.
+
+// Config - the relying party. RPID is the registrable domain passkeys are bound
+// to; Origins are the exact origins (scheme://host[:port]) the ceremonies may run on.
+type Config struct {
+	RPID    string
+	RPName  string
+	Origins []string
+	Timeout time.Duration
+}
+
+// Authenticator data flags
+const (
+	flagUserPresent      = 0x01
+	flagUserVerified     = 0x04
+	flagAttestedCredData = 0x40
+	flagExtensionData    = 0x80
+)
+
+const (
+	challengeSize      = 32
+	maxCredentialIDLen = 1023
+)
+
+var (
+	ErrCloneDetected = errors.New("authenticator sign count did not increase")
+	errInvalidData   = errors.New("invalid authenticator data")
+)
+
+// CreationOptions - publicKey options for navigator.credentials.create()
+type CreationOptions struct {
+	Challenge              string                 `json:"challenge"`
+	RP                     RelyingParty           `json:"rp"`
+	User                   User                   `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"` // Milliseconds
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions - publicKey options for navigator.credentials.get(). Without
+// AllowCredentials the authenticator offers any discoverable passkey for the RP.
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	Timeout          int64                  `json:"timeout"` // Milliseconds
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials,omitempty"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+type RelyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type User struct {
+	ID          string `json:"id"` // User handle, base64url
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// RegistrationCredential - the PublicKeyCredential returned by create()
+type RegistrationCredential struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AttestationObject string `json:"attestationObject"`
+	} `json:"response"`
+}
+
+// AssertionCredential - the PublicKeyCredential returned by get()
+type AssertionCredential struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response"`
+}
+
+// Credential - what registration yields and is stored per passkey
+type Credential struct {
+	ID        []byte
+	PublicKey []byte // COSE_Key
+	SignCount uint32
+	AAGUID    []byte // Authenticator model, all zeros for most passkey providers
+}
+
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+type authenticatorData struct {
+	rpIDHash     []byte
+	flags        byte
+	signCount    uint32
+	aaguid       []byte
+	credentialID []byte
+	publicKey    []byte
+}
+
+// NewChallenge - Random challenge for one ceremony, base64url
+func NewChallenge() (string, error) {
+	challenge := make([]byte, challengeSize)
+	if _, err := rand.Read(challenge); err != nil {
+		return "", fmt.Errorf("failed to generate challenge: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(challenge), nil
+}
+
+// NewCreationOptions - Ask for a discoverable, user-verified passkey, excluding
+// ones the account already has on the same authenticator
+func (c *Config) NewCreationOptions(challenge string, userHandle []byte, name, displayName string, exclude [][]byte) CreationOptions {
+	excluded := make([]CredentialDescriptor, 0, len(exclude))
+	for _, id := range exclude {
+		excluded = append(excluded, CredentialDescriptor{Type: "public-key", ID: base64.RawURLEncoding.EncodeToString(id)})
+	}
+
+	return CreationOptions{
+		Challenge: challenge,
+		RP:        RelyingParty{ID: c.RPID, Name: c.RPName},
+		User: User{
+			ID:          base64.RawURLEncoding.EncodeToString(userHandle),
+			Name:        name,
+			DisplayName: displayName,
+		},
+		PubKeyCredParams: []CredentialParameter{
+			{Type: "public-key", Alg: AlgES256},
+			{Type: "public-key", Alg: AlgEdDSA},
+			{Type: "public-key", Alg: AlgRS256},
+		},
+		Timeout:            c.Timeout.Milliseconds(),
+		ExcludeCredentials: excluded,
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "required",
+			UserVerification: "required",
+		},
+		Attestation: "none",
+	}
+}
+
+// NewRequestOptions - Options for logging in, optionally limited to allow
+func (c *Config) NewRequestOptions(challenge string, allow [][]byte) RequestOptions {
+	var allowed []CredentialDescriptor
+	for _, id := range allow {
+		allowed = append(allowed, CredentialDescriptor{Type: "public-key", ID: base64.RawURLEncoding.EncodeToString(id)})
+	}
+
+	return RequestOptions{
+		Challenge:        challenge,
+		Timeout:          c.Timeout.Milliseconds(),
+		RPID:             c.RPID,
+		AllowCredentials: allowed,
+		UserVerification: "required",
+	}
+}
+
+// ClientDataChallenge - The challenge a response answers, for finding the
+// ceremony it belongs to. Nothing is verified yet.
+func ClientDataChallenge(clientDataJSON string) (string, error) {
+	raw, err := decodeBase64URL(clientDataJSON)
+	if err != nil {
+		return "", err
+	}
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return "", errors.New("invalid client data")
+	}
+	return data.Challenge, nil
+}
+
+// VerifyRegistration - Check a create() response against the ceremony's
+// challenge and return the new credential
+func (c *Config) VerifyRegistration(challenge string, cred *RegistrationCredential) (*Credential, error) {
+	if cred.Type != "public-key" {
+		return nil, errors.New("invalid credential type")
+	}
+
+	if _, err := c.verifyClientData(cred.Response.ClientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+
+	attestationObject, err := decodeBase64URL(cred.Response.AttestationObject)
+	if err != nil {
+		return nil, err
+	}
+	decoded, _, err := decodeCBOR(attestationObject)
+	if err != nil {
+		return nil, errors.New("invalid attestation object")
+	}
+	attestation, ok := decoded.(map[any]any)
+	if !ok {
+		return nil, errors.New("invalid attestation object")
+	}
+	rawAuthData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return nil, errors.New("invalid attestation object")
+	}
+
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkAuthenticatorData(authData); err != nil {
+		return nil, err
+	}
+	if authData.flags&flagAttestedCredData == 0 {
+		return nil, errors.New("missing attested credential data")
+	}
+
+	rawID, err := decodeBase64URL(cred.RawID)
+	if err != nil || !bytes.Equal(rawID, authData.credentialID) {
+		return nil, errors.New("credential id mismatch")
+	}
+
+	if _, err := parsePublicKey(authData.publicKey); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:        authData.credentialID,
+		PublicKey: authData.publicKey,
+		SignCount: authData.signCount,
+		AAGUID:    authData.aaguid,
+	}, nil
+}
+
+// VerifyAssertion - Check a get() response against the ceremony's challenge and
+// the stored passkey, returning the authenticator's new sign count. A count that
+// did not increase means the key was cloned, unless the authenticator doesn't
+// keep one (always 0).
+func (c *Config) VerifyAssertion(challenge string, cred *AssertionCredential, publicKey []byte, storedSignCount uint32) (uint32, error) {
+	if cred.Type != "public-key" {
+		return 0, errors.New("invalid credential type")
+	}
+
+	rawClientData, err := c.verifyClientData(cred.Response.ClientDataJSON, "webauthn.get", challenge)
+	if err != nil {
+		return 0, err
+	}
+
+	rawAuthData, err := decodeBase64URL(cred.Response.AuthenticatorData)
+	if err != nil {
+		return 0, err
+	}
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return 0, err
+	}
+	if err := c.checkAuthenticatorData(authData); err != nil {
+		return 0, err
+	}
+
+	signature, err := decodeBase64URL(cred.Response.Signature)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(rawClientData)
+	if err := verifySignature(publicKey, append(bytes.Clone(rawAuthData), clientDataHash[:]...), signature); err != nil {
+		return 0, err
+	}
+
+	if (authData.signCount != 0 || storedSignCount != 0) && authData.signCount <= storedSignCount {
+		return 0, ErrCloneDetected
+	}
+	return authData.signCount, nil
+}
+
+func (c *Config) verifyClientData(encoded, ceremony, challenge string) ([]byte, error) {
+	raw, err := decodeBase64URL(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, errors.New("invalid client data")
+	}
+	if data.Type != ceremony {
+		return nil, errors.New("unexpected ceremony type")
+	}
+	if subtle.ConstantTimeCompare([]byte(data.Challenge), []byte(challenge)) != 1 {
+		return nil, errors.New("challenge mismatch")
+	}
+	if !slices.Contains(c.Origins, data.Origin) || data.CrossOrigin {
+		return nil, fmt.Errorf("origin %q not allowed", data.Origin)
+	}
+	return raw, nil
+}
+
+// checkAuthenticatorData - Bound to our RP ID, with the user present and verified
+func (c *Config) checkAuthenticatorData(authData *authenticatorData) error {
+	rpIDHash := sha256.Sum256([]byte(c.RPID))
+	if subtle.ConstantTimeCompare(authData.rpIDHash, rpIDHash[:]) != 1 {
+		return errors.New("rp id mismatch")
+	}
+	if authData.flags&flagUserPresent == 0 {
+		return errors.New("user not present")
+	}
+	if authData.flags&flagUserVerified == 0 {
+		return errors.New("user not verified")
+	}
+	return nil
+}
+
+// parseAuthenticatorData - rpIdHash(32) flags(1) signCount(4), then the attested
+// credential (aaguid(16) idLen(2) id publicKey) and extensions when flagged
+func parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, errInvalidData
+	}
+
+	authData := &authenticatorData{
+		rpIDHash:  data[:32],
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	rest := data[37:]
+
+	if authData.flags&flagAttestedCredData != 0 {
+		if len(rest) < 18 {
+			return nil, errInvalidData
+		}
+		authData.aaguid = rest[:16]
+		idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLen == 0 || idLen > maxCredentialIDLen || len(rest) < idLen {
+			return nil, errInvalidData
+		}
+		authData.credentialID = rest[:idLen]
+		rest = rest[idLen:]
+
+		// The key is one CBOR item, its length is only known by decoding it
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, errInvalidData
+		}
+		authData.publicKey = rest[:len(rest)-len(after)]
+		rest = after
+	}
+
+	if authData.flags&flagExtensionData != 0 {
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, errInvalidData
+		}
+		rest = after
+	}
+
+	if len(rest) != 0 {
+		return nil, errInvalidData
+	}
+	return authData, nil
+}
+
+// decodeBase64URL - Browsers omit padding, some client libraries keep it
+func decodeBase64URL(s string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, errors.New("invalid base64url data")
+	}
+	return b, nil
+}
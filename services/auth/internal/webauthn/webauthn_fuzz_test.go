@@ -0,0 +1,59 @@
+package webauthn_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/teammachinist/tutuplapak/services/auth/internal/webauthn/webauthntest"
+)
+
+// FuzzVerifyRegistration - Attestation objects as a client might send them. Only
+// one carrying the credential id the client named may ever be accepted.
+func FuzzVerifyRegistration(f *testing.F) {
+	challenge := challenge(f)
+	a := webauthntest.New(testConfig.Origins[0])
+	response, err := a.Create(testConfig.NewCreationOptions(challenge, []byte("user-1"), "alice", "Alice", nil))
+	if err != nil {
+		f.Fatal(err)
+	}
+	attestationObject, _ := base64.RawURLEncoding.DecodeString(response.Response.AttestationObject)
+	f.Add(attestationObject)
+	f.Add(attestationObject[:len(attestationObject)/2])
+
+	rawID, _ := base64.RawURLEncoding.DecodeString(response.RawID)
+	f.Fuzz(func(t *testing.T, attestationObject []byte) {
+		tampered := *response
+		tampered.Response.AttestationObject = base64.RawURLEncoding.EncodeToString(attestationObject)
+
+		cred, err := testConfig.VerifyRegistration(challenge, &tampered)
+		if err != nil {
+			return
+		}
+		if !bytes.Equal(cred.ID, rawID) {
+			t.Fatalf("registered credential %x, the client named %x", cred.ID, rawID)
+		}
+	})
+}
+
+// FuzzVerifyAssertion - Stored keys and authenticator data that don't match the
+// signature. Only the genuine pair may ever be accepted.
+func FuzzVerifyAssertion(f *testing.F) {
+	a := webauthntest.New(testConfig.Origins[0])
+	cred := register(f, a)
+	challenge, response := assert(f, a)
+	authData, _ := base64.RawURLEncoding.DecodeString(response.Response.AuthenticatorData)
+	f.Add(cred.PublicKey, authData)
+	f.Add(cred.PublicKey[:len(cred.PublicKey)-1], authData)
+	f.Add(cred.PublicKey, authData[:36])
+
+	f.Fuzz(func(t *testing.T, publicKey, authData []byte) {
+		tampered := *response
+		tampered.Response.AuthenticatorData = base64.RawURLEncoding.EncodeToString(authData)
+
+		_, err := testConfig.VerifyAssertion(challenge, &tampered, publicKey, 0)
+		if err == nil && tampered.Response.AuthenticatorData != response.Response.AuthenticatorData {
+			t.Fatalf("authenticator data %x accepted, it was never signed", authData)
+		}
+	})
+}
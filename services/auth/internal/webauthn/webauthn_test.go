@@ -0,0 +1,254 @@
+package webauthn_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/teammachinist/tutuplapak/services/auth/internal/webauthn"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/webauthn/webauthntest"
+)
+
+var testConfig = &webauthn.Config{
+	RPID:    "tutuplapak.example",
+	RPName:  "Tutuplapak",
+	Origins: []string{"https://tutuplapak.example"},
+	Timeout: 5 * time.Minute,
+}
+
+func challenge(t testing.TB) string {
+	t.Helper()
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return challenge
+}
+
+// register - Run a registration ceremony with a and return the stored passkey
+func register(t testing.TB, a *webauthntest.Authenticator) *webauthn.Credential {
+	t.Helper()
+
+	challenge := challenge(t)
+	response, err := a.Create(testConfig.NewCreationOptions(challenge, []byte("user-1"), "alice", "Alice", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred, err := testConfig.VerifyRegistration(challenge, response)
+	if err != nil {
+		t.Fatalf("registration rejected: %v", err)
+	}
+	return cred
+}
+
+// assert - A login response from a for a fresh challenge
+func assert(t testing.TB, a *webauthntest.Authenticator) (string, *webauthn.AssertionCredential) {
+	t.Helper()
+
+	challenge := challenge(t)
+	response, err := a.Get(testConfig.NewRequestOptions(challenge, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return challenge, response
+}
+
+// resign - Change the authenticator data of response and sign it again with the
+// passkey's own key, so only the changed field can fail verification
+func resign(t *testing.T, a *webauthntest.Authenticator, response *webauthn.AssertionCredential, change func(authData []byte)) {
+	t.Helper()
+	if err := a.Resign(response, change); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	a := webauthntest.New(testConfig.Origins[0])
+	cred := register(t, a)
+
+	if cred.SignCount != 0 {
+		t.Errorf("new passkey sign count %d", cred.SignCount)
+	}
+
+	signCount := cred.SignCount
+	for range 2 {
+		challenge, response := assert(t, a)
+		got, err := testConfig.VerifyAssertion(challenge, response, cred.PublicKey, signCount)
+		if err != nil {
+			t.Fatalf("login rejected: %v", err)
+		}
+		if got != signCount+1 {
+			t.Errorf("sign count %d, want %d", got, signCount+1)
+		}
+		signCount = got
+	}
+}
+
+func TestRegistrationRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		origin string
+		rpID   string
+		setup  func(a *webauthntest.Authenticator)
+		tamper func(t *testing.T, challenge *string, response *webauthn.RegistrationCredential)
+		want   string
+	}{
+		{name: "wrong origin", origin: "https://evil.example", want: `origin "https://evil.example" not allowed`},
+		{name: "wrong rp id", rpID: "evil.example", want: "rp id mismatch"},
+		{name: "wrong challenge", tamper: func(t *testing.T, c *string, _ *webauthn.RegistrationCredential) {
+			*c = challenge(t)
+		}, want: "challenge mismatch"},
+		{name: "missing user verification", setup: func(a *webauthntest.Authenticator) { a.UserVerified = false }, want: "user not verified"},
+		{name: "missing user presence", setup: func(a *webauthntest.Authenticator) { a.UserPresent = false }, want: "user not present"},
+		{name: "other credential id", tamper: func(t *testing.T, _ *string, r *webauthn.RegistrationCredential) {
+			r.RawID = base64.RawURLEncoding.EncodeToString([]byte("another-credential"))
+		}, want: "credential id mismatch"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			origin := testConfig.Origins[0]
+			if tt.origin != "" {
+				origin = tt.origin
+			}
+			a := webauthntest.New(origin)
+			if tt.setup != nil {
+				tt.setup(a)
+			}
+
+			challenge := challenge(t)
+			options := testConfig.NewCreationOptions(challenge, []byte("user-1"), "alice", "Alice", nil)
+			if tt.rpID != "" {
+				options.RP.ID = tt.rpID
+			}
+			response, err := a.Create(options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.tamper != nil {
+				tt.tamper(t, &challenge, response)
+			}
+
+			if _, err := testConfig.VerifyRegistration(challenge, response); err == nil || err.Error() != tt.want {
+				t.Errorf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoginRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(t *testing.T, a *webauthntest.Authenticator, challenge *string, response *webauthn.AssertionCredential)
+		want   string
+	}{
+		{"wrong origin", func(t *testing.T, a *webauthntest.Authenticator, c *string, r *webauthn.AssertionCredential) {
+			a.Origin = "https://evil.example"
+			challenge, response := assert(t, a)
+			*c, *r = challenge, *response
+		}, `origin "https://evil.example" not allowed`},
+		{"wrong challenge", func(t *testing.T, _ *webauthntest.Authenticator, c *string, _ *webauthn.AssertionCredential) {
+			*c = challenge(t)
+		}, "challenge mismatch"},
+		{"wrong rp id hash", func(t *testing.T, a *webauthntest.Authenticator, _ *string, r *webauthn.AssertionCredential) {
+			evil := sha256.Sum256([]byte("evil.example"))
+			resign(t, a, r, func(authData []byte) { copy(authData, evil[:]) })
+		}, "rp id mismatch"},
+		{"missing user verification", func(t *testing.T, a *webauthntest.Authenticator, c *string, r *webauthn.AssertionCredential) {
+			a.UserVerified = false
+			challenge, response := assert(t, a)
+			*c, *r = challenge, *response
+		}, "user not verified"},
+		{"missing user presence", func(t *testing.T, a *webauthntest.Authenticator, c *string, r *webauthn.AssertionCredential) {
+			a.UserPresent = false
+			challenge, response := assert(t, a)
+			*c, *r = challenge, *response
+		}, "user not present"},
+		{"signature", func(t *testing.T, a *webauthntest.Authenticator, _ *string, r *webauthn.AssertionCredential) {
+			authData, _ := base64.RawURLEncoding.DecodeString(r.Response.AuthenticatorData)
+			authData[len(authData)-1]++ // Sign count raised without signing it
+			r.Response.AuthenticatorData = base64.RawURLEncoding.EncodeToString(authData)
+		}, "invalid signature"},
+		{"registration response", func(t *testing.T, a *webauthntest.Authenticator, c *string, r *webauthn.AssertionCredential) {
+			clientDataJSON, _ := a.ClientData("webauthn.create", *c)
+			r.Response.ClientDataJSON = base64.RawURLEncoding.EncodeToString(clientDataJSON)
+		}, "unexpected ceremony type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := webauthntest.New(testConfig.Origins[0])
+			cred := register(t, a)
+			challenge, response := assert(t, a)
+			tt.tamper(t, a, &challenge, response)
+
+			if _, err := testConfig.VerifyAssertion(challenge, response, cred.PublicKey, cred.SignCount); err == nil || err.Error() != tt.want {
+				t.Errorf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoginOtherPasskey(t *testing.T) {
+	a := webauthntest.New(testConfig.Origins[0])
+	register(t, a)
+	other := register(t, webauthntest.New(testConfig.Origins[0]))
+
+	challenge, response := assert(t, a)
+	if _, err := testConfig.VerifyAssertion(challenge, response, other.PublicKey, 0); err == nil || err.Error() != "invalid signature" {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestLoginReplayed(t *testing.T) {
+	a := webauthntest.New(testConfig.Origins[0])
+	cred := register(t, a)
+	challenge, response := assert(t, a)
+
+	signCount, err := testConfig.VerifyAssertion(challenge, response, cred.PublicKey, cred.SignCount)
+	if err != nil {
+		t.Fatalf("login rejected: %v", err)
+	}
+
+	// Even if the challenge were still outstanding, the counter has moved on
+	if _, err := testConfig.VerifyAssertion(challenge, response, cred.PublicKey, signCount); !errors.Is(err, webauthn.ErrCloneDetected) {
+		t.Errorf("replay: got error %v", err)
+	}
+}
+
+func TestLoginCloneDetected(t *testing.T) {
+	a := webauthntest.New(testConfig.Origins[0])
+	cred := register(t, a)
+	clone := a.Clone()
+
+	signCount := cred.SignCount
+	for range 3 {
+		challenge, response := assert(t, a)
+		var err error
+		if signCount, err = testConfig.VerifyAssertion(challenge, response, cred.PublicKey, signCount); err != nil {
+			t.Fatalf("login rejected: %v", err)
+		}
+	}
+
+	// The copy's counter is behind the genuine authenticator's
+	challenge, response := assert(t, clone)
+	if _, err := testConfig.VerifyAssertion(challenge, response, cred.PublicKey, signCount); !errors.Is(err, webauthn.ErrCloneDetected) {
+		t.Errorf("decreasing sign count: got error %v", err)
+	}
+}
+
+func TestLoginWithoutSignCount(t *testing.T) {
+	a := webauthntest.New(testConfig.Origins[0])
+	cred := register(t, a)
+
+	// Authenticators that keep no counter always report 0
+	for range 2 {
+		challenge, response := assert(t, a)
+		resign(t, a, response, func(authData []byte) { clear(authData[33:37]) })
+		if _, err := testConfig.VerifyAssertion(challenge, response, cred.PublicKey, 0); err != nil {
+			t.Errorf("login without a sign count rejected: %v", err)
+		}
+	}
+}
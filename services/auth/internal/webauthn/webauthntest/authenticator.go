@@ -0,0 +1,251 @@
+// Package webauthntest provides a software authenticator for driving passkey
+// ceremonies from tests and development tools. It must never be linked into the
+// auth service itself: it vouches for a user it never saw.
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/teammachinist/tutuplapak/services/auth/internal/webauthn"
+)
+
+// Authenticator data flags (WebAuthn §6.1)
+const (
+	FlagUserPresent      = 0x01
+	FlagUserVerified     = 0x04
+	FlagAttestedCredData = 0x40
+)
+
+// COSE_Key parameters of an ES256 key (RFC 9053)
+const (
+	coseKeyType    = 1
+	coseKeyAlg     = 3
+	coseCurve      = -1
+	coseX          = -2
+	coseY          = -3
+	coseKeyTypeEC2 = 2
+	coseCurveP256  = 1
+)
+
+// Authenticator - an in-memory ES256 authenticator that answers creation and
+// request options the way a browser and platform authenticator would. It
+// reports the user present and verified unless told otherwise.
+type Authenticator struct {
+	Origin       string
+	UserPresent  bool
+	UserVerified bool
+	credentials  []*credential
+}
+
+type credential struct {
+	id         []byte
+	rpID       string
+	userHandle []byte
+	key        *ecdsa.PrivateKey
+	signCount  uint32
+}
+
+func New(origin string) *Authenticator {
+	return &Authenticator{Origin: origin, UserPresent: true, UserVerified: true}
+}
+
+// Clone - A copy holding the same keys and counters, as an attacker who
+// extracted them would
+func (a *Authenticator) Clone() *Authenticator {
+	clone := *a
+	clone.credentials = nil
+	for _, cred := range a.credentials {
+		copied := *cred
+		clone.credentials = append(clone.credentials, &copied)
+	}
+	return &clone
+}
+
+// Create - navigator.credentials.create()
+func (a *Authenticator) Create(options webauthn.CreationOptions) (*webauthn.RegistrationCredential, error) {
+	userHandle, err := decodeBase64URL(options.User.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.ContainsFunc(options.PubKeyCredParams, func(p webauthn.CredentialParameter) bool { return p.Alg == webauthn.AlgES256 }) {
+		return nil, errors.New("webauthntest: ES256 not offered")
+	}
+	for _, excluded := range options.ExcludeCredentials {
+		if a.find(options.RP.ID, excluded.ID) != nil {
+			return nil, errors.New("webauthntest: credential already registered")
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	id := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	cred := &credential{id: id, rpID: options.RP.ID, userHandle: userHandle, key: key}
+
+	publicKey := encodeCBOR(map[any]any{
+		coseKeyType: coseKeyTypeEC2,
+		coseKeyAlg:  webauthn.AlgES256,
+		coseCurve:   coseCurveP256,
+		coseX:       key.X.FillBytes(make([]byte, 32)),
+		coseY:       key.Y.FillBytes(make([]byte, 32)),
+	})
+
+	authData := a.authenticatorData(cred, FlagAttestedCredData)
+	authData = append(authData, make([]byte, 16)...) // AAGUID
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(id)))
+	authData = append(authData, id...)
+	authData = append(authData, publicKey...)
+
+	attestationObject := encodeCBOR(map[any]any{
+		"fmt":      "none",
+		"attStmt":  map[any]any{},
+		"authData": authData,
+	})
+
+	clientDataJSON, err := a.ClientData("webauthn.create", options.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	a.credentials = append(a.credentials, cred)
+
+	response := &webauthn.RegistrationCredential{
+		ID:    base64.RawURLEncoding.EncodeToString(id),
+		RawID: base64.RawURLEncoding.EncodeToString(id),
+		Type:  "public-key",
+	}
+	response.Response.ClientDataJSON = base64.RawURLEncoding.EncodeToString(clientDataJSON)
+	response.Response.AttestationObject = base64.RawURLEncoding.EncodeToString(attestationObject)
+	return response, nil
+}
+
+// Get - navigator.credentials.get(), using the newest matching passkey
+func (a *Authenticator) Get(options webauthn.RequestOptions) (*webauthn.AssertionCredential, error) {
+	var cred *credential
+	for i := len(a.credentials) - 1; i >= 0 && cred == nil; i-- {
+		candidate := a.credentials[i]
+		if candidate.rpID != options.RPID {
+			continue
+		}
+		if len(options.AllowCredentials) == 0 || slices.ContainsFunc(options.AllowCredentials, func(d webauthn.CredentialDescriptor) bool {
+			return d.ID == base64.RawURLEncoding.EncodeToString(candidate.id)
+		}) {
+			cred = candidate
+		}
+	}
+	if cred == nil {
+		return nil, errors.New("webauthntest: no matching credential")
+	}
+
+	cred.signCount++
+	authData := a.authenticatorData(cred, 0)
+
+	clientDataJSON, err := a.ClientData("webauthn.get", options.Challenge)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := sign(cred, authData, clientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &webauthn.AssertionCredential{
+		ID:    base64.RawURLEncoding.EncodeToString(cred.id),
+		RawID: base64.RawURLEncoding.EncodeToString(cred.id),
+		Type:  "public-key",
+	}
+	response.Response.ClientDataJSON = base64.RawURLEncoding.EncodeToString(clientDataJSON)
+	response.Response.AuthenticatorData = base64.RawURLEncoding.EncodeToString(authData)
+	response.Response.Signature = base64.RawURLEncoding.EncodeToString(signature)
+	response.Response.UserHandle = base64.RawURLEncoding.EncodeToString(cred.userHandle)
+	return response, nil
+}
+
+// Resign - Let change edit the authenticator data of a get() response, then sign
+// it again with the passkey that answered, so only the edited field can fail
+// verification
+func (a *Authenticator) Resign(response *webauthn.AssertionCredential, change func(authData []byte)) error {
+	var cred *credential
+	for _, candidate := range a.credentials {
+		if base64.RawURLEncoding.EncodeToString(candidate.id) == response.RawID {
+			cred = candidate
+		}
+	}
+	if cred == nil {
+		return errors.New("webauthntest: response from another authenticator")
+	}
+
+	authData, err := decodeBase64URL(response.Response.AuthenticatorData)
+	if err != nil {
+		return err
+	}
+	clientDataJSON, err := decodeBase64URL(response.Response.ClientDataJSON)
+	if err != nil {
+		return err
+	}
+	change(authData)
+
+	signature, err := sign(cred, authData, clientDataJSON)
+	if err != nil {
+		return err
+	}
+	response.Response.AuthenticatorData = base64.RawURLEncoding.EncodeToString(authData)
+	response.Response.Signature = base64.RawURLEncoding.EncodeToString(signature)
+	return nil
+}
+
+// ClientData - The clientDataJSON a browser at Origin sends for ceremony
+func (a *Authenticator) ClientData(ceremony, challenge string) ([]byte, error) {
+	data, err := json.Marshal(map[string]any{"type": ceremony, "challenge": challenge, "origin": a.Origin, "crossOrigin": false})
+	if err != nil {
+		return nil, fmt.Errorf("webauthntest: %w", err)
+	}
+	return data, nil
+}
+
+func (a *Authenticator) find(rpID, id string) *credential {
+	for _, cred := range a.credentials {
+		if cred.rpID == rpID && base64.RawURLEncoding.EncodeToString(cred.id) == id {
+			return cred
+		}
+	}
+	return nil
+}
+
+func (a *Authenticator) authenticatorData(cred *credential, flags byte) []byte {
+	if a.UserPresent {
+		flags |= FlagUserPresent
+	}
+	if a.UserVerified {
+		flags |= FlagUserVerified
+	}
+	rpIDHash := sha256.Sum256([]byte(cred.rpID))
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, flags)
+	return binary.BigEndian.AppendUint32(data, cred.signCount)
+}
+
+// sign - The assertion signature over authData and the client data's hash
+func sign(cred *credential, authData, clientDataJSON []byte) ([]byte, error) {
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	return ecdsa.SignASN1(rand.Reader, cred.key, digest[:])
+}
+
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
@@ -0,0 +1,79 @@
+package webauthntest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// encodeCBOR - Encode value in CTAP2 canonical form (RFC 8949 §4.2.1), as an
+// authenticator does. Supports int, int64, string, []byte and map[any]any with
+// int/string keys; anything else is a bug in this package.
+func encodeCBOR(value any) []byte {
+	var buf bytes.Buffer
+	encodeCBORItem(&buf, value)
+	return buf.Bytes()
+}
+
+func encodeCBORItem(buf *bytes.Buffer, value any) {
+	switch v := value.(type) {
+	case int:
+		encodeCBORItem(buf, int64(v))
+	case int64:
+		if v >= 0 {
+			writeCBORHead(buf, 0, uint64(v))
+		} else {
+			writeCBORHead(buf, 1, uint64(-1-v))
+		}
+	case []byte:
+		writeCBORHead(buf, 2, uint64(len(v)))
+		buf.Write(v)
+	case string:
+		writeCBORHead(buf, 3, uint64(len(v)))
+		buf.WriteString(v)
+	case map[any]any:
+		// Canonical order: keys sorted by their encoded form, shorter first
+		keys := make([][]byte, 0, len(v))
+		encoded := make(map[string]any, len(v))
+		for key, item := range v {
+			var keyBuf bytes.Buffer
+			encodeCBORItem(&keyBuf, key)
+			keys = append(keys, keyBuf.Bytes())
+			encoded[keyBuf.String()] = item
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if len(keys[i]) != len(keys[j]) {
+				return len(keys[i]) < len(keys[j])
+			}
+			return bytes.Compare(keys[i], keys[j]) < 0
+		})
+
+		writeCBORHead(buf, 5, uint64(len(v)))
+		for _, key := range keys {
+			buf.Write(key)
+			encodeCBORItem(buf, encoded[string(key)])
+		}
+	default:
+		panic(fmt.Sprintf("webauthntest: cannot encode %T", value))
+	}
+}
+
+func writeCBORHead(buf *bytes.Buffer, major byte, arg uint64) {
+	switch {
+	case arg < 24:
+		buf.WriteByte(major<<5 | byte(arg))
+	case arg <= 0xff:
+		buf.WriteByte(major<<5 | 24)
+		buf.WriteByte(byte(arg))
+	case arg <= 0xffff:
+		buf.WriteByte(major<<5 | 25)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(arg)))
+	case arg <= 0xffffffff:
+		buf.WriteByte(major<<5 | 26)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(arg)))
+	default:
+		buf.WriteByte(major<<5 | 27)
+		buf.Write(binary.BigEndian.AppendUint64(nil, arg))
+	}
+}
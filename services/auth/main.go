@@ -14,8 +14,12 @@ import (
 	"github.com/teammachinist/tutuplapak/services/auth/internal/database"
 	"github.com/teammachinist/tutuplapak/services/auth/internal/handler"
 	"github.com/teammachinist/tutuplapak/services/auth/internal/logger"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/notifier"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/password"
 	"github.com/teammachinist/tutuplapak/services/auth/internal/repository"
 	"github.com/teammachinist/tutuplapak/services/auth/internal/service"
+	"github.com/teammachinist/tutuplapak/services/auth/internal/webauthn"
+	"github.com/teammachinist/tutuplapak/services/auth/pkg/authz"
 
 	"github.com/caarlos0/env/v8"
 	"github.com/gin-gonic/gin"
@@ -28,9 +32,69 @@ type Config struct {
 	CoreServiceURL string `env:"CORE_SERVICE_URL" envDefault:""`
 
 	// JWT Configuration
-	JWTSecret   string        `env:"JWT_SECRET" envDefault:"your-secret-key"`
-	JWTDuration time.Duration `env:"JWT_DURATION" envDefault:"24h"`
-	JWTIssuer   string        `env:"JWT_ISSUER" envDefault:"fitbyte-app"`
+	JWTKeysDir         string        `env:"JWT_KEYS_DIR" envDefault:""`
+	JWTActiveKeyID     string        `env:"JWT_ACTIVE_KEY_ID" envDefault:""`
+	JWTDuration        time.Duration `env:"JWT_DURATION" envDefault:"15m"`
+	JWTRefreshDuration time.Duration `env:"JWT_REFRESH_DURATION" envDefault:"720h"`
+	JWTIssuer          string        `env:"JWT_ISSUER" envDefault:"tutuplapak-auth"`
+
+	// Admin impersonation tokens, never refreshed
+	ImpersonationTTL time.Duration `env:"IMPERSONATION_TTL" envDefault:"15m"`
+
+	// Login throttling
+	LoginMaxAttempts   int           `env:"LOGIN_MAX_ATTEMPTS" envDefault:"5"`
+	LoginMaxIPAttempts int           `env:"LOGIN_MAX_IP_ATTEMPTS" envDefault:"50"`
+	LoginAttemptWindow time.Duration `env:"LOGIN_ATTEMPT_WINDOW" envDefault:"15m"`
+	LoginLockout       time.Duration `env:"LOGIN_LOCKOUT" envDefault:"1m"`
+	LoginMaxLockout    time.Duration `env:"LOGIN_MAX_LOCKOUT" envDefault:"1h"`
+
+	// Proxies allowed to set X-Forwarded-For / X-Real-IP (nginx, ingress)
+	TrustedProxies []string `env:"TRUSTED_PROXIES" envSeparator:"," envDefault:"10.0.0.0/8,172.16.0.0/12,192.168.0.0/16,127.0.0.1/32"`
+
+	// Service-to-service signing keys, "auth:secret,core:secret,files:secret"
+	InternalServiceKeys string `env:"INTERNAL_SERVICE_KEYS" envDefault:""`
+
+	// Password hashing (argon2id), calibrate with `go run ./cmd/passwordbench`
+	PasswordMemory      uint32 `env:"PASSWORD_ARGON2_MEMORY" envDefault:"65536"` // KiB
+	PasswordIterations  uint32 `env:"PASSWORD_ARGON2_ITERATIONS" envDefault:"3"`
+	PasswordParallelism uint8  `env:"PASSWORD_ARGON2_PARALLELISM" envDefault:"2"`
+
+	// Issuer shown in authenticator apps next to the account
+	TOTPIssuer string `env:"TOTP_ISSUER" envDefault:"TutupLapak"`
+
+	// Passwordless login (one-time codes, magic links). Links need both the
+	// frontend page that receives them and a secret of at least 32 bytes.
+	PasswordlessCodeTTL    time.Duration `env:"PASSWORDLESS_CODE_TTL" envDefault:"10m"`
+	PasswordlessCooldown   time.Duration `env:"PASSWORDLESS_COOLDOWN" envDefault:"1m"`
+	PasswordlessMaxSends   int           `env:"PASSWORDLESS_MAX_SENDS" envDefault:"5"`     // Per account per hour
+	PasswordlessMaxIPSends int           `env:"PASSWORDLESS_MAX_IP_SENDS" envDefault:"30"` // Per client IP per hour
+	MagicLinkURL           string        `env:"MAGIC_LINK_URL" envDefault:""`
+	MagicLinkSecret        string        `env:"MAGIC_LINK_SECRET" envDefault:""`
+
+	// Passkeys (WebAuthn). RP ID is the domain passkeys are bound to, origins are
+	// the exact frontend origins allowed to run the ceremonies.
+	WebAuthnRPID    string        `env:"WEBAUTHN_RP_ID" envDefault:"localhost"`
+	WebAuthnRPName  string        `env:"WEBAUTHN_RP_NAME" envDefault:"TutupLapak"`
+	WebAuthnOrigins []string      `env:"WEBAUTHN_ORIGINS" envSeparator:"," envDefault:"http://localhost:8080"`
+	WebAuthnTimeout time.Duration `env:"WEBAUTHN_TIMEOUT" envDefault:"5m"`
+
+	// Registration outbox, delivers new accounts to Core until it succeeds
+	OutboxInterval  time.Duration `env:"OUTBOX_INTERVAL" envDefault:"5s"`
+	OutboxBatchSize int           `env:"OUTBOX_BATCH_SIZE" envDefault:"50"`
+
+	// Reconciliation of accounts against Core's profiles, 0 disables it
+	ReconcileInterval    time.Duration `env:"RECONCILE_INTERVAL" envDefault:"1h"`
+	ReconcileGracePeriod time.Duration `env:"RECONCILE_GRACE_PERIOD" envDefault:"1h"`
+	ReconcilePageSize    int           `env:"RECONCILE_PAGE_SIZE" envDefault:"500"`
+
+	// Audit log retention, 0 keeps entries forever
+	AuditRetention      time.Duration `env:"AUDIT_RETENTION" envDefault:"2160h"` // 90 days
+	AuditPurgeInterval  time.Duration `env:"AUDIT_PURGE_INTERVAL" envDefault:"1h"`
+	AuditPurgeBatchSize int           `env:"AUDIT_PURGE_BATCH_SIZE" envDefault:"5000"`
+
+	// Notifications (password reset codes, ...)
+	NotifierDriver   string `env:"NOTIFIER_DRIVER" envDefault:"log"`
+	NotifierFilePath string `env:"NOTIFIER_FILE_PATH" envDefault:"/tmp/tutuplapak-notifications.log"`
 
 	// Redis Configuration
 	RedisAddr     string `env:"REDIS_ADDR" envDefault:"redis:6378"`
@@ -48,6 +112,12 @@ func main() {
 	if err := env.Parse(&cfg); err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	if cfg.AuditRetention > 0 && cfg.AuditPurgeInterval <= 0 {
+		log.Fatalf("AUDIT_PURGE_INTERVAL must be greater than 0")
+	}
+	if cfg.AuditRetention > 0 && cfg.AuditPurgeBatchSize <= 0 {
+		log.Fatalf("AUDIT_PURGE_BATCH_SIZE must be greater than 0")
+	}
 
 	// Initialize database
 	ctx := context.Background()
@@ -58,10 +128,78 @@ func main() {
 	}
 	defer db.Close()
 
+	signingKeys, err := service.LoadSigningKeys(cfg.JWTKeysDir, cfg.JWTActiveKeyID)
+	if err != nil {
+		log.Fatalf("Failed to load signing keys: %v", err)
+	}
+
+	jwks, err := signingKeys.JWKS()
+	if err != nil {
+		log.Fatalf("Failed to build JWKS: %v", err)
+	}
+
 	jwtConfig := &service.JWTConfig{
-		Key:      cfg.JWTSecret,
-		Duration: cfg.JWTDuration,
-		Issuer:   cfg.JWTIssuer,
+		Keys:             signingKeys,
+		Duration:         cfg.JWTDuration,
+		RefreshDuration:  cfg.JWTRefreshDuration,
+		ImpersonationTTL: cfg.ImpersonationTTL,
+		Issuer:           cfg.JWTIssuer,
+	}
+
+	serviceKeys, err := authz.ParseServiceKeys(cfg.InternalServiceKeys)
+	if err != nil {
+		log.Fatalf("Invalid INTERNAL_SERVICE_KEYS: %v", err)
+	}
+
+	serviceSigner, err := authz.NewServiceSigner(authz.ServiceAuth, serviceKeys)
+	if err != nil {
+		log.Fatalf("Failed to initialize service signer: %v", err)
+	}
+	serviceVerifier := authz.NewServiceVerifier(serviceKeys)
+
+	loginThrottle := service.LoginThrottleConfig{
+		MaxAttempts:   cfg.LoginMaxAttempts,
+		MaxIPAttempts: cfg.LoginMaxIPAttempts,
+		Window:        cfg.LoginAttemptWindow,
+		BaseLockout:   cfg.LoginLockout,
+		MaxLockout:    cfg.LoginMaxLockout,
+	}
+
+	passwordParams := password.DefaultParams()
+	passwordParams.Memory = cfg.PasswordMemory
+	passwordParams.Iterations = cfg.PasswordIterations
+	passwordParams.Parallelism = cfg.PasswordParallelism
+
+	passwordHasher, err := password.NewHasher(passwordParams)
+	if err != nil {
+		log.Fatalf("Invalid password hashing config: %v", err)
+	}
+
+	if cfg.MagicLinkSecret != "" && len(cfg.MagicLinkSecret) < 32 {
+		log.Fatalf("MAGIC_LINK_SECRET must be at least 32 bytes")
+	}
+	passwordless := service.PasswordlessConfig{
+		CodeTTL:    cfg.PasswordlessCodeTTL,
+		Cooldown:   cfg.PasswordlessCooldown,
+		MaxSends:   cfg.PasswordlessMaxSends,
+		MaxIPSends: cfg.PasswordlessMaxIPSends,
+		LinkURL:    cfg.MagicLinkURL,
+		LinkSecret: []byte(cfg.MagicLinkSecret),
+	}
+
+	webauthnConfig := &webauthn.Config{
+		RPID:    cfg.WebAuthnRPID,
+		RPName:  cfg.WebAuthnRPName,
+		Origins: cfg.WebAuthnOrigins,
+		Timeout: cfg.WebAuthnTimeout,
+	}
+
+	userNotifier, err := notifier.New(notifier.Config{
+		Driver:   cfg.NotifierDriver,
+		FilePath: cfg.NotifierFilePath,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize notifier: %v", err)
 	}
 
 	// Initialize Redis cache
@@ -83,19 +221,26 @@ func main() {
 	}
 
 	// Initialize layers
-	userRepo := repository.NewUserRepository(db.Queries)
-	userService := service.NewUserService(userRepo, db.Queries, jwtConfig, cfg.CoreServiceURL, redisCache)
+	userRepo := repository.NewUserRepository(db.Pool, db.Queries)
+	userService := service.NewUserService(userRepo, db.Queries, jwtConfig, cfg.CoreServiceURL, serviceSigner, redisCache, loginThrottle, userNotifier, passwordHasher, cfg.TOTPIssuer, passwordless, webauthnConfig)
 	userHandler := handler.NewUserHandler(userService)
 	healthHandler := handler.NewHealthHandler(db, redisCache)
 	internalHandler := handler.NewInternalHandler(userService)
+	jwksHandler := handler.NewJWKSHandler(jwks)
 
 	router := gin.Default()
-	router.SetTrustedProxies(nil)
+	// Client IP feeds login throttling, so only trust forwarded headers from our proxies
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid trusted proxies: %v", err)
+	}
 
 	// Health check endpoints
 	router.GET("/healthz", healthHandler.HealthCheck)
 	router.GET("/readyz", healthHandler.ReadinessCheck)
 
+	// Public keys for local token verification in other services
+	router.GET(authz.JWKSPath, jwksHandler.GetJWKS)
+
 	// Authentication endpoints
 	// v1 := router.Group("/api/v1")
 	v1 := router.Group("/v1")
@@ -103,10 +248,62 @@ func main() {
 		v1.POST("/login/phone", userHandler.LoginByPhone)
 		v1.POST("/register/phone", userHandler.RegisterByPhone)
 		v1.POST("/login/email", userHandler.LoginWithEmail)
+		v1.POST("/login/totp", userHandler.LoginWithTOTP)
+		v1.POST("/login/passwordless", userHandler.StartPasswordlessLogin)
+		v1.POST("/login/passwordless/verify", userHandler.VerifyPasswordlessLogin)
 		v1.POST("/register/email", userHandler.RegisterWithEmail)
+		v1.POST("/token/refresh", userHandler.RefreshToken)
+		v1.POST("/logout", userHandler.Logout)
+		v1.POST("/password/forgot", userHandler.ForgotPassword)
+		v1.POST("/password/reset", userHandler.ResetPassword)
+		v1.GET("/sessions", userHandler.ListSessions)
+		v1.DELETE("/sessions", userHandler.RevokeAllSessions)
+		v1.DELETE("/sessions/:sessionId", userHandler.RevokeSession)
+		v1.GET("/2fa/totp", userHandler.TOTPStatus)
+		v1.POST("/2fa/totp", userHandler.EnrollTOTP)
+		v1.POST("/2fa/totp/confirm", userHandler.ConfirmTOTP)
+		v1.POST("/2fa/totp/disable", userHandler.DisableTOTP)
+		v1.POST("/passkey/register/begin", userHandler.BeginPasskeyRegistration)
+		v1.POST("/passkey/register/finish", userHandler.FinishPasskeyRegistration)
+		v1.POST("/passkey/login/begin", userHandler.BeginPasskeyLogin)
+		v1.POST("/passkey/login/finish", userHandler.FinishPasskeyLogin)
+		v1.GET("/passkey", userHandler.ListPasskeys)
+		v1.DELETE("/passkey/:passkeyId", userHandler.DeletePasskey)
+		v1.GET("/account/merge", userHandler.ListAccountMerges)
+		v1.POST("/account/merge", userHandler.StartAccountMerge)
+		v1.POST("/account/merge/confirm", userHandler.ConfirmAccountMerge)
+
+		admin := v1.Group("/admin", userHandler.RequireAuth())
+		admin.GET("/audit", authz.RequirePermissionGin(authz.PermissionAuditRead), userHandler.ListAuditLog)
+		admin.POST("/impersonate", authz.RequirePermissionGin(authz.PermissionImpersonate), userHandler.Impersonate)
 	}
 
-	internalHandler.RegisterInternalRoutes(router)
+	internalHandler.RegisterInternalRoutes(router, serviceVerifier)
+
+	// Background workers stop with the server
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	go userService.RunOutboxDispatcher(workerCtx, service.OutboxConfig{
+		Interval:  cfg.OutboxInterval,
+		BatchSize: cfg.OutboxBatchSize,
+	})
+
+	if cfg.ReconcileInterval > 0 {
+		go userService.RunReconciler(workerCtx, service.ReconcileConfig{
+			Interval:    cfg.ReconcileInterval,
+			GracePeriod: cfg.ReconcileGracePeriod,
+			PageSize:    cfg.ReconcilePageSize,
+		})
+	}
+
+	if cfg.AuditRetention > 0 {
+		go userService.RunAuditRetention(workerCtx, service.AuditRetentionConfig{
+			Retention: cfg.AuditRetention,
+			Interval:  cfg.AuditPurgeInterval,
+			BatchSize: cfg.AuditPurgeBatchSize,
+		})
+	}
 
 	// Create HTTP server
 	server := &http.Server{
@@ -135,6 +332,7 @@ func main() {
 	defer cancel()
 
 	logger.Info("Shutting down server...")
+	stopWorkers()
 
 	// Shutdown HTTP server
 	if err := server.Shutdown(shutdownCtx); err != nil {
@@ -0,0 +1,31 @@
+package authz
+
+import "fmt"
+
+// DeleteAccountRequest - the password re-entered by the user, and a TOTP or
+// recovery code when the account has two-factor enabled. IPAddress counts
+// towards the same failed login limits as logins do.
+type DeleteAccountRequest struct {
+	Password  string `json:"password"`
+	TOTPCode  string `json:"totp_code"`
+	IPAddress string `json:"ip_address"`
+}
+
+// DeleteAccountResponse - the account is gone; the deletion stays pending until
+// its profile, products and files are removed
+type DeleteAccountResponse struct {
+	DeletionID string `json:"deletion_id"`
+	Status     string `json:"status"`
+}
+
+// DeleteAccount - Ask auth to delete the account after checking the password.
+// Auth then has Core remove everything else, retrying until it has.
+// POST /internal/userauth/:userAuthID/delete
+func (c *AuthClient) DeleteAccount(userAuthID string, req DeleteAccountRequest) (*DeleteAccountResponse, error) {
+	var result DeleteAccountResponse
+	url := fmt.Sprintf("%s/internal/userauth/%s/delete", c.baseURL, userAuthID)
+	if err := c.postJSON(url, req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -14,11 +14,14 @@ type AuthClient struct {
 	httpClient *http.Client
 }
 
-func NewAuthClient(baseURL string) *AuthClient {
+// NewAuthClient - Requests are signed as the calling service, /internal routes
+// reject anything unsigned
+func NewAuthClient(baseURL string, signer *ServiceSigner) *AuthClient {
 	return &AuthClient{
 		baseURL: baseURL,
 		httpClient: &http.Client{
-			Timeout: 5 * time.Second,
+			Timeout:   5 * time.Second,
+			Transport: signer.Transport(nil),
 		},
 	}
 }
@@ -30,9 +33,14 @@ type ValidateTokenRequest struct {
 
 // ValidateTokenResponse from internal API
 type ValidateTokenResponse struct {
-	Valid  bool   `json:"valid"`
-	UserID string `json:"user_id,omitempty"`
-	Error  string `json:"error,omitempty"`
+	Valid       bool     `json:"valid"`
+	UserID      string   `json:"user_id,omitempty"`
+	SessionID   string   `json:"session_id,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	Actor       *Actor   `json:"act,omitempty"`
+	ReadOnly    bool     `json:"read_only,omitempty"`
+	Error       string   `json:"error,omitempty"`
 }
 
 // UserInfo for user profile requests
@@ -72,66 +80,44 @@ func (c *AuthClient) ValidateTokenHTTP(token string) (*ValidateTokenResponse, er
 	return &result, nil
 }
 
-// GetUserInfo - Get user profile from auth service
-func (c *AuthClient) GetUserInfo(userID string) (*UserInfo, error) {
-	resp, err := c.httpClient.Get(
-		fmt.Sprintf("%s/internal/user/%s", c.baseURL, userID),
-	)
+// GetJWKS - Fetch the public keys used to sign access tokens
+func (c *AuthClient) GetJWKS() (*JWKS, error) {
+	resp, err := c.httpClient.Get(c.baseURL + JWKSPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("user not found")
+		return nil, fmt.Errorf("failed to fetch jwks, status: %d", resp.StatusCode)
 	}
 
-	var userInfo UserInfo
-	if err := json.NewDecoder(resp.Body).Decode(&userInfo); err != nil {
+	var jwks JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	return &userInfo, nil
+	return &jwks, nil
 }
 
-type UpdateUserAuthRequest struct {
-	Type  string `json:"type"`
-	Phone string `json:"phone"`
-	Email string `json:"email"`
-}
-
-// About link phone/email
-// Let's use single endpoint, single handler (with
-// Need your help to edit the handler/internal and service/internal, I can do the main, repo, and query level.
-
-func (c *AuthClient) UpdateUserAuth(userAuthID, phone, email string) error {
-	req := UpdateUserAuthRequest{
-		Phone: phone,
-		Email: email,
-	}
-
-	jsonData, err := json.Marshal(req)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	httpReq, err := http.NewRequest("PUT",
-		fmt.Sprintf("%s/internal/userauth/%s", c.baseURL, userAuthID),
-		bytes.NewBuffer(jsonData))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.httpClient.Do(httpReq)
+// GetUserInfo - Get user profile from auth service
+func (c *AuthClient) GetUserInfo(userID string) (*UserInfo, error) {
+	resp, err := c.httpClient.Get(
+		fmt.Sprintf("%s/internal/user/%s", c.baseURL, userID),
+	)
 	if err != nil {
-		return fmt.Errorf("failed to make request: %w", err)
+		return nil, fmt.Errorf("failed to make request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to update user auth, status: %d", resp.StatusCode)
+		return nil, fmt.Errorf("user not found")
+	}
+
+	var userInfo UserInfo
+	if err := json.NewDecoder(resp.Body).Decode(&userInfo); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	return nil
+	return &userInfo, nil
 }
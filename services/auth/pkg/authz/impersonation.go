@@ -0,0 +1,82 @@
+package authz
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Impersonation tokens let an admin act as another user. They carry the admin
+// in the act claim and are read-only unless the admin asked otherwise; the auth
+// middlewares enforce read-only. Routes that change credentials or bank details
+// refuse them outright with DenyImpersonation*, chained after the middleware:
+//
+//	user.Put("", authMiddleware.FiberMiddleware(), authz.DenyImpersonationFiber(), handler)
+
+const (
+	errReadOnlyToken       = "read-only token"
+	errImpersonationDenied = "not allowed while impersonating"
+)
+
+// allowsMethod - false when a read-only token tries anything but a safe method
+func allowsMethod(claims *Claims, method string) bool {
+	if !claims.ReadOnly {
+		return true
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// DenyImpersonationFiber - Refuse impersonation tokens
+func DenyImpersonationFiber() fiber.Handler {
+	return requireFiber(func(claims *Claims) bool {
+		_, impersonated := claims.Impersonator()
+		return !impersonated
+	}, errImpersonationDenied)
+}
+
+// DenyImpersonationGin - Refuse impersonation tokens
+func DenyImpersonationGin() gin.HandlerFunc {
+	return requireGin(func(claims *Claims) bool {
+		_, impersonated := claims.Impersonator()
+		return !impersonated
+	}, errImpersonationDenied)
+}
+
+// DenyImpersonationChi - Refuse impersonation tokens
+func DenyImpersonationChi() func(http.Handler) http.Handler {
+	return requireChi(func(claims *Claims) bool {
+		_, impersonated := claims.Impersonator()
+		return !impersonated
+	}, errImpersonationDenied)
+}
+
+// GetImpersonatorFromFiber - the admin behind the request when it was made with
+// an impersonation token
+func GetImpersonatorFromFiber(c *fiber.Ctx) (string, bool) {
+	claims, ok := GetClaimsFromFiber(c)
+	if !ok {
+		return "", false
+	}
+	return claims.Impersonator()
+}
+
+func GetImpersonatorFromChi(r *http.Request) (string, bool) {
+	claims, ok := GetClaimsFromChi(r)
+	if !ok {
+		return "", false
+	}
+	return claims.Impersonator()
+}
+
+func GetImpersonatorFromGin(c *gin.Context) (string, bool) {
+	claims, ok := GetClaimsFromGin(c)
+	if !ok {
+		return "", false
+	}
+	return claims.Impersonator()
+}
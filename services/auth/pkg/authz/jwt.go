@@ -2,17 +2,79 @@ package authz
 
 import (
 	"context"
+	"slices"
+)
+
+// Roles granted by the auth service
+const (
+	RoleBuyer  = "buyer"
+	RoleSeller = "seller"
+	RoleAdmin  = "admin"
+)
+
+// Permissions granted through roles, checked with RequirePermission*
+const (
+	PermissionProductCreate  = "product:create"
+	PermissionProductUpdate  = "product:update"
+	PermissionProductDelete  = "product:delete"
+	PermissionPurchaseCreate = "purchase:create"
+	PermissionAuditRead      = "audit:read"
+	PermissionImpersonate    = "user:impersonate"
+	PermissionCategoryManage = "category:manage"
 )
 
 // Claims - shared structure for responses (no JWT logic here)
 type Claims struct {
-	UserID string `json:"user_id"`
+	UserID      string   `json:"user_id"`
+	TokenID     string   `json:"token_id,omitempty"`
+	SessionID   string   `json:"session_id,omitempty"` // Login session, revoking it ends the token
+	IssuedAt    int64    `json:"issued_at,omitempty"`  // Unix seconds
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	Actor       *Actor   `json:"act,omitempty"`       // Set on impersonation tokens
+	ReadOnly    bool     `json:"read_only,omitempty"` // Only GET, HEAD and OPTIONS requests pass
+}
+
+// Actor - the admin acting as the token's user, the "act" claim of RFC 8693
+type Actor struct {
+	UserID string `json:"sub"`
+}
+
+// Impersonator - the admin's user id when the token is an impersonation token
+func (c *Claims) Impersonator() (string, bool) {
+	if c.Actor == nil || c.Actor.UserID == "" {
+		return "", false
+	}
+	return c.Actor.UserID, true
+}
+
+// HasRole - true when the token carries any of roles
+func (c *Claims) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if slices.Contains(c.Roles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission - true when the token carries every one of permissions
+func (c *Claims) HasPermission(permissions ...string) bool {
+	for _, permission := range permissions {
+		if !slices.Contains(c.Permissions, permission) {
+			return false
+		}
+	}
+	return true
 }
 
 // Context helpers
 type contextKey string
 
-const UserIDKey contextKey = "user_id"
+const (
+	UserIDKey contextKey = "user_id"
+	ClaimsKey contextKey = "claims"
+)
 
 func WithUserID(ctx context.Context, userID string) context.Context {
 	return context.WithValue(ctx, UserIDKey, userID)
@@ -22,3 +84,13 @@ func GetUserID(ctx context.Context) (string, bool) {
 	userID, ok := ctx.Value(UserIDKey).(string)
 	return userID, ok
 }
+
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	ctx = WithUserID(ctx, claims.UserID)
+	return context.WithValue(ctx, ClaimsKey, claims)
+}
+
+func GetClaims(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(ClaimsKey).(*Claims)
+	return claims, ok
+}
@@ -0,0 +1,181 @@
+package authz
+
+import (
+	"crypto"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// Audience of the access tokens the auth service issues, tokens minted for
+	// anything else are not accepted as access tokens
+	AccessTokenAudience = "tutuplapak-api"
+
+	DefaultKeyRefreshInterval = 5 * time.Minute
+	// Unknown kids trigger a refetch, but not more often than this
+	minKeyRefreshInterval = 30 * time.Second
+)
+
+// ErrKeysUnavailable - the token could not be checked locally as no JWKS has
+// loaded yet, callers should fall back to validating over HTTP
+var ErrKeysUnavailable = errors.New("signing key unavailable")
+
+var errUnknownKey = errors.New("unknown signing key")
+
+// tokenClaims mirrors the claims the auth service signs
+type tokenClaims struct {
+	UserID      string   `json:"user_id"`
+	SessionID   string   `json:"sid,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	Actor       *Actor   `json:"act,omitempty"`
+	ReadOnly    bool     `json:"read_only,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// KeySet - cached copy of the auth service JWKS for verifying tokens in-process
+type KeySet struct {
+	client          *AuthClient
+	issuer          string
+	refreshInterval time.Duration
+
+	mu        sync.RWMutex
+	keys      map[string]crypto.PublicKey
+	loaded    bool // A JWKS was fetched at least once
+	fetchedAt time.Time
+}
+
+func NewKeySet(client *AuthClient, issuer string, refreshInterval time.Duration) *KeySet {
+	return &KeySet{
+		client:          client,
+		issuer:          issuer,
+		refreshInterval: refreshInterval,
+		keys:            make(map[string]crypto.PublicKey),
+	}
+}
+
+// Start - Load keys now (best effort, auth may not be up yet) and keep them fresh
+func (k *KeySet) Start() {
+	k.Refresh()
+
+	go func() {
+		ticker := time.NewTicker(k.refreshInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			k.Refresh()
+		}
+	}()
+}
+
+// Refresh - Replace the cached keys with the current JWKS. On failure the previous
+// keys are kept so a short auth outage doesn't break verification.
+func (k *KeySet) Refresh() error {
+	k.mu.Lock()
+	k.fetchedAt = time.Now()
+	k.mu.Unlock()
+
+	jwks, err := k.client.GetJWKS()
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		publicKey, err := jwk.PublicKey()
+		if err != nil {
+			continue // Skip keys we can't use, keep the rest
+		}
+		keys[jwk.KeyID] = publicKey
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.loaded = true
+	k.mu.Unlock()
+
+	return nil
+}
+
+// Verify - Check signature, expiry, issuer and audience locally. Returns
+// ErrKeysUnavailable only while no JWKS has loaded; once one has, a kid that is
+// missing or still unknown after a refresh makes the token invalid, so made-up
+// tokens can't push every request onto the auth service. Revocation is not
+// checked here, see AuthMiddleware.
+func (k *KeySet) Verify(tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &tokenClaims{}, k.keyFunc,
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuer(k.issuer),
+		jwt.WithAudience(AccessTokenAudience),
+	)
+
+	if err != nil {
+		if errors.Is(err, ErrKeysUnavailable) {
+			return nil, ErrKeysUnavailable
+		}
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, errors.New("token has expired")
+		}
+		return nil, errors.New("invalid token")
+	}
+
+	claims, ok := token.Claims.(*tokenClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	result := &Claims{
+		UserID:      claims.UserID,
+		TokenID:     claims.ID,
+		SessionID:   claims.SessionID,
+		Roles:       claims.Roles,
+		Permissions: claims.Permissions,
+		Actor:       claims.Actor,
+		ReadOnly:    claims.ReadOnly,
+	}
+	if claims.IssuedAt != nil {
+		result.IssuedAt = claims.IssuedAt.Unix()
+	}
+	return result, nil
+}
+
+func (k *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid != "" {
+		if key, ok := k.lookup(kid); ok {
+			return key, nil
+		}
+	}
+
+	// Possibly a freshly rotated key, or auth was down when we started; refetch
+	// once in a while
+	k.mu.RLock()
+	stale := time.Since(k.fetchedAt) > minKeyRefreshInterval
+	k.mu.RUnlock()
+
+	if stale {
+		if err := k.Refresh(); err == nil && kid != "" {
+			if key, ok := k.lookup(kid); ok {
+				return key, nil
+			}
+		}
+	}
+
+	k.mu.RLock()
+	loaded := k.loaded
+	k.mu.RUnlock()
+	if !loaded {
+		return nil, ErrKeysUnavailable
+	}
+	return nil, errUnknownKey
+}
+
+func (k *KeySet) lookup(kid string) (crypto.PublicKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok := k.keys[kid]
+	return key, ok
+}
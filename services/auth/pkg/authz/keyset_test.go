@@ -0,0 +1,178 @@
+package authz
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testIssuer = "tutuplapak-auth"
+
+func newTestKeySet(t *testing.T) (*KeySet, ed25519.PrivateKey) {
+	t.Helper()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwk, err := NewJWK("test-key", publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(JWKS{Keys: []JWK{jwk}})
+	}))
+	t.Cleanup(server.Close)
+
+	signer, err := NewServiceSigner(ServiceCore, ServiceKeys{ServiceCore: []byte("core-secret-0123456789")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := NewKeySet(NewAuthClient(server.URL, signer), testIssuer, DefaultKeyRefreshInterval)
+	if err := keys.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	return keys, privateKey
+}
+
+func signTestToken(t *testing.T, key ed25519.PrivateKey, kid string, claims tokenClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func validTestClaims() tokenClaims {
+	now := time.Now()
+	return tokenClaims{
+		UserID:    "user-1",
+		SessionID: "session-1",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "token-1",
+			Issuer:    testIssuer,
+			Audience:  jwt.ClaimStrings{AccessTokenAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+	}
+}
+
+func TestKeySetVerify(t *testing.T) {
+	keys, privateKey := newTestKeySet(t)
+
+	claims, err := keys.Verify(signTestToken(t, privateKey, "test-key", validTestClaims()))
+	if err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+	if claims.UserID != "user-1" || claims.TokenID != "token-1" || claims.SessionID != "session-1" {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+}
+
+func TestKeySetVerifyRejects(t *testing.T) {
+	keys, privateKey := newTestKeySet(t)
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		key    ed25519.PrivateKey
+		modify func(*tokenClaims)
+		want   string
+	}{
+		{"wrong issuer", privateKey, func(c *tokenClaims) { c.Issuer = "someone-else" }, "invalid token"},
+		{"no issuer", privateKey, func(c *tokenClaims) { c.Issuer = "" }, "invalid token"},
+		{"wrong audience", privateKey, func(c *tokenClaims) { c.Audience = jwt.ClaimStrings{"other-api"} }, "invalid token"},
+		{"no audience", privateKey, func(c *tokenClaims) { c.Audience = nil }, "invalid token"},
+		{"no expiry", privateKey, func(c *tokenClaims) { c.ExpiresAt = nil }, "invalid token"},
+		{"expired", privateKey, func(c *tokenClaims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute)) }, "token has expired"},
+		{"wrong signature", otherKey, func(c *tokenClaims) {}, "invalid token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validTestClaims()
+			tt.modify(&claims)
+
+			_, err := keys.Verify(signTestToken(t, tt.key, "test-key", claims))
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeySetUnknownKid(t *testing.T) {
+	keys, privateKey := newTestKeySet(t)
+
+	// With the JWKS loaded, a kid it doesn't have makes the token invalid
+	// rather than sending it on to the auth service
+	for _, kid := range []string{"", "made-up"} {
+		_, err := keys.Verify(signTestToken(t, privateKey, kid, validTestClaims()))
+		if err == nil || err.Error() != "invalid token" {
+			t.Errorf("kid %q: got error %v, want invalid token", kid, err)
+		}
+	}
+}
+
+func TestKeySetNeverLoaded(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwk, err := NewJWK("test-key", publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	up, fetches := false, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		if !up {
+			http.Error(w, "starting", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(JWKS{Keys: []JWK{jwk}})
+	}))
+	t.Cleanup(server.Close)
+
+	signer, err := NewServiceSigner(ServiceCore, ServiceKeys{ServiceCore: []byte("core-secret-0123456789")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := NewKeySet(NewAuthClient(server.URL, signer), testIssuer, DefaultKeyRefreshInterval)
+
+	// Auth unreachable since startup, only the HTTP fallback can tell
+	if _, err := keys.Verify(signTestToken(t, privateKey, "made-up", validTestClaims())); err != ErrKeysUnavailable {
+		t.Fatalf("got error %v, want ErrKeysUnavailable", err)
+	}
+	if _, err := keys.Verify(signTestToken(t, privateKey, "made-up", validTestClaims())); err != ErrKeysUnavailable {
+		t.Fatalf("got error %v, want ErrKeysUnavailable", err)
+	}
+	if fetches != 1 {
+		t.Errorf("%d JWKS fetches, want refetches rate limited", fetches)
+	}
+
+	up = true
+	if err := keys.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keys.Verify(signTestToken(t, privateKey, "test-key", validTestClaims())); err != nil {
+		t.Errorf("valid token rejected once keys loaded: %v", err)
+	}
+}
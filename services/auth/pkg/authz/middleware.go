@@ -1,23 +1,102 @@
 package authz
 
 import (
+	"context"
+	"errors"
+	"net"
 	"net/http"
 
+	"github.com/gin-gonic/gin"
 	"github.com/gofiber/fiber/v2"
 )
 
 // AuthClient instance for middleware
 type AuthMiddleware struct {
-	client *AuthClient
+	client      *AuthClient
+	keys        *KeySet
+	revocations *Revocations
+	rejections  *rejectionReporter
 }
 
-func NewAuthMiddleware(authServiceURL string) *AuthMiddleware {
+// NewAuthMiddleware - issuer must match the auth service's JWT_ISSUER, revocations
+// reads the Redis the auth service writes its revocation markers to
+func NewAuthMiddleware(authServiceURL string, signer *ServiceSigner, issuer string, revocations *Revocations) *AuthMiddleware {
+	client := NewAuthClient(authServiceURL, signer)
+	keys := NewKeySet(client, issuer, DefaultKeyRefreshInterval)
+	keys.Start()
+	rejections := newRejectionReporter(client)
+	rejections.start(DefaultRejectionReportInterval)
+
 	return &AuthMiddleware{
-		client: NewAuthClient(authServiceURL),
+		client:      client,
+		keys:        keys,
+		revocations: revocations,
+		rejections:  rejections,
+	}
+}
+
+var errValidationUnavailable = errors.New("failed to validate token")
+
+// validate - Verify locally against the cached JWKS and the revocation markers in
+// Redis, only call the auth service while no JWKS has loaded (auth unreachable
+// since startup) or Redis can't be asked.
+//
+// Logout, session revocation and account deletion take effect on the next
+// request. What a token carries is not re-checked: role and permission changes
+// reach a token only when it is refreshed, up to JWT_DURATION later, and a
+// signing key dropped from the JWKS keeps verifying until the next key refresh,
+// up to DefaultKeyRefreshInterval later.
+//
+// Auth audits the tokens it rejects over HTTP itself, those rejected here are
+// reported to it in batches every DefaultRejectionReportInterval, so their audit
+// entries are up to that late. Past MaxTokenRejectionsPerReport per batch only
+// their number reaches the audit log.
+func (a *AuthMiddleware) validate(ctx context.Context, token string, client ClientInfo, requestID string) (*Claims, error) {
+	claims, err := a.keys.Verify(token)
+	if err == nil {
+		revoked, err := a.revocations.IsRevoked(ctx, claims)
+		if err == nil {
+			if revoked {
+				return nil, a.reject(errors.New("token has been revoked"), client, requestID)
+			}
+			return claims, nil
+		}
+		// The auth service also checks the session against its database
+	} else if !errors.Is(err, ErrKeysUnavailable) {
+		return nil, a.reject(err, client, requestID)
+	}
+
+	// Fallback: validate via HTTP call to auth service
+	response, err := a.client.ValidateTokenHTTP(token)
+	if err != nil {
+		return nil, errValidationUnavailable
 	}
+
+	if !response.Valid {
+		return nil, errors.New(response.Error)
+	}
+
+	return &Claims{
+		UserID:      response.UserID,
+		SessionID:   response.SessionID,
+		Roles:       response.Roles,
+		Permissions: response.Permissions,
+		Actor:       response.Actor,
+		ReadOnly:    response.ReadOnly,
+	}, nil
+}
+
+// reject - Queue err for auth's audit log and return it
+func (a *AuthMiddleware) reject(err error, client ClientInfo, requestID string) error {
+	a.rejections.add(TokenRejection{
+		Reason:     err.Error(),
+		RequestID:  requestID,
+		ClientInfo: client,
+	})
+	return err
 }
 
-// Fiber Middleware - Uses HTTP validation
+// Fiber Middleware - Uses local validation with HTTP fallback
 func (a *AuthMiddleware) FiberMiddleware() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
@@ -34,27 +113,28 @@ func (a *AuthMiddleware) FiberMiddleware() fiber.Handler {
 			token = authHeader[7:]
 		}
 
-		// Validate via HTTP call to auth service
-		response, err := a.client.ValidateTokenHTTP(token)
+		client := ClientInfo{IPAddress: c.IP(), UserAgent: c.Get(fiber.HeaderUserAgent)}
+		claims, err := a.validate(c.Context(), token, client, c.GetRespHeader(fiber.HeaderXRequestID))
 		if err != nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "failed to validate token",
+				"error": err.Error(),
 			})
 		}
 
-		if !response.Valid {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": response.Error,
+		if !allowsMethod(claims, c.Method()) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": errReadOnlyToken,
 			})
 		}
 
-		// Store user ID in context
-		c.Locals("user_id", response.UserID)
+		// Store user ID and claims in context
+		c.Locals("user_id", claims.UserID)
+		c.Locals("claims", claims)
 		return c.Next()
 	}
 }
 
-// Chi Middleware - Uses HTTP validation
+// Chi Middleware - Uses local validation with HTTP fallback
 func (a *AuthMiddleware) ChiMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
@@ -70,24 +150,69 @@ func (a *AuthMiddleware) ChiMiddleware(next http.Handler) http.Handler {
 			token = authHeader[7:]
 		}
 
-		// Validate via HTTP call to auth service
-		response, err := a.client.ValidateTokenHTTP(token)
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+		client := ClientInfo{IPAddress: ip, UserAgent: r.UserAgent()}
+		claims, err := a.validate(r.Context(), token, client, r.Header.Get("X-Request-ID"))
 		if err != nil {
-			http.Error(w, "failed to validate token", http.StatusUnauthorized)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
 
-		if !response.Valid {
-			http.Error(w, response.Error, http.StatusUnauthorized)
+		if !allowsMethod(claims, r.Method) {
+			http.Error(w, errReadOnlyToken, http.StatusForbidden)
 			return
 		}
 
-		// Store user ID in context
-		ctx := WithUserID(r.Context(), response.UserID)
+		// Store user ID and claims in context
+		ctx := WithClaims(r.Context(), claims)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// Gin Middleware - Uses local validation with HTTP fallback
+func (a *AuthMiddleware) GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+
+		if authHeader == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "authorization token required",
+			})
+			return
+		}
+
+		// Extract token
+		token := authHeader
+		if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
+			token = authHeader[7:]
+		}
+
+		client := ClientInfo{IPAddress: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+		claims, err := a.validate(c.Request.Context(), token, client, c.Writer.Header().Get("X-Request-ID"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		if !allowsMethod(claims, c.Request.Method) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": errReadOnlyToken,
+			})
+			return
+		}
+
+		// Store user ID and claims in context
+		c.Set("user_id", claims.UserID)
+		c.Set("claims", claims)
+		c.Next()
+	}
+}
+
 // Helper functions for getting user ID from context
 func GetUserIDFromFiber(c *fiber.Ctx) (string, bool) {
 	userID := c.Locals("user_id")
@@ -100,3 +225,27 @@ func GetUserIDFromFiber(c *fiber.Ctx) (string, bool) {
 func GetUserIDFromChi(r *http.Request) (string, bool) {
 	return GetUserID(r.Context())
 }
+
+func GetUserIDFromGin(c *gin.Context) (string, bool) {
+	userID := c.GetString("user_id")
+	return userID, userID != ""
+}
+
+// Helper functions for getting the verified claims from context
+func GetClaimsFromFiber(c *fiber.Ctx) (*Claims, bool) {
+	claims, ok := c.Locals("claims").(*Claims)
+	return claims, ok
+}
+
+func GetClaimsFromChi(r *http.Request) (*Claims, bool) {
+	return GetClaims(r.Context())
+}
+
+func GetClaimsFromGin(c *gin.Context) (*Claims, bool) {
+	value, exists := c.Get("claims")
+	if !exists {
+		return nil, false
+	}
+	claims, ok := value.(*Claims)
+	return claims, ok
+}
@@ -0,0 +1,104 @@
+package authz
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Role and permission checks. They read the claims stored by the matching auth
+// middleware, so they must be chained after it:
+//
+//	products.Post("", authMiddleware.FiberMiddleware(), authz.RequirePermissionFiber(authz.PermissionProductCreate), handler)
+//
+// Roles and permissions are embedded in the access token, so a change only takes
+// effect once the token is refreshed.
+
+const errInsufficientPermissions = "insufficient permissions"
+
+// RequireRoleFiber - Allow the request when the token carries any of roles
+func RequireRoleFiber(roles ...string) fiber.Handler {
+	return requireFiber(func(claims *Claims) bool { return claims.HasRole(roles...) }, errInsufficientPermissions)
+}
+
+// RequirePermissionFiber - Allow the request when the token carries all permissions
+func RequirePermissionFiber(permissions ...string) fiber.Handler {
+	return requireFiber(func(claims *Claims) bool { return claims.HasPermission(permissions...) }, errInsufficientPermissions)
+}
+
+func requireFiber(allowed func(*Claims) bool, denied string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := GetClaimsFromFiber(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "authorization token required",
+			})
+		}
+
+		if !allowed(claims) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": denied,
+			})
+		}
+		return c.Next()
+	}
+}
+
+// RequireRoleGin - Allow the request when the token carries any of roles
+func RequireRoleGin(roles ...string) gin.HandlerFunc {
+	return requireGin(func(claims *Claims) bool { return claims.HasRole(roles...) }, errInsufficientPermissions)
+}
+
+// RequirePermissionGin - Allow the request when the token carries all permissions
+func RequirePermissionGin(permissions ...string) gin.HandlerFunc {
+	return requireGin(func(claims *Claims) bool { return claims.HasPermission(permissions...) }, errInsufficientPermissions)
+}
+
+func requireGin(allowed func(*Claims) bool, denied string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetClaimsFromGin(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "authorization token required",
+			})
+			return
+		}
+
+		if !allowed(claims) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": denied,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireRoleChi - Allow the request when the token carries any of roles
+func RequireRoleChi(roles ...string) func(http.Handler) http.Handler {
+	return requireChi(func(claims *Claims) bool { return claims.HasRole(roles...) }, errInsufficientPermissions)
+}
+
+// RequirePermissionChi - Allow the request when the token carries all permissions
+func RequirePermissionChi(permissions ...string) func(http.Handler) http.Handler {
+	return requireChi(func(claims *Claims) bool { return claims.HasPermission(permissions...) }, errInsufficientPermissions)
+}
+
+func requireChi(allowed func(*Claims) bool, denied string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := GetClaimsFromChi(r)
+			if !ok {
+				http.Error(w, "authorization token required", http.StatusUnauthorized)
+				return
+			}
+
+			if !allowed(claims) {
+				http.Error(w, denied, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package authz
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// Local rejections are sent to auth in batches, at most this often
+	DefaultRejectionReportInterval = 10 * time.Second
+	// Rejections held between reports, beyond it they are only counted. Auth
+	// records no more than this from one report.
+	MaxTokenRejectionsPerReport = 100
+)
+
+// TokenRejection - a token a service turned away on its own, without asking auth
+type TokenRejection struct {
+	Reason    string `json:"reason"`
+	RequestID string `json:"request_id,omitempty"`
+	ClientInfo
+}
+
+// TokenRejectionReport - the rejections since the last report, Dropped counts
+// those that didn't fit in the batch or were lost with a failed report
+type TokenRejectionReport struct {
+	Rejections []TokenRejection `json:"rejections"`
+	Dropped    int              `json:"dropped,omitempty"`
+}
+
+// ReportTokenRejections - Hand locally rejected tokens to auth's audit log
+// POST /internal/audit/token-rejections
+func (c *AuthClient) ReportTokenRejections(report TokenRejectionReport) error {
+	jsonData, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := c.httpClient.Post(
+		c.baseURL+"/internal/audit/token-rejections",
+		"application/json",
+		bytes.NewBuffer(jsonData),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to report token rejections, status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// rejectionReporter - buffers local rejections so a flood of bad tokens costs
+// auth one request per interval, not one per token
+type rejectionReporter struct {
+	client *AuthClient
+
+	mu      sync.Mutex
+	pending []TokenRejection
+	dropped int
+}
+
+func newRejectionReporter(client *AuthClient) *rejectionReporter {
+	return &rejectionReporter{client: client}
+}
+
+// start - Report every interval until the process exits
+func (r *rejectionReporter) start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			r.flush()
+		}
+	}()
+}
+
+func (r *rejectionReporter) add(rejection TokenRejection) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.pending) >= MaxTokenRejectionsPerReport {
+		r.dropped++
+		return
+	}
+	r.pending = append(r.pending, rejection)
+}
+
+// flush - Send what is pending. A failed report is not retried, its rejections
+// are counted as dropped in the next one.
+func (r *rejectionReporter) flush() error {
+	r.mu.Lock()
+	report := TokenRejectionReport{Rejections: r.pending, Dropped: r.dropped}
+	r.pending, r.dropped = nil, 0
+	r.mu.Unlock()
+
+	if len(report.Rejections) == 0 && report.Dropped == 0 {
+		return nil
+	}
+
+	err := r.client.ReportTokenRejections(report)
+	if err != nil {
+		r.mu.Lock()
+		r.dropped += len(report.Rejections) + report.Dropped
+		r.mu.Unlock()
+	}
+	return err
+}
@@ -0,0 +1,87 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestLocalRejectionsReported(t *testing.T) {
+	keys, privateKey := newTestKeySet(t)
+
+	up := true
+	var reports []TokenRejectionReport
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up {
+			http.Error(w, "starting", http.StatusServiceUnavailable)
+			return
+		}
+		var report TokenRejectionReport
+		if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+			t.Errorf("undecodable report: %v", err)
+		}
+		reports = append(reports, report)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	signer, err := NewServiceSigner(ServiceCore, ServiceKeys{ServiceCore: []byte("core-secret-0123456789")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewAuthClient(server.URL, signer)
+	middleware := &AuthMiddleware{client: client, keys: keys, rejections: newRejectionReporter(client)}
+
+	claims := validTestClaims()
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	expired := signTestToken(t, privateKey, "test-key", claims)
+	browser := ClientInfo{IPAddress: "203.0.113.7", UserAgent: "Mozilla/5.0"}
+
+	// One past the batch is only counted
+	for range MaxTokenRejectionsPerReport + 1 {
+		if _, err := middleware.validate(context.Background(), expired, browser, "req-1"); err == nil {
+			t.Fatal("expired token accepted")
+		}
+	}
+	if err := middleware.rejections.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 {
+		t.Fatalf("%d reports sent, want 1", len(reports))
+	}
+	report := reports[0]
+	if len(report.Rejections) != MaxTokenRejectionsPerReport || report.Dropped != 1 {
+		t.Errorf("got %d rejections, %d dropped, want %d and 1", len(report.Rejections), report.Dropped, MaxTokenRejectionsPerReport)
+	}
+	want := TokenRejection{Reason: "token has expired", RequestID: "req-1", ClientInfo: browser}
+	if report.Rejections[0] != want {
+		t.Errorf("got rejection %+v, want %+v", report.Rejections[0], want)
+	}
+
+	// Nothing pending, nothing sent
+	if err := middleware.rejections.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 {
+		t.Errorf("%d reports sent, want no empty report", len(reports))
+	}
+
+	// A report auth didn't take is counted in the next one
+	up = false
+	middleware.validate(context.Background(), expired, browser, "req-2")
+	if err := middleware.rejections.flush(); err == nil {
+		t.Fatal("failed report not reported as failed")
+	}
+	up = true
+	if err := middleware.rejections.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if last := reports[len(reports)-1]; len(last.Rejections) != 0 || last.Dropped != 1 {
+		t.Errorf("got %d rejections, %d dropped, want the lost one counted", len(last.Rejections), last.Dropped)
+	}
+}
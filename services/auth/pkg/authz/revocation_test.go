@@ -0,0 +1,44 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/teammachinist/tutuplapak/services/auth/pkg/redistest"
+)
+
+func TestRevocationsUserMarker(t *testing.T) {
+	server := redistest.Start(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	revocations := NewRevocations(client)
+
+	revokedAt := time.Now().Unix()
+	server.Set(fmt.Sprintf(RevokedUserKey, "user-1"), strconv.FormatInt(revokedAt, 10))
+
+	tests := []struct {
+		name     string
+		issuedAt int64
+		want     bool
+	}{
+		{"before", revokedAt - 1, true},
+		{"same second", revokedAt, true},
+		{"after", revokedAt + 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revoked, err := revocations.IsRevoked(context.Background(), &Claims{UserID: "user-1", TokenID: "token-1", IssuedAt: tt.issuedAt})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if revoked != tt.want {
+				t.Errorf("revoked %v, want %v", revoked, tt.want)
+			}
+		})
+	}
+}
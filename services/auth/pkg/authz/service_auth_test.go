@@ -0,0 +1,182 @@
+package authz
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testServiceKeys = ServiceKeys{
+	ServiceAuth:  []byte("auth-secret-0123456789"),
+	ServiceCore:  []byte("core-secret-0123456789"),
+	ServiceFiles: []byte("files-secret-0123456789"),
+}
+
+func signedRequest(t *testing.T, service, method, target, body string) *http.Request {
+	t.Helper()
+
+	signer, err := NewServiceSigner(service, testServiceKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	if err := signer.Sign(req); err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func verify(v *ServiceVerifier, req *http.Request) (string, error) {
+	body, _ := readBody(req)
+	return v.Verify(req.Method, req.URL.RequestURI(), req.Header, body)
+}
+
+func TestServiceSignatureVerifies(t *testing.T) {
+	v := NewServiceVerifier(testServiceKeys)
+	req := signedRequest(t, ServiceCore, http.MethodPost, "/internal/users?merge=true", `{"userId":"1"}`)
+
+	caller, err := verify(v, req)
+	if err != nil {
+		t.Fatalf("signed request rejected: %v", err)
+	}
+	if caller != ServiceCore {
+		t.Errorf("caller %q, want %q", caller, ServiceCore)
+	}
+
+	// Signing puts the body back for the handler
+	if body, _ := io.ReadAll(req.Body); string(body) != `{"userId":"1"}` {
+		t.Errorf("body after verifying %q", body)
+	}
+}
+
+func TestServiceSignatureRejectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(*http.Request)
+		want   string
+	}{
+		{"body", func(r *http.Request) {
+			r.Body = io.NopCloser(strings.NewReader(`{"userId":"2"}`))
+		}, "invalid service signature"},
+		{"method", func(r *http.Request) { r.Method = http.MethodDelete }, "invalid service signature"},
+		{"path", func(r *http.Request) { r.URL.Path = "/internal/admins" }, "invalid service signature"},
+		{"query", func(r *http.Request) { r.URL.RawQuery = "merge=false" }, "invalid service signature"},
+		{"nonce", func(r *http.Request) { r.Header.Set(HeaderServiceNonce, "00000000000000000000000000000000") }, "invalid service signature"},
+		{"claimed service", func(r *http.Request) { r.Header.Set(HeaderServiceName, ServiceFiles) }, "invalid service signature"},
+		{"unknown service", func(r *http.Request) { r.Header.Set(HeaderServiceName, "billing") }, "unknown service"},
+		{"missing signature", func(r *http.Request) { r.Header.Del(HeaderServiceSignature) }, errServiceUnauthorized.Error()},
+		{"timestamp", func(r *http.Request) {
+			r.Header.Set(HeaderServiceTimestamp, strconv.FormatInt(time.Now().Unix()+1, 10))
+		}, "invalid service signature"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewServiceVerifier(testServiceKeys)
+			req := signedRequest(t, ServiceCore, http.MethodPost, "/internal/users?merge=true", `{"userId":"1"}`)
+			tt.tamper(req)
+
+			if _, err := verify(v, req); err == nil || err.Error() != tt.want {
+				t.Errorf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceSignatureWrongKey(t *testing.T) {
+	// The receiving side holds a different secret for core
+	v := NewServiceVerifier(ServiceKeys{ServiceCore: []byte("another-core-secret-0123")})
+	req := signedRequest(t, ServiceCore, http.MethodGet, "/internal/users/1", "")
+
+	if _, err := verify(v, req); err == nil || err.Error() != "invalid service signature" {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestServiceSignatureExpires(t *testing.T) {
+	v := NewServiceVerifier(testServiceKeys)
+
+	for _, age := range []time.Duration{ServiceSignatureMaxAge + time.Second, -ServiceSignatureMaxAge - time.Second} {
+		req := httptest.NewRequest(http.MethodGet, "/internal/users/1", nil)
+		timestamp := strconv.FormatInt(time.Now().Add(-age).Unix(), 10)
+		req.Header.Set(HeaderServiceName, ServiceCore)
+		req.Header.Set(HeaderServiceTimestamp, timestamp)
+		req.Header.Set(HeaderServiceNonce, "0123456789abcdef")
+		req.Header.Set(HeaderServiceSignature, signRequest(testServiceKeys[ServiceCore], http.MethodGet, "/internal/users/1", timestamp, "0123456789abcdef", nil))
+
+		if _, err := verify(v, req); err == nil || err.Error() != "service signature expired" {
+			t.Errorf("signature %v old: got error %v", age, err)
+		}
+	}
+}
+
+func TestServiceSignatureReplay(t *testing.T) {
+	v := NewServiceVerifier(testServiceKeys)
+	req := signedRequest(t, ServiceCore, http.MethodPost, "/internal/users", `{}`)
+	replay := req.Clone(req.Context())
+	replay.Body = io.NopCloser(strings.NewReader(`{}`))
+
+	if _, err := verify(v, req); err != nil {
+		t.Fatalf("first use rejected: %v", err)
+	}
+	if _, err := verify(v, replay); err == nil || err.Error() != "service signature already used" {
+		t.Errorf("replay: got error %v", err)
+	}
+}
+
+func TestInternalOnlyChi(t *testing.T) {
+	v := NewServiceVerifier(testServiceKeys)
+	handler := v.InternalOnlyChi(ServiceAuth)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{"allowed service", signedRequest(t, ServiceAuth, http.MethodPost, "/internal/files", `{"id":1}`), http.StatusOK},
+		{"other service", signedRequest(t, ServiceCore, http.MethodPost, "/internal/files", `{"id":1}`), http.StatusForbidden},
+		{"unsigned", httptest.NewRequest(http.MethodPost, "/internal/files", strings.NewReader(`{"id":1}`)), http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, tt.req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusOK && rec.Body.String() != `{"id":1}` {
+				t.Errorf("handler saw body %q", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestParseServiceKeys(t *testing.T) {
+	keys, err := ParseServiceKeys(" auth:auth-secret-0123456789 , core:core-secret-0123456789,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || string(keys[ServiceCore]) != "core-secret-0123456789" {
+		t.Errorf("unexpected keys %v", keys)
+	}
+
+	for _, value := range []string{"core", "core:short", ":core-secret-0123456789"} {
+		if _, err := ParseServiceKeys(value); err == nil {
+			t.Errorf("%q accepted", value)
+		}
+	}
+}
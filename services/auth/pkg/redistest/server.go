@@ -0,0 +1,393 @@
+// Package redistest runs an in-memory stand-in for Redis, so the services' Redis
+// code (caches, throttles, revocation markers) can be tested without a server.
+package redistest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Server - speaks enough RESP2 for the commands the services send. Keys
+// expire by the server's own clock, which only moves with FastForward.
+type Server struct {
+	listener net.Listener
+
+	mu      sync.Mutex
+	data    map[string]entry
+	now     time.Time
+	failure string // Returned for every data command while set
+}
+
+type entry struct {
+	value     string
+	expiresAt time.Time // Zero when the key doesn't expire
+}
+
+// Start - A server listening on a free local port until the test ends
+func Start(t testing.TB) *Server {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("redistest: %v", err)
+	}
+
+	s := &Server{
+		listener: listener,
+		data:     make(map[string]entry),
+		now:      time.Now(),
+	}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// FastForward - Move the clock, expiring keys whose TTL ran out
+func (s *Server) FastForward(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = s.now.Add(d)
+}
+
+// SetFailure - Answer every data command with an error until cleared with "",
+// as a Redis that is down or out of memory would
+func (s *Server) SetFailure(message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failure = message
+}
+
+// Get - The raw value of key, skipping the protocol
+func (s *Server) Get(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.lookup(key)
+	return e.value, ok
+}
+
+// Set - Store a raw value without expiry, skipping the protocol
+func (s *Server) Set(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = entry{value: value}
+}
+
+// TTL - Time until key expires, 0 when it doesn't exist or doesn't expire
+func (s *Server) TTL(key string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.lookup(key)
+	if !ok || e.expiresAt.IsZero() {
+		return 0
+	}
+	return e.expiresAt.Sub(s.now)
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	var queued [][]string
+	inMulti := false
+
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		switch name := strings.ToUpper(args[0]); {
+		case name == "MULTI":
+			inMulti = true
+			queued = nil
+			w.WriteString("+OK\r\n")
+		case name == "EXEC" && inMulti:
+			inMulti = false
+			replies := s.execAll(queued)
+			fmt.Fprintf(w, "*%d\r\n", len(replies))
+			for _, reply := range replies {
+				w.WriteString(reply)
+			}
+		case name == "DISCARD" && inMulti:
+			inMulti = false
+			w.WriteString("+OK\r\n")
+		case inMulti:
+			queued = append(queued, args)
+			w.WriteString("+QUEUED\r\n")
+		default:
+			w.WriteString(s.execAll([][]string{args})[0])
+		}
+
+		// Pipelined commands are answered together
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// execAll - Run commands under one lock, so a MULTI block is atomic
+func (s *Server) execAll(commands [][]string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	replies := make([]string, len(commands))
+	for i, args := range commands {
+		replies[i] = s.exec(strings.ToUpper(args[0]), args[1:])
+	}
+	return replies
+}
+
+func (s *Server) exec(name string, args []string) string {
+	switch name {
+	case "HELLO":
+		return "-ERR unknown command 'HELLO'\r\n" // Keeps the client on RESP2
+	case "PING":
+		return "+PONG\r\n"
+	case "CLIENT", "SELECT", "AUTH":
+		return "+OK\r\n"
+	}
+
+	if s.failure != "" {
+		return "-ERR " + s.failure + "\r\n"
+	}
+
+	switch name {
+	case "GET":
+		if len(args) != 1 {
+			return errArgs(name)
+		}
+		e, ok := s.lookup(args[0])
+		if !ok {
+			return nullBulk
+		}
+		return bulk(e.value)
+
+	case "MGET":
+		reply := fmt.Sprintf("*%d\r\n", len(args))
+		for _, key := range args {
+			if e, ok := s.lookup(key); ok {
+				reply += bulk(e.value)
+			} else {
+				reply += nullBulk
+			}
+		}
+		return reply
+
+	case "SET":
+		return s.set(args)
+
+	case "DEL", "EXISTS":
+		count := 0
+		for _, key := range args {
+			if _, ok := s.lookup(key); ok {
+				count++
+				if name == "DEL" {
+					delete(s.data, key)
+				}
+			}
+		}
+		return integer(int64(count))
+
+	case "INCR":
+		if len(args) != 1 {
+			return errArgs(name)
+		}
+		e, _ := s.lookup(args[0])
+		n := int64(0)
+		if e.value != "" {
+			var err error
+			if n, err = strconv.ParseInt(e.value, 10, 64); err != nil {
+				return "-ERR value is not an integer or out of range\r\n"
+			}
+		}
+		n++
+		e.value = strconv.FormatInt(n, 10)
+		s.data[args[0]] = e
+		return integer(n)
+
+	case "EXPIRE", "PEXPIRE":
+		if len(args) < 2 {
+			return errArgs(name)
+		}
+		amount, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return "-ERR value is not an integer or out of range\r\n"
+		}
+		unit := time.Second
+		if name == "PEXPIRE" {
+			unit = time.Millisecond
+		}
+		e, ok := s.lookup(args[0])
+		if !ok {
+			return integer(0)
+		}
+		if len(args) > 2 && strings.EqualFold(args[2], "NX") && !e.expiresAt.IsZero() {
+			return integer(0)
+		}
+		e.expiresAt = s.now.Add(time.Duration(amount) * unit)
+		s.data[args[0]] = e
+		return integer(1)
+
+	case "TTL", "PTTL":
+		if len(args) != 1 {
+			return errArgs(name)
+		}
+		e, ok := s.lookup(args[0])
+		switch {
+		case !ok:
+			return integer(-2)
+		case e.expiresAt.IsZero():
+			return integer(-1)
+		case name == "PTTL":
+			return integer(e.expiresAt.Sub(s.now).Milliseconds())
+		default:
+			return integer(int64((e.expiresAt.Sub(s.now) + time.Second/2) / time.Second))
+		}
+
+	default:
+		return "-ERR unknown command '" + name + "'\r\n"
+	}
+}
+
+// set - SET key value [EX seconds | PX milliseconds | KEEPTTL] [NX | XX]
+func (s *Server) set(args []string) string {
+	if len(args) < 2 {
+		return errArgs("SET")
+	}
+	key := args[0]
+	existing, exists := s.lookup(key)
+	e := entry{value: args[1]}
+
+	for i := 2; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "EX", "PX":
+			if i+1 >= len(args) {
+				return "-ERR syntax error\r\n"
+			}
+			amount, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil || amount <= 0 {
+				return "-ERR invalid expire time in 'set' command\r\n"
+			}
+			unit := time.Second
+			if strings.EqualFold(args[i], "PX") {
+				unit = time.Millisecond
+			}
+			e.expiresAt = s.now.Add(time.Duration(amount) * unit)
+			i++
+		case "KEEPTTL":
+			e.expiresAt = existing.expiresAt
+		case "NX":
+			if exists {
+				return nullBulk
+			}
+		case "XX":
+			if !exists {
+				return nullBulk
+			}
+		default:
+			return "-ERR syntax error\r\n"
+		}
+	}
+
+	s.data[key] = e
+	return "+OK\r\n"
+}
+
+// lookup - The live entry for key, dropping it when it has expired
+func (s *Server) lookup(key string) (entry, bool) {
+	e, ok := s.data[key]
+	if !ok {
+		return entry{}, false
+	}
+	if !e.expiresAt.IsZero() && !s.now.Before(e.expiresAt) {
+		delete(s.data, key)
+		return entry{}, false
+	}
+	return e, true
+}
+
+const nullBulk = "$-1\r\n"
+
+func bulk(value string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+}
+
+func integer(n int64) string {
+	return fmt.Sprintf(":%d\r\n", n)
+}
+
+func errArgs(name string) string {
+	return "-ERR wrong number of arguments for '" + strings.ToLower(name) + "' command\r\n"
+}
+
+// readCommand - One array of bulk strings, the only form clients send commands in
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '*' {
+		return nil, errors.New("redistest: expected an array")
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		header, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(header) == 0 || header[0] != '$' {
+			return nil, errors.New("redistest: expected a bulk string")
+		}
+		size, err := strconv.Atoi(header[1:])
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\r\n"), nil
+}
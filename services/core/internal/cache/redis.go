@@ -21,22 +21,24 @@ type CacheConfig struct {
 
 // Cache key constants for consistency
 const (
-	UserFileListKey = "user:files:%s"    // user:files:{userID}
-	FileMetadataKey = "file:metadata:%s" // file:metadata:{fileID}
-	FileExistsKey   = "file:exists:%s"   // file:exists:{fileID}
-	ProductListKey  = "products:list:%s" // products:list:{filters_hash}
-	ProductKey      = "product:%s"       // product:{productID}
-	UserProfileKey  = "user:profile:%s"  // user:profile:{userID}
+	UserFileListKey = "user:files:%s"         // user:files:{userID}
+	FileMetadataKey = "core:file:metadata:%s" // core:file:metadata:{fileID}, apart from the files service's own
+	FileExistsKey   = "file:exists:%s"        // file:exists:{fileID}
+	ProductListKey  = "products:list:%d:%s"   // products:list:{generation}:{filters_hash}
+	UserProfileKey  = "user:profile:%s"       // user:profile:{userID}
+	CategoryListKey = "categories:all"        // every category, active or not
+
+	ProductListGenerationKey = "products:list:generation" // bumped on every product write
 )
 
 // TTL constants for different data types
 const (
-	FileMetadataTTL = 1 * time.Hour    // File metadata rarely changes
+	FileMetadataTTL = 5 * time.Minute  // Short, so deleted files drop out soon
 	FileListTTL     = 30 * time.Minute // User file lists change more often
 	FileExistsTTL   = 5 * time.Minute  // Quick existence checks
 	ProductListTTL  = 10 * time.Minute // Product search results
-	ProductTTL      = 30 * time.Minute // Individual products
 	UserProfileTTL  = 15 * time.Minute // User profiles
+	CategoryListTTL = 10 * time.Minute // Dropped on every category change
 )
 
 func NewRedisCache(config CacheConfig) *RedisCache {
@@ -106,6 +108,31 @@ func (c *RedisCache) Delete(ctx context.Context, key string) error {
 	return err
 }
 
+// Generation - The counter at key, 0 until BumpGeneration first advances it.
+// Keys built from it are retired together by the next bump.
+func (c *RedisCache) Generation(ctx context.Context, key string) (int64, error) {
+	generation, err := c.client.Get(ctx, key).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		logger.ErrorCtx(ctx, "Redis GET generation failed", "key", key, "error", err)
+	}
+	return generation, err
+}
+
+// BumpGeneration - Advance the counter at key, leaving entries cached under the
+// old generation unread until they expire
+func (c *RedisCache) BumpGeneration(ctx context.Context, key string) error {
+	err := c.client.Incr(ctx, key).Err()
+	if err != nil {
+		logger.ErrorCtx(ctx, "Redis BUMP generation failed", "key", key, "error", err)
+	} else {
+		logger.DebugCtx(ctx, "Redis BUMP generation success", "key", key)
+	}
+	return err
+}
+
 func (c *RedisCache) Exists(ctx context.Context, key string) (bool, error) {
 	result, err := c.client.Exists(ctx, key).Result()
 	if err != nil {
@@ -126,6 +153,11 @@ func (c *RedisCache) Ping(ctx context.Context) error {
 	return err
 }
 
+// Client - The underlying connection, for packages that read Redis directly
+func (c *RedisCache) Client() *redis.Client {
+	return c.client
+}
+
 func (c *RedisCache) Close() error {
 	logger.Info("Closing Redis connection")
 	return c.client.Close()
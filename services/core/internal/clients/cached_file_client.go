@@ -0,0 +1,123 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/teammachinist/tutuplapak/services/core/internal/cache"
+	"github.com/teammachinist/tutuplapak/services/core/internal/logger"
+)
+
+// CachedFileClient - A FileClientInterface that reads file metadata through
+// Redis, keyed by file ID. A lookup asks the files service only for the files
+// Redis doesn't have, all of them in one call.
+type CachedFileClient struct {
+	FileClientInterface
+	cache *cache.RedisCache
+}
+
+func NewCachedFileClient(client FileClientInterface, cache *cache.RedisCache) *CachedFileClient {
+	return &CachedFileClient{
+		FileClientInterface: client,
+		cache:               cache,
+	}
+}
+
+func (c *CachedFileClient) GetFileByID(ctx context.Context, fileID uuid.UUID) (*FileMetadataResponse, error) {
+	files, err := c.GetFilesByIDList(ctx, []string{fileID.String()})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, errors.New("file not found")
+	}
+	return files[0], nil
+}
+
+// GetFilesByIDList - The files of fileIDs that exist, from Redis where cached.
+// Files the files service doesn't know are left out and not cached.
+func (c *CachedFileClient) GetFilesByIDList(ctx context.Context, fileIDs []string) ([]*FileMetadataResponse, error) {
+	keys := make([]string, len(fileIDs))
+	for i, id := range fileIDs {
+		keys[i] = fmt.Sprintf(cache.FileMetadataKey, id)
+	}
+
+	// Without Redis every file comes from the files service
+	cached, err := c.cache.GetMultiple(ctx, keys)
+	if err != nil {
+		cached = nil
+	}
+
+	files := []*FileMetadataResponse{}
+	var missing []string
+	seen := make(map[string]bool, len(fileIDs))
+	for i, id := range fileIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		if data, ok := cached[keys[i]]; ok {
+			var file FileMetadataResponse
+			if err := json.Unmarshal([]byte(data), &file); err == nil {
+				files = append(files, &file)
+				continue
+			}
+		}
+		missing = append(missing, id)
+	}
+	if len(missing) == 0 {
+		return files, nil
+	}
+
+	fetched, err := c.FileClientInterface.GetFilesByIDList(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(fetched) > 0 {
+		entries := make(map[string]interface{}, len(fetched))
+		for _, file := range fetched {
+			entries[fmt.Sprintf(cache.FileMetadataKey, file.ID.String())] = file
+		}
+		if err := c.cache.SetMultiple(ctx, entries, cache.FileMetadataTTL); err != nil {
+			logger.WarnCtx(ctx, "Failed to cache file metadata", "error", err.Error())
+		}
+	}
+
+	logger.DebugCtx(ctx, "File metadata fetched", "cached", len(files), "fetched", len(fetched), "missing", len(missing)-len(fetched))
+	return append(files, fetched...), nil
+}
+
+// ReassignFiles - Move the files, then drop the cached copies naming the old owner
+func (c *CachedFileClient) ReassignFiles(ctx context.Context, fromUserID, toUserID uuid.UUID) ([]uuid.UUID, error) {
+	fileIDs, err := c.FileClientInterface.ReassignFiles(ctx, fromUserID, toUserID)
+	if err != nil {
+		return nil, err
+	}
+	c.forget(ctx, fileIDs)
+	return fileIDs, nil
+}
+
+// DeleteUserFiles - Delete the files, then drop their cached copies so they
+// stop resolving before the cache expires
+func (c *CachedFileClient) DeleteUserFiles(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	fileIDs, err := c.FileClientInterface.DeleteUserFiles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	c.forget(ctx, fileIDs)
+	return fileIDs, nil
+}
+
+func (c *CachedFileClient) forget(ctx context.Context, fileIDs []uuid.UUID) {
+	for _, fileID := range fileIDs {
+		if err := c.cache.Delete(ctx, fmt.Sprintf(cache.FileMetadataKey, fileID.String())); err != nil {
+			logger.WarnCtx(ctx, "Failed to remove file from cache", "file_id", fileID.String(), "error", err.Error())
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/teammachinist/tutuplapak/services/auth/pkg/redistest"
+	"github.com/teammachinist/tutuplapak/services/core/internal/cache"
+	"github.com/teammachinist/tutuplapak/services/core/internal/logger"
+)
+
+func TestMain(m *testing.M) {
+	logger.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	os.Exit(m.Run())
+}
+
+// filesService - the files service's records in memory, counting lookups
+type filesService struct {
+	owner   map[uuid.UUID]uuid.UUID // file ID -> user ID
+	lookups int
+}
+
+func (f *filesService) GetFileByID(ctx context.Context, fileID uuid.UUID) (*FileMetadataResponse, error) {
+	panic("not used")
+}
+
+func (f *filesService) GetFilesByIDList(ctx context.Context, fileIDs []string) ([]*FileMetadataResponse, error) {
+	f.lookups++
+	var files []*FileMetadataResponse
+	for _, id := range fileIDs {
+		fileID := uuid.MustParse(id)
+		if owner, ok := f.owner[fileID]; ok {
+			files = append(files, &FileMetadataResponse{ID: fileID, UserID: owner.String(), FileURI: "https://files.example/" + id})
+		}
+	}
+	return files, nil
+}
+
+func (f *filesService) ReassignFiles(ctx context.Context, fromUserID, toUserID uuid.UUID) ([]uuid.UUID, error) {
+	var moved []uuid.UUID
+	for id, owner := range f.owner {
+		if owner == fromUserID {
+			f.owner[id] = toUserID
+			moved = append(moved, id)
+		}
+	}
+	return moved, nil
+}
+
+func (f *filesService) DeleteUserFiles(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	var deleted []uuid.UUID
+	for id, owner := range f.owner {
+		if owner == userID {
+			delete(f.owner, id)
+			deleted = append(deleted, id)
+		}
+	}
+	return deleted, nil
+}
+
+func newCachedTestClient(t *testing.T, owner map[uuid.UUID]uuid.UUID) (*CachedFileClient, *filesService, *redistest.Server) {
+	t.Helper()
+
+	redis := redistest.Start(t)
+	redisCache := cache.NewRedisCache(cache.CacheConfig{Addr: redis.Addr()})
+	t.Cleanup(func() { redisCache.Close() })
+
+	files := &filesService{owner: owner}
+	return NewCachedFileClient(files, redisCache), files, redis
+}
+
+func lookup(t *testing.T, c *CachedFileClient, fileIDs ...uuid.UUID) map[uuid.UUID]*FileMetadataResponse {
+	t.Helper()
+
+	ids := make([]string, len(fileIDs))
+	for i, id := range fileIDs {
+		ids[i] = id.String()
+	}
+	files, err := c.GetFilesByIDList(context.Background(), ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := make(map[uuid.UUID]*FileMetadataResponse, len(files))
+	for _, file := range files {
+		found[file.ID] = file
+	}
+	return found
+}
+
+func TestCachedFileClientFetchesOnlyMisses(t *testing.T) {
+	alice := uuid.New()
+	a, b, gone := uuid.New(), uuid.New(), uuid.New()
+	c, files, redis := newCachedTestClient(t, map[uuid.UUID]uuid.UUID{a: alice, b: alice})
+
+	if found := lookup(t, c, a); len(found) != 1 {
+		t.Fatalf("found %d files", len(found))
+	}
+	found := lookup(t, c, a, b, a, gone)
+	if len(found) != 2 || found[a] == nil || found[b] == nil {
+		t.Errorf("found %v, want a and b", found)
+	}
+	if files.lookups != 2 {
+		t.Errorf("%d calls to the files service, want 2", files.lookups)
+	}
+
+	// Files that don't exist aren't cached, they may still be uploaded
+	if _, ok := redis.Get(fmt.Sprintf(cache.FileMetadataKey, gone)); ok {
+		t.Error("missing file cached")
+	}
+
+	// An entry the files service wrote in its own shape is never read as core's
+	redis.Set(fmt.Sprintf("file:metadata:%s", gone), `{"productId":"`+gone.String()+`"}`)
+	if found := lookup(t, c, gone); len(found) != 0 {
+		t.Errorf("found %v for a file that doesn't exist", found)
+	}
+}
+
+func TestCachedFileClientForgetsReassignedFiles(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+	a, b := uuid.New(), uuid.New()
+	c, _, redis := newCachedTestClient(t, map[uuid.UUID]uuid.UUID{a: alice, b: bob})
+	lookup(t, c, a, b)
+
+	moved, err := c.ReassignFiles(context.Background(), alice, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(moved, []uuid.UUID{a}) {
+		t.Errorf("moved %v, want %v", moved, []uuid.UUID{a})
+	}
+
+	if _, ok := redis.Get(fmt.Sprintf(cache.FileMetadataKey, a)); ok {
+		t.Error("moved file still cached")
+	}
+	if _, ok := redis.Get(fmt.Sprintf(cache.FileMetadataKey, b)); !ok {
+		t.Error("untouched file dropped from the cache")
+	}
+	if found := lookup(t, c, a); found[a] == nil || found[a].UserID != bob.String() {
+		t.Errorf("moved file resolves to %+v, want owned by %s", found[a], bob)
+	}
+}
+
+func TestCachedFileClientForgetsDeletedFiles(t *testing.T) {
+	alice := uuid.New()
+	a := uuid.New()
+	c, _, _ := newCachedTestClient(t, map[uuid.UUID]uuid.UUID{a: alice})
+	lookup(t, c, a)
+
+	deleted, err := c.DeleteUserFiles(context.Background(), alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 1 {
+		t.Errorf("deleted %v", deleted)
+	}
+	if found := lookup(t, c, a); len(found) != 0 {
+		t.Errorf("deleted file still resolves: %v", found)
+	}
+}
@@ -1,6 +1,7 @@
 package clients
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -12,6 +13,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/teammachinist/tutuplapak/services/auth/pkg/authz"
 )
 
 type FileMetadataResponse struct {
@@ -22,9 +25,26 @@ type FileMetadataResponse struct {
 	CreatedAt        time.Time `json:"created_at"`
 }
 
+type reassignFilesRequest struct {
+	FromUserID uuid.UUID `json:"from_user_id"`
+	ToUserID   uuid.UUID `json:"to_user_id"`
+}
+
+type reassignFilesResponse struct {
+	Reassigned int         `json:"reassigned"`
+	FileIDs    []uuid.UUID `json:"file_ids"`
+}
+
+type deleteUserFilesResponse struct {
+	Deleted int         `json:"deleted"`
+	FileIDs []uuid.UUID `json:"file_ids"`
+}
+
 type FileClientInterface interface {
 	GetFileByID(ctx context.Context, fileID uuid.UUID) (*FileMetadataResponse, error)
 	GetFilesByIDList(ctx context.Context, fileIDs []string) ([]*FileMetadataResponse, error)
+	ReassignFiles(ctx context.Context, fromUserID, toUserID uuid.UUID) ([]uuid.UUID, error)
+	DeleteUserFiles(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
 }
 
 type FileClient struct {
@@ -32,22 +52,27 @@ type FileClient struct {
 	HTTPClient *http.Client
 }
 
-func NewFileClient(baseURL string) *FileClient {
+// NewFileClient - Requests are signed as core, files only serves metadata to
+// signed callers
+func NewFileClient(baseURL string, signer *authz.ServiceSigner) *FileClient {
 	return &FileClient{
-		BaseURL:    baseURL,
-		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+		BaseURL: baseURL,
+		HTTPClient: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: signer.Transport(nil),
+		},
 	}
 }
 
 func (fc *FileClient) GetFileByID(ctx context.Context, fileID uuid.UUID) (*FileMetadataResponse, error) {
-	url := fmt.Sprintf("%s/v1/file/%s", fc.BaseURL, fileID.String())
+	url := fmt.Sprintf("%s/internal/file/%s", fc.BaseURL, fileID.String())
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	// Tidak ada Authorization header — tanpa auth
+	// No user token, the request is signed as core by the transport
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := fc.HTTPClient.Do(req)
@@ -88,7 +113,7 @@ func (fc *FileClient) GetFilesByIDList(ctx context.Context, fileIDs []string) ([
 
 	fmt.Println(`print disini saja bos:`, joinId)
 
-	baseURL := fmt.Sprintf("%s/v1/file", fc.BaseURL)
+	baseURL := fmt.Sprintf("%s/internal/file", fc.BaseURL)
 	reqURL := fmt.Sprintf("%s?%s", baseURL, query.Encode())
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
@@ -120,3 +145,78 @@ func (fc *FileClient) GetFilesByIDList(ctx context.Context, fileIDs []string) ([
 
 	return filesResp, nil
 }
+
+// ReassignFiles - Move every file of fromUserID to toUserID, for account merges,
+// returning the IDs of the files moved. Files that already moved are not
+// returned again.
+func (fc *FileClient) ReassignFiles(ctx context.Context, fromUserID, toUserID uuid.UUID) ([]uuid.UUID, error) {
+	jsonData, err := json.Marshal(reassignFilesRequest{FromUserID: fromUserID, ToUserID: toUserID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/internal/file/reassign", fc.BaseURL)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := fc.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var reassignResp reassignFilesResponse
+	if err := json.Unmarshal(body, &reassignResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return reassignResp.FileIDs, nil
+}
+
+// DeleteUserFiles - Delete every file of userID, storage objects included,
+// returning the IDs of the files deleted. Files already deleted by an earlier
+// call are not returned again.
+func (fc *FileClient) DeleteUserFiles(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	url := fmt.Sprintf("%s/internal/file/user/%s", fc.BaseURL, userID.String())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := fc.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var deleteResp deleteUserFilesResponse
+	if err := json.Unmarshal(body, &deleteResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return deleteResp.FileIDs, nil
+}
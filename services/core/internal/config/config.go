@@ -28,6 +28,9 @@ type AppConfig struct {
 	FileUrl        string
 	AuthServiceURL string
 	Env            string
+
+	// Service-to-service signing keys, "auth:secret,core:secret,files:secret"
+	InternalServiceKeys string
 }
 
 type DatabaseConfig struct {
@@ -68,6 +71,8 @@ func Load() (*Config, error) {
 			FileUrl:        getEnv("FILES_SERVICE_URL", "http://localhost:8003"),
 			AuthServiceURL: getEnv("AUTH_SERVICE_URL", "http://localhost:8001"),
 			Env:            getEnv("ENV", "development"),
+
+			InternalServiceKeys: getEnv("INTERNAL_SERVICE_KEYS", ""),
 		},
 		Database: DatabaseConfig{
 			Host:        getEnv("DB_HOST", "localhost"),
@@ -81,7 +86,7 @@ func Load() (*Config, error) {
 		JWT: JWTConfig{
 			Secret:   getEnv("JWT_SECRET", "your-super-secret-key-change-in-production"),
 			Duration: jwtDuration,
-			Issuer:   getEnv("JWT_ISSUER", "tutuplapak-core-service"),
+			Issuer:   getEnv("JWT_ISSUER", "tutuplapak-auth"),
 		},
 		Redis: RedisConfig{
 			Addr:     getEnv("REDIS_ADDR", "localhost:6379"),
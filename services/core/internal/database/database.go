@@ -17,6 +17,25 @@ import (
 //go:embed migrations/*.sql
 var migrationFS embed.FS
 
+// Applied migrations are recorded by filename, so migrations that alter existing
+// tables run exactly once
+const createMigrationsTableSQL = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    filename VARCHAR(255) PRIMARY KEY,
+    applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+// Databases created before tracking already ran these; they create triggers and
+// types without IF NOT EXISTS, so they must not be replayed
+var baselineMigrations = []string{
+	"001_create_users_table.sql",
+	"002_create_products_table.sql",
+	"003_create_purchases_table.sql",
+}
+
+// Serializes migrations across replicas starting at the same time
+const migrationLockID = 7_240_002
+
 //go:embed seeds/seeds_data.sql
 var seedSQL string
 
@@ -92,16 +111,8 @@ func (db *DB) GetStats() *pgxpool.Stat {
 
 // initializeDatabase handles migrations and seeding intelligently
 func (db *DB) initializeDatabase(ctx context.Context) error {
-	// Check if migration needed
-	migrationNeeded, err := db.isMigrationNeeded(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to check migration status: %w", err)
-	}
-
-	if migrationNeeded {
-		if err := db.runMigrations(ctx); err != nil {
-			return fmt.Errorf("migration failed: %w", err)
-		}
+	if err := db.runMigrations(ctx); err != nil {
+		return fmt.Errorf("migration failed: %w", err)
 	}
 
 	// Check if seeding needed (development only)
@@ -121,38 +132,10 @@ func (db *DB) initializeDatabase(ctx context.Context) error {
 	return nil
 }
 
-func (db *DB) isMigrationNeeded(ctx context.Context) (bool, error) {
-	// Check if main table exists
-	var exists bool
-	err := db.Pool.QueryRow(ctx, `
-        SELECT EXISTS (
-            SELECT FROM information_schema.tables 
-            WHERE table_schema = 'public' 
-            AND table_name = 'users'
-        )
-    `).Scan(&exists)
-
-	if err != nil {
-		return false, fmt.Errorf("failed to check table existence: %w", err)
-	}
-
-	return !exists, nil // Need migration if table doesn't exist
-}
-
 func (db *DB) isSeedingNeeded(ctx context.Context) (bool, error) {
-	// First check if table exists
-	migrationNeeded, err := db.isMigrationNeeded(ctx)
-	if err != nil {
-		return false, err
-	}
-
-	if migrationNeeded {
-		return false, nil // Can't seed if table doesn't exist
-	}
-
 	// Check if any seed data exists
 	var count int
-	err = db.Pool.QueryRow(ctx, "SELECT COUNT(*) FROM users").Scan(&count)
+	err := db.Pool.QueryRow(ctx, "SELECT COUNT(*) FROM users").Scan(&count)
 	if err != nil {
 		return false, fmt.Errorf("failed to check seed data: %w", err)
 	}
@@ -175,24 +158,108 @@ func (db *DB) runMigrations(ctx context.Context) error {
 	}
 	sort.Strings(filenames)
 
-	// Execute migrations in order
+	conn, err := db.Pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	// Session-level lock, held on this connection until unlocked
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID)
+
+	if err := db.ensureMigrationsTable(ctx, conn); err != nil {
+		return err
+	}
+
+	// Execute pending migrations in order
 	for _, filename := range filenames {
+		var applied bool
+		err := conn.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM schema_migrations WHERE filename = $1)", filename).Scan(&applied)
+		if err != nil {
+			return fmt.Errorf("failed to check migration %s: %w", filename, err)
+		}
+		if applied {
+			continue
+		}
+
 		migrationSQL, err := migrationFS.ReadFile("migrations/" + filename)
 		if err != nil {
 			return fmt.Errorf("failed to read migration %s: %w", filename, err)
 		}
 
-		_, err = db.Pool.Exec(ctx, string(migrationSQL))
+		tx, err := conn.Begin(ctx)
 		if err != nil {
+			return fmt.Errorf("failed to begin migration %s: %w", filename, err)
+		}
+
+		if _, err := tx.Exec(ctx, string(migrationSQL)); err != nil {
+			tx.Rollback(ctx)
 			return fmt.Errorf("failed to execute migration %s: %w", filename, err)
 		}
 
+		if _, err := tx.Exec(ctx, "INSERT INTO schema_migrations (filename) VALUES ($1)", filename); err != nil {
+			tx.Rollback(ctx)
+			return fmt.Errorf("failed to record migration %s: %w", filename, err)
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return fmt.Errorf("failed to commit migration %s: %w", filename, err)
+		}
+
 		fmt.Printf("Applied migration: %s\n", filename)
 	}
 
 	return nil
 }
 
+// ensureMigrationsTable - Create schema_migrations, recording the baseline as applied
+// when the schema predates tracking
+func (db *DB) ensureMigrationsTable(ctx context.Context, conn *pgxpool.Conn) error {
+	var tracked bool
+	err := conn.QueryRow(ctx, `
+        SELECT EXISTS (
+            SELECT FROM information_schema.tables
+            WHERE table_schema = 'public'
+            AND table_name = 'schema_migrations'
+        )
+    `).Scan(&tracked)
+	if err != nil {
+		return fmt.Errorf("failed to check schema_migrations: %w", err)
+	}
+	if tracked {
+		return nil
+	}
+
+	var legacy bool
+	err = conn.QueryRow(ctx, `
+        SELECT EXISTS (
+            SELECT FROM information_schema.tables
+            WHERE table_schema = 'public'
+            AND table_name = 'users'
+        )
+    `).Scan(&legacy)
+	if err != nil {
+		return fmt.Errorf("failed to check table existence: %w", err)
+	}
+
+	if _, err := conn.Exec(ctx, createMigrationsTableSQL); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	if legacy {
+		for _, filename := range baselineMigrations {
+			if _, err := conn.Exec(ctx, "INSERT INTO schema_migrations (filename) VALUES ($1) ON CONFLICT DO NOTHING", filename); err != nil {
+				return fmt.Errorf("failed to record baseline migration %s: %w", filename, err)
+			}
+		}
+	}
+
+	return nil
+}
+
 func (db *DB) seedData(ctx context.Context) error {
 	_, err := db.Pool.Exec(ctx, seedSQL)
 	if err != nil {
@@ -0,0 +1,20 @@
+-- Auth retries profile creation until it sees a response, so one user_auth_id
+-- must map to exactly one user. Fold duplicates left by earlier retries into
+-- the oldest row before enforcing it.
+UPDATE products p SET user_id = keep.id
+FROM users dup
+JOIN LATERAL (
+    SELECT id FROM users u
+    WHERE u.user_auth_id = dup.user_auth_id
+    ORDER BY u.created_at, u.id
+    LIMIT 1
+) keep ON TRUE
+WHERE p.user_id = dup.id AND dup.id <> keep.id;
+
+DELETE FROM users dup
+USING users keep
+WHERE dup.user_auth_id = keep.user_auth_id
+  AND (dup.created_at, dup.id) > (keep.created_at, keep.id);
+
+DROP INDEX IF EXISTS idx_users_user_auth_id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_user_auth_id ON users(user_auth_id);
@@ -0,0 +1,18 @@
+-- Product search for GET /v1/product?q=. Names are matched by stem (indonesian)
+-- and as written (simple, for brand and foreign words), categories as written.
+-- A generated column is recomputed by Postgres on every insert and update of
+-- the row, so it never drifts from the name.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector tsvector
+GENERATED ALWAYS AS (
+    setweight(to_tsvector('indonesian', name), 'A') ||
+    setweight(to_tsvector('simple', name), 'B') ||
+    setweight(to_tsvector('simple', category), 'C')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector);
+
+-- Trigram index for misspelled queries, replacing the btree that no search could use
+DROP INDEX IF EXISTS idx_products_name_search;
+CREATE INDEX IF NOT EXISTS idx_products_name_search ON products USING GIN (name gin_trgm_ops);
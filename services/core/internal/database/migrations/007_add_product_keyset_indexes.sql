@@ -0,0 +1,14 @@
+-- Keyset pagination seeks on (sort key, id). Each index serves its sort in
+-- both directions and supersedes the single-column ones.
+CREATE INDEX IF NOT EXISTS idx_products_greatest_created_updated_id
+ON products (GREATEST(created_at, updated_at), id);
+
+CREATE INDEX IF NOT EXISTS idx_products_least_created_updated_id
+ON products (LEAST(created_at, updated_at), id);
+
+CREATE INDEX IF NOT EXISTS idx_products_price_id ON products (price, id);
+
+DROP INDEX IF EXISTS idx_products_greatest_created_updated_desc;
+DROP INDEX IF EXISTS idx_products_greatest_created_updated_asc;
+DROP INDEX IF EXISTS idx_products_price_asc;
+DROP INDEX IF EXISTS idx_products_price_desc;
@@ -0,0 +1,11 @@
+-- Listing filters: a seller's products (userId), newest first by default, and
+-- only products with stock left (inStock). Price ranges use idx_products_price_id
+-- and category lists the category indexes.
+CREATE INDEX IF NOT EXISTS idx_products_user_newest
+ON products (user_id, GREATEST(created_at, updated_at), id);
+
+CREATE INDEX IF NOT EXISTS idx_products_in_stock_newest
+ON products (GREATEST(created_at, updated_at), id) WHERE qty > 0;
+
+CREATE INDEX IF NOT EXISTS idx_products_in_stock_price
+ON products (price, id) WHERE qty > 0;
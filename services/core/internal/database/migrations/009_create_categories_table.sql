@@ -0,0 +1,40 @@
+-- Product categories, managed by admins instead of being compiled in. Products
+-- reference a category by slug; renaming a slug carries over to its products.
+CREATE TABLE IF NOT EXISTS categories (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    slug VARCHAR(64) NOT NULL UNIQUE,
+    name VARCHAR(100) NOT NULL,
+    parent_id UUID REFERENCES categories(id) ON DELETE RESTRICT,
+    active BOOLEAN NOT NULL DEFAULT TRUE, -- Inactive categories take no new products
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (parent_id IS NULL OR parent_id <> id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_categories_parent_id ON categories(parent_id);
+
+DROP TRIGGER IF EXISTS update_categories_updated_at ON categories;
+CREATE TRIGGER update_categories_updated_at
+    BEFORE UPDATE ON categories
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- The categories that used to be hard-coded
+INSERT INTO categories (slug, name) VALUES
+    ('food', 'Food'),
+    ('beverage', 'Beverage'),
+    ('clothes', 'Clothes'),
+    ('furniture', 'Furniture'),
+    ('tools', 'Tools')
+ON CONFLICT (slug) DO NOTHING;
+
+-- Not an edit by the seller, so updated_at (and newest-first order) stays put
+ALTER TABLE products DISABLE TRIGGER update_products_updated_at;
+UPDATE products p SET category = c.slug
+FROM categories c
+WHERE p.category = c.name;
+ALTER TABLE products ENABLE TRIGGER update_products_updated_at;
+
+ALTER TABLE products DROP CONSTRAINT IF EXISTS fk_products_category;
+ALTER TABLE products ADD CONSTRAINT fk_products_category
+    FOREIGN KEY (category) REFERENCES categories(slug) ON UPDATE CASCADE ON DELETE RESTRICT;
@@ -0,0 +1,30 @@
+-- The axes a product varies along, such as size or color, in display order
+CREATE TABLE IF NOT EXISTS product_options (
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    name VARCHAR(32) NOT NULL,
+    option_values TEXT[] NOT NULL,
+    PRIMARY KEY (product_id, position),
+    UNIQUE (product_id, name)
+);
+
+-- One purchasable combination of option values with its own SKU, price and
+-- stock. For a product with variants, products.qty is their total stock and
+-- products.price the lowest of their prices, so listings filter and sort the
+-- same way with or without variants.
+CREATE TABLE IF NOT EXISTS product_variants (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    sku VARCHAR(32) NOT NULL,
+    price INTEGER NOT NULL,
+    qty INTEGER NOT NULL DEFAULT 0 CHECK (qty >= 0),
+    attributes JSONB NOT NULL, -- Option name to value, one per option
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (product_id, sku)
+);
+
+CREATE TRIGGER update_product_variants_updated_at
+    BEFORE UPDATE ON product_variants
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
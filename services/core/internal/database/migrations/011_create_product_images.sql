@@ -0,0 +1,17 @@
+-- A product's gallery in display order. The cover is the image listings show;
+-- products.file_id holds it too, so readers of the single image keep working.
+CREATE TABLE IF NOT EXISTS product_images (
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    file_id UUID NOT NULL,
+    is_cover BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (product_id, position),
+    UNIQUE (product_id, file_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_images_cover ON product_images(product_id) WHERE is_cover;
+
+-- Existing products start with their one image as the cover
+INSERT INTO product_images (product_id, position, file_id, is_cover)
+SELECT id, 0, file_id, TRUE FROM products
+ON CONFLICT DO NOTHING;
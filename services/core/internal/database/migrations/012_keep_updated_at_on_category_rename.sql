@@ -0,0 +1,21 @@
+-- Renaming a category's slug reaches its products through ON UPDATE CASCADE.
+-- That isn't an edit by the seller, so updated_at (and newest-first order)
+-- stays put, as it did when 009 moved products over to slugs. Edits by the
+-- seller always set updated_at themselves, so they never look like a cascade.
+CREATE OR REPLACE FUNCTION update_products_updated_at_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.category IS DISTINCT FROM OLD.category
+        AND (to_jsonb(NEW) - 'category' - 'search_vector') = (to_jsonb(OLD) - 'category' - 'search_vector') THEN
+        RETURN NEW;
+    END IF;
+    NEW.updated_at = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS update_products_updated_at ON products;
+CREATE TRIGGER update_products_updated_at
+    BEFORE UPDATE ON products
+    FOR EACH ROW
+    EXECUTE FUNCTION update_products_updated_at_column();
@@ -0,0 +1,6 @@
+-- The listing queries take userId and inStock as optional filters, which a
+-- generic plan can't match to a user_id prefix or a qty > 0 index predicate.
+-- Listings walk the (sort key, id) indexes from 007 and filter as they go.
+DROP INDEX IF EXISTS idx_products_user_newest;
+DROP INDEX IF EXISTS idx_products_in_stock_newest;
+DROP INDEX IF EXISTS idx_products_in_stock_price;
@@ -54,17 +54,53 @@ func (ns NullPurchaseStatus) Value() (driver.Value, error) {
 	return string(ns.PurchaseStatus), nil
 }
 
-type Products struct {
-	ID        uuid.UUID `json:"id"`
-	Name      string    `json:"name"`
-	Category  string    `json:"category"`
-	Qty       int       `json:"qty"`
-	Price     int       `json:"price"`
-	Sku       string    `json:"sku"`
-	UserID    uuid.UUID `json:"user_id"`
+type Categories struct {
+	ID        uuid.UUID  `json:"id"`
+	Slug      string     `json:"slug"`
+	Name      string     `json:"name"`
+	ParentID  *uuid.UUID `json:"parent_id"`
+	Active    bool       `json:"active"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+type ProductImages struct {
+	ProductID uuid.UUID `json:"product_id"`
+	Position  int       `json:"position"`
 	FileID    uuid.UUID `json:"file_id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	IsCover   bool      `json:"is_cover"`
+}
+
+type ProductOptions struct {
+	ProductID    uuid.UUID `json:"product_id"`
+	Position     int       `json:"position"`
+	Name         string    `json:"name"`
+	OptionValues []string  `json:"option_values"`
+}
+
+type ProductVariants struct {
+	ID         uuid.UUID `json:"id"`
+	ProductID  uuid.UUID `json:"product_id"`
+	Sku        string    `json:"sku"`
+	Price      int       `json:"price"`
+	Qty        int       `json:"qty"`
+	Attributes []byte    `json:"attributes"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type Products struct {
+	ID           uuid.UUID   `json:"id"`
+	Name         string      `json:"name"`
+	Category     string      `json:"category"`
+	Qty          int         `json:"qty"`
+	Price        int         `json:"price"`
+	Sku          string      `json:"sku"`
+	UserID       uuid.UUID   `json:"user_id"`
+	FileID       uuid.UUID   `json:"file_id"`
+	CreatedAt    time.Time   `json:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at"`
+	SearchVector interface{} `json:"search_vector"`
 }
 
 type Purchases struct {
@@ -91,4 +127,6 @@ type Users struct {
 	BankAccountNumber string     `json:"bank_account_number"`
 	CreatedAt         time.Time  `json:"created_at"`
 	UpdatedAt         time.Time  `json:"updated_at"`
+	EmailVerified     bool       `json:"email_verified"`
+	PhoneVerified     bool       `json:"phone_verified"`
 }
@@ -79,7 +79,7 @@ INSERT INTO products (
     $9,
     $10
 )
-RETURNING id, name, category, qty, price, sku, user_id, file_id, created_at, updated_at
+RETURNING id, name, category, qty, price, sku, user_id, file_id, created_at, updated_at, search_vector
 `
 
 type CreateProductParams struct {
@@ -120,6 +120,7 @@ func (q *Queries) CreateProduct(ctx context.Context, arg CreateProductParams) (P
 		&i.FileID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.SearchVector,
 	)
 	return i, err
 }
@@ -139,11 +140,37 @@ func (q *Queries) DeleteProduct(ctx context.Context, arg DeleteProductParams) er
 	return err
 }
 
-const getAllProducts = `-- name: GetAllProducts :many
+const deleteProductsByUser = `-- name: DeleteProductsByUser :many
+DELETE FROM products
+WHERE user_id = $1::uuid
+RETURNING id
+`
+
+func (q *Queries) DeleteProductsByUser(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx, deleteProductsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getProductByID = `-- name: GetProductByID :one
 SELECT 
     p.id,
     p.name,
-    p.category,
+    c.name AS category,
     p.qty,
     p.price,
     p.sku,
@@ -152,30 +179,199 @@ SELECT
     p.created_at,
     p.updated_at
 FROM products p
-WHERE 
-    p.id = COALESCE(NULLIF($1::uuid, '00000000-0000-0000-0000-000000000000'::uuid), p.id)
-    AND p.sku = COALESCE(NULLIF($2::text, ''), p.sku)
-    AND p.category = COALESCE(NULLIF($3::text, ''), p.category)
-ORDER BY 
-    CASE WHEN $4::text = 'newest' THEN GREATEST(p.created_at, p.updated_at) END DESC,
-    CASE WHEN $4::text = 'oldest' THEN LEAST(p.created_at, p.updated_at) END ASC,
-    CASE WHEN $4::text = 'cheapest' THEN p.price END ASC,
-    CASE WHEN $4::text = 'expensive' THEN p.price END DESC,
-    p.created_at DESC
-LIMIT COALESCE($6::int, 5)
-OFFSET COALESCE($5::int, 0)
+JOIN categories c ON c.slug = p.category
+WHERE p.id = $1
 `
 
-type GetAllProductsParams struct {
+type GetProductByIDRow struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Category  string    `json:"category"`
+	Qty       int       `json:"qty"`
+	Price     int       `json:"price"`
+	Sku       string    `json:"sku"`
+	FileID    uuid.UUID `json:"file_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (q *Queries) GetProductByID(ctx context.Context, id uuid.UUID) (GetProductByIDRow, error) {
+	row := q.db.QueryRow(ctx, getProductByID, id)
+	var i GetProductByIDRow
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Category,
+		&i.Qty,
+		&i.Price,
+		&i.Sku,
+		&i.FileID,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const listProductsByRelevance = `-- name: ListProductsByRelevance :many
+SELECT
+    p.id,
+    p.name,
+    c.name AS category,
+    p.qty,
+    p.price,
+    p.sku,
+    p.file_id,
+    p.user_id,
+    p.created_at,
+    p.updated_at,
+    p.rank
+FROM (
+    SELECT
+        id, name, category, qty, price, sku, file_id, user_id, created_at, updated_at,
+        (ts_rank(search_vector, websearch_to_tsquery('indonesian', $1::text) || websearch_to_tsquery('simple', $1::text))
+            + word_similarity($1::text, name))::real AS rank
+    FROM products
+    WHERE search_vector @@ (websearch_to_tsquery('indonesian', $1::text) || websearch_to_tsquery('simple', $1::text))
+        OR $1::text <% name
+) p
+JOIN categories c ON c.slug = p.category
+WHERE (p.rank, p.id) < ($2::real, $3::uuid)
+    AND p.id = COALESCE(NULLIF($4::uuid, '00000000-0000-0000-0000-000000000000'::uuid), p.id)
+    AND p.sku = COALESCE(NULLIF($5::text, ''), p.sku)
+    AND (cardinality($6::text[]) = 0 OR p.category = ANY($6::text[]))
+    AND p.user_id = COALESCE(NULLIF($7::uuid, '00000000-0000-0000-0000-000000000000'::uuid), p.user_id)
+    AND p.price BETWEEN $8::int AND $9::int
+    AND (NOT $10::bool OR p.qty > 0)
+ORDER BY p.rank DESC, p.id DESC
+LIMIT $11::int
+OFFSET $12::int
+`
+
+type ListProductsByRelevanceParams struct {
+	Search      string    `json:"search"`
+	AfterRank   float32   `json:"after_rank"`
+	AfterID     uuid.UUID `json:"after_id"`
 	ProductID   uuid.UUID `json:"product_id"`
 	Sku         string    `json:"sku"`
-	Category    string    `json:"category"`
-	SortBy      string    `json:"sort_by"`
+	Categories  []string  `json:"categories"`
+	SellerID    uuid.UUID `json:"seller_id"`
+	MinPrice    int       `json:"min_price"`
+	MaxPrice    int       `json:"max_price"`
+	InStock     bool      `json:"in_stock"`
+	LimitCount  int       `json:"limit_count"`
 	OffsetCount int       `json:"offset_count"`
+}
+
+type ListProductsByRelevanceRow struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Category  string    `json:"category"`
+	Qty       int       `json:"qty"`
+	Price     int       `json:"price"`
+	Sku       string    `json:"sku"`
+	FileID    uuid.UUID `json:"file_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Rank      float32   `json:"rank"`
+}
+
+// A search without a sort, most relevant first. The rank is returned for the
+// next page's cursor to seek on (rank, id) like the other orders do.
+func (q *Queries) ListProductsByRelevance(ctx context.Context, arg ListProductsByRelevanceParams) ([]ListProductsByRelevanceRow, error) {
+	rows, err := q.db.Query(ctx, listProductsByRelevance,
+		arg.Search,
+		arg.AfterRank,
+		arg.AfterID,
+		arg.ProductID,
+		arg.Sku,
+		arg.Categories,
+		arg.SellerID,
+		arg.MinPrice,
+		arg.MaxPrice,
+		arg.InStock,
+		arg.LimitCount,
+		arg.OffsetCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListProductsByRelevanceRow{}
+	for rows.Next() {
+		var i ListProductsByRelevanceRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Category,
+			&i.Qty,
+			&i.Price,
+			&i.Sku,
+			&i.FileID,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Rank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProductsCheapest = `-- name: ListProductsCheapest :many
+SELECT
+    p.id,
+    p.name,
+    c.name AS category,
+    p.qty,
+    p.price,
+    p.sku,
+    p.file_id,
+    p.user_id,
+    p.created_at,
+    p.updated_at
+FROM products p
+JOIN categories c ON c.slug = p.category
+WHERE (p.price, p.id) > ($1::int, $2::uuid)
+    AND p.id = COALESCE(NULLIF($3::uuid, '00000000-0000-0000-0000-000000000000'::uuid), p.id)
+    AND p.sku = COALESCE(NULLIF($4::text, ''), p.sku)
+    AND (cardinality($5::text[]) = 0 OR p.category = ANY($5::text[]))
+    AND p.user_id = COALESCE(NULLIF($6::uuid, '00000000-0000-0000-0000-000000000000'::uuid), p.user_id)
+    AND p.price BETWEEN $7::int AND $8::int
+    AND (NOT $9::bool OR p.qty > 0)
+    AND (
+        $10::text = ''
+        OR p.search_vector @@ (websearch_to_tsquery('indonesian', $10::text) || websearch_to_tsquery('simple', $10::text))
+        OR $10::text <% p.name
+    )
+ORDER BY p.price ASC, p.id ASC
+LIMIT $11::int
+OFFSET $12::int
+`
+
+type ListProductsCheapestParams struct {
+	AfterPrice  int       `json:"after_price"`
+	AfterID     uuid.UUID `json:"after_id"`
+	ProductID   uuid.UUID `json:"product_id"`
+	Sku         string    `json:"sku"`
+	Categories  []string  `json:"categories"`
+	SellerID    uuid.UUID `json:"seller_id"`
+	MinPrice    int       `json:"min_price"`
+	MaxPrice    int       `json:"max_price"`
+	InStock     bool      `json:"in_stock"`
+	Search      string    `json:"search"`
 	LimitCount  int       `json:"limit_count"`
+	OffsetCount int       `json:"offset_count"`
 }
 
-type GetAllProductsRow struct {
+type ListProductsCheapestRow struct {
 	ID        uuid.UUID `json:"id"`
 	Name      string    `json:"name"`
 	Category  string    `json:"category"`
@@ -188,22 +384,243 @@ type GetAllProductsRow struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
-func (q *Queries) GetAllProducts(ctx context.Context, arg GetAllProductsParams) ([]GetAllProductsRow, error) {
-	rows, err := q.db.Query(ctx, getAllProducts,
+// As ListProductsNewest, cheapest first
+func (q *Queries) ListProductsCheapest(ctx context.Context, arg ListProductsCheapestParams) ([]ListProductsCheapestRow, error) {
+	rows, err := q.db.Query(ctx, listProductsCheapest,
+		arg.AfterPrice,
+		arg.AfterID,
 		arg.ProductID,
 		arg.Sku,
-		arg.Category,
-		arg.SortBy,
+		arg.Categories,
+		arg.SellerID,
+		arg.MinPrice,
+		arg.MaxPrice,
+		arg.InStock,
+		arg.Search,
+		arg.LimitCount,
+		arg.OffsetCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListProductsCheapestRow{}
+	for rows.Next() {
+		var i ListProductsCheapestRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Category,
+			&i.Qty,
+			&i.Price,
+			&i.Sku,
+			&i.FileID,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProductsExpensive = `-- name: ListProductsExpensive :many
+SELECT
+    p.id,
+    p.name,
+    c.name AS category,
+    p.qty,
+    p.price,
+    p.sku,
+    p.file_id,
+    p.user_id,
+    p.created_at,
+    p.updated_at
+FROM products p
+JOIN categories c ON c.slug = p.category
+WHERE (p.price, p.id) < ($1::int, $2::uuid)
+    AND p.id = COALESCE(NULLIF($3::uuid, '00000000-0000-0000-0000-000000000000'::uuid), p.id)
+    AND p.sku = COALESCE(NULLIF($4::text, ''), p.sku)
+    AND (cardinality($5::text[]) = 0 OR p.category = ANY($5::text[]))
+    AND p.user_id = COALESCE(NULLIF($6::uuid, '00000000-0000-0000-0000-000000000000'::uuid), p.user_id)
+    AND p.price BETWEEN $7::int AND $8::int
+    AND (NOT $9::bool OR p.qty > 0)
+    AND (
+        $10::text = ''
+        OR p.search_vector @@ (websearch_to_tsquery('indonesian', $10::text) || websearch_to_tsquery('simple', $10::text))
+        OR $10::text <% p.name
+    )
+ORDER BY p.price DESC, p.id DESC
+LIMIT $11::int
+OFFSET $12::int
+`
+
+type ListProductsExpensiveParams struct {
+	AfterPrice  int       `json:"after_price"`
+	AfterID     uuid.UUID `json:"after_id"`
+	ProductID   uuid.UUID `json:"product_id"`
+	Sku         string    `json:"sku"`
+	Categories  []string  `json:"categories"`
+	SellerID    uuid.UUID `json:"seller_id"`
+	MinPrice    int       `json:"min_price"`
+	MaxPrice    int       `json:"max_price"`
+	InStock     bool      `json:"in_stock"`
+	Search      string    `json:"search"`
+	LimitCount  int       `json:"limit_count"`
+	OffsetCount int       `json:"offset_count"`
+}
+
+type ListProductsExpensiveRow struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Category  string    `json:"category"`
+	Qty       int       `json:"qty"`
+	Price     int       `json:"price"`
+	Sku       string    `json:"sku"`
+	FileID    uuid.UUID `json:"file_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// As ListProductsNewest, most expensive first
+func (q *Queries) ListProductsExpensive(ctx context.Context, arg ListProductsExpensiveParams) ([]ListProductsExpensiveRow, error) {
+	rows, err := q.db.Query(ctx, listProductsExpensive,
+		arg.AfterPrice,
+		arg.AfterID,
+		arg.ProductID,
+		arg.Sku,
+		arg.Categories,
+		arg.SellerID,
+		arg.MinPrice,
+		arg.MaxPrice,
+		arg.InStock,
+		arg.Search,
+		arg.LimitCount,
 		arg.OffsetCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListProductsExpensiveRow{}
+	for rows.Next() {
+		var i ListProductsExpensiveRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Category,
+			&i.Qty,
+			&i.Price,
+			&i.Sku,
+			&i.FileID,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProductsNewest = `-- name: ListProductsNewest :many
+SELECT
+    p.id,
+    p.name,
+    c.name AS category,
+    p.qty,
+    p.price,
+    p.sku,
+    p.file_id,
+    p.user_id,
+    p.created_at,
+    p.updated_at
+FROM products p
+JOIN categories c ON c.slug = p.category
+WHERE (GREATEST(p.created_at, p.updated_at), p.id) < ($1::timestamptz, $2::uuid)
+    AND p.id = COALESCE(NULLIF($3::uuid, '00000000-0000-0000-0000-000000000000'::uuid), p.id)
+    AND p.sku = COALESCE(NULLIF($4::text, ''), p.sku)
+    AND (cardinality($5::text[]) = 0 OR p.category = ANY($5::text[]))
+    AND p.user_id = COALESCE(NULLIF($6::uuid, '00000000-0000-0000-0000-000000000000'::uuid), p.user_id)
+    AND p.price BETWEEN $7::int AND $8::int
+    AND (NOT $9::bool OR p.qty > 0)
+    AND (
+        $10::text = ''
+        OR p.search_vector @@ (websearch_to_tsquery('indonesian', $10::text) || websearch_to_tsquery('simple', $10::text))
+        OR $10::text <% p.name
+    )
+ORDER BY GREATEST(p.created_at, p.updated_at) DESC, p.id DESC
+LIMIT $11::int
+OFFSET $12::int
+`
+
+type ListProductsNewestParams struct {
+	AfterTime   time.Time `json:"after_time"`
+	AfterID     uuid.UUID `json:"after_id"`
+	ProductID   uuid.UUID `json:"product_id"`
+	Sku         string    `json:"sku"`
+	Categories  []string  `json:"categories"`
+	SellerID    uuid.UUID `json:"seller_id"`
+	MinPrice    int       `json:"min_price"`
+	MaxPrice    int       `json:"max_price"`
+	InStock     bool      `json:"in_stock"`
+	Search      string    `json:"search"`
+	LimitCount  int       `json:"limit_count"`
+	OffsetCount int       `json:"offset_count"`
+}
+
+type ListProductsNewestRow struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Category  string    `json:"category"`
+	Qty       int       `json:"qty"`
+	Price     int       `json:"price"`
+	Sku       string    `json:"sku"`
+	FileID    uuid.UUID `json:"file_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Products by last change, newest first. Every listing order has its own query
+// so that each is a plain walk of its (sort key, id) index from migration 007:
+// only rows past (after_*, after_id) in the order are returned, the id breaks
+// ties so every row has one position, and a first page seeks from bounds past
+// every product. A non-empty search matches names and categories by full text,
+// and names with a typo by trigram word similarity. category is the display
+// name, products only store the slug.
+func (q *Queries) ListProductsNewest(ctx context.Context, arg ListProductsNewestParams) ([]ListProductsNewestRow, error) {
+	rows, err := q.db.Query(ctx, listProductsNewest,
+		arg.AfterTime,
+		arg.AfterID,
+		arg.ProductID,
+		arg.Sku,
+		arg.Categories,
+		arg.SellerID,
+		arg.MinPrice,
+		arg.MaxPrice,
+		arg.InStock,
+		arg.Search,
 		arg.LimitCount,
+		arg.OffsetCount,
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []GetAllProductsRow{}
+	items := []ListProductsNewestRow{}
 	for rows.Next() {
-		var i GetAllProductsRow
+		var i ListProductsNewestRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.Name,
@@ -226,23 +643,53 @@ func (q *Queries) GetAllProducts(ctx context.Context, arg GetAllProductsParams)
 	return items, nil
 }
 
-const getProductByID = `-- name: GetProductByID :one
-SELECT 
-    id,
-    name,
-    category,
-    qty,
-    price,
-    sku,
-    file_id,
-    user_id,
-    created_at,
-    updated_at
-FROM products 
-WHERE id = $1
+const listProductsOldest = `-- name: ListProductsOldest :many
+SELECT
+    p.id,
+    p.name,
+    c.name AS category,
+    p.qty,
+    p.price,
+    p.sku,
+    p.file_id,
+    p.user_id,
+    p.created_at,
+    p.updated_at
+FROM products p
+JOIN categories c ON c.slug = p.category
+WHERE (LEAST(p.created_at, p.updated_at), p.id) > ($1::timestamptz, $2::uuid)
+    AND p.id = COALESCE(NULLIF($3::uuid, '00000000-0000-0000-0000-000000000000'::uuid), p.id)
+    AND p.sku = COALESCE(NULLIF($4::text, ''), p.sku)
+    AND (cardinality($5::text[]) = 0 OR p.category = ANY($5::text[]))
+    AND p.user_id = COALESCE(NULLIF($6::uuid, '00000000-0000-0000-0000-000000000000'::uuid), p.user_id)
+    AND p.price BETWEEN $7::int AND $8::int
+    AND (NOT $9::bool OR p.qty > 0)
+    AND (
+        $10::text = ''
+        OR p.search_vector @@ (websearch_to_tsquery('indonesian', $10::text) || websearch_to_tsquery('simple', $10::text))
+        OR $10::text <% p.name
+    )
+ORDER BY LEAST(p.created_at, p.updated_at) ASC, p.id ASC
+LIMIT $11::int
+OFFSET $12::int
 `
 
-type GetProductByIDRow struct {
+type ListProductsOldestParams struct {
+	AfterTime   time.Time `json:"after_time"`
+	AfterID     uuid.UUID `json:"after_id"`
+	ProductID   uuid.UUID `json:"product_id"`
+	Sku         string    `json:"sku"`
+	Categories  []string  `json:"categories"`
+	SellerID    uuid.UUID `json:"seller_id"`
+	MinPrice    int       `json:"min_price"`
+	MaxPrice    int       `json:"max_price"`
+	InStock     bool      `json:"in_stock"`
+	Search      string    `json:"search"`
+	LimitCount  int       `json:"limit_count"`
+	OffsetCount int       `json:"offset_count"`
+}
+
+type ListProductsOldestRow struct {
 	ID        uuid.UUID `json:"id"`
 	Name      string    `json:"name"`
 	Category  string    `json:"category"`
@@ -255,22 +702,68 @@ type GetProductByIDRow struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
-func (q *Queries) GetProductByID(ctx context.Context, id uuid.UUID) (GetProductByIDRow, error) {
-	row := q.db.QueryRow(ctx, getProductByID, id)
-	var i GetProductByIDRow
-	err := row.Scan(
-		&i.ID,
-		&i.Name,
-		&i.Category,
-		&i.Qty,
-		&i.Price,
-		&i.Sku,
-		&i.FileID,
-		&i.UserID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
+// As ListProductsNewest, by creation, oldest first
+func (q *Queries) ListProductsOldest(ctx context.Context, arg ListProductsOldestParams) ([]ListProductsOldestRow, error) {
+	rows, err := q.db.Query(ctx, listProductsOldest,
+		arg.AfterTime,
+		arg.AfterID,
+		arg.ProductID,
+		arg.Sku,
+		arg.Categories,
+		arg.SellerID,
+		arg.MinPrice,
+		arg.MaxPrice,
+		arg.InStock,
+		arg.Search,
+		arg.LimitCount,
+		arg.OffsetCount,
 	)
-	return i, err
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListProductsOldestRow{}
+	for rows.Next() {
+		var i ListProductsOldestRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Category,
+			&i.Qty,
+			&i.Price,
+			&i.Sku,
+			&i.FileID,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const reassignProducts = `-- name: ReassignProducts :execrows
+UPDATE products
+SET user_id = $1::uuid
+WHERE user_id = $2::uuid
+`
+
+type ReassignProductsParams struct {
+	ToUserID   uuid.UUID `json:"to_user_id"`
+	FromUserID uuid.UUID `json:"from_user_id"`
+}
+
+func (q *Queries) ReassignProducts(ctx context.Context, arg ReassignProductsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, reassignProducts, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
 const updateProduct = `-- name: UpdateProduct :one
@@ -339,6 +832,7 @@ const updateProductQty = `-- name: UpdateProductQty :execrows
 UPDATE products
 SET qty = qty - $1::int
 WHERE id = $2::uuid AND qty >= $1::int
+  AND NOT EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = products.id)
 `
 
 type UpdateProductQtyParams struct {
@@ -346,6 +840,7 @@ type UpdateProductQtyParams struct {
 	ID  uuid.UUID `json:"id"`
 }
 
+// Products with variants keep stock per variant, see UpdateVariantQty
 func (q *Queries) UpdateProductQty(ctx context.Context, arg UpdateProductQtyParams) (int64, error) {
 	result, err := q.db.Exec(ctx, updateProductQty, arg.Qty, arg.ID)
 	if err != nil {
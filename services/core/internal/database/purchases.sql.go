@@ -67,6 +67,61 @@ func (q *Queries) GetPurchaseByID(ctx context.Context, purchaseid uuid.UUID) (Pu
 	return i, err
 }
 
+const scrubBuyerPurchases = `-- name: ScrubBuyerPurchases :execrows
+UPDATE purchases
+SET sender_name = 'Deleted user',
+    sender_contact_detail = ''
+WHERE sender_contact_detail <> ''
+  AND ((sender_contact_type = 'email' AND sender_contact_detail = $1::text)
+    OR (sender_contact_type = 'phone' AND sender_contact_detail = $2::text))
+`
+
+type ScrubBuyerPurchasesParams struct {
+	Email string `json:"email"`
+	Phone string `json:"phone"`
+}
+
+// Purchases made by a deleted user, found by the contact they bought with. The
+// amounts stay for the books.
+func (q *Queries) ScrubBuyerPurchases(ctx context.Context, arg ScrubBuyerPurchasesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, scrubBuyerPurchases, arg.Email, arg.Phone)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const scrubSellerPurchases = `-- name: ScrubSellerPurchases :execrows
+UPDATE purchases
+SET payment_details = (
+    SELECT jsonb_agg(
+        CASE WHEN jsonb_array_length(purchases.payment_details) = 1
+                  OR detail->>'bankAccountNumber' = $1::text
+            THEN detail || '{"bankAccountName": "", "bankAccountHolder": "", "bankAccountNumber": ""}'::jsonb
+            ELSE detail
+        END ORDER BY position)
+    FROM jsonb_array_elements(purchases.payment_details) WITH ORDINALITY AS details(detail, position)
+)
+WHERE purchased_items @> jsonb_build_array(jsonb_build_object('sellerId', $2::text))
+  AND jsonb_array_length(payment_details) > 0
+`
+
+type ScrubSellerPurchasesParams struct {
+	BankAccountNumber string `json:"bank_account_number"`
+	SellerID          string `json:"seller_id"`
+}
+
+// Purchases from a deleted seller. Payment details carry no seller id, so the
+// bank account is blanked where it matches the seller's, or where the seller was
+// the only one paid. Totals stay for the books.
+func (q *Queries) ScrubSellerPurchases(ctx context.Context, arg ScrubSellerPurchasesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, scrubSellerPurchases, arg.BankAccountNumber, arg.SellerID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updatePurchaseStatus = `-- name: UpdatePurchaseStatus :exec
 UPDATE purchases
 SET status = $1::purchase_status,
@@ -12,27 +12,81 @@ import (
 
 type Querier interface {
 	CheckEmailExists(ctx context.Context, email string) (bool, error)
-	CheckPhoneExists(ctx context.Context, email string) (bool, error)
+	CheckPhoneExists(ctx context.Context, phone string) (bool, error)
 	CheckProductOwnership(ctx context.Context, arg CheckProductOwnershipParams) (bool, error)
 	CheckSKUExistsByUser(ctx context.Context, arg CheckSKUExistsByUserParams) (CheckSKUExistsByUserRow, error)
+	CreateCategory(ctx context.Context, arg CreateCategoryParams) (Categories, error)
 	CreateProduct(ctx context.Context, arg CreateProductParams) (Products, error)
+	// Store file_ids as the gallery in the order given, cover_file_id marked as
+	// the cover
+	CreateProductImages(ctx context.Context, arg CreateProductImagesParams) error
+	CreateProductOption(ctx context.Context, arg CreateProductOptionParams) error
 	CreatePurchase(ctx context.Context, arg CreatePurchaseParams) error
 	CreateUserFromUserAuth(ctx context.Context, arg CreateUserFromUserAuthParams) (Users, error)
+	// Fails while products or subcategories still reference the category
+	DeleteCategory(ctx context.Context, id uuid.UUID) (int64, error)
 	DeleteProduct(ctx context.Context, arg DeleteProductParams) error
+	DeleteProductImages(ctx context.Context, productID uuid.UUID) error
+	DeleteProductOptions(ctx context.Context, productID uuid.UUID) error
+	// Variants no longer in skus, when a product's variants are replaced
+	DeleteProductVariantsExcept(ctx context.Context, arg DeleteProductVariantsExceptParams) error
+	DeleteProductsByUser(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
 	DeleteUser(ctx context.Context, id uuid.UUID) error
-	GetAllProducts(ctx context.Context, arg GetAllProductsParams) ([]GetAllProductsRow, error)
+	DeleteUserByAuthID(ctx context.Context, userAuthID uuid.UUID) error
 	GetProductByID(ctx context.Context, id uuid.UUID) (GetProductByIDRow, error)
+	GetProductVariant(ctx context.Context, arg GetProductVariantParams) (ProductVariants, error)
 	GetPurchaseByID(ctx context.Context, purchaseid uuid.UUID) (Purchases, error)
 	GetUserByAuthID(ctx context.Context, userAuthID uuid.UUID) (Users, error)
 	GetUserByEmail(ctx context.Context, email string) (Users, error)
 	GetUserByID(ctx context.Context, id uuid.UUID) (Users, error)
 	GetUserByPhone(ctx context.Context, phone string) (Users, error)
+	HasProductVariants(ctx context.Context, productID uuid.UUID) (bool, error)
+	ListCategories(ctx context.Context) ([]Categories, error)
+	ListProductImages(ctx context.Context, productIds []uuid.UUID) ([]ProductImages, error)
+	ListProductOptions(ctx context.Context, productIds []uuid.UUID) ([]ProductOptions, error)
+	ListProductVariants(ctx context.Context, productIds []uuid.UUID) ([]ProductVariants, error)
+	// A search without a sort, most relevant first. The rank is returned for the
+	// next page's cursor to seek on (rank, id) like the other orders do.
+	ListProductsByRelevance(ctx context.Context, arg ListProductsByRelevanceParams) ([]ListProductsByRelevanceRow, error)
+	// As ListProductsNewest, cheapest first
+	ListProductsCheapest(ctx context.Context, arg ListProductsCheapestParams) ([]ListProductsCheapestRow, error)
+	// As ListProductsNewest, most expensive first
+	ListProductsExpensive(ctx context.Context, arg ListProductsExpensiveParams) ([]ListProductsExpensiveRow, error)
+	// Products by last change, newest first. Every listing order has its own query
+	// so that each is a plain walk of its (sort key, id) index from migration 007:
+	// only rows past (after_*, after_id) in the order are returned, the id breaks
+	// ties so every row has one position, and a first page seeks from bounds past
+	// every product. A non-empty search matches names and categories by full text,
+	// and names with a typo by trigram word similarity. category is the display
+	// name, products only store the slug.
+	ListProductsNewest(ctx context.Context, arg ListProductsNewestParams) ([]ListProductsNewestRow, error)
+	// As ListProductsNewest, by creation, oldest first
+	ListProductsOldest(ctx context.Context, arg ListProductsOldestParams) ([]ListProductsOldestRow, error)
+	ListUsersByAuthIDPage(ctx context.Context, arg ListUsersByAuthIDPageParams) ([]ListUsersByAuthIDPageRow, error)
+	ReassignProducts(ctx context.Context, arg ReassignProductsParams) (int64, error)
+	// Purchases made by a deleted user, found by the contact they bought with. The
+	// amounts stay for the books.
+	ScrubBuyerPurchases(ctx context.Context, arg ScrubBuyerPurchasesParams) (int64, error)
+	// Purchases from a deleted seller. Payment details carry no seller id, so the
+	// bank account is blanked where it matches the seller's, or where the seller was
+	// the only one paid. Totals stay for the books.
+	ScrubSellerPurchases(ctx context.Context, arg ScrubSellerPurchasesParams) (int64, error)
+	// Point the product's qty and price at its variants' total stock and lowest price
+	SyncProductStock(ctx context.Context, id uuid.UUID) (SyncProductStockRow, error)
+	UpdateCategory(ctx context.Context, arg UpdateCategoryParams) (Categories, error)
 	UpdateProduct(ctx context.Context, arg UpdateProductParams) (UpdateProductRow, error)
+	// Products with variants keep stock per variant, see UpdateVariantQty
 	UpdateProductQty(ctx context.Context, arg UpdateProductQtyParams) (int64, error)
 	UpdatePurchaseStatus(ctx context.Context, arg UpdatePurchaseStatusParams) error
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (Users, error)
 	UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams) (Users, error)
 	UpdateUserPhone(ctx context.Context, arg UpdateUserPhoneParams) (Users, error)
+	// Takes qty off the variant and off its product's total in one statement, or
+	// neither when the variant has less left
+	UpdateVariantQty(ctx context.Context, arg UpdateVariantQtyParams) (int64, error)
+	// A variant keeps its ID across edits while its SKU stays, so carts referencing
+	// it stay valid
+	UpsertProductVariant(ctx context.Context, arg UpsertProductVariantParams) (ProductVariants, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,21 @@
+-- name: ListCategories :many
+SELECT * FROM categories
+ORDER BY name, slug;
+
+-- name: CreateCategory :one
+INSERT INTO categories (slug, name, parent_id, active)
+VALUES (@slug, @name, sqlc.narg('parent_id'), @active)
+RETURNING *;
+
+-- name: UpdateCategory :one
+UPDATE categories SET
+    slug = @slug,
+    name = @name,
+    parent_id = sqlc.narg('parent_id'),
+    active = @active
+WHERE id = @id
+RETURNING *;
+
+-- Fails while products or subcategories still reference the category
+-- name: DeleteCategory :execrows
+DELETE FROM categories WHERE id = $1;
@@ -0,0 +1,14 @@
+-- Store file_ids as the gallery in the order given, cover_file_id marked as
+-- the cover
+-- name: CreateProductImages :exec
+INSERT INTO product_images (product_id, position, file_id, is_cover)
+SELECT @product_id::uuid, (t.ord - 1)::int, t.file_id, t.file_id = @cover_file_id::uuid
+FROM unnest(@file_ids::uuid[]) WITH ORDINALITY AS t(file_id, ord);
+
+-- name: DeleteProductImages :exec
+DELETE FROM product_images WHERE product_id = $1;
+
+-- name: ListProductImages :many
+SELECT * FROM product_images
+WHERE product_id = ANY(@product_ids::uuid[])
+ORDER BY product_id, position;
@@ -0,0 +1,72 @@
+-- name: CreateProductOption :exec
+INSERT INTO product_options (product_id, position, name, option_values)
+VALUES ($1, $2, $3, $4);
+
+-- name: DeleteProductOptions :exec
+DELETE FROM product_options WHERE product_id = $1;
+
+-- Variants no longer in skus, when a product's variants are replaced
+-- name: DeleteProductVariantsExcept :exec
+DELETE FROM product_variants
+WHERE product_id = @product_id::uuid
+  AND NOT (sku = ANY(@skus::text[]));
+
+-- name: GetProductVariant :one
+SELECT * FROM product_variants
+WHERE id = @id::uuid AND product_id = @product_id::uuid;
+
+-- name: HasProductVariants :one
+SELECT EXISTS(
+    SELECT 1 FROM product_variants WHERE product_id = $1
+) AS exists;
+
+-- name: ListProductOptions :many
+SELECT * FROM product_options
+WHERE product_id = ANY(@product_ids::uuid[])
+ORDER BY product_id, position;
+
+-- name: ListProductVariants :many
+SELECT * FROM product_variants
+WHERE product_id = ANY(@product_ids::uuid[])
+ORDER BY product_id, created_at, id;
+
+-- Point the product's qty and price at its variants' total stock and lowest price
+-- name: SyncProductStock :one
+UPDATE products
+SET qty = v.qty,
+    price = v.price
+FROM (
+    SELECT COALESCE(SUM(qty), 0)::int AS qty, MIN(price) AS price
+    FROM product_variants
+    WHERE product_id = @id::uuid
+) v
+WHERE products.id = @id::uuid
+  AND v.price IS NOT NULL
+RETURNING products.qty, products.price;
+
+-- Takes qty off the variant and off its product's total in one statement, or
+-- neither when the variant has less left
+-- name: UpdateVariantQty :execrows
+WITH variant AS (
+    UPDATE product_variants
+    SET qty = product_variants.qty - @qty::int
+    WHERE product_variants.id = @id::uuid
+      AND product_variants.product_id = @product_id::uuid
+      AND product_variants.qty >= @qty::int
+    RETURNING product_variants.product_id
+)
+UPDATE products
+SET qty = products.qty - @qty::int
+FROM variant
+WHERE products.id = variant.product_id;
+
+-- A variant keeps its ID across edits while its SKU stays, so carts referencing
+-- it stay valid
+-- name: UpsertProductVariant :one
+INSERT INTO product_variants (id, product_id, sku, price, qty, attributes)
+VALUES (@id::uuid, @product_id::uuid, @sku::text, @price::int, @qty::int, @attributes::jsonb)
+ON CONFLICT (product_id, sku) DO UPDATE
+SET price = EXCLUDED.price,
+    qty = EXCLUDED.qty,
+    attributes = EXCLUDED.attributes
+RETURNING *;
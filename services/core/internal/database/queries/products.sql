@@ -31,11 +31,111 @@ FROM products
 WHERE sku = @sku::text AND user_id = @user_id::uuid
 LIMIT 1;
 
--- name: GetAllProducts :many
-SELECT 
+-- Products by last change, newest first. Every listing order has its own query
+-- so that each is a plain walk of its (sort key, id) index from migration 007:
+-- only rows past (after_*, after_id) in the order are returned, the id breaks
+-- ties so every row has one position, and a first page seeks from bounds past
+-- every product. A non-empty search matches names and categories by full text,
+-- and names with a typo by trigram word similarity. category is the display
+-- name, products only store the slug.
+-- name: ListProductsNewest :many
+SELECT
+    p.id,
+    p.name,
+    c.name AS category,
+    p.qty,
+    p.price,
+    p.sku,
+    p.file_id,
+    p.user_id,
+    p.created_at,
+    p.updated_at
+FROM products p
+JOIN categories c ON c.slug = p.category
+WHERE (GREATEST(p.created_at, p.updated_at), p.id) < (@after_time::timestamptz, @after_id::uuid)
+    AND p.id = COALESCE(NULLIF(@product_id::uuid, '00000000-0000-0000-0000-000000000000'::uuid), p.id)
+    AND p.sku = COALESCE(NULLIF(@sku::text, ''), p.sku)
+    AND (cardinality(@categories::text[]) = 0 OR p.category = ANY(@categories::text[]))
+    AND p.user_id = COALESCE(NULLIF(@seller_id::uuid, '00000000-0000-0000-0000-000000000000'::uuid), p.user_id)
+    AND p.price BETWEEN @min_price::int AND @max_price::int
+    AND (NOT @in_stock::bool OR p.qty > 0)
+    AND (
+        @search::text = ''
+        OR p.search_vector @@ (websearch_to_tsquery('indonesian', @search::text) || websearch_to_tsquery('simple', @search::text))
+        OR @search::text <% p.name
+    )
+ORDER BY GREATEST(p.created_at, p.updated_at) DESC, p.id DESC
+LIMIT @limit_count::int
+OFFSET @offset_count::int;
+
+-- As ListProductsNewest, by creation, oldest first
+-- name: ListProductsOldest :many
+SELECT
+    p.id,
+    p.name,
+    c.name AS category,
+    p.qty,
+    p.price,
+    p.sku,
+    p.file_id,
+    p.user_id,
+    p.created_at,
+    p.updated_at
+FROM products p
+JOIN categories c ON c.slug = p.category
+WHERE (LEAST(p.created_at, p.updated_at), p.id) > (@after_time::timestamptz, @after_id::uuid)
+    AND p.id = COALESCE(NULLIF(@product_id::uuid, '00000000-0000-0000-0000-000000000000'::uuid), p.id)
+    AND p.sku = COALESCE(NULLIF(@sku::text, ''), p.sku)
+    AND (cardinality(@categories::text[]) = 0 OR p.category = ANY(@categories::text[]))
+    AND p.user_id = COALESCE(NULLIF(@seller_id::uuid, '00000000-0000-0000-0000-000000000000'::uuid), p.user_id)
+    AND p.price BETWEEN @min_price::int AND @max_price::int
+    AND (NOT @in_stock::bool OR p.qty > 0)
+    AND (
+        @search::text = ''
+        OR p.search_vector @@ (websearch_to_tsquery('indonesian', @search::text) || websearch_to_tsquery('simple', @search::text))
+        OR @search::text <% p.name
+    )
+ORDER BY LEAST(p.created_at, p.updated_at) ASC, p.id ASC
+LIMIT @limit_count::int
+OFFSET @offset_count::int;
+
+-- As ListProductsNewest, cheapest first
+-- name: ListProductsCheapest :many
+SELECT
+    p.id,
+    p.name,
+    c.name AS category,
+    p.qty,
+    p.price,
+    p.sku,
+    p.file_id,
+    p.user_id,
+    p.created_at,
+    p.updated_at
+FROM products p
+JOIN categories c ON c.slug = p.category
+WHERE (p.price, p.id) > (@after_price::int, @after_id::uuid)
+    AND p.id = COALESCE(NULLIF(@product_id::uuid, '00000000-0000-0000-0000-000000000000'::uuid), p.id)
+    AND p.sku = COALESCE(NULLIF(@sku::text, ''), p.sku)
+    AND (cardinality(@categories::text[]) = 0 OR p.category = ANY(@categories::text[]))
+    AND p.user_id = COALESCE(NULLIF(@seller_id::uuid, '00000000-0000-0000-0000-000000000000'::uuid), p.user_id)
+    AND p.price BETWEEN @min_price::int AND @max_price::int
+    AND (NOT @in_stock::bool OR p.qty > 0)
+    AND (
+        @search::text = ''
+        OR p.search_vector @@ (websearch_to_tsquery('indonesian', @search::text) || websearch_to_tsquery('simple', @search::text))
+        OR @search::text <% p.name
+    )
+ORDER BY p.price ASC, p.id ASC
+LIMIT @limit_count::int
+OFFSET @offset_count::int;
+
+-- As ListProductsNewest, most expensive first
+-- name: ListProductsExpensive :many
+SELECT
     p.id,
     p.name,
-    p.category,
+    c.name AS category,
     p.qty,
     p.price,
     p.sku,
@@ -44,18 +144,58 @@ SELECT
     p.created_at,
     p.updated_at
 FROM products p
-WHERE 
-    p.id = COALESCE(NULLIF(@product_id::uuid, '00000000-0000-0000-0000-000000000000'::uuid), p.id)
+JOIN categories c ON c.slug = p.category
+WHERE (p.price, p.id) < (@after_price::int, @after_id::uuid)
+    AND p.id = COALESCE(NULLIF(@product_id::uuid, '00000000-0000-0000-0000-000000000000'::uuid), p.id)
+    AND p.sku = COALESCE(NULLIF(@sku::text, ''), p.sku)
+    AND (cardinality(@categories::text[]) = 0 OR p.category = ANY(@categories::text[]))
+    AND p.user_id = COALESCE(NULLIF(@seller_id::uuid, '00000000-0000-0000-0000-000000000000'::uuid), p.user_id)
+    AND p.price BETWEEN @min_price::int AND @max_price::int
+    AND (NOT @in_stock::bool OR p.qty > 0)
+    AND (
+        @search::text = ''
+        OR p.search_vector @@ (websearch_to_tsquery('indonesian', @search::text) || websearch_to_tsquery('simple', @search::text))
+        OR @search::text <% p.name
+    )
+ORDER BY p.price DESC, p.id DESC
+LIMIT @limit_count::int
+OFFSET @offset_count::int;
+
+-- A search without a sort, most relevant first. The rank is returned for the
+-- next page's cursor to seek on (rank, id) like the other orders do.
+-- name: ListProductsByRelevance :many
+SELECT
+    p.id,
+    p.name,
+    c.name AS category,
+    p.qty,
+    p.price,
+    p.sku,
+    p.file_id,
+    p.user_id,
+    p.created_at,
+    p.updated_at,
+    p.rank
+FROM (
+    SELECT
+        id, name, category, qty, price, sku, file_id, user_id, created_at, updated_at,
+        (ts_rank(search_vector, websearch_to_tsquery('indonesian', @search::text) || websearch_to_tsquery('simple', @search::text))
+            + word_similarity(@search::text, name))::real AS rank
+    FROM products
+    WHERE search_vector @@ (websearch_to_tsquery('indonesian', @search::text) || websearch_to_tsquery('simple', @search::text))
+        OR @search::text <% name
+) p
+JOIN categories c ON c.slug = p.category
+WHERE (p.rank, p.id) < (@after_rank::real, @after_id::uuid)
+    AND p.id = COALESCE(NULLIF(@product_id::uuid, '00000000-0000-0000-0000-000000000000'::uuid), p.id)
     AND p.sku = COALESCE(NULLIF(@sku::text, ''), p.sku)
-    AND p.category = COALESCE(NULLIF(@category::text, ''), p.category)
-ORDER BY 
-    CASE WHEN @sort_by::text = 'newest' THEN GREATEST(p.created_at, p.updated_at) END DESC,
-    CASE WHEN @sort_by::text = 'oldest' THEN LEAST(p.created_at, p.updated_at) END ASC,
-    CASE WHEN @sort_by::text = 'cheapest' THEN p.price END ASC,
-    CASE WHEN @sort_by::text = 'expensive' THEN p.price END DESC,
-    p.created_at DESC
-LIMIT COALESCE(@limit_count::int, 5)
-OFFSET COALESCE(@offset_count::int, 0);
+    AND (cardinality(@categories::text[]) = 0 OR p.category = ANY(@categories::text[]))
+    AND p.user_id = COALESCE(NULLIF(@seller_id::uuid, '00000000-0000-0000-0000-000000000000'::uuid), p.user_id)
+    AND p.price BETWEEN @min_price::int AND @max_price::int
+    AND (NOT @in_stock::bool OR p.qty > 0)
+ORDER BY p.rank DESC, p.id DESC
+LIMIT @limit_count::int
+OFFSET @offset_count::int;
 
 -- name: UpdateProduct :one
 UPDATE products SET
@@ -81,22 +221,35 @@ DELETE FROM products
 WHERE id = @id::uuid AND user_id = @user_id::uuid;
 
 
+-- Products with variants keep stock per variant, see UpdateVariantQty
 -- name: UpdateProductQty :execrows
 UPDATE products
 SET qty = qty - @qty::int
-WHERE id = @id::uuid AND qty >= @qty::int;
+WHERE id = @id::uuid AND qty >= @qty::int
+  AND NOT EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = products.id);
 
+-- With the category's display name, as a purchase snapshot shows it
 -- name: GetProductByID :one
 SELECT 
-    id,
-    name,
-    category,
-    qty,
-    price,
-    sku,
-    file_id,
-    user_id,
-    created_at,
-    updated_at
-FROM products 
-WHERE id = $1;
\ No newline at end of file
+    p.id,
+    p.name,
+    c.name AS category,
+    p.qty,
+    p.price,
+    p.sku,
+    p.file_id,
+    p.user_id,
+    p.created_at,
+    p.updated_at
+FROM products p
+JOIN categories c ON c.slug = p.category
+WHERE p.id = $1;
+-- name: ReassignProducts :execrows
+UPDATE products
+SET user_id = @to_user_id::uuid
+WHERE user_id = @from_user_id::uuid;
+
+-- name: DeleteProductsByUser :many
+DELETE FROM products
+WHERE user_id = @user_id::uuid
+RETURNING id;
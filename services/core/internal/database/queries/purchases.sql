@@ -16,3 +16,30 @@ UPDATE purchases
 SET status = @status::purchase_status,
     updated_at = NOW()
 WHERE id = @purchaseId::uuid;
+
+-- Purchases made by a deleted user, found by the contact they bought with. The
+-- amounts stay for the books.
+-- name: ScrubBuyerPurchases :execrows
+UPDATE purchases
+SET sender_name = 'Deleted user',
+    sender_contact_detail = ''
+WHERE sender_contact_detail <> ''
+  AND ((sender_contact_type = 'email' AND sender_contact_detail = @email::text)
+    OR (sender_contact_type = 'phone' AND sender_contact_detail = @phone::text));
+
+-- Purchases from a deleted seller. Payment details carry no seller id, so the
+-- bank account is blanked where it matches the seller's, or where the seller was
+-- the only one paid. Totals stay for the books.
+-- name: ScrubSellerPurchases :execrows
+UPDATE purchases
+SET payment_details = (
+    SELECT jsonb_agg(
+        CASE WHEN jsonb_array_length(purchases.payment_details) = 1
+                  OR detail->>'bankAccountNumber' = @bank_account_number::text
+            THEN detail || '{"bankAccountName": "", "bankAccountHolder": "", "bankAccountNumber": ""}'::jsonb
+            ELSE detail
+        END ORDER BY position)
+    FROM jsonb_array_elements(purchases.payment_details) WITH ORDINALITY AS details(detail, position)
+)
+WHERE purchased_items @> jsonb_build_array(jsonb_build_object('sellerId', @seller_id::text))
+  AND jsonb_array_length(payment_details) > 0;
@@ -17,6 +17,7 @@ RETURNING *;
 -- name: UpdateUserEmail :one
 UPDATE users SET
     email = $2,
+    email_verified = TRUE,
     updated_at = CURRENT_TIMESTAMP
 WHERE id = $1
 RETURNING *;
@@ -24,6 +25,7 @@ RETURNING *;
 -- name: UpdateUserPhone :one
 UPDATE users SET
     phone = $2,
+    phone_verified = TRUE,
     updated_at = CURRENT_TIMESTAMP
 WHERE id = $1
 RETURNING *;
@@ -41,8 +43,17 @@ DELETE FROM users WHERE id = $1;
 SELECT EXISTS(SELECT 1 FROM users WHERE email = $1) as exists;
 
 -- name: CheckPhoneExists :one
-SELECT EXISTS(SELECT 1 FROM users WHERE email = $1) as exists;
+SELECT EXISTS(SELECT 1 FROM users WHERE phone = $1) as exists;
 
 -- name: CreateUserFromUserAuth :one
 INSERT INTO users (id, user_auth_id, file_id, email, phone, bank_account_name, bank_account_holder, bank_account_number)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING *;
\ No newline at end of file
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING *;
+
+-- name: ListUsersByAuthIDPage :many
+SELECT id, user_auth_id, created_at FROM users
+WHERE user_auth_id > $1
+ORDER BY user_auth_id
+LIMIT $2;
+
+-- name: DeleteUserByAuthID :exec
+DELETE FROM users WHERE user_auth_id = $1;
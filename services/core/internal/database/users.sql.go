@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -23,11 +24,11 @@ func (q *Queries) CheckEmailExists(ctx context.Context, email string) (bool, err
 }
 
 const checkPhoneExists = `-- name: CheckPhoneExists :one
-SELECT EXISTS(SELECT 1 FROM users WHERE email = $1) as exists
+SELECT EXISTS(SELECT 1 FROM users WHERE phone = $1) as exists
 `
 
-func (q *Queries) CheckPhoneExists(ctx context.Context, email string) (bool, error) {
-	row := q.db.QueryRow(ctx, checkPhoneExists, email)
+func (q *Queries) CheckPhoneExists(ctx context.Context, phone string) (bool, error) {
+	row := q.db.QueryRow(ctx, checkPhoneExists, phone)
 	var exists bool
 	err := row.Scan(&exists)
 	return exists, err
@@ -35,7 +36,7 @@ func (q *Queries) CheckPhoneExists(ctx context.Context, email string) (bool, err
 
 const createUserFromUserAuth = `-- name: CreateUserFromUserAuth :one
 INSERT INTO users (id, user_auth_id, file_id, email, phone, bank_account_name, bank_account_holder, bank_account_number)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, user_auth_id, file_id, email, phone, bank_account_name, bank_account_holder, bank_account_number, created_at, updated_at
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, user_auth_id, file_id, email, phone, bank_account_name, bank_account_holder, bank_account_number, created_at, updated_at, email_verified, phone_verified
 `
 
 type CreateUserFromUserAuthParams struct {
@@ -72,6 +73,8 @@ func (q *Queries) CreateUserFromUserAuth(ctx context.Context, arg CreateUserFrom
 		&i.BankAccountNumber,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerified,
+		&i.PhoneVerified,
 	)
 	return i, err
 }
@@ -85,8 +88,17 @@ func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const deleteUserByAuthID = `-- name: DeleteUserByAuthID :exec
+DELETE FROM users WHERE user_auth_id = $1
+`
+
+func (q *Queries) DeleteUserByAuthID(ctx context.Context, userAuthID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteUserByAuthID, userAuthID)
+	return err
+}
+
 const getUserByAuthID = `-- name: GetUserByAuthID :one
-SELECT id, user_auth_id, file_id, email, phone, bank_account_name, bank_account_holder, bank_account_number, created_at, updated_at FROM users WHERE user_auth_id = $1
+SELECT id, user_auth_id, file_id, email, phone, bank_account_name, bank_account_holder, bank_account_number, created_at, updated_at, email_verified, phone_verified FROM users WHERE user_auth_id = $1
 `
 
 func (q *Queries) GetUserByAuthID(ctx context.Context, userAuthID uuid.UUID) (Users, error) {
@@ -103,12 +115,14 @@ func (q *Queries) GetUserByAuthID(ctx context.Context, userAuthID uuid.UUID) (Us
 		&i.BankAccountNumber,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerified,
+		&i.PhoneVerified,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, user_auth_id, file_id, email, phone, bank_account_name, bank_account_holder, bank_account_number, created_at, updated_at FROM users WHERE email = $1
+SELECT id, user_auth_id, file_id, email, phone, bank_account_name, bank_account_holder, bank_account_number, created_at, updated_at, email_verified, phone_verified FROM users WHERE email = $1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (Users, error) {
@@ -125,12 +139,14 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (Users, erro
 		&i.BankAccountNumber,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerified,
+		&i.PhoneVerified,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, user_auth_id, file_id, email, phone, bank_account_name, bank_account_holder, bank_account_number, created_at, updated_at FROM users WHERE id = $1
+SELECT id, user_auth_id, file_id, email, phone, bank_account_name, bank_account_holder, bank_account_number, created_at, updated_at, email_verified, phone_verified FROM users WHERE id = $1
 `
 
 func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (Users, error) {
@@ -147,12 +163,14 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (Users, error)
 		&i.BankAccountNumber,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerified,
+		&i.PhoneVerified,
 	)
 	return i, err
 }
 
 const getUserByPhone = `-- name: GetUserByPhone :one
-SELECT id, user_auth_id, file_id, email, phone, bank_account_name, bank_account_holder, bank_account_number, created_at, updated_at FROM users WHERE phone = $1
+SELECT id, user_auth_id, file_id, email, phone, bank_account_name, bank_account_holder, bank_account_number, created_at, updated_at, email_verified, phone_verified FROM users WHERE phone = $1
 `
 
 func (q *Queries) GetUserByPhone(ctx context.Context, phone string) (Users, error) {
@@ -169,10 +187,50 @@ func (q *Queries) GetUserByPhone(ctx context.Context, phone string) (Users, erro
 		&i.BankAccountNumber,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerified,
+		&i.PhoneVerified,
 	)
 	return i, err
 }
 
+const listUsersByAuthIDPage = `-- name: ListUsersByAuthIDPage :many
+SELECT id, user_auth_id, created_at FROM users
+WHERE user_auth_id > $1
+ORDER BY user_auth_id
+LIMIT $2
+`
+
+type ListUsersByAuthIDPageParams struct {
+	UserAuthID uuid.UUID `json:"user_auth_id"`
+	Limit      int32     `json:"limit"`
+}
+
+type ListUsersByAuthIDPageRow struct {
+	ID         uuid.UUID `json:"id"`
+	UserAuthID uuid.UUID `json:"user_auth_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (q *Queries) ListUsersByAuthIDPage(ctx context.Context, arg ListUsersByAuthIDPageParams) ([]ListUsersByAuthIDPageRow, error) {
+	rows, err := q.db.Query(ctx, listUsersByAuthIDPage, arg.UserAuthID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListUsersByAuthIDPageRow{}
+	for rows.Next() {
+		var i ListUsersByAuthIDPageRow
+		if err := rows.Scan(&i.ID, &i.UserAuthID, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateUser = `-- name: UpdateUser :one
 UPDATE users SET
     file_id = COALESCE($2, file_id),
@@ -181,7 +239,7 @@ UPDATE users SET
     bank_account_number = $5,
     updated_at = CURRENT_TIMESTAMP
 WHERE id = $1
-RETURNING id, user_auth_id, file_id, email, phone, bank_account_name, bank_account_holder, bank_account_number, created_at, updated_at
+RETURNING id, user_auth_id, file_id, email, phone, bank_account_name, bank_account_holder, bank_account_number, created_at, updated_at, email_verified, phone_verified
 `
 
 type UpdateUserParams struct {
@@ -212,6 +270,8 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (Users,
 		&i.BankAccountNumber,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerified,
+		&i.PhoneVerified,
 	)
 	return i, err
 }
@@ -219,9 +279,10 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (Users,
 const updateUserEmail = `-- name: UpdateUserEmail :one
 UPDATE users SET
     email = $2,
+    email_verified = TRUE,
     updated_at = CURRENT_TIMESTAMP
 WHERE id = $1
-RETURNING id, user_auth_id, file_id, email, phone, bank_account_name, bank_account_holder, bank_account_number, created_at, updated_at
+RETURNING id, user_auth_id, file_id, email, phone, bank_account_name, bank_account_holder, bank_account_number, created_at, updated_at, email_verified, phone_verified
 `
 
 type UpdateUserEmailParams struct {
@@ -243,6 +304,8 @@ func (q *Queries) UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams
 		&i.BankAccountNumber,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerified,
+		&i.PhoneVerified,
 	)
 	return i, err
 }
@@ -250,9 +313,10 @@ func (q *Queries) UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams
 const updateUserPhone = `-- name: UpdateUserPhone :one
 UPDATE users SET
     phone = $2,
+    phone_verified = TRUE,
     updated_at = CURRENT_TIMESTAMP
 WHERE id = $1
-RETURNING id, user_auth_id, file_id, email, phone, bank_account_name, bank_account_holder, bank_account_number, created_at, updated_at
+RETURNING id, user_auth_id, file_id, email, phone, bank_account_name, bank_account_holder, bank_account_number, created_at, updated_at, email_verified, phone_verified
 `
 
 type UpdateUserPhoneParams struct {
@@ -274,6 +338,8 @@ func (q *Queries) UpdateUserPhone(ctx context.Context, arg UpdateUserPhoneParams
 		&i.BankAccountNumber,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerified,
+		&i.PhoneVerified,
 	)
 	return i, err
 }
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/teammachinist/tutuplapak/services/core/internal/logger"
+	"github.com/teammachinist/tutuplapak/services/core/internal/model"
+	"github.com/teammachinist/tutuplapak/services/core/internal/service"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+type CategoryHandler struct {
+	categoryService service.CategoryServiceInterface
+}
+
+func NewCategoryHandler(categoryService service.CategoryServiceInterface) *CategoryHandler {
+	return &CategoryHandler{categoryService: categoryService}
+}
+
+// ListCategories - The active categories, each with its parent
+// GET /v1/category
+func (h *CategoryHandler) ListCategories(c *fiber.Ctx) error {
+	return h.listCategories(c, false)
+}
+
+// ListAllCategories - Every category, inactive ones included
+// GET /v1/category/all
+func (h *CategoryHandler) ListAllCategories(c *fiber.Ctx) error {
+	return h.listCategories(c, true)
+}
+
+func (h *CategoryHandler) listCategories(c *fiber.Ctx, includeInactive bool) error {
+	ctx := c.Context()
+
+	categories, err := h.categoryService.ListCategories(ctx, includeInactive)
+	if err != nil {
+		logger.ErrorCtx(ctx, "Failed to list categories", "error", err.Error())
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to fetch categories",
+		})
+	}
+
+	return c.JSON(model.CategoryListResponse{Categories: categories})
+}
+
+// CreateCategory - POST /v1/category
+func (h *CategoryHandler) CreateCategory(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	req, errResp := parseCategoryRequest(c)
+	if errResp != nil {
+		return errResp
+	}
+
+	category, err := h.categoryService.CreateCategory(ctx, req)
+	if err != nil {
+		return h.handleCategoryError(c, ctx, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(category)
+}
+
+// UpdateCategory - PUT /v1/category/:categoryId
+func (h *CategoryHandler) UpdateCategory(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	categoryID, err := uuid.Parse(c.Params("categoryId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid category id format",
+		})
+	}
+
+	req, errResp := parseCategoryRequest(c)
+	if errResp != nil {
+		return errResp
+	}
+
+	category, err := h.categoryService.UpdateCategory(ctx, categoryID, req)
+	if err != nil {
+		return h.handleCategoryError(c, ctx, err)
+	}
+
+	return c.JSON(category)
+}
+
+// DeleteCategory - DELETE /v1/category/:categoryId
+func (h *CategoryHandler) DeleteCategory(c *fiber.Ctx) error {
+	ctx := c.Context()
+
+	categoryID, err := uuid.Parse(c.Params("categoryId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid category id format",
+		})
+	}
+
+	if err := h.categoryService.DeleteCategory(ctx, categoryID); err != nil {
+		return h.handleCategoryError(c, ctx, err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// parseCategoryRequest - The request body, or the error response to send when
+// it is malformed or invalid
+func parseCategoryRequest(c *fiber.Ctx) (model.CategoryRequest, error) {
+	var req model.CategoryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return req, c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	validate := validator.New()
+	validate.RegisterValidation("slug", func(fl validator.FieldLevel) bool {
+		return slugPattern.MatchString(fl.Field().String())
+	})
+
+	if err := validate.Struct(req); err != nil {
+		var details []string
+		for _, ve := range err.(validator.ValidationErrors) {
+			fieldName := categoryFieldName(ve.StructField())
+			switch ve.Tag() {
+			case "required":
+				details = append(details, fieldName+" is required")
+			case "min":
+				details = append(details, fieldName+" must be at least "+ve.Param())
+			case "max":
+				details = append(details, fieldName+" must be at most "+ve.Param())
+			case "slug":
+				details = append(details, fieldName+" must be lowercase letters and digits separated by single hyphens")
+			default:
+				details = append(details, fieldName+" is not valid")
+			}
+		}
+		return req, c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation error",
+			"details": details,
+		})
+	}
+
+	return req, nil
+}
+
+func categoryFieldName(fieldName string) string {
+	if f, ok := reflect.TypeOf(model.CategoryRequest{}).FieldByName(fieldName); ok {
+		if jsonTag, _, _ := strings.Cut(f.Tag.Get("json"), ","); jsonTag != "" && jsonTag != "-" {
+			return jsonTag
+		}
+	}
+	return fieldName
+}
+
+func (h *CategoryHandler) handleCategoryError(c *fiber.Ctx, ctx context.Context, err error) error {
+	switch err.Error() {
+	case "category not found":
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case "category slug already exists", "category in use":
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case "parent category not found", "category cannot be moved under itself", "parentId is not valid":
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	default:
+		logger.ErrorCtx(ctx, "Category write failed", "error", err.Error())
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+}
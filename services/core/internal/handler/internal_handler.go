@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"strconv"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 
@@ -57,7 +59,7 @@ func (h *InternalHandler) CreateUserFromAuth(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusCreated).JSON(response)
 }
 
-// CreateUserFromAuth - Called by Auth service to create user record
+// GetUserFromAuth - Called by Auth service to resolve user.id from user_auth_id
 func (h *InternalHandler) GetUserFromAuth(c *fiber.Ctx) error {
 	ctx := logger.WithRequestID(c.Context())
 	logger.InfoCtx(ctx, "Get user from auth request")
@@ -79,3 +81,77 @@ func (h *InternalHandler) GetUserFromAuth(c *fiber.Ctx) error {
 		"user_auth_id": user.UserAuthID,
 	})
 }
+
+// ListUsersFromAuth - Called by Auth's reconciler, pages through users ordered by
+// user_auth_id starting after ?after=
+func (h *InternalHandler) ListUsersFromAuth(c *fiber.Ctx) error {
+	ctx := logger.WithRequestID(c.Context())
+
+	after := uuid.Nil
+	if value := c.Query("after"); value != "" {
+		parsed, err := uuid.Parse(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid after"})
+		}
+		after = parsed
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "limit must be between 1 and 1000"})
+	}
+
+	response, err := h.userService.ListUsersFromAuth(ctx, after, int32(limit))
+	if err != nil {
+		logger.ErrorCtx(ctx, "Failed to list users for auth", "error", err.Error())
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(response)
+}
+
+// DeleteUserFromAuth - Called by Auth to drop a profile without an account, after
+// an account deletion or from the reconciler. Removes its products and files too.
+func (h *InternalHandler) DeleteUserFromAuth(c *fiber.Ctx) error {
+	ctx := logger.WithRequestID(c.Context())
+
+	userAuthUUID, err := uuid.Parse(c.Params("userAuthID"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid user auth ID"})
+	}
+
+	response, err := h.userService.DeleteUserFromAuth(ctx, userAuthUUID)
+	if err != nil {
+		logger.ErrorCtx(ctx, "Failed to delete user for auth", "user_auth_id", userAuthUUID.String(), "error", err.Error())
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(response)
+}
+
+// MergeUsersFromAuth - Called by Auth when two accounts merge, moves the source
+// user's products and files to the target and drops the source
+func (h *InternalHandler) MergeUsersFromAuth(c *fiber.Ctx) error {
+	ctx := logger.WithRequestID(c.Context())
+
+	var req model.MergeUsersFromAuthRequest
+	if err := c.BodyParser(&req); err != nil {
+		logger.WarnCtx(ctx, "Invalid merge users request", "error", err.Error())
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	response, err := h.userService.MergeUsersFromAuth(ctx, req)
+	if err != nil {
+		logger.WarnCtx(ctx, "Failed to merge users from auth", "merge_id", req.MergeID, "error", err.Error())
+
+		switch err.Error() {
+		case "invalid source user id", "invalid target user id", "cannot merge a user into itself":
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		case "user not found":
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(response)
+}
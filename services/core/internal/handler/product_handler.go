@@ -6,6 +6,7 @@ import (
 	"errors"
 	"net/http"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -20,95 +21,197 @@ import (
 )
 
 type ProductHandler struct {
-	productService service.ProductServiceInterface
+	productService  service.ProductServiceInterface
+	categoryService service.CategoryServiceInterface
 }
 
-func NewProductHandler(productService service.ProductServiceInterface) *ProductHandler {
-	return &ProductHandler{productService: productService}
+func NewProductHandler(productService service.ProductServiceInterface, categoryService service.CategoryServiceInterface) *ProductHandler {
+	return &ProductHandler{
+		productService:  productService,
+		categoryService: categoryService,
+	}
 }
 
+// maxSearchLength - characters of the q parameter searched for
+const maxSearchLength = 100
+
+// HeaderNextCursor - carries the ?cursor= token for the next page of a product
+// listing, absent on the last page
+const HeaderNextCursor = "X-Next-Cursor"
+
 func (h *ProductHandler) GetAllProducts(c *fiber.Ctx) error {
 	ctx := c.Context()
 
-	limit := 5
-	offset := 0
+	filter, details, err := h.parseProductFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to fetch products",
+		})
+	}
+	if len(details) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation error",
+			"details": details,
+		})
+	}
+
+	// A cursor picks up where the previous page ended and overrides offset.
+	// It is only valid for the sort it was issued for.
+	if token := c.Query("cursor"); token != "" {
+		cursor, err := service.DecodeProductCursor(token)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if cursor.SortBy != filter.Sort() {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "cursor does not match sortBy",
+			})
+		}
+		filter.Cursor = cursor
+		filter.Offset = 0
+	}
+
+	page, err := h.productService.GetAllProducts(ctx, filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to fetch products",
+		})
+	}
+
+	// The body stays a bare array; the next page is offered out of band
+	if page.NextCursor != "" {
+		c.Set(HeaderNextCursor, page.NextCursor)
+	}
 
-	var productID *uuid.UUID
-	var sku *string
-	var category *string
-	var sortBy *string
+	if len(page.Products) == 0 {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "no products found",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(page.Products)
+}
+
+var allowedProductSorts = map[string]bool{
+	"newest":    true,
+	"oldest":    true,
+	"cheapest":  true,
+	"expensive": true,
+}
+
+// parseProductFilter - The GET /v1/product query as listing filters, with one
+// detail per parameter that is malformed or out of range
+func (h *ProductHandler) parseProductFilter(c *fiber.Ctx) (model.GetAllProductsParams, []string, error) {
+	filter := model.GetAllProductsParams{Limit: 5}
+	var details []string
 
 	if limStr := c.Query("limit"); limStr != "" {
 		if l, err := strconv.Atoi(limStr); err == nil && l > 0 {
-			limit = l
+			filter.Limit = l
+		} else {
+			details = append(details, "limit must be a positive integer")
 		}
 	}
 
 	if offStr := c.Query("offset"); offStr != "" {
 		if o, err := strconv.Atoi(offStr); err == nil && o >= 0 {
-			offset = o
+			filter.Offset = o
+		} else {
+			details = append(details, "offset must be a non-negative integer")
 		}
 	}
 
 	if pidStr := c.Query("productId"); pidStr != "" {
 		if pid, err := uuid.Parse(pidStr); err == nil {
-			productID = &pid
+			filter.ProductID = &pid
+		} else {
+			details = append(details, "productId is not valid")
 		}
 	}
 
 	if s := c.Query("sku"); s != "" {
-		sku = &s
+		filter.SKU = &s
 	}
 
-	if cat := c.Query("category"); cat != "" {
-		allowedCategories := map[string]bool{
-			"Food":      true,
-			"Beverage":  true,
-			"Clothes":   true,
-			"Furniture": true,
-			"Tools":     true,
+	// Several categories come as category=food,tools or as repeated parameters,
+	// each by slug or name. A parent category takes in its subcategories.
+	var categories []string
+	for _, value := range c.Context().QueryArgs().PeekMulti("category") {
+		for _, cat := range strings.Split(string(value), ",") {
+			if cat = strings.TrimSpace(cat); cat != "" {
+				categories = append(categories, cat)
+			}
 		}
-		if allowedCategories[cat] {
-			category = &cat
+	}
+	if len(categories) > 0 {
+		slugs, err := h.categoryService.ExpandCategories(c.Context(), categories)
+		switch {
+		case err != nil && err.Error() == "category not found":
+			details = append(details, "category must be an active category")
+		case err != nil:
+			return filter, nil, err
+		default:
+			for _, slug := range slugs {
+				if !slices.Contains(filter.Categories, slug) {
+					filter.Categories = append(filter.Categories, slug)
+				}
+			}
 		}
 	}
 
-	if sb := c.Query("sortBy"); sb != "" {
-		validSorts := map[string]bool{
-			"newest":    true,
-			"oldest":    true,
-			"cheapest":  true,
-			"expensive": true,
+	if sellerStr := c.Query("userId"); sellerStr != "" {
+		if sellerID, err := uuid.Parse(sellerStr); err == nil {
+			filter.SellerID = &sellerID
+		} else {
+			details = append(details, "userId is not valid")
 		}
-		if validSorts[strings.ToLower(sb)] {
-			lower := strings.ToLower(sb)
-			sortBy = &lower
+	}
+
+	for _, bound := range []struct {
+		param string
+		dest  **int
+	}{{"minPrice", &filter.MinPrice}, {"maxPrice", &filter.MaxPrice}} {
+		if value := c.Query(bound.param); value != "" {
+			price, err := strconv.Atoi(value)
+			if err != nil || price < 0 {
+				details = append(details, bound.param+" must be a non-negative integer")
+				continue
+			}
+			*bound.dest = &price
 		}
 	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		details = append(details, "minPrice must not be greater than maxPrice")
+	}
 
-	filter := model.GetAllProductsParams{
-		Limit:     limit,
-		Offset:    offset,
-		ProductID: productID,
-		SKU:       sku,
-		Category:  category,
-		SortBy:    sortBy,
+	if stockStr := c.Query("inStock"); stockStr != "" {
+		if inStock, err := strconv.ParseBool(stockStr); err == nil {
+			filter.InStock = inStock
+		} else {
+			details = append(details, "inStock must be true or false")
+		}
 	}
 
-	products, err := h.productService.GetAllProducts(ctx, filter)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "failed to fetch products",
-		})
+	// Anything past the limit can't match a name better, it only costs more to rank
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		if len([]rune(q)) > maxSearchLength {
+			q = string([]rune(q)[:maxSearchLength])
+		}
+		filter.Search = &q
 	}
 
-	if len(products) == 0 {
-		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"message": "no products found",
-		})
+	if sb := c.Query("sortBy"); sb != "" {
+		lower := strings.ToLower(sb)
+		if allowedProductSorts[lower] {
+			filter.SortBy = &lower
+		} else {
+			details = append(details, "sortBy must be one of: newest, oldest, cheapest, expensive")
+		}
 	}
 
-	return c.Status(fiber.StatusOK).JSON(products)
+	return filter, details, nil
 }
 
 func (h *ProductHandler) CreateProduct(c *fiber.Ctx) error {
@@ -122,19 +225,6 @@ func (h *ProductHandler) CreateProduct(c *fiber.Ctx) error {
 	}
 
 	validate := validator.New()
-
-	validate.RegisterValidation("category_enum", func(fl validator.FieldLevel) bool {
-		category := fl.Field().String()
-		allowed := map[string]bool{
-			"Food":      true,
-			"Beverage":  true,
-			"Clothes":   true,
-			"Furniture": true,
-			"Tools":     true,
-		}
-		return allowed[category]
-	})
-
 	if err := validate.Struct(req); err != nil {
 		var details []string
 		for _, ve := range err.(validator.ValidationErrors) {
@@ -146,8 +236,8 @@ func (h *ProductHandler) CreateProduct(c *fiber.Ctx) error {
 				details = append(details, fieldName+" must be at least "+ve.Param())
 			case "max":
 				details = append(details, fieldName+" must be at most "+ve.Param())
-			case "category_enum":
-				details = append(details, fieldName+" must be one of: Food, Beverage, Clothes, Furniture, Tools")
+			case "required_without":
+				details = append(details, fieldName+" is required without "+getJSONTagName(ve.Param()))
 			default:
 				details = append(details, fieldName+" is not valid")
 			}
@@ -156,6 +246,25 @@ func (h *ProductHandler) CreateProduct(c *fiber.Ctx) error {
 			"error": details,
 		})
 	}
+	if details := append(req.GalleryDetails(), req.VariantDetails()...); len(details) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": details,
+		})
+	}
+
+	category, err := h.categoryService.ResolveCategory(ctx, req.Category)
+	if err != nil {
+		if err.Error() == "category not found" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": []string{"category must be an active category"},
+			})
+		}
+		logger.WarnCtx(ctx, "Failed to resolve product category", "error", err.Error())
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+	req.Category = category.Slug
 
 	// Get user ID from authz
 	userIDStr, ok := authz.GetUserIDFromFiber(c)
@@ -199,6 +308,9 @@ func (h *ProductHandler) CreateProduct(c *fiber.Ctx) error {
 		}
 	}
 
+	// Stored by slug, shown by name
+	productResp.Category = category.Name
+
 	return c.Status(fiber.StatusCreated).JSON(productResp)
 }
 
@@ -258,19 +370,6 @@ func (h *ProductHandler) UpdateProduct(c *fiber.Ctx) error {
 	// userID := uuid.MustParse("11111111-1111-1111-1111-111111111111") // UUID dummy valid
 
 	validate := validator.New()
-
-	validate.RegisterValidation("category_enum", func(fl validator.FieldLevel) bool {
-		category := fl.Field().String()
-		allowed := map[string]bool{
-			"Food":      true,
-			"Beverage":  true,
-			"Clothes":   true,
-			"Furniture": true,
-			"Tools":     true,
-		}
-		return allowed[category]
-	})
-
 	if err := validate.Struct(req); err != nil {
 		var details []string
 		for _, ve := range err.(validator.ValidationErrors) {
@@ -282,8 +381,8 @@ func (h *ProductHandler) UpdateProduct(c *fiber.Ctx) error {
 				details = append(details, fieldName+" must be at least "+ve.Param())
 			case "max":
 				details = append(details, fieldName+" must be at most "+ve.Param())
-			case "category_enum":
-				details = append(details, fieldName+" must be one of: Food, Beverage, Clothes, Furniture, Tools")
+			case "required_without":
+				details = append(details, fieldName+" is required without "+getJSONTagName(ve.Param()))
 			default:
 				details = append(details, fieldName+" is not valid")
 			}
@@ -293,6 +392,27 @@ func (h *ProductHandler) UpdateProduct(c *fiber.Ctx) error {
 			"details": details,
 		})
 	}
+	if details := append(req.GalleryDetails(), req.VariantDetails()...); len(details) > 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation error",
+			"details": details,
+		})
+	}
+
+	category, err := h.categoryService.ResolveCategory(ctx, req.Category)
+	if err != nil {
+		if err.Error() == "category not found" {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error":   "Validation error",
+				"details": []string{"category must be an active category"},
+			})
+		}
+		logger.WarnCtx(ctx, "Failed to resolve product category", "error", err.Error())
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+	req.Category = category.Slug
 
 	resp, err := h.productService.UpdateProduct(ctx, productID, req, userID)
 	if err != nil {
@@ -318,6 +438,8 @@ func (h *ProductHandler) UpdateProduct(c *fiber.Ctx) error {
 		}
 	}
 
+	resp.Category = category.Name
+
 	return c.Status(fiber.StatusOK).JSON(resp)
 }
 
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/teammachinist/tutuplapak/services/core/internal/model"
+	"github.com/teammachinist/tutuplapak/services/core/internal/service"
+)
+
+// listingService - serves page for every listing, keeping the filter it got
+type listingService struct {
+	service.ProductServiceInterface
+	page   model.ProductListResponse
+	filter model.GetAllProductsParams
+}
+
+func (s *listingService) GetAllProducts(ctx context.Context, filter model.GetAllProductsParams) (model.ProductListResponse, error) {
+	s.filter = filter
+	return s.page, nil
+}
+
+func listProducts(t *testing.T, products *listingService, target string) *http.Response {
+	t.Helper()
+
+	app := fiber.New()
+	app.Get("/v1/product", NewProductHandler(products, nil).GetAllProducts)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, target, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestGetAllProductsNextCursorHeader(t *testing.T) {
+	next := service.EncodeProductCursor(model.ProductCursor{SortBy: "newest", ID: uuid.New(), Time: 1})
+	products := &listingService{page: model.ProductListResponse{
+		Products:   []model.ProductResponse{{ProductID: uuid.New(), Name: "Kopi"}},
+		NextCursor: next,
+	}}
+
+	resp := listProducts(t, products, "/v1/product?limit=1")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get(HeaderNextCursor); got != next {
+		t.Errorf("%s %q, want %q", HeaderNextCursor, got, next)
+	}
+
+	// The body is the array of products it always was
+	body, _ := io.ReadAll(resp.Body)
+	var listed []model.ProductResponse
+	if err := json.Unmarshal(body, &listed); err != nil || len(listed) != 1 || listed[0].Name != "Kopi" {
+		t.Errorf("body %s is not the page's products", body)
+	}
+}
+
+func TestGetAllProductsLastPage(t *testing.T) {
+	products := &listingService{page: model.ProductListResponse{
+		Products: []model.ProductResponse{{ProductID: uuid.New()}},
+	}}
+
+	resp := listProducts(t, products, "/v1/product")
+	if _, ok := resp.Header[HeaderNextCursor]; ok {
+		t.Errorf("%s set on the last page", HeaderNextCursor)
+	}
+}
+
+func TestGetAllProductsCursor(t *testing.T) {
+	cursor := model.ProductCursor{SortBy: "cheapest", ID: uuid.New(), Price: 1500}
+	products := &listingService{}
+
+	resp := listProducts(t, products, "/v1/product?sortBy=cheapest&offset=20&cursor="+service.EncodeProductCursor(cursor))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	if products.filter.Cursor == nil || *products.filter.Cursor != cursor {
+		t.Errorf("listed from cursor %+v, want %+v", products.filter.Cursor, cursor)
+	}
+	if products.filter.Offset != 0 {
+		t.Errorf("offset %d alongside the cursor", products.filter.Offset)
+	}
+}
+
+func TestGetAllProductsRejectsCursor(t *testing.T) {
+	tests := map[string]string{
+		"malformed":                       "/v1/product?cursor=not-a-cursor",
+		"other sort":                      "/v1/product?sortBy=expensive&cursor=" + service.EncodeProductCursor(model.ProductCursor{SortBy: "cheapest", ID: uuid.New()}),
+		"relevance cursor without search": "/v1/product?cursor=" + service.EncodeProductCursor(model.ProductCursor{ID: uuid.New(), Rank: 0.5}),
+	}
+
+	for name, target := range tests {
+		t.Run(name, func(t *testing.T) {
+			if resp := listProducts(t, &listingService{}, target); resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("status %d, want %d", resp.StatusCode, http.StatusBadRequest)
+			}
+		})
+	}
+}
@@ -76,6 +76,10 @@ func (h *PurchaseHandler) CreatePurchase(c *fiber.Ctx) error {
 		switch {
 		case err.Error() == "product not found":
 			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid product ID"})
+		case err.Error() == "variant not found":
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid variant ID"})
+		case strings.HasPrefix(err.Error(), "variantId is required for"):
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 		case strings.HasPrefix(err.Error(), "insufficient stock for"):
 			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "quantity exceeds available stock"})
 		default:
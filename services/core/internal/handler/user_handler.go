@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"context"
 	"net/http"
 	"regexp"
 	"strings"
@@ -82,13 +83,17 @@ func (h *UserHandler) LinkPhone(c *fiber.Ctx) error {
 	}
 
 	// Call service to link phone
-	resp, err := h.userService.LinkPhone(c.Context(), userID, req.Phone)
+	resp, err := h.userService.LinkPhone(c.Context(), userID, req.Phone, clientInfo(c))
 	if err != nil {
 		switch err.Error() {
 		case "phone is taken":
 			return c.Status(http.StatusConflict).JSON(fiber.Map{
 				"error": "Phone is taken",
 			})
+		case "verification code recently sent, try again later":
+			return c.Status(http.StatusTooManyRequests).JSON(fiber.Map{
+				"error": "Verification code recently sent, try again later",
+			})
 		case "phone must start with international calling code prefix '+'":
 			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
 				"error": "Phone must start with international calling code prefix '+'",
@@ -104,7 +109,7 @@ func (h *UserHandler) LinkPhone(c *fiber.Ctx) error {
 		}
 	}
 
-	return c.Status(http.StatusOK).JSON(resp)
+	return c.Status(http.StatusAccepted).JSON(resp)
 }
 
 func (h *UserHandler) LinkEmail(c *fiber.Ctx) error {
@@ -159,13 +164,17 @@ func (h *UserHandler) LinkEmail(c *fiber.Ctx) error {
 		})
 	}
 
-	resp, err := h.userService.LinkEmail(c.Context(), userID, req.Email)
+	resp, err := h.userService.LinkEmail(c.Context(), userID, req.Email, clientInfo(c))
 	if err != nil {
 		switch err.Error() {
 		case "email is taken":
 			return c.Status(http.StatusConflict).JSON(fiber.Map{
 				"error": "Email is taken",
 			})
+		case "verification code recently sent, try again later":
+			return c.Status(http.StatusTooManyRequests).JSON(fiber.Map{
+				"error": "Verification code recently sent, try again later",
+			})
 		case "email is required":
 			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
 				"error": "Email is required",
@@ -181,7 +190,23 @@ func (h *UserHandler) LinkEmail(c *fiber.Ctx) error {
 		}
 	}
 
-	return c.Status(http.StatusOK).JSON(resp)
+	return c.Status(http.StatusAccepted).JSON(resp)
+}
+
+// VerifyLinkPhone - Redeem the OTP sent by LinkPhone and link the phone
+// POST /v1/user/link/phone/verify
+func (h *UserHandler) VerifyLinkPhone(c *fiber.Ctx) error {
+	return verifyLink(c, func(ctx context.Context, userID uuid.UUID, code string) (interface{}, error) {
+		return h.userService.VerifyLinkPhone(ctx, userID, code, clientInfo(c))
+	})
+}
+
+// VerifyLinkEmail - Redeem the OTP sent by LinkEmail and link the email
+// POST /v1/user/link/email/verify
+func (h *UserHandler) VerifyLinkEmail(c *fiber.Ctx) error {
+	return verifyLink(c, func(ctx context.Context, userID uuid.UUID, code string) (interface{}, error) {
+		return h.userService.VerifyLinkEmail(ctx, userID, code, clientInfo(c))
+	})
 }
 
 func (h *UserHandler) GetUserWithFileId(c *fiber.Ctx) error {
@@ -293,3 +318,125 @@ func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
 
 	return c.Status(fiber.StatusOK).JSON(rows)
 }
+
+// clientInfo - the caller's IP and user agent, auth records them in its audit log
+func clientInfo(c *fiber.Ctx) authz.ClientInfo {
+	return authz.ClientInfo{
+		IPAddress: c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+	}
+}
+
+func verifyLink(c *fiber.Ctx, verify func(ctx context.Context, userID uuid.UUID, code string) (interface{}, error)) error {
+	userIDStr, ok := authz.GetUserIDFromFiber(c)
+	if !ok {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Authorization header required",
+		})
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid user id in token",
+		})
+	}
+
+	var req model.VerifyLinkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := validator.New().Struct(req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation error",
+			"details": []string{"code is required"},
+		})
+	}
+
+	resp, err := verify(c.Context(), userID, req.Code)
+	if err != nil {
+		switch err.Error() {
+		case "invalid or expired code":
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid or expired code",
+			})
+		case "phone is taken":
+			return c.Status(http.StatusConflict).JSON(fiber.Map{
+				"error": "Phone is taken",
+			})
+		case "email is taken":
+			return c.Status(http.StatusConflict).JSON(fiber.Map{
+				"error": "Email is taken",
+			})
+		default:
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Internal server error",
+			})
+		}
+	}
+
+	return c.Status(http.StatusOK).JSON(resp)
+}
+
+// DeleteUser - Delete the account and everything it owns. Needs the password,
+// and a TOTP or recovery code when two-factor is on. Products and files are
+// removed in the background, purchases keep their amounts with the user's
+// name and contact details scrubbed.
+// DELETE /v1/user
+func (h *UserHandler) DeleteUser(c *fiber.Ctx) error {
+	userIDStr, ok := authz.GetUserIDFromFiber(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized: user not authenticated",
+		})
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "invalid user id in token",
+		})
+	}
+
+	req := model.DeleteUserRequest{}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	resp, err := h.userService.DeleteAccount(c.Context(), userID, req.Password, req.TOTPCode, c.IP())
+	if err != nil {
+		switch err.Error() {
+		case "password is required", "two-factor code required":
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case "invalid credentials", "invalid two-factor code":
+			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case "user does not exist":
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{
+				"error": "User not found",
+			})
+		case "account merge in progress":
+			return c.Status(http.StatusConflict).JSON(fiber.Map{
+				"error": "Account merge in progress, try again later",
+			})
+		case "too many failed login attempts":
+			return c.Status(http.StatusTooManyRequests).JSON(fiber.Map{
+				"error": "Too many failed attempts, try again later",
+			})
+		default:
+			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Internal server error",
+			})
+		}
+	}
+
+	return c.Status(http.StatusAccepted).JSON(resp)
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Category struct {
+	ID        uuid.UUID  `json:"categoryId"`
+	Slug      string     `json:"slug"`
+	Name      string     `json:"name"`
+	ParentID  *uuid.UUID `json:"parentId"`
+	Active    bool       `json:"active"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+// CategoryRequest - a whole category for create and update. Without ParentID
+// the category is top-level; Active defaults to true.
+type CategoryRequest struct {
+	Slug     string  `json:"slug" validate:"required,max=64,slug"`
+	Name     string  `json:"name" validate:"required,min=1,max=100"`
+	ParentID *string `json:"parentId" validate:"omitempty,uuid"`
+	Active   *bool   `json:"active"`
+}
+
+type CategoryListResponse struct {
+	Categories []Category `json:"categories"`
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -19,16 +20,159 @@ type Product struct {
 	UserID           uuid.UUID `json:"userId"`
 	CreatedAt        time.Time `json:"createdAt"`
 	UpdatedAt        time.Time `json:"updatedAt"`
+	Rank             float32   `json:"-"` // Relevance to the search, when listed by it
 }
 
+// ProductRequest - a whole product for create and update. With Variants the
+// product's qty and price come from them and need not be given; an update
+// without Variants removes any the product had. FileIDs is the gallery in
+// display order, FileID alone stands for a gallery of one.
 type ProductRequest struct {
-	Name     string    `json:"name" validate:"required,min=4,max=32"`
-	Category string    `json:"category" validate:"required,category_enum"`
-	Qty      int       `json:"qty" validate:"required,min=1"`
-	Price    int       `json:"price" validate:"required,min=100"`
-	SKU      string    `json:"sku" validate:"required,max=32"`
-	FileID   string    `json:"fileId" validate:"required,uuid" `
-	UserID   uuid.UUID `json:"userId" `
+	Name        string                  `json:"name" validate:"required,min=4,max=32"`
+	Category    string                  `json:"category" validate:"required,max=64"`
+	Qty         int                     `json:"qty" validate:"required_without=Variants,omitempty,min=1"`
+	Price       int                     `json:"price" validate:"required_without=Variants,omitempty,min=100"`
+	SKU         string                  `json:"sku" validate:"required,max=32"`
+	FileID      string                  `json:"fileId" validate:"required_without=FileIDs,omitempty,uuid"`
+	FileIDs     []string                `json:"fileIds" validate:"omitempty,max=10,dive,uuid"`
+	CoverFileID string                  `json:"coverFileId" validate:"omitempty,uuid"`
+	Options     []ProductOption         `json:"options" validate:"omitempty,max=3,dive"`
+	Variants    []ProductVariantRequest `json:"variants" validate:"omitempty,max=100,dive"`
+	UserID      uuid.UUID               `json:"userId" `
+}
+
+// ProductOption - an axis variants differ along, such as size, and the values
+// it takes
+type ProductOption struct {
+	Name   string   `json:"name" validate:"required,max=32"`
+	Values []string `json:"values" validate:"required,min=1,max=50,dive,required,max=32"`
+}
+
+type ProductVariantRequest struct {
+	SKU        string            `json:"sku" validate:"required,max=32"`
+	Price      int               `json:"price" validate:"required,min=100"`
+	Qty        int               `json:"qty" validate:"min=0"`
+	Attributes map[string]string `json:"attributes" validate:"required"` // Option name to value
+}
+
+type ProductVariant struct {
+	ID         uuid.UUID         `json:"variantId"`
+	SKU        string            `json:"sku"`
+	Price      int               `json:"price"`
+	Qty        int               `json:"qty"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+// ProductVariants - the options of one product and its variants
+type ProductVariants struct {
+	Options  []ProductOption
+	Variants []ProductVariant
+}
+
+// Gallery - The file IDs of the gallery and the one of them that is the
+// cover: CoverFileID if given, else FileID, else the first image
+func (r ProductRequest) Gallery() ([]string, string) {
+	fileIDs := r.FileIDs
+	if len(fileIDs) == 0 {
+		fileIDs = []string{r.FileID}
+	}
+
+	switch {
+	case r.CoverFileID != "":
+		return fileIDs, r.CoverFileID
+	case r.FileID != "":
+		return fileIDs, r.FileID
+	default:
+		return fileIDs, fileIDs[0]
+	}
+}
+
+// GalleryDetails - What makes the gallery inconsistent, one detail each
+func (r ProductRequest) GalleryDetails() []string {
+	var details []string
+	fileIDs, cover := r.Gallery()
+
+	seen := make(map[string]bool, len(fileIDs))
+	for _, fileID := range fileIDs {
+		if seen[fileID] {
+			details = append(details, "fileIds must not repeat a file")
+			break
+		}
+		seen[fileID] = true
+	}
+
+	if !seen[cover] {
+		field := "coverFileId"
+		if r.CoverFileID == "" {
+			field = "fileId"
+		}
+		details = append(details, field+" must be one of fileIds")
+	}
+	return details
+}
+
+// VariantDetails - What makes Options and Variants inconsistent, one detail
+// each: every variant must pick one value of every option, and no two
+// variants may share a SKU or a combination of values
+func (r ProductRequest) VariantDetails() []string {
+	if len(r.Options) == 0 && len(r.Variants) == 0 {
+		return nil
+	}
+	if len(r.Options) == 0 {
+		return []string{"options are required with variants"}
+	}
+	if len(r.Variants) == 0 {
+		return []string{"variants are required with options"}
+	}
+
+	var details []string
+	values := make(map[string]map[string]bool, len(r.Options))
+	for _, option := range r.Options {
+		if values[option.Name] != nil {
+			details = append(details, "option "+option.Name+" is given twice")
+			continue
+		}
+		values[option.Name] = make(map[string]bool, len(option.Values))
+		for _, value := range option.Values {
+			values[option.Name][value] = true
+		}
+	}
+
+	skus := make(map[string]bool, len(r.Variants))
+	combinations := make(map[string]bool, len(r.Variants))
+	for _, variant := range r.Variants {
+		if skus[variant.SKU] {
+			details = append(details, "variant sku "+variant.SKU+" is given twice")
+		}
+		skus[variant.SKU] = true
+
+		valid := len(variant.Attributes) == len(values)
+		for name, value := range variant.Attributes {
+			valid = valid && values[name][value]
+		}
+		if !valid {
+			details = append(details, "variant "+variant.SKU+" must have one value of every option")
+			continue
+		}
+
+		var combination strings.Builder
+		for _, option := range r.Options {
+			combination.WriteString(variant.Attributes[option.Name] + "\x00")
+		}
+		if combinations[combination.String()] {
+			details = append(details, "variant "+variant.SKU+" has the same options as another variant")
+		}
+		combinations[combination.String()] = true
+	}
+	return details
+}
+
+// ProductImage - one image of a product's gallery
+type ProductImage struct {
+	FileID           uuid.UUID `json:"fileId"`
+	FileURI          string    `json:"fileUri"`
+	FileThumbnailURI string    `json:"fileThumbnailUri"`
+	Cover            bool      `json:"cover"`
 }
 
 type ProductResponse struct {
@@ -44,14 +188,58 @@ type ProductResponse struct {
 	CreatedAt        time.Time `json:"createdAt"`
 	UpdatedAt        time.Time `json:"updatedAt"`
 
+	Images   []ProductImage   `json:"images,omitempty"` // FileID is the cover
+	Options  []ProductOption  `json:"options,omitempty"`
+	Variants []ProductVariant `json:"variants,omitempty"`
+
+	// Set on a purchased item bought as a variant
+	VariantID  *uuid.UUID        `json:"variantId,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+
 	UserID uuid.UUID `json:"-"` // ← ini tidak akan muncul di response!
 }
 
 type GetAllProductsParams struct {
-	Limit     int
-	Offset    int
-	ProductID *uuid.UUID
-	SKU       *string
-	Category  *string
-	SortBy    *string
+	Limit      int
+	Offset     int
+	ProductID  *uuid.UUID
+	SKU        *string
+	Categories []string // Any of them, all when empty
+	SellerID   *uuid.UUID
+	MinPrice   *int
+	MaxPrice   *int
+	InStock    bool    // Only products with qty left
+	Search     *string // Free text, ranked by relevance unless SortBy is set
+	SortBy     *string
+	Cursor     *ProductCursor // Replaces Offset when set
+}
+
+// Sort - the order products are listed in: SortBy, else relevance ("") when
+// searching, else newest
+func (p GetAllProductsParams) Sort() string {
+	if p.SortBy != nil {
+		return *p.SortBy
+	}
+	if p.Search != nil {
+		return ""
+	}
+	return "newest"
+}
+
+// ProductCursor - where the next page starts: after the product ID with the
+// given sort key (Time in Unix microseconds for newest/oldest, Price for
+// cheapest/expensive, Rank for a search by relevance)
+type ProductCursor struct {
+	SortBy string    `json:"s"`
+	ID     uuid.UUID `json:"id"`
+	Time   int64     `json:"t,omitempty"`
+	Price  int       `json:"p,omitempty"`
+	Rank   float32   `json:"r,omitempty"`
+}
+
+// ProductListResponse - one page of a listing as the service returns and caches
+// it. Clients get Products as the body and NextCursor in a header.
+type ProductListResponse struct {
+	Products   []ProductResponse `json:"products"`
+	NextCursor string            `json:"nextCursor,omitempty"` // Empty on the last page
 }
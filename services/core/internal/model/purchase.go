@@ -16,20 +16,23 @@ type PurchaseRequest struct {
 
 type PurchaseItemRequest struct {
 	ProductID uuid.UUID `json:"productId"`
+	VariantID uuid.UUID `json:"variantId"` // Required when the product has variants
 	Qty       int       `json:"qty"`
 }
 
 type PurchasedItemSnapshot struct {
-	ProductID uuid.UUID `json:"productId" db:"product_id"`
-	Name      string    `json:"name" db:"name"`
-	Category  string    `json:"category" db:"category"`
-	Qty       int       `json:"qty" db:"qty"`
-	Price     int       `json:"price" db:"price"`
-	SKU       string    `json:"sku" db:"sku"`
-	FileID    uuid.UUID `json:"fileId" db:"file_id"`
-	SellerID  uuid.UUID `json:"sellerId" db:"seller_id"`
-	CreatedAt time.Time `json:"createdAt" db:"created_at"`
-	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+	ProductID  uuid.UUID         `json:"productId" db:"product_id"`
+	Name       string            `json:"name" db:"name"`
+	Category   string            `json:"category" db:"category"`
+	Qty        int               `json:"qty" db:"qty"`
+	Price      int               `json:"price" db:"price"`
+	SKU        string            `json:"sku" db:"sku"`
+	VariantID  *uuid.UUID        `json:"variantId,omitempty" db:"variant_id"`
+	Attributes map[string]string `json:"attributes,omitempty" db:"attributes"`
+	FileID     uuid.UUID         `json:"fileId" db:"file_id"`
+	SellerID   uuid.UUID         `json:"sellerId" db:"seller_id"`
+	CreatedAt  time.Time         `json:"createdAt" db:"created_at"`
+	UpdatedAt  time.Time         `json:"updatedAt" db:"updated_at"`
 }
 
 type PurchaseResponse struct {
@@ -1,6 +1,10 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type User struct {
 	ID                uuid.UUID `json:"id"`
@@ -18,6 +22,8 @@ type LinkPhoneRequest struct {
 type LinkPhoneResponse struct {
 	Email             string `json:"email"`
 	Phone             string `json:"phone"`
+	EmailVerified     bool   `json:"emailVerified"`
+	PhoneVerified     bool   `json:"phoneVerified"`
 	FileID            string `json:"fileId"`
 	FileURI           string `json:"fileUri"`
 	FileThumbnailURI  string `json:"fileThumbnailUri"`
@@ -33,6 +39,8 @@ type LinkEmailRequest struct {
 type LinkEmailResponse struct {
 	Email             string `json:"email"`
 	Phone             string `json:"phone"`
+	EmailVerified     bool   `json:"emailVerified"`
+	PhoneVerified     bool   `json:"phoneVerified"`
 	FileID            string `json:"fileId"`
 	FileURI           string `json:"fileUri"`
 	FileThumbnailURI  string `json:"fileThumbnailUri"`
@@ -41,6 +49,30 @@ type LinkEmailResponse struct {
 	BankAccountNumber string `json:"bankAccountNumber"`
 }
 
+// LinkPendingResponse - an OTP was sent to Target, it is linked once verified
+type LinkPendingResponse struct {
+	Target    string    `json:"target"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type VerifyLinkRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// DeleteUserRequest - the password again, and a TOTP or recovery code when the
+// account has two-factor enabled
+type DeleteUserRequest struct {
+	Password string `json:"password" validate:"required"`
+	TOTPCode string `json:"totpCode"`
+}
+
+// DeleteUserResponse - the account is deleted, the rest of the user's data
+// follows while Status is pending
+type DeleteUserResponse struct {
+	DeletionID string `json:"deletionId"`
+	Status     string `json:"status"`
+}
+
 type UserRequest struct {
 	FileID            *string `json:"fileId"`
 	BankAccountName   string  `json:"bankAccountName" validate:"required,min=4,max=32"`
@@ -51,6 +83,8 @@ type UserRequest struct {
 type UserResponse struct {
 	Email             string `json:"email"`
 	Phone             string `json:"phone"`
+	EmailVerified     bool   `json:"emailVerified"`
+	PhoneVerified     bool   `json:"phoneVerified"`
 	FileID            string `json:"fileId"`
 	URI               string `json:"fileUri"`
 	ThumbnailURI      string `json:"fileThumbnailUri"`
@@ -76,3 +110,37 @@ type GetUserFromAuthResponse struct {
 	ID         string `json:"id"`
 	UserAuthID string `json:"user_auth_id"`
 }
+
+// UserFromAuthItem - one row of the listing auth reconciles its accounts against
+type UserFromAuthItem struct {
+	ID         string    `json:"id"`
+	UserAuthID string    `json:"user_auth_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type ListUsersFromAuthResponse struct {
+	Users []UserFromAuthItem `json:"users"`
+}
+
+// DeleteUserFromAuthResponse - what went with the profile. All zero when it was
+// already gone.
+type DeleteUserFromAuthResponse struct {
+	ProductsDeleted   int32 `json:"products_deleted"`
+	FilesDeleted      int32 `json:"files_deleted"`
+	PurchasesScrubbed int32 `json:"purchases_scrubbed"`
+}
+
+// MergeUsersFromAuthRequest - auth's account.merged event: the source profile's
+// products and files move to the target, which takes over its email/phone
+type MergeUsersFromAuthRequest struct {
+	MergeID      string `json:"merge_id"`
+	SourceUserID string `json:"source_user_id"`
+	TargetUserID string `json:"target_user_id"`
+	Email        string `json:"email"`
+	Phone        string `json:"phone"`
+}
+
+type MergeUsersFromAuthResponse struct {
+	ProductsMoved int32 `json:"products_moved"`
+	FilesMoved    int32 `json:"files_moved"`
+}
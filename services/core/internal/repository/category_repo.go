@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/teammachinist/tutuplapak/services/core/internal/database"
+	"github.com/teammachinist/tutuplapak/services/core/internal/model"
+
+	"github.com/google/uuid"
+)
+
+type CategoryRepositoryInterface interface {
+	ListCategories(ctx context.Context) ([]model.Category, error)
+	CreateCategory(ctx context.Context, category model.Category) (model.Category, error)
+	UpdateCategory(ctx context.Context, category model.Category) (model.Category, error)
+	DeleteCategory(ctx context.Context, categoryID uuid.UUID) (int64, error)
+}
+
+type CategoryRepository struct {
+	db database.Querier
+}
+
+func NewCategoryRepository(database database.Querier) CategoryRepositoryInterface {
+	return &CategoryRepository{db: database}
+}
+
+func (r *CategoryRepository) ListCategories(ctx context.Context) ([]model.Category, error) {
+	rows, err := r.db.ListCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	categories := make([]model.Category, len(rows))
+	for i, row := range rows {
+		categories[i] = toCategory(row)
+	}
+	return categories, nil
+}
+
+func (r *CategoryRepository) CreateCategory(ctx context.Context, category model.Category) (model.Category, error) {
+	row, err := r.db.CreateCategory(ctx, database.CreateCategoryParams{
+		Slug:     category.Slug,
+		Name:     category.Name,
+		ParentID: category.ParentID,
+		Active:   category.Active,
+	})
+	if err != nil {
+		return model.Category{}, err
+	}
+	return toCategory(row), nil
+}
+
+func (r *CategoryRepository) UpdateCategory(ctx context.Context, category model.Category) (model.Category, error) {
+	row, err := r.db.UpdateCategory(ctx, database.UpdateCategoryParams{
+		ID:       category.ID,
+		Slug:     category.Slug,
+		Name:     category.Name,
+		ParentID: category.ParentID,
+		Active:   category.Active,
+	})
+	if err != nil {
+		return model.Category{}, err
+	}
+	return toCategory(row), nil
+}
+
+func (r *CategoryRepository) DeleteCategory(ctx context.Context, categoryID uuid.UUID) (int64, error) {
+	return r.db.DeleteCategory(ctx, categoryID)
+}
+
+func toCategory(row database.Categories) model.Category {
+	return model.Category{
+		ID:        row.ID,
+		Slug:      row.Slug,
+		Name:      row.Name,
+		ParentID:  row.ParentID,
+		Active:    row.Active,
+		CreatedAt: row.CreatedAt,
+		UpdatedAt: row.UpdatedAt,
+	}
+}
@@ -2,29 +2,36 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"math"
 	"time"
 
 	"github.com/teammachinist/tutuplapak/services/core/internal/database"
 	"github.com/teammachinist/tutuplapak/services/core/internal/model"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type ProductRepositoryInterface interface {
 	CreateProduct(ctx context.Context, req model.ProductRequest) (model.ProductResponse, error)
 	CheckSKUExistsByUser(ctx context.Context, sku string, userID uuid.UUID) (CheckSKUExistsByUserRow, error)
 	GetAllProducts(ctx context.Context, params model.GetAllProductsParams) ([]model.Product, error)
-	UpdateProduct(ctx context.Context, params database.UpdateProductParams) (database.UpdateProductRow, error)
+	UpdateProduct(ctx context.Context, params database.UpdateProductParams, req model.ProductRequest) (model.ProductResponse, error)
 	CheckProductOwnership(ctx context.Context, productID uuid.UUID, userID uuid.UUID) (bool, error)
 	DeleteProduct(ctx context.Context, productID uuid.UUID, userID uuid.UUID) error
 	UpdateProductQty(ctx context.Context, productID string, qty int) error
+	GetProductVariants(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID]model.ProductVariants, error)
+	GetProductImages(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID][]model.ProductImage, error)
+	UpdateVariantQty(ctx context.Context, productID uuid.UUID, variantID uuid.UUID, qty int) error
 }
 
 type ProductRepository struct {
-	db database.Querier
+	pool *pgxpool.Pool
+	db   database.Querier
 }
 
 // UpdateProductQty implements ProductRepositoryInterface.
@@ -49,15 +56,89 @@ func (r *ProductRepository) UpdateProductQty(ctx context.Context, productID stri
 	return nil
 }
 
+// UpdateVariantQty - Take qty off a variant's stock, and with it off its
+// product's total
+func (r *ProductRepository) UpdateVariantQty(ctx context.Context, productID uuid.UUID, variantID uuid.UUID, qty int) error {
+	result, err := r.db.UpdateVariantQty(ctx, database.UpdateVariantQtyParams{
+		Qty:       qty,
+		ID:        variantID,
+		ProductID: productID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if result == 0 {
+		return errors.New("insufficient stock or variant not found")
+	}
+
+	return nil
+}
+
+// GetProductVariants - The options and variants of each product in productIDs
+// that has any, in two queries however many products there are
+func (r *ProductRepository) GetProductVariants(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID]model.ProductVariants, error) {
+	options, err := r.db.ListProductOptions(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	variants, err := r.db.ListProductVariants(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	byProduct := make(map[uuid.UUID]model.ProductVariants)
+	for _, row := range options {
+		entry := byProduct[row.ProductID]
+		entry.Options = append(entry.Options, model.ProductOption{Name: row.Name, Values: row.OptionValues})
+		byProduct[row.ProductID] = entry
+	}
+	for _, row := range variants {
+		variant, err := toProductVariant(row)
+		if err != nil {
+			return nil, err
+		}
+		entry := byProduct[row.ProductID]
+		entry.Variants = append(entry.Variants, variant)
+		byProduct[row.ProductID] = entry
+	}
+	return byProduct, nil
+}
+
+// GetProductImages - The gallery of each product in productIDs, without URIs
+func (r *ProductRepository) GetProductImages(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID][]model.ProductImage, error) {
+	rows, err := r.db.ListProductImages(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	images := make(map[uuid.UUID][]model.ProductImage)
+	for _, row := range rows {
+		images[row.ProductID] = append(images[row.ProductID], model.ProductImage{
+			FileID: row.FileID,
+			Cover:  row.IsCover,
+		})
+	}
+	return images, nil
+}
+
 func (r *ProductRepository) CreateProduct(ctx context.Context, req model.ProductRequest) (model.ProductResponse, error) {
 	productID := uuid.Must(uuid.NewV7())
 
-	fileID, err := uuid.Parse(req.FileID)
+	_, cover := req.Gallery()
+	fileID, err := uuid.Parse(cover)
+	if err != nil {
+		return model.ProductResponse{}, err
+	}
+
+	tx, err := r.pool.Begin(ctx)
 	if err != nil {
 		return model.ProductResponse{}, err
 	}
+	defer tx.Rollback(ctx)
 
-	dbProduct, err := r.db.CreateProduct(ctx, database.CreateProductParams{
+	q := database.New(tx)
+	dbProduct, err := q.CreateProduct(ctx, database.CreateProductParams{
 		ID:        productID,
 		Name:      req.Name,
 		Category:  req.Category,
@@ -87,6 +168,17 @@ func (r *ProductRepository) CreateProduct(ctx context.Context, req model.Product
 		UpdatedAt:        time.Now(),
 	}
 
+	if err := saveProductImages(ctx, q, &resp, req); err != nil {
+		return model.ProductResponse{}, err
+	}
+	if err := saveProductVariants(ctx, q, &resp, req.Options, req.Variants); err != nil {
+		return model.ProductResponse{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return model.ProductResponse{}, err
+	}
+
 	return resp, nil
 }
 
@@ -109,75 +201,340 @@ func (r *ProductRepository) CheckSKUExistsByUser(ctx context.Context, sku string
 	}, nil
 }
 
+// Seek bounds of a first page, before every product in their order
+var (
+	seekLatest   = time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+	seekEarliest = time.Time{}
+	seekHighest  = float32(math.Inf(1))
+)
+
+// GetAllProducts - One page in params.Sort() order, each order by its own
+// query. With a cursor the page starts after it and Offset is ignored.
 func (r *ProductRepository) GetAllProducts(ctx context.Context, params model.GetAllProductsParams) ([]model.Product, error) {
-	// limit := int32(5)
-	// if params.Limit > 0 {
-	// 	limit = int32(params.Limit)
-	// }
-
-	// offset := int32(0)
-	// if params.Offset >= 0 {
-	// 	offset = int32(params.Offset)
-	// }
-
-	args := database.GetAllProductsParams{
-		// LimitCount:  limit,
-		// OffsetCount: offset,
+	// The filters every listing query takes, in the newest query's shape
+	f := database.ListProductsNewestParams{
 		LimitCount:  params.Limit,
 		OffsetCount: params.Offset,
-		ProductID:   uuid.Nil,
-		Sku:         "",
-		Category:    "",
-		SortBy:      "newest",
+		Categories:  params.Categories,
+		MaxPrice:    math.MaxInt32,
+		InStock:     params.InStock,
 	}
-
 	if params.ProductID != nil {
-		args.ProductID = *params.ProductID
+		f.ProductID = *params.ProductID
 	}
-
 	if params.SKU != nil {
-		args.Sku = *params.SKU
+		f.Sku = *params.SKU
 	}
-
-	if params.Category != nil {
-		args.Category = *params.Category
+	if f.Categories == nil {
+		f.Categories = []string{}
+	}
+	if params.SellerID != nil {
+		f.SellerID = *params.SellerID
 	}
-	if params.SortBy != nil {
-		args.SortBy = *params.SortBy
+	if params.MinPrice != nil {
+		f.MinPrice = *params.MinPrice
+	}
+	if params.MaxPrice != nil {
+		f.MaxPrice = *params.MaxPrice
+	}
+	if params.Search != nil {
+		f.Search = *params.Search
+	}
+	cursor := params.Cursor
+	if cursor != nil {
+		f.OffsetCount = 0
 	}
 
-	log.Printf("Query Args: %+v", args)
+	// The price orders take the same filters with a price to seek from
+	priced := database.ListProductsCheapestParams{
+		ProductID:   f.ProductID,
+		Sku:         f.Sku,
+		Categories:  f.Categories,
+		SellerID:    f.SellerID,
+		MinPrice:    f.MinPrice,
+		MaxPrice:    f.MaxPrice,
+		InStock:     f.InStock,
+		Search:      f.Search,
+		LimitCount:  f.LimitCount,
+		OffsetCount: f.OffsetCount,
+	}
 
-	rows, err := r.db.GetAllProducts(ctx, args)
+	var products []model.Product
+	var err error
+	switch params.Sort() {
+	case "newest":
+		args := f
+		args.AfterTime, args.AfterID = seekLatest, uuid.Max
+		if cursor != nil {
+			args.AfterTime, args.AfterID = time.UnixMicro(cursor.Time), cursor.ID
+		}
+		var rows []database.ListProductsNewestRow
+		rows, err = r.db.ListProductsNewest(ctx, args)
+		products = listedProducts(rows)
+
+	case "oldest":
+		args := database.ListProductsOldestParams(f)
+		args.AfterTime, args.AfterID = seekEarliest, uuid.Nil
+		if cursor != nil {
+			args.AfterTime, args.AfterID = time.UnixMicro(cursor.Time), cursor.ID
+		}
+		var rows []database.ListProductsOldestRow
+		rows, err = r.db.ListProductsOldest(ctx, args)
+		products = listedProducts(rows)
+
+	case "cheapest":
+		args := priced
+		args.AfterPrice, args.AfterID = math.MinInt32, uuid.Nil
+		if cursor != nil {
+			args.AfterPrice, args.AfterID = cursor.Price, cursor.ID
+		}
+		var rows []database.ListProductsCheapestRow
+		rows, err = r.db.ListProductsCheapest(ctx, args)
+		products = listedProducts(rows)
+
+	case "expensive":
+		args := database.ListProductsExpensiveParams(priced)
+		args.AfterPrice, args.AfterID = math.MaxInt32, uuid.Max
+		if cursor != nil {
+			args.AfterPrice, args.AfterID = cursor.Price, cursor.ID
+		}
+		var rows []database.ListProductsExpensiveRow
+		rows, err = r.db.ListProductsExpensive(ctx, args)
+		products = listedProducts(rows)
+
+	case "":
+		args := database.ListProductsByRelevanceParams{
+			Search:      f.Search,
+			AfterRank:   seekHighest,
+			AfterID:     uuid.Max,
+			ProductID:   f.ProductID,
+			Sku:         f.Sku,
+			Categories:  f.Categories,
+			SellerID:    f.SellerID,
+			MinPrice:    f.MinPrice,
+			MaxPrice:    f.MaxPrice,
+			InStock:     f.InStock,
+			LimitCount:  f.LimitCount,
+			OffsetCount: f.OffsetCount,
+		}
+		if cursor != nil {
+			args.AfterRank, args.AfterID = cursor.Rank, cursor.ID
+		}
+		var rows []database.ListProductsByRelevanceRow
+		rows, err = r.db.ListProductsByRelevance(ctx, args)
+		for _, row := range rows {
+			product := listedProduct(database.ListProductsNewestRow{
+				ID:        row.ID,
+				Name:      row.Name,
+				Category:  row.Category,
+				Qty:       row.Qty,
+				Price:     row.Price,
+				Sku:       row.Sku,
+				FileID:    row.FileID,
+				UserID:    row.UserID,
+				CreatedAt: row.CreatedAt,
+				UpdatedAt: row.UpdatedAt,
+			})
+			product.Rank = row.Rank
+			products = append(products, product)
+		}
+
+	default:
+		return nil, errors.New("invalid sort")
+	}
 	if err != nil {
 		fmt.Println("Error fetching products:", err)
 		return nil, err
 	}
 
+	log.Printf("Retrieved %d products", len(products))
+	return products, nil
+}
+
+// listingRow - the rows of the sorted listing queries, which all have the same
+// columns
+type listingRow interface {
+	database.ListProductsNewestRow | database.ListProductsOldestRow |
+		database.ListProductsCheapestRow | database.ListProductsExpensiveRow
+}
+
+func listedProducts[Row listingRow](rows []Row) []model.Product {
 	products := make([]model.Product, len(rows))
 	for i, row := range rows {
-		products[i] = model.Product{
-			ID:               row.ID,
-			Name:             row.Name,
-			Category:         row.Category,
-			Qty:              row.Qty,
-			Price:            row.Price,
-			SKU:              row.Sku,
-			FileID:           row.FileID,
-			FileURI:          "",
-			FileThumbnailURI: "",
-			UserID:           row.UserID,
-			CreatedAt:        row.CreatedAt,
-			UpdatedAt:        row.UpdatedAt,
+		products[i] = listedProduct(database.ListProductsNewestRow(row))
+	}
+	return products
+}
+
+func listedProduct(row database.ListProductsNewestRow) model.Product {
+	return model.Product{
+		ID:        row.ID,
+		Name:      row.Name,
+		Category:  row.Category,
+		Qty:       row.Qty,
+		Price:     row.Price,
+		SKU:       row.Sku,
+		FileID:    row.FileID,
+		UserID:    row.UserID,
+		CreatedAt: row.CreatedAt,
+		UpdatedAt: row.UpdatedAt,
+	}
+}
+
+// UpdateProduct - Update the product and replace its gallery, options and
+// variants with req's in one transaction
+func (r *ProductRepository) UpdateProduct(ctx context.Context, params database.UpdateProductParams, req model.ProductRequest) (model.ProductResponse, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return model.ProductResponse{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	q := database.New(tx)
+	updatedRow, err := q.UpdateProduct(ctx, params)
+	if err != nil {
+		return model.ProductResponse{}, err
+	}
+
+	resp := model.ProductResponse{
+		ProductID: updatedRow.ID,
+		Name:      updatedRow.Name,
+		Category:  updatedRow.Category,
+		Qty:       updatedRow.Qty,
+		Price:     updatedRow.Price,
+		SKU:       updatedRow.Sku,
+		FileID:    updatedRow.FileID,
+		CreatedAt: updatedRow.CreatedAt,
+		UpdatedAt: updatedRow.UpdatedAt,
+	}
+
+	if err := saveProductImages(ctx, q, &resp, req); err != nil {
+		return model.ProductResponse{}, err
+	}
+	if err := saveProductVariants(ctx, q, &resp, req.Options, req.Variants); err != nil {
+		return model.ProductResponse{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return model.ProductResponse{}, err
+	}
+
+	return resp, nil
+}
+
+// saveProductImages - Replace the gallery of resp's product with req's
+func saveProductImages(ctx context.Context, q *database.Queries, resp *model.ProductResponse, req model.ProductRequest) error {
+	gallery, cover := req.Gallery()
+	fileIDs := make([]uuid.UUID, len(gallery))
+	for i, id := range gallery {
+		fileID, err := uuid.Parse(id)
+		if err != nil {
+			return err
 		}
+		fileIDs[i] = fileID
+	}
+	coverID, err := uuid.Parse(cover)
+	if err != nil {
+		return err
 	}
 
-	log.Printf("Retrieved %d products", len(products))
-	return products, nil
+	if err := q.DeleteProductImages(ctx, resp.ProductID); err != nil {
+		return err
+	}
+	err = q.CreateProductImages(ctx, database.CreateProductImagesParams{
+		ProductID:   resp.ProductID,
+		CoverFileID: coverID,
+		FileIds:     fileIDs,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp.Images = make([]model.ProductImage, len(fileIDs))
+	for i, fileID := range fileIDs {
+		resp.Images[i] = model.ProductImage{FileID: fileID, Cover: fileID == coverID}
+	}
+	return nil
+}
+
+// saveProductVariants - Replace the options and variants of resp's product,
+// then take its qty and price from the variants when it has any. Variants are
+// matched by SKU, so those kept keep their IDs.
+func saveProductVariants(ctx context.Context, q *database.Queries, resp *model.ProductResponse, options []model.ProductOption, variants []model.ProductVariantRequest) error {
+	if err := q.DeleteProductOptions(ctx, resp.ProductID); err != nil {
+		return err
+	}
+	for i, option := range options {
+		err := q.CreateProductOption(ctx, database.CreateProductOptionParams{
+			ProductID:    resp.ProductID,
+			Position:     i,
+			Name:         option.Name,
+			OptionValues: option.Values,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	skus := make([]string, len(variants))
+	for i, variant := range variants {
+		skus[i] = variant.SKU
+	}
+	err := q.DeleteProductVariantsExcept(ctx, database.DeleteProductVariantsExceptParams{
+		ProductID: resp.ProductID,
+		Skus:      skus,
+	})
+	if err != nil {
+		return err
+	}
+	if len(variants) == 0 {
+		return nil
+	}
+
+	for _, variant := range variants {
+		attributes, err := json.Marshal(variant.Attributes)
+		if err != nil {
+			return err
+		}
+		row, err := q.UpsertProductVariant(ctx, database.UpsertProductVariantParams{
+			ID:         uuid.Must(uuid.NewV7()),
+			ProductID:  resp.ProductID,
+			Sku:        variant.SKU,
+			Price:      variant.Price,
+			Qty:        variant.Qty,
+			Attributes: attributes,
+		})
+		if err != nil {
+			return err
+		}
+		saved, err := toProductVariant(row)
+		if err != nil {
+			return err
+		}
+		resp.Variants = append(resp.Variants, saved)
+	}
+	resp.Options = options
+
+	stock, err := q.SyncProductStock(ctx, resp.ProductID)
+	if err != nil {
+		return err
+	}
+	resp.Qty = stock.Qty
+	resp.Price = stock.Price
+	return nil
 }
 
-func (r *ProductRepository) UpdateProduct(ctx context.Context, params database.UpdateProductParams) (database.UpdateProductRow, error) {
-	return r.db.UpdateProduct(ctx, params)
+func toProductVariant(row database.ProductVariants) (model.ProductVariant, error) {
+	variant := model.ProductVariant{
+		ID:    row.ID,
+		SKU:   row.Sku,
+		Price: row.Price,
+		Qty:   row.Qty,
+	}
+	if err := json.Unmarshal(row.Attributes, &variant.Attributes); err != nil {
+		return model.ProductVariant{}, err
+	}
+	return variant, nil
 }
 
 func (r *ProductRepository) CheckProductOwnership(ctx context.Context, productID uuid.UUID, userID uuid.UUID) (bool, error) {
@@ -200,6 +557,6 @@ func (r *ProductRepository) DeleteProduct(ctx context.Context, productID uuid.UU
 	return err
 }
 
-func NewProductRepository(database database.Querier) ProductRepositoryInterface {
-	return &ProductRepository{db: database}
+func NewProductRepository(pool *pgxpool.Pool, database database.Querier) ProductRepositoryInterface {
+	return &ProductRepository{pool: pool, db: database}
 }
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/teammachinist/tutuplapak/services/core/internal/database"
+	"github.com/teammachinist/tutuplapak/services/core/internal/model"
+)
+
+// listingQuerier - keeps the name and arguments of the listing query instead of
+// running it
+type listingQuerier struct {
+	database.Querier
+	query string
+	args  any
+}
+
+func (q *listingQuerier) ListProductsNewest(ctx context.Context, arg database.ListProductsNewestParams) ([]database.ListProductsNewestRow, error) {
+	q.query, q.args = "ListProductsNewest", arg
+	return nil, nil
+}
+
+func (q *listingQuerier) ListProductsOldest(ctx context.Context, arg database.ListProductsOldestParams) ([]database.ListProductsOldestRow, error) {
+	q.query, q.args = "ListProductsOldest", arg
+	return nil, nil
+}
+
+func (q *listingQuerier) ListProductsCheapest(ctx context.Context, arg database.ListProductsCheapestParams) ([]database.ListProductsCheapestRow, error) {
+	q.query, q.args = "ListProductsCheapest", arg
+	return nil, nil
+}
+
+func (q *listingQuerier) ListProductsExpensive(ctx context.Context, arg database.ListProductsExpensiveParams) ([]database.ListProductsExpensiveRow, error) {
+	q.query, q.args = "ListProductsExpensive", arg
+	return nil, nil
+}
+
+func (q *listingQuerier) ListProductsByRelevance(ctx context.Context, arg database.ListProductsByRelevanceParams) ([]database.ListProductsByRelevanceRow, error) {
+	q.query, q.args = "ListProductsByRelevance", arg
+	return nil, nil
+}
+
+func listingQuery(t *testing.T, params model.GetAllProductsParams) (string, any) {
+	t.Helper()
+
+	q := &listingQuerier{}
+	if _, err := NewProductRepository(nil, q).GetAllProducts(context.Background(), params); err != nil {
+		t.Fatal(err)
+	}
+	return q.query, q.args
+}
+
+func TestGetAllProductsSeeksFromCursor(t *testing.T) {
+	after := time.Date(2026, 3, 1, 12, 0, 0, 123456000, time.UTC)
+	cursor := model.ProductCursor{SortBy: "newest", ID: uuid.New(), Time: after.UnixMicro()}
+
+	query, got := listingQuery(t, model.GetAllProductsParams{Limit: 6, Offset: 30, Cursor: &cursor})
+	args, ok := got.(database.ListProductsNewestParams)
+	if query != "ListProductsNewest" || !ok {
+		t.Fatalf("listed by %s", query)
+	}
+
+	if args.AfterID != cursor.ID || !args.AfterTime.Equal(after) {
+		t.Errorf("seeks after (%v, %s), want (%v, %s)", args.AfterTime, args.AfterID, after, cursor.ID)
+	}
+	if args.OffsetCount != 0 {
+		t.Errorf("offset %d on top of the seek", args.OffsetCount)
+	}
+	if args.LimitCount != 6 {
+		t.Errorf("limit %d", args.LimitCount)
+	}
+}
+
+func TestGetAllProductsFirstPage(t *testing.T) {
+	sortBy := func(s string) *string { return &s }
+	search := "kopi"
+
+	// Without a cursor every query seeks from before its first product
+	tests := []struct {
+		filter model.GetAllProductsParams
+		query  string
+		want   any
+	}{
+		{model.GetAllProductsParams{}, "ListProductsNewest", [2]any{seekLatest, uuid.Max}},
+		{model.GetAllProductsParams{SortBy: sortBy("oldest")}, "ListProductsOldest", [2]any{seekEarliest, uuid.Nil}},
+		{model.GetAllProductsParams{SortBy: sortBy("cheapest")}, "ListProductsCheapest", [2]any{math.MinInt32, uuid.Nil}},
+		{model.GetAllProductsParams{SortBy: sortBy("expensive")}, "ListProductsExpensive", [2]any{math.MaxInt32, uuid.Max}},
+		{model.GetAllProductsParams{Search: &search}, "ListProductsByRelevance", [2]any{seekHighest, uuid.Max}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			tt.filter.Limit, tt.filter.Offset = 5, 10
+			query, args := listingQuery(t, tt.filter)
+			if query != tt.query {
+				t.Fatalf("listed by %s", query)
+			}
+
+			var seek [2]any
+			var offset int
+			switch args := args.(type) {
+			case database.ListProductsNewestParams:
+				seek, offset = [2]any{args.AfterTime, args.AfterID}, args.OffsetCount
+			case database.ListProductsOldestParams:
+				seek, offset = [2]any{args.AfterTime, args.AfterID}, args.OffsetCount
+			case database.ListProductsCheapestParams:
+				seek, offset = [2]any{args.AfterPrice, args.AfterID}, args.OffsetCount
+			case database.ListProductsExpensiveParams:
+				seek, offset = [2]any{args.AfterPrice, args.AfterID}, args.OffsetCount
+			case database.ListProductsByRelevanceParams:
+				seek, offset = [2]any{args.AfterRank, args.AfterID}, args.OffsetCount
+			}
+			if seek != tt.want {
+				t.Errorf("seeks after %v, want %v", seek, tt.want)
+			}
+			if offset != 10 {
+				t.Errorf("offset %d, want 10", offset)
+			}
+		})
+	}
+}
+
+func TestGetAllProductsSortWithSearch(t *testing.T) {
+	search, sortBy := "kopi", "cheapest"
+	cursor := model.ProductCursor{SortBy: sortBy, ID: uuid.New(), Price: 1500}
+
+	// A sorted search seeks on price and id
+	query, got := listingQuery(t, model.GetAllProductsParams{Limit: 5, Search: &search, SortBy: &sortBy, Cursor: &cursor})
+	args, ok := got.(database.ListProductsCheapestParams)
+	if query != "ListProductsCheapest" || !ok {
+		t.Fatalf("listed by %s", query)
+	}
+	if args.Search != search || args.AfterPrice != 1500 || args.AfterID != cursor.ID {
+		t.Errorf("unexpected args %+v", args)
+	}
+
+	// Without a sort, on rank and id
+	cursor = model.ProductCursor{ID: uuid.New(), Rank: 0.4375}
+	query, got = listingQuery(t, model.GetAllProductsParams{Limit: 5, Search: &search, Cursor: &cursor})
+	relevance, ok := got.(database.ListProductsByRelevanceParams)
+	if query != "ListProductsByRelevance" || !ok {
+		t.Fatalf("listed by %s", query)
+	}
+	if relevance.Search != search || relevance.AfterRank != 0.4375 || relevance.AfterID != cursor.ID {
+		t.Errorf("relevance page from args %+v", relevance)
+	}
+}
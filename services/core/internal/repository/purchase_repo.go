@@ -96,8 +96,35 @@ func (r *PurchaseRepository) CreatePurchase(ctx context.Context, req model.Purch
 			return model.PurchaseResponse{}, errors.New("product not found")
 		}
 
+		// A product with variants is bought as one of them, at its SKU, price and stock
+		price, sku, stock := productInTx.Price, productInTx.Sku, productInTx.Qty
+		var variantID *uuid.UUID
+		var attributes map[string]string
+		if itemReq.VariantID != uuid.Nil {
+			variant, err := q.GetProductVariant(ctx, database.GetProductVariantParams{
+				ID:        itemReq.VariantID,
+				ProductID: itemReq.ProductID,
+			})
+			if err != nil {
+				return model.PurchaseResponse{}, errors.New("variant not found")
+			}
+			if err := json.Unmarshal(variant.Attributes, &attributes); err != nil {
+				return model.PurchaseResponse{}, err
+			}
+			price, sku, stock = variant.Price, variant.Sku, variant.Qty
+			variantID = &variant.ID
+		} else {
+			hasVariants, err := q.HasProductVariants(ctx, itemReq.ProductID)
+			if err != nil {
+				return model.PurchaseResponse{}, err
+			}
+			if hasVariants {
+				return model.PurchaseResponse{}, errors.New("variantId is required for product: " + productInTx.Name)
+			}
+		}
+
 		//  Validasi stok
-		if itemReq.Qty > productInTx.Qty {
+		if itemReq.Qty > stock {
 			return model.PurchaseResponse{}, errors.New("insufficient stock for product: " + productInTx.Name)
 		}
 
@@ -116,19 +143,21 @@ func (r *PurchaseRepository) CreatePurchase(ctx context.Context, req model.Purch
 
 		//  Simpan snapshot — tanpa FileURI (akan diisi di service)
 		snapshot := model.PurchasedItemSnapshot{
-			ProductID: itemReq.ProductID,
-			Name:      productInTx.Name,
-			Category:  productInTx.Category,
-			Qty:       itemReq.Qty,
-			Price:     int(productInTx.Price),
-			SKU:       productInTx.Sku,
-			FileID:    productInTx.FileID, // ← Simpan FileID
-			SellerID:  productInTx.UserID,
-			CreatedAt: now,
-			UpdatedAt: now,
+			ProductID:  itemReq.ProductID,
+			Name:       productInTx.Name,
+			Category:   productInTx.Category,
+			Qty:        itemReq.Qty,
+			Price:      price,
+			SKU:        sku,
+			VariantID:  variantID,
+			Attributes: attributes,
+			FileID:     productInTx.FileID, // ← Simpan FileID
+			SellerID:   productInTx.UserID,
+			CreatedAt:  now,
+			UpdatedAt:  now,
 		}
 		snapshots = append(snapshots, snapshot)
-		sellerTotals[productInTx.UserID] += int(productInTx.Price) * itemReq.Qty
+		sellerTotals[productInTx.UserID] += price * itemReq.Qty
 
 		//  Tambahkan ke purchasedItems — tanpa FileURI dulu
 		purchasedItems = append(purchasedItems, model.ProductResponse{
@@ -136,8 +165,10 @@ func (r *PurchaseRepository) CreatePurchase(ctx context.Context, req model.Purch
 			Name:             productInTx.Name,
 			Category:         productInTx.Category,
 			Qty:              itemReq.Qty,
-			Price:            int(productInTx.Price),
-			SKU:              productInTx.Sku,
+			Price:            price,
+			SKU:              sku,
+			VariantID:        variantID,
+			Attributes:       attributes,
 			FileID:           productInTx.FileID,
 			UserID:           productInTx.UserID, // ← isi untuk kebutuhan internal
 			FileURI:          "",
@@ -6,6 +6,7 @@ import (
 	"github.com/teammachinist/tutuplapak/services/core/internal/database"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type UserRepositoryInterface interface {
@@ -17,14 +18,20 @@ type UserRepositoryInterface interface {
 	UpdateUserEmail(ctx context.Context, userID uuid.UUID, email string) error
 	GetUserByAuthID(ctx context.Context, userAuthID uuid.UUID) (database.Users, error)
 	CreateUserFromUserAuth(ctx context.Context, userID, userAuthID uuid.UUID, email, phone string) (database.Users, error)
+	ListUsersByAuthIDPage(ctx context.Context, afterUserAuthID uuid.UUID, limit int32) ([]database.ListUsersByAuthIDPageRow, error)
+	DeleteUserByAuthID(ctx context.Context, userAuthID uuid.UUID) error
+	MergeUsers(ctx context.Context, fromUserID, toUserID uuid.UUID, email, phone string) (int64, error)
+	ScrubPurchases(ctx context.Context, user database.Users) (int64, error)
+	DeleteUserWithProducts(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
 }
 
 type UserRepository struct {
-	db database.Querier
+	pool *pgxpool.Pool
+	db   database.Querier
 }
 
-func NewUserRepository(database database.Querier) UserRepositoryInterface {
-	return &UserRepository{db: database}
+func NewUserRepository(pool *pgxpool.Pool, database database.Querier) UserRepositoryInterface {
+	return &UserRepository{pool: pool, db: database}
 }
 
 func (r *UserRepository) GetUserByID(ctx context.Context, userID uuid.UUID) (database.Users, error) {
@@ -102,3 +109,114 @@ func (r *UserRepository) CreateUserFromUserAuth(ctx context.Context, userID, use
 
 	return result, nil
 }
+
+func (r *UserRepository) ListUsersByAuthIDPage(ctx context.Context, afterUserAuthID uuid.UUID, limit int32) ([]database.ListUsersByAuthIDPageRow, error) {
+	return r.db.ListUsersByAuthIDPage(ctx, database.ListUsersByAuthIDPageParams{
+		UserAuthID: afterUserAuthID,
+		Limit:      limit,
+	})
+}
+
+func (r *UserRepository) DeleteUserByAuthID(ctx context.Context, userAuthID uuid.UUID) error {
+	return r.db.DeleteUserByAuthID(ctx, userAuthID)
+}
+
+// MergeUsers - Move fromUserID's products to toUserID and delete fromUserID,
+// taking over its email/phone when given. The profile goes before its
+// email/phone is reused since both are unique.
+func (r *UserRepository) MergeUsers(ctx context.Context, fromUserID, toUserID uuid.UUID, email, phone string) (int64, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	q := database.New(tx)
+
+	moved, err := q.ReassignProducts(ctx, database.ReassignProductsParams{
+		ToUserID:   toUserID,
+		FromUserID: fromUserID,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := q.DeleteUser(ctx, fromUserID); err != nil {
+		return 0, err
+	}
+
+	if email != "" {
+		if _, err := q.UpdateUserEmail(ctx, database.UpdateUserEmailParams{ID: toUserID, Email: email}); err != nil {
+			return 0, err
+		}
+	}
+	if phone != "" {
+		if _, err := q.UpdateUserPhone(ctx, database.UpdateUserPhoneParams{ID: toUserID, Phone: phone}); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return moved, nil
+}
+
+// ScrubPurchases - Remove the user's contact and bank details from purchases it
+// made or sold in, keeping the amounts. Returns how many purchases were touched.
+func (r *UserRepository) ScrubPurchases(ctx context.Context, user database.Users) (int64, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	q := database.New(tx)
+
+	bought, err := q.ScrubBuyerPurchases(ctx, database.ScrubBuyerPurchasesParams{
+		Email: user.Email,
+		Phone: user.Phone,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	sold, err := q.ScrubSellerPurchases(ctx, database.ScrubSellerPurchasesParams{
+		BankAccountNumber: user.BankAccountNumber,
+		SellerID:          user.ID.String(),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return bought + sold, nil
+}
+
+// DeleteUserWithProducts - Delete the user and its products, returning the
+// ids of the products deleted
+func (r *UserRepository) DeleteUserWithProducts(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	q := database.New(tx)
+
+	productIDs, err := q.DeleteProductsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := q.DeleteUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return productIDs, nil
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/teammachinist/tutuplapak/services/core/internal/cache"
+	"github.com/teammachinist/tutuplapak/services/core/internal/logger"
+	"github.com/teammachinist/tutuplapak/services/core/internal/model"
+	"github.com/teammachinist/tutuplapak/services/core/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type CategoryServiceInterface interface {
+	ListCategories(ctx context.Context, includeInactive bool) ([]model.Category, error)
+	CreateCategory(ctx context.Context, req model.CategoryRequest) (model.Category, error)
+	UpdateCategory(ctx context.Context, categoryID uuid.UUID, req model.CategoryRequest) (model.Category, error)
+	DeleteCategory(ctx context.Context, categoryID uuid.UUID) error
+	ResolveCategory(ctx context.Context, value string) (model.Category, error)
+	ExpandCategories(ctx context.Context, values []string) ([]string, error)
+}
+
+type CategoryService struct {
+	categoryRepo repository.CategoryRepositoryInterface
+	cache        *cache.RedisCache
+}
+
+func NewCategoryService(categoryRepo repository.CategoryRepositoryInterface, cache *cache.RedisCache) CategoryServiceInterface {
+	return &CategoryService{
+		categoryRepo: categoryRepo,
+		cache:        cache,
+	}
+}
+
+// ListCategories - Every category sorted by name, the inactive ones only for
+// admins
+func (s *CategoryService) ListCategories(ctx context.Context, includeInactive bool) ([]model.Category, error) {
+	categories, err := s.loadCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if includeInactive {
+		return categories, nil
+	}
+
+	active := []model.Category{}
+	for _, category := range categories {
+		if category.Active {
+			active = append(active, category)
+		}
+	}
+	return active, nil
+}
+
+func (s *CategoryService) CreateCategory(ctx context.Context, req model.CategoryRequest) (model.Category, error) {
+	category, err := s.categoryFromRequest(ctx, uuid.Nil, req)
+	if err != nil {
+		return model.Category{}, err
+	}
+
+	created, err := s.categoryRepo.CreateCategory(ctx, category)
+	if err != nil {
+		return model.Category{}, categoryWriteError(err, "parent category not found")
+	}
+
+	s.invalidateCategories(ctx)
+	logger.InfoCtx(ctx, "Category created", "category_id", created.ID.String(), "slug", created.Slug)
+	return created, nil
+}
+
+// UpdateCategory - Replace a category. A new slug carries over to its products.
+func (s *CategoryService) UpdateCategory(ctx context.Context, categoryID uuid.UUID, req model.CategoryRequest) (model.Category, error) {
+	category, err := s.categoryFromRequest(ctx, categoryID, req)
+	if err != nil {
+		return model.Category{}, err
+	}
+
+	updated, err := s.categoryRepo.UpdateCategory(ctx, category)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Category{}, errors.New("category not found")
+		}
+		return model.Category{}, categoryWriteError(err, "parent category not found")
+	}
+
+	s.invalidateCategories(ctx)
+	// A new slug carries over to products; retire listings showing the old one
+	invalidateProducts(ctx, s.cache)
+	logger.InfoCtx(ctx, "Category updated", "category_id", updated.ID.String(), "slug", updated.Slug)
+	return updated, nil
+}
+
+// DeleteCategory - Remove a category no product or subcategory uses; otherwise
+// it can be made inactive instead
+func (s *CategoryService) DeleteCategory(ctx context.Context, categoryID uuid.UUID) error {
+	deleted, err := s.categoryRepo.DeleteCategory(ctx, categoryID)
+	if err != nil {
+		return categoryWriteError(err, "category in use")
+	}
+	if deleted == 0 {
+		return errors.New("category not found")
+	}
+
+	s.invalidateCategories(ctx)
+	logger.InfoCtx(ctx, "Category deleted", "category_id", categoryID.String())
+	return nil
+}
+
+// ResolveCategory - The active category value names, by slug or by display
+// name in any case
+func (s *CategoryService) ResolveCategory(ctx context.Context, value string) (model.Category, error) {
+	categories, err := s.loadCategories(ctx)
+	if err != nil {
+		return model.Category{}, err
+	}
+
+	value = strings.TrimSpace(value)
+	for _, category := range categories {
+		if category.Active && (category.Slug == value || strings.EqualFold(category.Name, value)) {
+			return category, nil
+		}
+	}
+	return model.Category{}, errors.New("category not found")
+}
+
+// ExpandCategories - The slugs of the categories values name and of all their
+// subcategories, for filtering on a parent to take in its children
+func (s *CategoryService) ExpandCategories(ctx context.Context, values []string) ([]string, error) {
+	categories, err := s.loadCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	children := make(map[uuid.UUID][]model.Category)
+	for _, category := range categories {
+		if category.ParentID != nil {
+			children[*category.ParentID] = append(children[*category.ParentID], category)
+		}
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	var slugs []string
+	var visit func(category model.Category)
+	visit = func(category model.Category) {
+		if seen[category.ID] {
+			return
+		}
+		seen[category.ID] = true
+		slugs = append(slugs, category.Slug)
+		for _, child := range children[category.ID] {
+			visit(child)
+		}
+	}
+
+	for _, value := range values {
+		category, err := s.ResolveCategory(ctx, value)
+		if err != nil {
+			return nil, err
+		}
+		visit(category)
+	}
+	return slugs, nil
+}
+
+// loadCategories - The whole taxonomy, from Redis while cached. It is small
+// and read on every product listing and write.
+func (s *CategoryService) loadCategories(ctx context.Context) ([]model.Category, error) {
+	var categories []model.Category
+	err := s.cache.GetOrSet(ctx, cache.CategoryListKey, &categories, cache.CategoryListTTL, func() (interface{}, error) {
+		return s.categoryRepo.ListCategories(ctx)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load categories: %w", err)
+	}
+	return categories, nil
+}
+
+func (s *CategoryService) invalidateCategories(ctx context.Context) {
+	if err := s.cache.Delete(ctx, cache.CategoryListKey); err != nil {
+		logger.WarnCtx(ctx, "Failed to invalidate categories", "error", err.Error())
+	}
+}
+
+// categoryFromRequest - The category req describes, after checking its parent
+// exists and isn't the category itself or one of its subcategories
+func (s *CategoryService) categoryFromRequest(ctx context.Context, categoryID uuid.UUID, req model.CategoryRequest) (model.Category, error) {
+	category := model.Category{
+		ID:     categoryID,
+		Slug:   req.Slug,
+		Name:   strings.TrimSpace(req.Name),
+		Active: req.Active == nil || *req.Active,
+	}
+	if req.ParentID == nil || *req.ParentID == "" {
+		return category, nil
+	}
+
+	parentID, err := uuid.Parse(*req.ParentID)
+	if err != nil {
+		return model.Category{}, errors.New("parentId is not valid")
+	}
+
+	categories, err := s.loadCategories(ctx)
+	if err != nil {
+		return model.Category{}, err
+	}
+	byID := make(map[uuid.UUID]model.Category, len(categories))
+	for _, c := range categories {
+		byID[c.ID] = c
+	}
+
+	if _, ok := byID[parentID]; !ok {
+		return model.Category{}, errors.New("parent category not found")
+	}
+	// Walking up from the new parent must never reach the category being moved.
+	// A loop already in the tree (written concurrently) stops the walk too.
+	visited := make(map[uuid.UUID]bool)
+	for id := &parentID; id != nil && !visited[*id]; id = byID[*id].ParentID {
+		if *id == categoryID {
+			return model.Category{}, errors.New("category cannot be moved under itself")
+		}
+		visited[*id] = true
+	}
+
+	category.ParentID = &parentID
+	return category, nil
+}
+
+// categoryWriteError - The error for a failed write. A foreign key violation
+// means a missing parent on create and update, and a category still in use on
+// delete.
+func categoryWriteError(err error, onForeignKey string) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505":
+			return errors.New("category slug already exists")
+		case "23503":
+			return errors.New(onForeignKey)
+		}
+	}
+	return err
+}
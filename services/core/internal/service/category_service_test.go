@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/teammachinist/tutuplapak/services/core/internal/model"
+	"github.com/teammachinist/tutuplapak/services/core/internal/repository"
+)
+
+// taxonomyRepo - categories in memory
+type taxonomyRepo struct {
+	repository.CategoryRepositoryInterface
+	categories []model.Category
+}
+
+func (r *taxonomyRepo) ListCategories(ctx context.Context) ([]model.Category, error) {
+	return r.categories, nil
+}
+
+func newCategoryTestService(t *testing.T, categories ...model.Category) *CategoryService {
+	t.Helper()
+
+	redisCache, _ := newTestCache(t)
+	return &CategoryService{categoryRepo: &taxonomyRepo{categories: categories}, cache: redisCache}
+}
+
+func category(slug string, parent *model.Category) model.Category {
+	c := model.Category{ID: uuid.New(), Slug: slug, Name: slug, Active: true}
+	if parent != nil {
+		c.ParentID = &parent.ID
+	}
+	return c
+}
+
+func moveUnder(parent model.Category) model.CategoryRequest {
+	id := parent.ID.String()
+	return model.CategoryRequest{Slug: "moved", Name: "Moved", ParentID: &id}
+}
+
+func TestCategoryCannotMoveUnderItself(t *testing.T) {
+	food := category("food", nil)
+	snacks := category("snacks", &food)
+	chips := category("chips", &snacks)
+	s := newCategoryTestService(t, food, snacks, chips)
+
+	for _, parent := range []model.Category{food, chips} {
+		if _, err := s.categoryFromRequest(context.Background(), food.ID, moveUnder(parent)); err == nil || err.Error() != "category cannot be moved under itself" {
+			t.Errorf("food under %s: got error %v", parent.Slug, err)
+		}
+	}
+
+	if _, err := s.categoryFromRequest(context.Background(), chips.ID, moveUnder(food)); err != nil {
+		t.Errorf("chips under food: %v", err)
+	}
+}
+
+func TestCategoryParentWalkStopsOnLoop(t *testing.T) {
+	// Two moves that raced each other left a and b each other's parent
+	a := category("a", nil)
+	b := category("b", &a)
+	a.ParentID = &b.ID
+	tools := category("tools", nil)
+	s := newCategoryTestService(t, a, b, tools)
+
+	if _, err := s.categoryFromRequest(context.Background(), tools.ID, moveUnder(a)); err != nil {
+		t.Errorf("tools under a: %v", err)
+	}
+	if _, err := s.categoryFromRequest(context.Background(), b.ID, moveUnder(a)); err == nil {
+		t.Error("b moved under its own child")
+	}
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/teammachinist/tutuplapak/services/auth/pkg/redistest"
+	"github.com/teammachinist/tutuplapak/services/core/internal/cache"
+	"github.com/teammachinist/tutuplapak/services/core/internal/logger"
+)
+
+func TestMain(m *testing.M) {
+	logger.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// newTestCache - A cache over a fake Redis that lasts until the test ends
+func newTestCache(t *testing.T) (*cache.RedisCache, *redistest.Server) {
+	t.Helper()
+
+	redis := redistest.Start(t)
+	redisCache := cache.NewRedisCache(cache.CacheConfig{Addr: redis.Addr()})
+	t.Cleanup(func() { redisCache.Close() })
+	return redisCache, redis
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/teammachinist/tutuplapak/services/auth/pkg/redistest"
+	"github.com/teammachinist/tutuplapak/services/core/internal/cache"
+	"github.com/teammachinist/tutuplapak/services/core/internal/model"
+	"github.com/teammachinist/tutuplapak/services/core/internal/repository"
+)
+
+// ownedListingRepo - a listing whose products all belong to one seller, who
+// may delete them
+type ownedListingRepo struct {
+	*listingRepo
+}
+
+func (r ownedListingRepo) CheckProductOwnership(ctx context.Context, productID uuid.UUID, userID uuid.UUID) (bool, error) {
+	return true, nil
+}
+
+func (r ownedListingRepo) DeleteProduct(ctx context.Context, productID uuid.UUID, userID uuid.UUID) error {
+	r.products = slices.DeleteFunc(r.products, func(p model.Product) bool { return p.ID == productID })
+	return nil
+}
+
+func listedIDs(t *testing.T, s *ProductService, filter model.GetAllProductsParams) []uuid.UUID {
+	t.Helper()
+
+	page, err := s.GetAllProducts(context.Background(), filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]uuid.UUID, len(page.Products))
+	for i, p := range page.Products {
+		ids[i] = p.ProductID
+	}
+	return ids
+}
+
+func generation(t *testing.T, redis *redistest.Server) int {
+	t.Helper()
+	value, _ := redis.Get(cache.ProductListGenerationKey)
+	n, _ := strconv.Atoi(value)
+	return n
+}
+
+func TestProductWriteRetiresCachedListings(t *testing.T) {
+	repo := &listingRepo{products: testProducts(4)}
+	s, redis := newListingTestService(t, ownedListingRepo{repo})
+	filters := []model.GetAllProductsParams{{Limit: 10}, {Limit: 2}}
+
+	for _, filter := range filters {
+		listedIDs(t, s, filter)
+		listedIDs(t, s, filter)
+	}
+	if repo.queries != len(filters) {
+		t.Fatalf("%d queries for %d listings read twice", repo.queries, len(filters))
+	}
+
+	deleted := repo.products[0].ID
+	if err := s.DeleteProduct(context.Background(), deleted, uuid.New()); err != nil {
+		t.Fatal(err)
+	}
+	if generation(t, redis) != 1 {
+		t.Errorf("generation %d after one write, want 1", generation(t, redis))
+	}
+
+	// Every listing cached before the write is read again, whatever its filter
+	for _, filter := range filters {
+		if slices.Contains(listedIDs(t, s, filter), deleted) {
+			t.Errorf("deleted product still listed with limit %d", filter.Limit)
+		}
+	}
+	if repo.queries != 2*len(filters) {
+		t.Errorf("%d queries, want every listing read again once", repo.queries)
+	}
+
+	// The new generation's pages are cached in turn
+	listedIDs(t, s, filters[0])
+	if repo.queries != 2*len(filters) {
+		t.Errorf("listing not cached under the new generation")
+	}
+}
+
+func TestProductListingWithoutRedis(t *testing.T) {
+	repo := &listingRepo{products: testProducts(3)}
+	s, redis := newListingTestService(t, ownedListingRepo{repo})
+	filter := model.GetAllProductsParams{Limit: 10}
+	listedIDs(t, s, filter)
+
+	// Down Redis can't say which generation is current, so listings and writes
+	// go on without it
+	redis.SetFailure("LOADING Redis is loading the dataset in memory")
+	if err := s.DeleteProduct(context.Background(), repo.products[0].ID, uuid.New()); err != nil {
+		t.Fatalf("write failed with Redis down: %v", err)
+	}
+	if got := listedIDs(t, s, filter); len(got) != 2 {
+		t.Errorf("%d products listed with Redis down, want 2", len(got))
+	}
+	if repo.queries != 2 {
+		t.Errorf("%d queries, want the listing read from the repository", repo.queries)
+	}
+}
+
+// failingStock - stock that runs out after the first item
+type failingStock struct {
+	repository.ProductRepositoryInterface
+	decremented int
+}
+
+func (r *failingStock) UpdateProductQty(ctx context.Context, productID string, qty int) error {
+	if r.decremented > 0 {
+		return errors.New("insufficient stock or product not found")
+	}
+	r.decremented++
+	return nil
+}
+
+func TestPaymentProofRetiresListingsAfterPartialStockUpdate(t *testing.T) {
+	proof := uuid.NewString()
+	purchases := &unpaidPurchase{purchase: model.PurchaseResponse{
+		PurchaseID:     uuid.New(),
+		PurchasedItems: []model.ProductResponse{{ProductID: uuid.New(), Qty: 1}, {ProductID: uuid.New(), Qty: 1}},
+		PaymentDetails: make([]model.PaymentDetail, 1),
+		Status:         model.PurchaseStatusUnpaid,
+	}}
+	redisCache, redis := newTestCache(t)
+	s := &PurchaseService{
+		purchaseRepo: purchases,
+		productRepo:  &failingStock{},
+		fileClient:   existingFiles{ids: map[string]bool{proof: true}},
+		cache:        redisCache,
+	}
+
+	if err := s.UploadPaymentProof(context.Background(), purchases.purchase.PurchaseID.String(), []string{proof}); err == nil {
+		t.Fatal("payment accepted with an item out of stock")
+	}
+	if generation(t, redis) != 1 {
+		t.Errorf("generation %d, want listings retired for the stock already taken", generation(t, redis))
+	}
+}
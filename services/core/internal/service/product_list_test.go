@@ -0,0 +1,249 @@
+package service
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/teammachinist/tutuplapak/services/auth/pkg/redistest"
+	"github.com/teammachinist/tutuplapak/services/core/internal/clients"
+	"github.com/teammachinist/tutuplapak/services/core/internal/model"
+	"github.com/teammachinist/tutuplapak/services/core/internal/repository"
+)
+
+// listingRepo - the product listing queries over products in memory, ordered
+// and seeked the way the ListProducts* queries in products.sql do
+type listingRepo struct {
+	repository.ProductRepositoryInterface
+	products []model.Product
+	queries  int
+}
+
+func (r *listingRepo) GetAllProducts(ctx context.Context, params model.GetAllProductsParams) ([]model.Product, error) {
+	r.queries++
+	sortBy := params.Sort()
+
+	key := func(p model.Product) float64 {
+		switch sortBy {
+		case "newest":
+			return float64(latest(p.CreatedAt, p.UpdatedAt).UnixMicro())
+		case "oldest":
+			return float64(earliest(p.CreatedAt, p.UpdatedAt).UnixMicro())
+		case "cheapest", "expensive":
+			return float64(p.Price)
+		}
+		return float64(p.Rank)
+	}
+	descending := sortBy == "newest" || sortBy == "expensive" || sortBy == ""
+	compare := func(a, b model.Product) int {
+		c := cmp.Or(cmp.Compare(key(a), key(b)), bytes.Compare(a.ID[:], b.ID[:]))
+		if descending {
+			return -c
+		}
+		return c
+	}
+
+	sorted := slices.SortedFunc(slices.Values(r.products), compare)
+
+	offset := params.Offset
+	if cursor := params.Cursor; cursor != nil {
+		offset = 0
+		after := model.Product{ID: cursor.ID, Price: cursor.Price, Rank: cursor.Rank, CreatedAt: time.UnixMicro(cursor.Time), UpdatedAt: time.UnixMicro(cursor.Time)}
+		sorted = slices.DeleteFunc(sorted, func(p model.Product) bool { return compare(p, after) <= 0 })
+	}
+
+	sorted = sorted[min(offset, len(sorted)):]
+	return sorted[:min(params.Limit, len(sorted))], nil
+}
+
+func (r *listingRepo) GetProductVariants(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID]model.ProductVariants, error) {
+	return nil, nil
+}
+
+func (r *listingRepo) GetProductImages(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID][]model.ProductImage, error) {
+	return nil, nil
+}
+
+// noFiles - a files service without any files
+type noFiles struct {
+	clients.FileClientInterface
+}
+
+func (noFiles) GetFilesByIDList(ctx context.Context, fileIDs []string) ([]*clients.FileMetadataResponse, error) {
+	return nil, nil
+}
+
+// newListingTestService - A product service over repo, caching in a fake Redis
+func newListingTestService(t *testing.T, repo repository.ProductRepositoryInterface) (*ProductService, *redistest.Server) {
+	t.Helper()
+
+	redisCache, redis := newTestCache(t)
+	return &ProductService{productRepo: repo, fileClient: noFiles{}, cache: redisCache}, redis
+}
+
+// testProducts - n products with prices, times and ranks that repeat, so pages
+// have to break ties by id
+func testProducts(n int) []model.Product {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	products := make([]model.Product, n)
+	for i := range products {
+		created := start.Add(time.Duration(i/2) * time.Hour)
+		products[i] = model.Product{
+			ID:        uuid.New(),
+			Name:      "product",
+			Price:     1000 * (1 + i%3),
+			Rank:      float32(i%4) / 3, // Not exact in binary
+			CreatedAt: created,
+			UpdatedAt: created.Add(time.Duration(i%2) * 90 * time.Minute), // Some edited after newer ones were added
+		}
+	}
+	return products
+}
+
+// listAll - Follow NextCursor from the first page to the last, returning the
+// product IDs in the order they were served
+func listAll(t *testing.T, s *ProductService, filter model.GetAllProductsParams) []uuid.UUID {
+	t.Helper()
+
+	var ids []uuid.UUID
+	for range 100 {
+		page, err := s.GetAllProducts(context.Background(), filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Products) > filter.Limit {
+			t.Fatalf("page of %d products, limit %d", len(page.Products), filter.Limit)
+		}
+		for _, p := range page.Products {
+			ids = append(ids, p.ProductID)
+		}
+		if page.NextCursor == "" {
+			return ids
+		}
+
+		cursor, err := DecodeProductCursor(page.NextCursor)
+		if err != nil {
+			t.Fatalf("next cursor %q: %v", page.NextCursor, err)
+		}
+		if cursor.SortBy != filter.Sort() {
+			t.Fatalf("next cursor for sort %q, listing by %q", cursor.SortBy, filter.Sort())
+		}
+		filter.Cursor = cursor
+		filter.Offset = 0
+	}
+	t.Fatal("listing never reached its last page")
+	return nil
+}
+
+func TestProductListingPagesThroughEveryProduct(t *testing.T) {
+	search := "product"
+	sorts := map[string]model.GetAllProductsParams{}
+	for _, sortBy := range []string{"newest", "oldest", "cheapest", "expensive"} {
+		sorts[sortBy] = model.GetAllProductsParams{Limit: 3, SortBy: &sortBy}
+		sorts[sortBy+" with search"] = model.GetAllProductsParams{Limit: 3, SortBy: &sortBy, Search: &search}
+	}
+	sorts["default"] = model.GetAllProductsParams{Limit: 3}
+	sorts["relevance"] = model.GetAllProductsParams{Limit: 3, Search: &search}
+
+	for name, filter := range sorts {
+		t.Run(name, func(t *testing.T) {
+			repo := &listingRepo{products: testProducts(10)}
+			s, _ := newListingTestService(t, repo)
+
+			want, _ := repo.GetAllProducts(context.Background(), model.GetAllProductsParams{Limit: len(repo.products), SortBy: filter.SortBy, Search: filter.Search})
+			got := listAll(t, s, filter)
+
+			if len(got) != len(want) {
+				t.Fatalf("%d products over all pages, want %d", len(got), len(want))
+			}
+			for i := range want {
+				if got[i] != want[i].ID {
+					t.Fatalf("product %d is %s, want %s", i, got[i], want[i].ID)
+				}
+			}
+		})
+	}
+}
+
+func TestProductListingLastPage(t *testing.T) {
+	repo := &listingRepo{products: testProducts(6)}
+	s, _ := newListingTestService(t, repo)
+
+	// A full last page: the extra row fetched shows there is nothing after it
+	page, err := s.GetAllProducts(context.Background(), model.GetAllProductsParams{Limit: 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Products) != 6 || page.NextCursor != "" {
+		t.Errorf("%d products with next cursor %q, want all 6 and none", len(page.Products), page.NextCursor)
+	}
+}
+
+func TestProductListingCachedWithCursor(t *testing.T) {
+	repo := &listingRepo{products: testProducts(6)}
+	s, _ := newListingTestService(t, repo)
+	filter := model.GetAllProductsParams{Limit: 2}
+
+	first, _ := s.GetAllProducts(context.Background(), filter)
+	cached, _ := s.GetAllProducts(context.Background(), filter)
+	if repo.queries != 1 {
+		t.Errorf("%d queries for the same page twice, want 1", repo.queries)
+	}
+	if cached.NextCursor != first.NextCursor || cached.NextCursor == "" {
+		t.Errorf("cached page continues at %q, first served at %q", cached.NextCursor, first.NextCursor)
+	}
+}
+
+func TestNextProductCursor(t *testing.T) {
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	last := model.Product{ID: uuid.New(), Price: 2500, Rank: 0.1, CreatedAt: created, UpdatedAt: created.Add(time.Hour)}
+	sortBy := func(s string) *string { return &s }
+	search := "kopi"
+
+	tests := []struct {
+		name   string
+		filter model.GetAllProductsParams
+		want   model.ProductCursor
+	}{
+		{"newest by last change", model.GetAllProductsParams{Limit: 5}, model.ProductCursor{SortBy: "newest", ID: last.ID, Time: last.UpdatedAt.UnixMicro()}},
+		{"oldest by creation", model.GetAllProductsParams{Limit: 5, SortBy: sortBy("oldest")}, model.ProductCursor{SortBy: "oldest", ID: last.ID, Time: created.UnixMicro()}},
+		{"cheapest", model.GetAllProductsParams{Limit: 5, SortBy: sortBy("cheapest")}, model.ProductCursor{SortBy: "cheapest", ID: last.ID, Price: 2500}},
+		{"expensive with search", model.GetAllProductsParams{Limit: 5, SortBy: sortBy("expensive"), Search: &search}, model.ProductCursor{SortBy: "expensive", ID: last.ID, Price: 2500}},
+		{"relevance by rank", model.GetAllProductsParams{Limit: 5, Offset: 10, Search: &search}, model.ProductCursor{ID: last.ID, Rank: 0.1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextProductCursor(tt.filter, last); got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProductCursorEncoding(t *testing.T) {
+	// A rank comes back to the bit, or the seek would skip or repeat rows
+	for _, cursor := range []model.ProductCursor{
+		{SortBy: "cheapest", ID: uuid.New(), Price: 1500},
+		{ID: uuid.New(), Rank: float32(2) / 3},
+	} {
+		decoded, err := DecodeProductCursor(EncodeProductCursor(cursor))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *decoded != cursor {
+			t.Errorf("decoded %+v, want %+v", *decoded, cursor)
+		}
+	}
+
+	for _, token := range []string{"not base64!", "bm90IGpzb24", EncodeProductCursor(model.ProductCursor{SortBy: "newest"})} {
+		if _, err := DecodeProductCursor(token); err == nil || err.Error() != "invalid cursor" {
+			t.Errorf("%q: got error %v", token, err)
+		}
+	}
+}
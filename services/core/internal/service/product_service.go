@@ -4,9 +4,12 @@ import (
 	"context"
 	"crypto/sha256"
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -23,7 +26,7 @@ import (
 
 type ProductServiceInterface interface {
 	CreateProduct(ctx context.Context, req model.ProductRequest) (model.ProductResponse, error)
-	GetAllProducts(ctx context.Context, filter model.GetAllProductsParams) ([]model.ProductResponse, error)
+	GetAllProducts(ctx context.Context, filter model.GetAllProductsParams) (model.ProductListResponse, error)
 	UpdateProduct(
 		ctx context.Context,
 		productID uuid.UUID,
@@ -68,22 +71,9 @@ func (s *ProductService) CreateProduct(ctx context.Context, req model.ProductReq
 		return model.ProductResponse{}, errors.New("sku already exists")
 	}
 
-	var parsedFileId uuid.UUID
-
-	fileID, err := uuid.Parse(req.FileID)
+	files, err := s.galleryFiles(ctx, req)
 	if err != nil {
-		return model.ProductResponse{}, err
-	}
-
-	parsedFileId = fileID
-
-	var file *clients.FileMetadataResponse
-	if req.FileID != "" {
-		f, err := s.fileClient.GetFileByID(ctx, parsedFileId)
-		if err != nil {
-			return model.ProductResponse{}, errors.New("file not found") // tetap pakai string ini untuk handler
-		}
-		file = f
+		return model.ProductResponse{}, errors.New("file not found") // tetap pakai string ini untuk handler
 	}
 
 	logger.DebugCtx(ctx, "Get request information", "request", req)
@@ -94,35 +84,74 @@ func (s *ProductService) CreateProduct(ctx context.Context, req model.ProductReq
 		return model.ProductResponse{}, fmt.Errorf("failed to create product: %w", err)
 	}
 
-	// Attach file info if present
-	if file != nil {
-		productResp.FileURI = file.FileURI
-		productResp.FileThumbnailURI = file.FileThumbnailURI
-	}
+	invalidateProducts(ctx, s.cache)
+
+	// Attach file info
+	attachFiles(&productResp, files)
 
 	log.Printf("[CreateProduct] Product created successfully with ID: %s", productResp.ProductID)
 
 	return productResp, nil
 }
 
-func (s *ProductService) GetAllProducts(ctx context.Context, filter model.GetAllProductsParams) ([]model.ProductResponse, error) {
-	key := cache.ProductListKey + generateFilterHash(filter)
+// GetAllProducts - One page of products. A cached page keeps the cursor it
+// was served with, so the next page follows on from what the buyer saw. Pages
+// are cached under the current listing generation, which every product write
+// advances; without it from Redis nothing is cached.
+func (s *ProductService) GetAllProducts(ctx context.Context, filter model.GetAllProductsParams) (model.ProductListResponse, error) {
+	var page model.ProductListResponse
 
-	var products []model.ProductResponse
-
-	err := s.cache.Get(ctx, key, &products)
+	key := ""
+	generation, err := s.cache.Generation(ctx, cache.ProductListGenerationKey)
 	if err == nil {
-		log.Printf("[GetAllProducts] Cache HIT for key: %s", key)
-		return products, nil
+		key = fmt.Sprintf(cache.ProductListKey, generation, generateFilterHash(filter))
+		if err := s.cache.Get(ctx, key, &page); err == nil {
+			log.Printf("[GetAllProducts] Cache HIT for key: %s", key)
+			return page, nil
+		}
+		log.Printf("[GetAllProducts] Cache MISS for key: %s", key)
 	}
 
-	log.Printf("[GetAllProducts] Cache MISS for key: %s", key)
-	productsDB, err := s.productRepo.GetAllProducts(ctx, filter)
+	// One extra row tells whether there is a next page
+	query := filter
+	query.Limit++
+	productsDB, err := s.productRepo.GetAllProducts(ctx, query)
 	if err != nil {
-		return nil, err
+		return model.ProductListResponse{}, err
 	}
 
-	var responses []model.ProductResponse
+	hasMore := len(productsDB) > filter.Limit
+	if hasMore {
+		productsDB = productsDB[:filter.Limit]
+	}
+
+	productIDs := make([]uuid.UUID, len(productsDB))
+	for i, p := range productsDB {
+		productIDs[i] = p.ID
+	}
+	variants, err := s.productRepo.GetProductVariants(ctx, productIDs)
+	if err != nil {
+		return model.ProductListResponse{}, err
+	}
+	images, err := s.productRepo.GetProductImages(ctx, productIDs)
+	if err != nil {
+		return model.ProductListResponse{}, err
+	}
+
+	// Every image on the page in one call to the files service
+	var fileIDs []string
+	for _, p := range productsDB {
+		fileIDs = append(fileIDs, p.FileID.String())
+		for _, image := range images[p.ID] {
+			fileIDs = append(fileIDs, image.FileID.String())
+		}
+	}
+	files, err := getFiles(ctx, s.fileClient, fileIDs)
+	if err != nil {
+		log.Printf("[GetAllProducts] Failed to fetch files: %v", err)
+	}
+
+	responses := []model.ProductResponse{}
 	for _, p := range productsDB {
 		resp := model.ProductResponse{
 			ProductID: p.ID,
@@ -134,27 +163,27 @@ func (s *ProductService) GetAllProducts(ctx context.Context, filter model.GetAll
 			FileID:    p.FileID,
 			CreatedAt: p.CreatedAt,
 			UpdatedAt: p.UpdatedAt,
+			Images:    images[p.ID],
+			Options:   variants[p.ID].Options,
+			Variants:  variants[p.ID].Variants,
 		}
-
-		if p.FileID != uuid.Nil {
-			file, err := s.fileClient.GetFileByID(ctx, p.FileID)
-			if err == nil {
-				resp.FileURI = file.FileURI
-				resp.FileThumbnailURI = file.FileThumbnailURI
-			} else {
-				log.Printf("[GetAllProducts] Failed to fetch file %s: %v", p.FileID, err)
-			}
-		}
+		attachFiles(&resp, files)
 
 		responses = append(responses, resp)
 	}
 
-	err = s.cache.Set(ctx, key, responses, cache.ProductListTTL)
-	if err != nil {
-		log.Printf("[GetAllProducts] Failed to set cache: %v", err)
+	page = model.ProductListResponse{Products: responses}
+	if hasMore {
+		page.NextCursor = EncodeProductCursor(nextProductCursor(filter, productsDB[len(productsDB)-1]))
 	}
 
-	return responses, nil
+	if key != "" {
+		if err := s.cache.Set(ctx, key, page, cache.ProductListTTL); err != nil {
+			log.Printf("[GetAllProducts] Failed to set cache: %v", err)
+		}
+	}
+
+	return page, nil
 }
 
 func (s *ProductService) UpdateProduct(
@@ -186,26 +215,18 @@ func (s *ProductService) UpdateProduct(
 			return model.ProductResponse{}, errors.New("sku already exists for this user")
 		}
 	}
-	var fileMetadata *clients.FileMetadataResponse
-
-	var parsedFileId uuid.UUID
-
-	fileID, err := uuid.Parse(req.FileID)
+	files, err := s.galleryFiles(ctx, req)
 	if err != nil {
-		return model.ProductResponse{}, err
+		return model.ProductResponse{}, errors.New("fileId is not valid / exists")
 	}
 
-	parsedFileId = fileID
-
-	if req.FileID != "" {
-		file, err := s.fileClient.GetFileByID(ctx, parsedFileId)
-		if err != nil {
-			return model.ProductResponse{}, errors.New("fileId is not valid / exists")
-		}
-		fileMetadata = file
+	_, cover := req.Gallery()
+	parsedFileId, err := uuid.Parse(cover)
+	if err != nil {
+		return model.ProductResponse{}, err
 	}
 
-	updatedRow, err := s.productRepo.UpdateProduct(ctx, database.UpdateProductParams{
+	resp, err := s.productRepo.UpdateProduct(ctx, database.UpdateProductParams{
 		ID:        productID,
 		Name:      req.Name,
 		Category:  req.Category,
@@ -214,35 +235,13 @@ func (s *ProductService) UpdateProduct(
 		Sku:       req.SKU,
 		FileID:    parsedFileId,
 		UpdatedAt: time.Now(),
-	})
+	}, req)
 	if err != nil {
 		return model.ProductResponse{}, err
 	}
+	invalidateProducts(ctx, s.cache)
 
-	resp := model.ProductResponse{
-		ProductID:        updatedRow.ID,
-		Name:             updatedRow.Name,
-		Category:         updatedRow.Category,
-		Qty:              int(updatedRow.Qty),
-		Price:            int(updatedRow.Price),
-		SKU:              updatedRow.Sku,
-		FileID:           updatedRow.FileID,
-		CreatedAt:        updatedRow.CreatedAt,
-		UpdatedAt:        updatedRow.UpdatedAt,
-		FileURI:          "",
-		FileThumbnailURI: "",
-	}
-
-	if fileMetadata != nil {
-		resp.FileURI = fileMetadata.FileURI
-		resp.FileThumbnailURI = fileMetadata.FileThumbnailURI
-	} else if updatedRow.FileID != uuid.Nil {
-		file, err := s.fileClient.GetFileByID(ctx, updatedRow.FileID)
-		if err == nil {
-			resp.FileURI = file.FileURI
-			resp.FileThumbnailURI = file.FileThumbnailURI
-		}
-	}
+	attachFiles(&resp, files)
 
 	return resp, nil
 }
@@ -263,10 +262,74 @@ func (s *ProductService) DeleteProduct(ctx context.Context, productID uuid.UUID,
 	if err != nil {
 		return err
 	}
+	invalidateProducts(ctx, s.cache)
 
 	return nil
 }
 
+// invalidateProducts - Retire every cached listing once a write to products has
+// committed. Redis is shared by all replicas, so none serves a page cached
+// before the write after this returns.
+func invalidateProducts(ctx context.Context, c *cache.RedisCache) {
+	if err := c.BumpGeneration(ctx, cache.ProductListGenerationKey); err != nil {
+		logger.ErrorCtx(ctx, "Failed to invalidate product cache", "error", err.Error())
+	}
+}
+
+// getFiles - The metadata of fileIDs by ID, fetched in one call. Files the
+// files service doesn't know are left out.
+func getFiles(ctx context.Context, fileClient clients.FileClientInterface, fileIDs []string) (map[uuid.UUID]*clients.FileMetadataResponse, error) {
+	slices.Sort(fileIDs)
+	fileIDs = slices.Compact(fileIDs)
+
+	found, err := fileClient.GetFilesByIDList(ctx, fileIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[uuid.UUID]*clients.FileMetadataResponse, len(found))
+	for _, file := range found {
+		files[file.ID] = file
+	}
+	return files, nil
+}
+
+// galleryFiles - The metadata of every image of req's gallery, or an error if
+// any of them doesn't exist
+func (s *ProductService) galleryFiles(ctx context.Context, req model.ProductRequest) (map[uuid.UUID]*clients.FileMetadataResponse, error) {
+	gallery, _ := req.Gallery()
+	files, err := getFiles(ctx, s.fileClient, slices.Clone(gallery))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range gallery {
+		fileID, err := uuid.Parse(id)
+		if err != nil {
+			return nil, err
+		}
+		if files[fileID] == nil {
+			return nil, errors.New("file not found")
+		}
+	}
+	return files, nil
+}
+
+// attachFiles - Fill in the URIs of resp's cover and gallery from files. Those
+// missing stay empty.
+func attachFiles(resp *model.ProductResponse, files map[uuid.UUID]*clients.FileMetadataResponse) {
+	if file := files[resp.FileID]; file != nil {
+		resp.FileURI = file.FileURI
+		resp.FileThumbnailURI = file.FileThumbnailURI
+	}
+	for i, image := range resp.Images {
+		if file := files[image.FileID]; file != nil {
+			resp.Images[i].FileURI = file.FileURI
+			resp.Images[i].FileThumbnailURI = file.FileThumbnailURI
+		}
+	}
+}
+
 func generateFilterHash(filter model.GetAllProductsParams) string {
 	// Konversi semua field filter ke string, urutkan agar konsisten
 	var parts []string
@@ -283,12 +346,32 @@ func generateFilterHash(filter model.GetAllProductsParams) string {
 	if filter.SKU != nil {
 		parts = append(parts, fmt.Sprintf("sku=%s", *filter.SKU))
 	}
-	if filter.Category != nil {
-		parts = append(parts, fmt.Sprintf("category=%s", *filter.Category))
+	if len(filter.Categories) > 0 {
+		categories := slices.Clone(filter.Categories)
+		sort.Strings(categories)
+		parts = append(parts, fmt.Sprintf("category=%s", strings.Join(categories, ",")))
+	}
+	if filter.SellerID != nil {
+		parts = append(parts, fmt.Sprintf("userId=%s", filter.SellerID.String()))
+	}
+	if filter.MinPrice != nil {
+		parts = append(parts, fmt.Sprintf("minPrice=%d", *filter.MinPrice))
+	}
+	if filter.MaxPrice != nil {
+		parts = append(parts, fmt.Sprintf("maxPrice=%d", *filter.MaxPrice))
+	}
+	if filter.InStock {
+		parts = append(parts, "inStock=true")
+	}
+	if filter.Search != nil {
+		parts = append(parts, fmt.Sprintf("q=%s", *filter.Search))
 	}
 	if filter.SortBy != nil {
 		parts = append(parts, fmt.Sprintf("sortBy=%s", *filter.SortBy))
 	}
+	if filter.Cursor != nil {
+		parts = append(parts, fmt.Sprintf("cursor=%s", EncodeProductCursor(*filter.Cursor)))
+	}
 
 	// Urutkan agar key konsisten meski parameter beda urutan
 	sort.Strings(parts)
@@ -298,3 +381,52 @@ func generateFilterHash(filter model.GetAllProductsParams) string {
 	hash := sha256.Sum256([]byte(input))
 	return fmt.Sprintf("%x", hash[:])
 }
+
+// nextProductCursor - The cursor of the page after the one ending with last
+func nextProductCursor(filter model.GetAllProductsParams, last model.Product) model.ProductCursor {
+	cursor := model.ProductCursor{SortBy: filter.Sort(), ID: last.ID}
+	switch cursor.SortBy {
+	case "newest":
+		cursor.Time = latest(last.CreatedAt, last.UpdatedAt).UnixMicro()
+	case "oldest":
+		cursor.Time = earliest(last.CreatedAt, last.UpdatedAt).UnixMicro()
+	case "cheapest", "expensive":
+		cursor.Price = last.Price
+	case "":
+		cursor.Rank = last.Rank
+	}
+	return cursor
+}
+
+// EncodeProductCursor - The opaque token clients pass back as ?cursor=
+func EncodeProductCursor(cursor model.ProductCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeProductCursor - Parse a token from EncodeProductCursor
+func DecodeProductCursor(token string) (*model.ProductCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	var cursor model.ProductCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == uuid.Nil {
+		return nil, errors.New("invalid cursor")
+	}
+	return &cursor, nil
+}
+
+func latest(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func earliest(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
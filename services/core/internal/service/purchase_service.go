@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/teammachinist/tutuplapak/services/core/internal/cache"
 	"github.com/teammachinist/tutuplapak/services/core/internal/clients"
 	"github.com/teammachinist/tutuplapak/services/core/internal/database"
+	"github.com/teammachinist/tutuplapak/services/core/internal/logger"
 	"github.com/teammachinist/tutuplapak/services/core/internal/model"
 	"github.com/teammachinist/tutuplapak/services/core/internal/repository"
 
@@ -23,6 +25,7 @@ type PurchaseService struct {
 	purchaseRepo repository.PurchaseRepositoryInterface
 	productRepo  repository.ProductRepositoryInterface
 	fileClient   clients.FileClientInterface
+	cache        *cache.RedisCache
 }
 
 func (s *PurchaseService) CreatePurchase(ctx context.Context, req model.PurchaseRequest) (model.PurchaseResponse, error) {
@@ -31,15 +34,17 @@ func (s *PurchaseService) CreatePurchase(ctx context.Context, req model.Purchase
 		return model.PurchaseResponse{}, err
 	}
 
+	// Every item's image in one call; those that fail stay empty
+	fileIDs := make([]string, len(resp.PurchasedItems))
 	for i, item := range resp.PurchasedItems {
-		if item.FileID != uuid.Nil && s.fileClient != nil {
-			fileMeta, err := s.fileClient.GetFileByID(ctx, item.FileID)
-			if err == nil {
-				resp.PurchasedItems[i].FileURI = fileMeta.FileURI
-				resp.PurchasedItems[i].FileThumbnailURI = fileMeta.FileThumbnailURI
-			}
-			// Jika error, biarkan kosong
-		}
+		fileIDs[i] = item.FileID.String()
+	}
+	files, err := getFiles(ctx, s.fileClient, fileIDs)
+	if err != nil {
+		logger.WarnCtx(ctx, "Failed to fetch purchased item files", "error", err.Error())
+	}
+	for i := range resp.PurchasedItems {
+		attachFiles(&resp.PurchasedItems[i], files)
 	}
 
 	return resp, nil
@@ -66,21 +71,37 @@ func (s *PurchaseService) UploadPaymentProof(ctx context.Context, purchaseId str
 		return fmt.Errorf("expected %d payment proof files, got %d", len(purchase.PaymentDetails), len(req))
 	}
 
-	// Validasi file IDs
-	_, err = s.fileClient.GetFilesByIDList(ctx, req)
+	// Validasi file IDs. Files that don't exist are left out of the result,
+	// and a file given twice comes back once.
+	files, err := s.fileClient.GetFilesByIDList(ctx, req)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "invalid") {
 			return fmt.Errorf("invalid or non-existent file IDs")
 		}
 		return fmt.Errorf("failed to validate file IDs: %w", err)
 	}
+	if len(files) != len(req) {
+		return fmt.Errorf("invalid or non-existent file IDs")
+	}
 
-	// Kurangi stok produk
+	// Kurangi stok produk. Listings showing the old stock are retired even when
+	// a later item fails.
+	decremented := false
+	defer func() {
+		if decremented {
+			invalidateProducts(ctx, s.cache)
+		}
+	}()
 	for _, item := range purchase.PurchasedItems {
-		err = s.productRepo.UpdateProductQty(ctx, item.ProductID.String(), item.Qty)
+		if item.VariantID != nil {
+			err = s.productRepo.UpdateVariantQty(ctx, item.ProductID, *item.VariantID, item.Qty)
+		} else {
+			err = s.productRepo.UpdateProductQty(ctx, item.ProductID.String(), item.Qty)
+		}
 		if err != nil {
 			return fmt.Errorf("failed to reduce stock for product %s: %w", item.ProductID, err)
 		}
+		decremented = true
 	}
 
 	// Update status jadi paid
@@ -96,11 +117,12 @@ func NewPurchaseService(
 	purchaseRepo repository.PurchaseRepositoryInterface,
 	productRepo repository.ProductRepositoryInterface,
 	fileClient clients.FileClientInterface,
-
+	cache *cache.RedisCache,
 ) PurchaseServiceInterface {
 	return &PurchaseService{
 		purchaseRepo: purchaseRepo,
 		productRepo:  productRepo,
 		fileClient:   fileClient,
+		cache:        cache,
 	}
 }
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/teammachinist/tutuplapak/services/core/internal/clients"
+	"github.com/teammachinist/tutuplapak/services/core/internal/database"
+	"github.com/teammachinist/tutuplapak/services/core/internal/model"
+	"github.com/teammachinist/tutuplapak/services/core/internal/repository"
+)
+
+// unpaidPurchase - one purchase awaiting proof for two sellers
+type unpaidPurchase struct {
+	repository.PurchaseRepositoryInterface
+	purchase model.PurchaseResponse
+	paid     bool
+}
+
+func (r *unpaidPurchase) GetPurchaseByid(ctx context.Context, purchaseId string) (model.PurchaseResponse, error) {
+	return r.purchase, nil
+}
+
+func (r *unpaidPurchase) UpdatePurchaseStatus(ctx context.Context, purchaseId string, newStatus database.PurchaseStatus) error {
+	r.paid = newStatus == database.PurchaseStatusPaid
+	return nil
+}
+
+// existingFiles - a files service knowing only some files
+type existingFiles struct {
+	clients.FileClientInterface
+	ids map[string]bool
+}
+
+func (f existingFiles) GetFilesByIDList(ctx context.Context, fileIDs []string) ([]*clients.FileMetadataResponse, error) {
+	var files []*clients.FileMetadataResponse
+	seen := make(map[string]bool)
+	for _, id := range fileIDs {
+		if f.ids[id] && !seen[id] {
+			seen[id] = true
+			files = append(files, &clients.FileMetadataResponse{ID: uuid.MustParse(id)})
+		}
+	}
+	return files, nil
+}
+
+func TestUploadPaymentProofFiles(t *testing.T) {
+	proofA, proofB := uuid.NewString(), uuid.NewString()
+
+	tests := []struct {
+		name    string
+		fileIDs []string
+		wantErr string
+	}{
+		{"one per payment", []string{proofA, proofB}, ""},
+		{"missing file", []string{proofA, uuid.NewString()}, "invalid or non-existent file IDs"},
+		{"same file twice", []string{proofA, proofA}, "invalid or non-existent file IDs"},
+		{"too few", []string{proofA}, "expected 2 payment proof files, got 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			purchases := &unpaidPurchase{purchase: model.PurchaseResponse{
+				PurchaseID:     uuid.New(),
+				PaymentDetails: make([]model.PaymentDetail, 2),
+				Status:         model.PurchaseStatusUnpaid,
+			}}
+			redisCache, _ := newTestCache(t)
+			s := &PurchaseService{
+				purchaseRepo: purchases,
+				fileClient:   existingFiles{ids: map[string]bool{proofA: true, proofB: true}},
+				cache:        redisCache,
+			}
+
+			err := s.UploadPaymentProof(context.Background(), purchases.purchase.PurchaseID.String(), tt.fileIDs)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("rejected: %v", err)
+			case tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr):
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+			if purchases.paid != (tt.wantErr == "") {
+				t.Errorf("purchase paid: %v", purchases.paid)
+			}
+		})
+	}
+}
@@ -16,15 +16,23 @@ import (
 	"github.com/teammachinist/tutuplapak/services/core/internal/repository"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type UserServiceInterface interface {
-	LinkPhone(ctx context.Context, userID uuid.UUID, phone string) (*model.LinkPhoneResponse, error)
-	LinkEmail(ctx context.Context, userID uuid.UUID, email string) (*model.LinkEmailResponse, error)
+	LinkPhone(ctx context.Context, userID uuid.UUID, phone string, client authz.ClientInfo) (*model.LinkPendingResponse, error)
+	LinkEmail(ctx context.Context, userID uuid.UUID, email string, client authz.ClientInfo) (*model.LinkPendingResponse, error)
+	VerifyLinkPhone(ctx context.Context, userID uuid.UUID, code string, client authz.ClientInfo) (*model.LinkPhoneResponse, error)
+	VerifyLinkEmail(ctx context.Context, userID uuid.UUID, code string, client authz.ClientInfo) (*model.LinkEmailResponse, error)
 	GetUserWithFileId(ctx context.Context, userID uuid.UUID) (model.UserResponse, error)
 	UpdateUser(ctx context.Context, userId uuid.UUID, req model.UserRequest) (model.UserResponse, error)
 	CreateUserFromAuth(ctx context.Context, req model.CreateUserFromAuthRequest) (*model.CreateUserFromAuthResponse, error)
 	GetUserFromAuth(ctx context.Context, userAuthUUID uuid.UUID) (*model.GetUserFromAuthResponse, error)
+	ListUsersFromAuth(ctx context.Context, afterUserAuthID uuid.UUID, limit int32) (*model.ListUsersFromAuthResponse, error)
+	DeleteAccount(ctx context.Context, userID uuid.UUID, password, totpCode, clientIP string) (*model.DeleteUserResponse, error)
+	DeleteUserFromAuth(ctx context.Context, userAuthID uuid.UUID) (*model.DeleteUserFromAuthResponse, error)
+	MergeUsersFromAuth(ctx context.Context, req model.MergeUsersFromAuthRequest) (*model.MergeUsersFromAuthResponse, error)
 }
 
 type UserService struct {
@@ -48,7 +56,9 @@ func NewUserService(
 	}
 }
 
-func (s *UserService) LinkPhone(ctx context.Context, userID uuid.UUID, phone string) (*model.LinkPhoneResponse, error) {
+// LinkPhone - Send an OTP to the phone, it is only linked once VerifyLinkPhone
+// redeems the code
+func (s *UserService) LinkPhone(ctx context.Context, userID uuid.UUID, phone string, client authz.ClientInfo) (*model.LinkPendingResponse, error) {
 	if err := s.validatePhone(phone); err != nil {
 		return nil, err
 	}
@@ -61,29 +71,32 @@ func (s *UserService) LinkPhone(ctx context.Context, userID uuid.UUID, phone str
 		return nil, errors.New("phone is taken")
 	}
 
-	err = s.userRepo.UpdateUserPhone(ctx, userID, phone)
+	return s.startLink(ctx, userID, authz.LinkChannelPhone, phone, client)
+}
+
+// VerifyLinkPhone - Redeem the OTP and commit the pending phone to both services
+func (s *UserService) VerifyLinkPhone(ctx context.Context, userID uuid.UUID, code string, client authz.ClientInfo) (*model.LinkPhoneResponse, error) {
+	phone, err := s.confirmLink(ctx, userID, authz.LinkChannelPhone, code, client)
 	if err != nil {
 		return nil, err
 	}
 
-	user, err := s.userRepo.GetUserByID(ctx, userID)
-	if err != nil {
+	if err := s.userRepo.UpdateUserPhone(ctx, userID, phone); err != nil {
 		return nil, err
 	}
+	s.invalidateUserProfile(ctx, userID)
 
-	// Update auth service (sync, don't fail on error)
-	userAuthID := user.UserAuthID.String()
-	if err := s.authzClient.UpdateUserAuth(userAuthID, phone, ""); err != nil {
-		logger.WarnCtx(ctx, "Failed to sync phone with auth service",
-			"user_id", userID.String(),
-			"user_auth_id", userAuthID,
-			"error", err.Error())
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
 	}
 
 	// Build response more efficiently
 	response := &model.LinkPhoneResponse{
 		Email:             user.Email,
 		Phone:             user.Phone,
+		EmailVerified:     user.EmailVerified,
+		PhoneVerified:     user.PhoneVerified,
 		BankAccountName:   user.BankAccountName,
 		BankAccountHolder: user.BankAccountHolder,
 		BankAccountNumber: user.BankAccountNumber,
@@ -102,7 +115,9 @@ func (s *UserService) LinkPhone(ctx context.Context, userID uuid.UUID, phone str
 	return response, nil
 }
 
-func (s *UserService) LinkEmail(ctx context.Context, userID uuid.UUID, email string) (*model.LinkEmailResponse, error) {
+// LinkEmail - Send an OTP to the email, it is only linked once VerifyLinkEmail
+// redeems the code
+func (s *UserService) LinkEmail(ctx context.Context, userID uuid.UUID, email string, client authz.ClientInfo) (*model.LinkPendingResponse, error) {
 	if err := s.validateEmail(email); err != nil {
 		return nil, err
 	}
@@ -115,23 +130,24 @@ func (s *UserService) LinkEmail(ctx context.Context, userID uuid.UUID, email str
 		return nil, errors.New("email is taken")
 	}
 
-	err = s.userRepo.UpdateUserEmail(ctx, userID, email)
+	return s.startLink(ctx, userID, authz.LinkChannelEmail, email, client)
+}
+
+// VerifyLinkEmail - Redeem the OTP and commit the pending email to both services
+func (s *UserService) VerifyLinkEmail(ctx context.Context, userID uuid.UUID, code string, client authz.ClientInfo) (*model.LinkEmailResponse, error) {
+	email, err := s.confirmLink(ctx, userID, authz.LinkChannelEmail, code, client)
 	if err != nil {
 		return nil, err
 	}
 
-	user, err := s.userRepo.GetUserByID(ctx, userID)
-	if err != nil {
+	if err := s.userRepo.UpdateUserEmail(ctx, userID, email); err != nil {
 		return nil, err
 	}
+	s.invalidateUserProfile(ctx, userID)
 
-	// Update auth service (sync, don't fail on error)
-	userAuthID := user.UserAuthID.String()
-	if err := s.authzClient.UpdateUserAuth(userAuthID, "", email); err != nil {
-		logger.WarnCtx(ctx, "Failed to sync email with auth service",
-			"user_id", userID.String(),
-			"user_auth_id", userAuthID,
-			"error", err.Error())
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
 	}
 
 	// Get file data if fileID exists
@@ -154,6 +170,8 @@ func (s *UserService) LinkEmail(ctx context.Context, userID uuid.UUID, email str
 		BankAccountName:   "",
 		BankAccountHolder: "",
 		BankAccountNumber: "",
+		EmailVerified:     user.EmailVerified,
+		PhoneVerified:     user.PhoneVerified,
 	}
 
 	if user.Email != "" {
@@ -183,6 +201,71 @@ func (s *UserService) LinkEmail(ctx context.Context, userID uuid.UUID, email str
 	return response, nil
 }
 
+// startLink - Have auth hold the address as a pending link and send it an OTP.
+// Auth owns the codes so a link is committed to users_auth before it lands here.
+func (s *UserService) startLink(ctx context.Context, userID uuid.UUID, channel, target string, client authz.ClientInfo) (*model.LinkPendingResponse, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, errors.New("user does not exist")
+	}
+
+	pending, err := s.authzClient.StartLinkVerification(user.UserAuthID.String(), channel, target, client)
+	if err != nil {
+		logger.WarnCtx(ctx, "Failed to start link verification",
+			"user_id", userID.String(),
+			"channel", channel,
+			"error", err.Error())
+		return nil, linkError(channel, err)
+	}
+
+	return &model.LinkPendingResponse{
+		Target:    pending.Target,
+		ExpiresAt: pending.ExpiresAt,
+	}, nil
+}
+
+// confirmLink - Redeem the OTP with auth, which commits the link on users_auth
+// and hands back the verified address
+func (s *UserService) confirmLink(ctx context.Context, userID uuid.UUID, channel, code string, client authz.ClientInfo) (string, error) {
+	if code == "" {
+		return "", errors.New("code is required")
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return "", errors.New("user does not exist")
+	}
+
+	linked, err := s.authzClient.ConfirmLinkVerification(user.UserAuthID.String(), channel, code, client)
+	if err != nil {
+		logger.WarnCtx(ctx, "Failed to verify link",
+			"user_id", userID.String(),
+			"channel", channel,
+			"error", err.Error())
+		return "", linkError(channel, err)
+	}
+
+	return linked.Target, nil
+}
+
+// linkError - Translate auth's link errors into the messages handlers map
+func linkError(channel string, err error) error {
+	switch err.Error() {
+	case "email address already exists", "phone number already exists":
+		return fmt.Errorf("%s is taken", channel)
+	case "invalid or expired code", "verification code recently sent, try again later":
+		return err
+	default:
+		return fmt.Errorf("link verification failed: %w", err)
+	}
+}
+
+func (s *UserService) invalidateUserProfile(ctx context.Context, userID uuid.UUID) {
+	if err := s.cache.Delete(ctx, fmt.Sprintf(cache.UserFileListKey, userID.String())); err != nil {
+		logger.WarnCtx(ctx, "Failed to invalidate user profile cache", "user_id", userID.String(), "error", err.Error())
+	}
+}
+
 func (s *UserService) validatePhone(phone string) error {
 	if phone == "" {
 		return errors.New("phone is required")
@@ -212,6 +295,8 @@ func (s *UserService) GetUserWithFileId(ctx context.Context, userID uuid.UUID) (
 			BankAccountName:   userFile.BankAccountName,
 			BankAccountHolder: userFile.BankAccountHolder,
 			BankAccountNumber: userFile.BankAccountNumber,
+			EmailVerified:     userFile.EmailVerified,
+			PhoneVerified:     userFile.PhoneVerified,
 		}, nil
 	}
 
@@ -224,18 +309,13 @@ func (s *UserService) GetUserWithFileId(ctx context.Context, userID uuid.UUID) (
 	fileURI := ""
 	fileThumbnailURI := ""
 
-	if rows.FileID != nil {
-		var file *clients.FileMetadataResponse
-		if err := s.cache.Get(ctx, cache.FileMetadataKey, file); err != nil {
-			clientFile, err := s.fileClient.GetFileByID(ctx, *rows.FileID)
-			if err != nil {
-				return model.UserResponse{}, err
-			}
-			file = clientFile
+	// A file the files service can't serve leaves the URIs empty
+	if rows.FileID != nil && *rows.FileID != uuid.Nil {
+		fileID = rows.FileID.String()
+		if file, err := s.fileClient.GetFileByID(ctx, *rows.FileID); err == nil {
+			fileURI = file.FileURI
+			fileThumbnailURI = file.FileThumbnailURI
 		}
-		fileID = file.ID.String()
-		fileURI = file.FileURI
-		fileThumbnailURI = file.FileThumbnailURI
 	}
 
 	resp := model.UserResponse{
@@ -247,6 +327,8 @@ func (s *UserService) GetUserWithFileId(ctx context.Context, userID uuid.UUID) (
 		BankAccountName:   rows.BankAccountName,
 		BankAccountHolder: rows.BankAccountHolder,
 		BankAccountNumber: rows.BankAccountNumber,
+		EmailVerified:     rows.EmailVerified,
+		PhoneVerified:     rows.PhoneVerified,
 	}
 	if err = s.cache.Set(ctx, fmt.Sprintf(cache.UserFileListKey, userID.String()), resp, cache.FileListTTL); err != nil {
 		fmt.Printf("[UserFileList] Failed to set cache: %v", err)
@@ -295,13 +377,9 @@ func (s *UserService) UpdateUser(ctx context.Context,
 	// check exist & ownership
 	if req.FileID != nil && *req.FileID != "" && *req.FileID != uuid.Nil.String() {
 		// Check file exist
-		var file *clients.FileMetadataResponse
-		if err := s.cache.Get(ctx, cache.FileMetadataKey, file); err != nil {
-			clientFile, err := s.fileClient.GetFileByID(ctx, *fileUUID)
-			if err != nil {
-				return model.UserResponse{}, err
-			}
-			file = clientFile
+		file, err := s.fileClient.GetFileByID(ctx, *fileUUID)
+		if err != nil {
+			return model.UserResponse{}, err
 		}
 
 		// Check Ownership
@@ -334,6 +412,8 @@ func (s *UserService) UpdateUser(ctx context.Context,
 			BankAccountName:   rows.BankAccountName,
 			BankAccountHolder: rows.BankAccountHolder,
 			BankAccountNumber: rows.BankAccountNumber,
+			EmailVerified:     rows.EmailVerified,
+			PhoneVerified:     rows.PhoneVerified,
 		}
 
 		if err = s.cache.Set(ctx, fmt.Sprintf(cache.UserFileListKey, userID.String()), resp, cache.FileListTTL); err != nil {
@@ -363,6 +443,8 @@ func (s *UserService) UpdateUser(ctx context.Context,
 		BankAccountName:   rows.BankAccountName,
 		BankAccountHolder: rows.BankAccountHolder,
 		BankAccountNumber: rows.BankAccountNumber,
+		EmailVerified:     rows.EmailVerified,
+		PhoneVerified:     rows.PhoneVerified,
 	}
 
 	if err = s.cache.Set(ctx, fmt.Sprintf(cache.UserFileListKey, userID.String()), resp, cache.FileListTTL); err != nil {
@@ -420,10 +502,19 @@ func (s *UserService) CreateUserFromAuth(ctx context.Context, req model.CreateUs
 	// Generate new user ID
 	userID := uuid.New()
 
-	// Create user record
+	// Create user record. Auth retries until it gets an answer, so a concurrent
+	// delivery may have won the unique user_auth_id; hand back that row instead.
 	user, err := s.userRepo.CreateUserFromUserAuth(ctx, userID, userAuthID, req.Email, req.Phone)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create user: %w", err)
+		var pgErr *pgconn.PgError
+		if !errors.As(err, &pgErr) || pgErr.Code != "23505" {
+			return nil, fmt.Errorf("failed to create user: %w", err)
+		}
+
+		user, err = s.userRepo.GetUserByAuthID(ctx, userAuthID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create user: %w", err)
+		}
 	}
 
 	return &model.CreateUserFromAuthResponse{
@@ -433,3 +524,148 @@ func (s *UserService) CreateUserFromAuth(ctx context.Context, req model.CreateUs
 		Phone:      user.Phone,
 	}, nil
 }
+
+// ListUsersFromAuth - Page through users by user_auth_id, for auth's reconciler
+func (s *UserService) ListUsersFromAuth(ctx context.Context, afterUserAuthID uuid.UUID, limit int32) (*model.ListUsersFromAuthResponse, error) {
+	rows, err := s.userRepo.ListUsersByAuthIDPage(ctx, afterUserAuthID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	users := make([]model.UserFromAuthItem, 0, len(rows))
+	for _, row := range rows {
+		users = append(users, model.UserFromAuthItem{
+			ID:         row.ID.String(),
+			UserAuthID: row.UserAuthID.String(),
+			CreatedAt:  row.CreatedAt,
+		})
+	}
+
+	return &model.ListUsersFromAuthResponse{Users: users}, nil
+}
+
+// DeleteAccount - Have auth check the password and delete the account. The
+// profile, products and files are removed when auth's account.deleted event
+// reaches DeleteUserFromAuth.
+func (s *UserService) DeleteAccount(ctx context.Context, userID uuid.UUID, password, totpCode, clientIP string) (*model.DeleteUserResponse, error) {
+	if password == "" {
+		return nil, errors.New("password is required")
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, errors.New("user does not exist")
+	}
+
+	deletion, err := s.authzClient.DeleteAccount(user.UserAuthID.String(), authz.DeleteAccountRequest{
+		Password:  password,
+		TOTPCode:  totpCode,
+		IPAddress: clientIP,
+	})
+	if err != nil {
+		logger.WarnCtx(ctx, "Failed to delete account", "user_id", userID.String(), "error", err.Error())
+		switch err.Error() {
+		case "invalid credentials", "two-factor code required", "invalid two-factor code",
+			"account merge in progress", "too many failed login attempts":
+			return nil, err
+		}
+		return nil, fmt.Errorf("account deletion failed: %w", err)
+	}
+
+	logger.InfoCtx(ctx, "Account deletion requested", "user_id", userID.String(), "deletion_id", deletion.DeletionID)
+	return &model.DeleteUserResponse{
+		DeletionID: deletion.DeletionID,
+		Status:     deletion.Status,
+	}, nil
+}
+
+// DeleteUserFromAuth - Remove a profile whose auth account no longer exists:
+// scrub it from purchases, delete its files, then the profile with its
+// products. The profile goes last, so when a step fails the next call still
+// finds it and picks up from there. A user that is already gone is not an
+// error.
+func (s *UserService) DeleteUserFromAuth(ctx context.Context, userAuthID uuid.UUID) (*model.DeleteUserFromAuthResponse, error) {
+	user, err := s.userRepo.GetUserByAuthID(ctx, userAuthID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &model.DeleteUserFromAuthResponse{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	purchasesScrubbed, err := s.userRepo.ScrubPurchases(ctx, user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scrub purchases: %w", err)
+	}
+
+	filesDeleted, err := s.fileClient.DeleteUserFiles(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete files: %w", err)
+	}
+
+	productIDs, err := s.userRepo.DeleteUserWithProducts(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	s.invalidateUserProfile(ctx, user.ID)
+	if len(productIDs) > 0 {
+		invalidateProducts(ctx, s.cache)
+	}
+
+	logger.InfoCtx(ctx, "User deleted from auth", "user_id", user.ID.String(), "user_auth_id", userAuthID.String(),
+		"products_deleted", len(productIDs), "files_deleted", len(filesDeleted), "purchases_scrubbed", purchasesScrubbed)
+	return &model.DeleteUserFromAuthResponse{
+		ProductsDeleted:   int32(len(productIDs)),
+		FilesDeleted:      int32(len(filesDeleted)),
+		PurchasesScrubbed: int32(purchasesScrubbed),
+	}, nil
+}
+
+// MergeUsersFromAuth - Fold the source profile into the target for an auth
+// account merge. Files move first since deleting the source profile ends the
+// merge; auth redelivers until this succeeds, and a source that is already gone
+// only has its leftover files moved.
+func (s *UserService) MergeUsersFromAuth(ctx context.Context, req model.MergeUsersFromAuthRequest) (*model.MergeUsersFromAuthResponse, error) {
+	sourceID, err := uuid.Parse(req.SourceUserID)
+	if err != nil {
+		return nil, errors.New("invalid source user id")
+	}
+	targetID, err := uuid.Parse(req.TargetUserID)
+	if err != nil {
+		return nil, errors.New("invalid target user id")
+	}
+	if sourceID == targetID {
+		return nil, errors.New("cannot merge a user into itself")
+	}
+
+	if _, err := s.userRepo.GetUserByID(ctx, targetID); err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	filesMoved, err := s.fileClient.ReassignFiles(ctx, sourceID, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to move files: %w", err)
+	}
+
+	var productsMoved int64
+	if _, err := s.userRepo.GetUserByID(ctx, sourceID); err == nil {
+		productsMoved, err = s.userRepo.MergeUsers(ctx, sourceID, targetID, req.Email, req.Phone)
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge users: %w", err)
+		}
+	}
+
+	s.invalidateUserProfile(ctx, sourceID)
+	s.invalidateUserProfile(ctx, targetID)
+	if productsMoved > 0 {
+		invalidateProducts(ctx, s.cache)
+	}
+
+	logger.InfoCtx(ctx, "Users merged from auth", "merge_id", req.MergeID, "user_id", targetID.String(),
+		"source_user_id", sourceID.String(), "products_moved", productsMoved, "files_moved", len(filesMoved))
+	return &model.MergeUsersFromAuthResponse{
+		ProductsMoved: int32(productsMoved),
+		FilesMoved:    int32(len(filesMoved)),
+	}, nil
+}
@@ -67,22 +67,37 @@ func main() {
 
 	app.Use(fiberlog.New())
 	app.Use(requestid.New())
+	app.Use(impersonationLogger())
+
+	// Calls to other services are signed as core, /internal routes verify callers
+	serviceKeys, err := authz.ParseServiceKeys(cfg.App.InternalServiceKeys)
+	if err != nil {
+		log.Fatal("Invalid INTERNAL_SERVICE_KEYS:", err)
+	}
+	serviceSigner, err := authz.NewServiceSigner(authz.ServiceCore, serviceKeys)
+	if err != nil {
+		log.Fatal("Failed to initialize service signer:", err)
+	}
+	serviceVerifier := authz.NewServiceVerifier(serviceKeys)
 
 	// Initiate auth middleware and client
-	authClient := authz.NewAuthClient(cfg.App.AuthServiceURL)
-	authMiddleware := authz.NewAuthMiddleware(cfg.App.AuthServiceURL)
+	authClient := authz.NewAuthClient(cfg.App.AuthServiceURL, serviceSigner)
+	authMiddleware := authz.NewAuthMiddleware(cfg.App.AuthServiceURL, serviceSigner, cfg.JWT.Issuer, authz.NewRevocations(redisClient.Client()))
 
-	fileClient := clients.NewFileClient(cfg.App.FileUrl)
+	fileClient := clients.NewCachedFileClient(clients.NewFileClient(cfg.App.FileUrl, serviceSigner), redisClient)
 
-	productRepo := repository.NewProductRepository(database.Queries)
+	categoryRepo := repository.NewCategoryRepository(database.Queries)
+	productRepo := repository.NewProductRepository(database.Pool, database.Queries)
 	purchaseRepo := repository.NewPurchaseRepository(database.Pool, database.Queries)
-	userRepo := repository.NewUserRepository(database.Queries)
+	userRepo := repository.NewUserRepository(database.Pool, database.Queries)
 
+	categoryService := service.NewCategoryService(categoryRepo, redisClient)
 	productService := service.NewProductService(productRepo, fileClient, redisClient)
-	purchaseService := service.NewPurchaseService(purchaseRepo, productRepo, fileClient)
+	purchaseService := service.NewPurchaseService(purchaseRepo, productRepo, fileClient, redisClient)
 	userService := service.NewUserService(userRepo, fileClient, redisClient, authClient)
 
-	productHandler := handler.NewProductHandler(productService)
+	categoryHandler := handler.NewCategoryHandler(categoryService)
+	productHandler := handler.NewProductHandler(productService, categoryService)
 	purchaseHandler := handler.NewPurchaseHandler(purchaseService)
 	userHandler := handler.NewUserHandler(userService)
 
@@ -95,22 +110,35 @@ func main() {
 	// v1 := app.Group("/api/v1")
 	v1 := app.Group("/v1")
 
+	categories := v1.Group("/category")
+	{
+		categories.Get("", categoryHandler.ListCategories)
+		categories.Get("/all", authMiddleware.FiberMiddleware(), authz.RequirePermissionFiber(authz.PermissionCategoryManage), categoryHandler.ListAllCategories)
+		categories.Post("", authMiddleware.FiberMiddleware(), authz.RequirePermissionFiber(authz.PermissionCategoryManage), categoryHandler.CreateCategory)
+		categories.Put("/:categoryId", authMiddleware.FiberMiddleware(), authz.RequirePermissionFiber(authz.PermissionCategoryManage), categoryHandler.UpdateCategory)
+		categories.Delete("/:categoryId", authMiddleware.FiberMiddleware(), authz.RequirePermissionFiber(authz.PermissionCategoryManage), categoryHandler.DeleteCategory)
+	}
+
 	products := v1.Group("/product")
 	{
 		products.Get("", productHandler.GetAllProducts)
-		products.Post("", authMiddleware.FiberMiddleware(), productHandler.CreateProduct)
-		products.Put("/:productId", authMiddleware.FiberMiddleware(), productHandler.UpdateProduct)
-		products.Delete("/:productId", authMiddleware.FiberMiddleware(), productHandler.DeleteProduct)
+		products.Post("", authMiddleware.FiberMiddleware(), authz.RequirePermissionFiber(authz.PermissionProductCreate), productHandler.CreateProduct)
+		products.Put("/:productId", authMiddleware.FiberMiddleware(), authz.RequirePermissionFiber(authz.PermissionProductUpdate), productHandler.UpdateProduct)
+		products.Delete("/:productId", authMiddleware.FiberMiddleware(), authz.RequirePermissionFiber(authz.PermissionProductDelete), productHandler.DeleteProduct)
 
 	}
 
 	// User management endpoints (auth-protected)
 	user := v1.Group("/user")
 	{
-		user.Post("/link/phone", authMiddleware.FiberMiddleware(), userHandler.LinkPhone)
-		user.Post("/link/email", authMiddleware.FiberMiddleware(), userHandler.LinkEmail)
+		// Credentials and bank details stay out of reach of admins impersonating the user
+		user.Post("/link/phone", authMiddleware.FiberMiddleware(), authz.DenyImpersonationFiber(), userHandler.LinkPhone)
+		user.Post("/link/email", authMiddleware.FiberMiddleware(), authz.DenyImpersonationFiber(), userHandler.LinkEmail)
+		user.Post("/link/phone/verify", authMiddleware.FiberMiddleware(), authz.DenyImpersonationFiber(), userHandler.VerifyLinkPhone)
+		user.Post("/link/email/verify", authMiddleware.FiberMiddleware(), authz.DenyImpersonationFiber(), userHandler.VerifyLinkEmail)
 		user.Get("", authMiddleware.FiberMiddleware(), userHandler.GetUserWithFileId)
-		user.Put("", authMiddleware.FiberMiddleware(), userHandler.UpdateUser)
+		user.Put("", authMiddleware.FiberMiddleware(), authz.DenyImpersonationFiber(), userHandler.UpdateUser)
+		user.Delete("", authMiddleware.FiberMiddleware(), authz.DenyImpersonationFiber(), userHandler.DeleteUser)
 	}
 
 	purchase := v1.Group("/purchase")
@@ -119,10 +147,13 @@ func main() {
 		purchase.Post("/:purchaseId", purchaseHandler.UploadPaymentProof)
 	}
 
-	internal := app.Group("/internal")
+	internal := app.Group("/internal", serviceVerifier.InternalOnlyFiber(authz.ServiceAuth))
 	{
 		internal.Get("/user/:userAuthID", internalHandler.GetUserFromAuth)
 		internal.Post("/user", internalHandler.CreateUserFromAuth)
+		internal.Get("/user", internalHandler.ListUsersFromAuth)
+		internal.Delete("/user/:userAuthID", internalHandler.DeleteUserFromAuth)
+		internal.Post("/user/merge", internalHandler.MergeUsersFromAuth)
 	}
 
 	c := make(chan os.Signal, 1)
@@ -140,6 +171,27 @@ func main() {
 	}
 }
 
+// impersonationLogger - Log every request made with an impersonation token once
+// it has been handled. The claims are only there after a route's auth
+// middleware ran, hence checking after c.Next().
+func impersonationLogger() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+		if actorID, ok := authz.GetImpersonatorFromFiber(c); ok {
+			userID, _ := authz.GetUserIDFromFiber(c)
+			logger.Warn("Impersonated request",
+				"request_id", c.GetRespHeader(fiber.HeaderXRequestID),
+				"actor_user_id", actorID,
+				"user_id", userID,
+				"method", c.Method(),
+				"path", c.Path(),
+				"status", c.Response().StatusCode(),
+			)
+		}
+		return err
+	}
+}
+
 func getOptimalFiberConfig(env string) (bool, string, int) {
 	if env == "production" {
 		// 2 processes per container for optimal CPU usage
@@ -127,6 +127,11 @@ func (c *RedisCache) Ping(ctx context.Context) error {
 	return err
 }
 
+// Client - The underlying connection, for packages that read Redis directly
+func (c *RedisCache) Client() *redis.Client {
+	return c.client
+}
+
 func (c *RedisCache) Close() error {
 	logger.Info("Closing Redis connection")
 	return c.client.Close()
@@ -125,3 +125,32 @@ func (q *Queries) GetFilesByUserID(ctx context.Context, userID uuid.UUID) ([]Fil
 	}
 	return items, nil
 }
+
+const reassignFiles = `-- name: ReassignFiles :many
+UPDATE files SET user_id = $1 WHERE user_id = $2 RETURNING id
+`
+
+type ReassignFilesParams struct {
+	ToUserID   uuid.UUID `json:"to_user_id"`
+	FromUserID uuid.UUID `json:"from_user_id"`
+}
+
+func (q *Queries) ReassignFiles(ctx context.Context, arg ReassignFilesParams) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx, reassignFiles, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -16,6 +16,7 @@ type Querier interface {
 	GetFile(ctx context.Context, id uuid.UUID) (Files, error)
 	GetFilesByID(ctx context.Context, dollar_1 []uuid.UUID) ([]Files, error)
 	GetFilesByUserID(ctx context.Context, userID uuid.UUID) ([]Files, error)
+	ReassignFiles(ctx context.Context, arg ReassignFilesParams) ([]uuid.UUID, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -11,4 +11,7 @@ SELECT * FROM files WHERE user_id = $1;
 DELETE FROM files WHERE id = $1;
 
 -- name: GetFilesByID :many
-SELECT * FROM files WHERE id = ANY($1::uuid[]);
\ No newline at end of file
+SELECT * FROM files WHERE id = ANY($1::uuid[]);
+
+-- name: ReassignFiles :many
+UPDATE files SET user_id = @to_user_id WHERE user_id = @from_user_id RETURNING id;
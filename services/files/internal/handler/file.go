@@ -3,6 +3,7 @@ package handler
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"image"
 	"image/jpeg"
@@ -167,6 +168,79 @@ func (h *FileHandler) GetFiles(w http.ResponseWriter, r *http.Request) {
 	logger.InfoCtx(ctx, "Files retrieved successfully", "file_ids", files)
 	api.WriteSuccess(w, r, files)
 }
+
+// ReassignFiles moves one user's files to another when core merges two
+// accounts. Internal only, core signs the request.
+func (h *FileHandler) ReassignFiles(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req model.ReassignFilesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.WarnCtx(ctx, "Invalid reassign files request body", "error", err)
+		api.WriteBadRequest(w, r, "Invalid request body")
+		return
+	}
+	if req.FromUserID == uuid.Nil || req.ToUserID == uuid.Nil {
+		api.WriteBadRequest(w, r, "from_user_id and to_user_id are required")
+		return
+	}
+	if req.FromUserID == req.ToUserID {
+		api.WriteBadRequest(w, r, "from_user_id and to_user_id must differ")
+		return
+	}
+
+	fileIDs, err := h.fileService.ReassignFiles(ctx, req.FromUserID, req.ToUserID)
+	if err != nil {
+		logger.ErrorCtx(ctx, "Failed to reassign files", "from_user_id", req.FromUserID, "to_user_id", req.ToUserID, "error", err)
+		api.WriteInternalServerError(w, r, "Failed to reassign files")
+		return
+	}
+
+	api.WriteSuccess(w, r, model.ReassignFilesResponse{Reassigned: len(fileIDs), FileIDs: fileIDs})
+}
+
+// DeleteUserFiles removes every file of a deleted account, objects first so a
+// failure leaves the row behind for the retry to find. Internal only, core
+// signs the request.
+func (h *FileHandler) DeleteUserFiles(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userIDStr := chi.URLParam(r, "userId")
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		logger.WarnCtx(ctx, "Invalid user ID format", "user_id", userIDStr, "error", err)
+		api.WriteBadRequest(w, r, "Invalid user ID format")
+		return
+	}
+
+	files, err := h.fileService.ListUserFiles(ctx, userID)
+	if err != nil {
+		api.WriteInternalServerError(w, r, "Failed to get user files")
+		return
+	}
+
+	deleted := []uuid.UUID{}
+	for _, file := range files {
+		for _, uri := range []string{file.FileUri, file.FileThumbnailUri} {
+			if err := h.storage.RemoveFile(ctx, uri); err != nil {
+				logger.ErrorCtx(ctx, "Failed to remove object from MinIO", "file_id", file.ID, "uri", uri, "error", err)
+				api.WriteInternalServerError(w, r, "Failed to delete files")
+				return
+			}
+		}
+
+		if err := h.fileService.DeleteFiles(ctx, file.ID, userID.String()); err != nil {
+			logger.ErrorCtx(ctx, "Failed to delete file", "file_id", file.ID, "user_id", userID, "error", err)
+			api.WriteInternalServerError(w, r, "Failed to delete files")
+			return
+		}
+		deleted = append(deleted, file.ID)
+	}
+
+	logger.InfoCtx(ctx, "User files deleted", "user_id", userID, "count", len(deleted))
+	api.WriteSuccess(w, r, model.DeleteUserFilesResponse{Deleted: len(deleted), FileIDs: deleted})
+}
+
 func (h *FileHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
 	requestCtx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
 	defer cancel()
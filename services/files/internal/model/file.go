@@ -13,3 +13,21 @@ type File struct {
 	FileThumbnailURI string    `json:"fileThumbnailUri"`
 	CreatedAt        time.Time `json:"createdAt"`
 }
+
+type ReassignFilesRequest struct {
+	FromUserID uuid.UUID `json:"from_user_id"`
+	ToUserID   uuid.UUID `json:"to_user_id"`
+}
+
+// ReassignFilesResponse - FileIDs are the files moved by this call, for callers
+// to drop their cached copies
+type ReassignFilesResponse struct {
+	Reassigned int         `json:"reassigned"`
+	FileIDs    []uuid.UUID `json:"file_ids"`
+}
+
+// DeleteUserFilesResponse - FileIDs are the files deleted by this call
+type DeleteUserFilesResponse struct {
+	Deleted int         `json:"deleted"`
+	FileIDs []uuid.UUID `json:"file_ids"`
+}
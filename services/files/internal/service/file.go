@@ -242,6 +242,64 @@ func (s FileService) DeleteFiles(ctx context.Context, fileID uuid.UUID, userID s
 	return nil
 }
 
+// ReassignFiles moves every file owned by fromUserID to toUserID, used when
+// two accounts are merged, and returns the IDs of the files it moved. Running it
+// again after a success moves nothing.
+func (s FileService) ReassignFiles(ctx context.Context, fromUserID, toUserID uuid.UUID) ([]uuid.UUID, error) {
+	logger.InfoCtx(ctx, "Reassigning files", "from_user_id", fromUserID, "to_user_id", toUserID)
+
+	fileIDs, err := s.queries.ReassignFiles(ctx, database.ReassignFilesParams{
+		ToUserID:   toUserID,
+		FromUserID: fromUserID,
+	})
+	if err != nil {
+		logger.ErrorCtx(ctx, "Failed to reassign files",
+			"error", err,
+			"from_user_id", fromUserID,
+			"to_user_id", toUserID,
+		)
+		return nil, fmt.Errorf("failed to reassign files: %w", err)
+	}
+
+	// Cached metadata still names the old owner
+	for _, fileID := range fileIDs {
+		cacheKey := fmt.Sprintf(cache.FileMetadataKey, fileID.String())
+		if err := s.cache.Delete(ctx, cacheKey); err != nil {
+			logger.WarnCtx(ctx, "Failed to remove file from cache",
+				"error", err,
+				"file_id", fileID,
+				"cache_key", cacheKey,
+			)
+		}
+	}
+
+	// Invalidate both users' file list caches
+	for _, userID := range []uuid.UUID{fromUserID, toUserID} {
+		userCacheKey := fmt.Sprintf(cache.UserFileListKey, userID.String())
+		if err := s.cache.Delete(ctx, userCacheKey); err != nil {
+			logger.WarnCtx(ctx, "Failed to invalidate user file list cache",
+				"error", err,
+				"user_id", userID,
+				"cache_key", userCacheKey,
+			)
+		}
+	}
+
+	logger.InfoCtx(ctx, "Files reassigned successfully", "from_user_id", fromUserID, "to_user_id", toUserID, "count", len(fileIDs))
+	return fileIDs, nil
+}
+
+// ListUserFiles reads a user's files straight from the database, for when a
+// stale cached list would leave files behind
+func (s FileService) ListUserFiles(ctx context.Context, userID uuid.UUID) ([]database.Files, error) {
+	files, err := s.queries.GetFilesByUserID(ctx, userID)
+	if err != nil {
+		logger.ErrorCtx(ctx, "Failed to get user files from database", "error", err, "user_id", userID)
+		return nil, fmt.Errorf("failed to get user files: %w", err)
+	}
+	return files, nil
+}
+
 // GetUserFiles retrieves all files for a specific user (with caching)
 func (s FileService) GetUserFiles(ctx context.Context, userID string) ([]model.File, error) {
 	logger.DebugCtx(ctx, "Getting user files", "user_id", userID)
@@ -6,6 +6,7 @@ import (
 	"io"
 	"mime/multipart"
 	"path/filepath"
+	"strings"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
@@ -79,3 +80,16 @@ func (s *MinIOStorage) UploadFile(ctx context.Context, file io.Reader, header *m
 	// Return public URL
 	return fmt.Sprintf("%s/%s/%s", s.config.PublicEndpoint, s.config.BucketName, objectName), nil
 }
+
+// RemoveFile deletes the object behind a URI returned by UploadFile. URIs
+// pointing elsewhere are left alone, and removing a missing object is not an
+// error, so a failed batch can be retried.
+func (s *MinIOStorage) RemoveFile(ctx context.Context, uri string) error {
+	prefix := fmt.Sprintf("%s/%s/", s.config.PublicEndpoint, s.config.BucketName)
+	objectName, ok := strings.CutPrefix(uri, prefix)
+	if !ok || objectName == "" {
+		return nil
+	}
+
+	return s.client.RemoveObject(ctx, s.config.BucketName, objectName, minio.RemoveObjectOptions{})
+}
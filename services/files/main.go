@@ -27,6 +27,9 @@ type Config struct {
 	DatabaseURL    string `env:"DATABASE_URL" envDefault:""`
 	AuthServiceURL string `env:"AUTH_SERVICE_URL" envDefault:""`
 
+	// Service-to-service signing keys, "auth:secret,core:secret,files:secret"
+	InternalServiceKeys string `env:"INTERNAL_SERVICE_KEYS" envDefault:""`
+
 	// JWT Configuration
 	JWTSecret   string        `env:"JWT_SECRET" envDefault:"tutupsecret"`
 	JWTDuration time.Duration `env:"JWT_DURATION" envDefault:"24h"`
@@ -47,11 +50,12 @@ type Config struct {
 }
 
 type Dependencies struct {
-	DB             *database.DB
-	RedisCache     *cache.RedisCache
-	AuthClient     *authz.AuthClient
-	AuthMiddleware *authz.AuthMiddleware
-	MinIO          *storage.MinIOStorage
+	DB              *database.DB
+	RedisCache      *cache.RedisCache
+	AuthClient      *authz.AuthClient
+	AuthMiddleware  *authz.AuthMiddleware
+	ServiceVerifier *authz.ServiceVerifier
+	MinIO           *storage.MinIOStorage
 }
 
 type Services struct {
@@ -130,8 +134,20 @@ func setupDependencies(cfg Config) Dependencies {
 	}
 
 	// Initialize authz middleware and client
-	authClient := authz.NewAuthClient(cfg.AuthServiceURL)
-	authMiddleware := authz.NewAuthMiddleware(cfg.AuthServiceURL)
+	serviceKeys, err := authz.ParseServiceKeys(cfg.InternalServiceKeys)
+	if err != nil {
+		logger.Error("Invalid INTERNAL_SERVICE_KEYS", "error", err)
+		os.Exit(1)
+	}
+
+	serviceSigner, err := authz.NewServiceSigner(authz.ServiceFiles, serviceKeys)
+	if err != nil {
+		logger.Error("Failed to initialize service signer", "error", err)
+		os.Exit(1)
+	}
+
+	authClient := authz.NewAuthClient(cfg.AuthServiceURL, serviceSigner)
+	authMiddleware := authz.NewAuthMiddleware(cfg.AuthServiceURL, serviceSigner, cfg.JWTIssuer, authz.NewRevocations(redisCache.Client()))
 
 	// Initialize MinIO storage
 	minioConfig := &storage.MinIOConfig{
@@ -151,11 +167,12 @@ func setupDependencies(cfg Config) Dependencies {
 	logger.Info("MinIO storage initialized", "endpoint", cfg.MinIOEndpoint, "bucket", cfg.MinIOBucket)
 
 	return Dependencies{
-		DB:             db,
-		RedisCache:     redisCache,
-		AuthClient:     authClient,
-		AuthMiddleware: authMiddleware,
-		MinIO:          minioStorage,
+		DB:              db,
+		RedisCache:      redisCache,
+		AuthClient:      authClient,
+		AuthMiddleware:  authMiddleware,
+		ServiceVerifier: authz.NewServiceVerifier(serviceKeys),
+		MinIO:           minioStorage,
 	}
 }
 
@@ -188,19 +205,23 @@ func setupRoutes(services Services, deps Dependencies) *chi.Mux {
 	r.Get("/healthz", services.HealthHandler.HealthCheck)
 	r.Get("/readyz", services.HealthHandler.ReadinessCheck)
 
-	// Pragmatically no auth for now, easier fetch for user and core services
-	// Can be changed to use `internal` prefix later, follow auth service design
-	// r.Get("/api/v1/file/{fileId}", services.FileHandler.GetFile)
-	// r.Get("/api/v1/file", services.FileHandler.GetFiles)
-	r.Get("/v1/file/{fileId}", services.FileHandler.GetFile)
-	r.Get("/v1/file", services.FileHandler.GetFiles)
+	// File metadata and account merges for core, only with a signed service request
+	r.Route("/internal", func(r chi.Router) {
+		r.Use(deps.ServiceVerifier.InternalOnlyChi(authz.ServiceCore))
+		r.Get("/file/{fileId}", services.FileHandler.GetFile)
+		r.Get("/file", services.FileHandler.GetFiles)
+		r.Post("/file/reassign", services.FileHandler.ReassignFiles)
+		r.Delete("/file/user/{userId}", services.FileHandler.DeleteUserFiles)
+	})
 
 	// API routes with authentication
 	// r.Route("/api/v1", func(r chi.Router) {
 	r.Route("/v1", func(r chi.Router) {
 		r.Use(deps.AuthMiddleware.ChiMiddleware)
+		r.Use(impersonationLoggerMiddleware)
 		r.Post("/file", services.FileHandler.UploadFile)
-		// r.Get("/file/{fileId}", services.FileHandler.GetFile)
+		r.Get("/file/{fileId}", services.FileHandler.GetFile)
+		r.Get("/file", services.FileHandler.GetFiles)
 		r.Delete("/file/{fileId}", services.FileHandler.DeleteFile)
 	})
 
@@ -303,6 +324,29 @@ func requestLoggerMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// Impersonation logging middleware, runs after the auth middleware has set the claims
+func impersonationLoggerMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actorID, ok := authz.GetImpersonatorFromChi(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		userID, _ := authz.GetUserIDFromChi(r)
+		logger.WarnCtx(r.Context(), "Impersonated request",
+			"actor_user_id", actorID,
+			"user_id", userID,
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", ww.Status(),
+		)
+	})
+}
+
 // Health handler
 func healthHandler(db *database.DB, redisCache *cache.RedisCache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {